@@ -14,25 +14,34 @@ CREATE TABLE IF NOT EXISTS workers
     username    varchar(20)     NOT NULL UNIQUE,
     worker_name varchar(50)     NOT NULL,
     hash        varchar(255)    NOT NULL,
+    role        enum ('worker', 'supervisor') NOT NULL DEFAULT 'worker', -- supervisors create and assign jobs
+    created_at  timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (worker_id),
     UNIQUE KEY (worker_name)
 ) ENGINE = InnoDB
   AUTO_INCREMENT = 6;
-INSERT INTO workers (worker_id, username, worker_name, hash)
-VALUES (141, 'john_shields', 'John Shields', '$2a$10$ttINUB.yZkZUKiKSBqRMf.jzRYIL8.MLMldre63SA5u9DtJjuvMNO'),
-       (174, 'steve_mon', 'Steve Maloney', '$2a$10$56hLopYTrwAvJs/4Q84vTOcC.T5KCUmR1.m92gcqkKBnQg7qnW8pW');
+INSERT INTO workers (worker_id, username, worker_name, hash, role)
+VALUES (141, 'john_shields', 'John Shields', '$2a$10$ttINUB.yZkZUKiKSBqRMf.jzRYIL8.MLMldre63SA5u9DtJjuvMNO', 'supervisor'),
+       (174, 'steve_mon', 'Steve Maloney', '$2a$10$56hLopYTrwAvJs/4Q84vTOcC.T5KCUmR1.m92gcqkKBnQg7qnW8pW', 'worker');
 COMMIT;
 
 
 CREATE TABLE IF NOT EXISTS jobreports
 (
     job_report_id       int(6) unsigned NOT NULL AUTO_INCREMENT,
-    worker_id           int(5) unsigned NOT NULL,
-    date_stamp          varchar(20)     NOT NULL,
+    worker_id           int(5) unsigned,          -- worker the job is assigned to, NULL while in the queue
+    created_by          int(5) unsigned,          -- supervisor who created the job, NULL for workers' own reports
+    assigned_at         timestamp       NULL,
+    date_stamp          date            NOT NULL,
     vehicle_model       varchar(60)     NOT NULL,
     vehicle_reg         varchar(60)     NOT NULL,
     vehicle_location    varchar(500)    NOT NULL,
-    miles_on_vehicle    int(20)         NOT NULL,
+    location_lat        decimal(8, 5)   NULL,     -- coordinates of vehicle_location from the gazetteer,
+    location_lon        decimal(8, 5)   NULL,     -- NULL if it is not a known place
+    odometer_km         int(20)         NOT NULL, -- canonical reading in kilometres
+    odometer_reading    int(20)         NOT NULL, -- reading as entered
+    odometer_unit       enum ('km', 'mi') NOT NULL DEFAULT 'mi', -- unit of reading as entered
     warranty            boolean         NOT NULL DEFAULT 1,
     breakdown           boolean         NOT NULL DEFAULT 0,
     cause               varchar(500),
@@ -40,28 +49,53 @@ CREATE TABLE IF NOT EXISTS jobreports
     parts               varchar(500),
     work_hours          int(10),
     job_report_complete boolean         NOT NULL DEFAULT 0,
+    created_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (job_report_id),
-    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+    INDEX (date_stamp),
+    INDEX (vehicle_reg),
+    INDEX (location_lat, location_lon),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
 ) ENGINE = InnoDB
   AUTO_INCREMENT = 6;
 INSERT INTO jobreports (job_report_id, worker_id, date_stamp, vehicle_model, vehicle_reg,
-                        vehicle_location,
-                        miles_on_vehicle, warranty, breakdown, cause, correction, parts, work_hours,
+                        vehicle_location, location_lat, location_lon,
+                        odometer_km, odometer_reading, odometer_unit, warranty, breakdown, cause, correction, parts, work_hours,
                         job_report_complete)
-VALUES (121, 141, '03-04-2020', 'Ford Focus', '151-DL-2308', 'Gort, Co. Galway', '508538', TRUE, FALSE,
+VALUES (121, 141, '2020-04-03', 'Ford Focus', '151-DL-2308', 'Gort, Co. Galway', 53.06610, -8.81890, 818413, 508538, 'mi', TRUE, FALSE,
         'The lock on the passenger door was broken.', 'A new lock has been fitted.', '1 DOOR LOCK', '1', TRUE),
-       (251, 174, '06-04-2020', 'Toyota Yaris', '08-KY-667', 'Laban, Co. Galway', '648598', TRUE, FALSE,
+       (251, 174, '2020-04-06', 'Toyota Yaris', '08-KY-667', 'Laban, Co. Galway', 53.15600, -8.80600, 1043817, 648598, 'mi', TRUE, FALSE,
         'The left back wheel bearing was worn.', 'Fitted a new wheel bearing.', '1 WHEEL BEARING', '2', TRUE),
-       (342, 174, '07-04-2020', 'Hyundai i30', '163-TS-1459', 'Barefield, Co. Clare', '700891', TRUE, FALSE,
+       (342, 174, '2020-04-07', 'Hyundai i30', '162-CE-1459', 'Barefield, Co. Clare', 52.88420, -8.95170, 1127975, 700891, 'mi', TRUE, FALSE,
         'The radio connections were disconnected.', 'The radio connections have been reconnected.', 'NONE', '1', TRUE),
-       (456, 141, '08-04-2020', 'Ford Mustang', '54-SF-135', 'Furbogh, Co. Galway', '1007538', TRUE, FALSE,
+       (456, 141, '2020-04-08', 'Ford Mustang', '04-G-135', 'Furbogh, Co. Galway', 53.24970, -9.19700, 1621475, 1007538, 'mi', TRUE, FALSE,
         'Worn out tyres.', 'New tyres have been fitted.', '4 TYRES', '1', TRUE),
-       (543, 141, '12-04-2020', 'Volkswagen Passat', '07-DL-298', 'Westside, Co. Galway', '708538', TRUE, FALSE,
+       (543, 141, '2020-04-12', 'Volkswagen Passat', '07-DL-298', 'Westside, Co. Galway', 53.27400, -9.07300, 1140281, 708538, 'mi', TRUE, FALSE,
         'Service on vehicle was due.', 'Serviced vehicle.', '1 OIL FILTER', '2', TRUE),
-       (651, 174, '14-04-2020', 'Honda Civic', '131-DL-298', 'Ballybane, Co. Galway', '318639', TRUE, TRUE,
+       (651, 174, '2020-04-14', 'Honda Civic', '131-DL-298', 'Ballybane, Co. Galway', 53.28300, -9.01300, 512800, 318639, 'mi', TRUE, TRUE,
         'Cables were eroded.', 'Entire system has been replaced.', '2 CABLES, 2 BRAKE PADS', '3', TRUE);
 COMMIT;
 
+-- notification_preferences table for how each customer is sent messages, the contact their reports and estimates --
+-- are linked to so customers with the same name are kept apart --
+CREATE TABLE IF NOT EXISTS notification_preferences
+(
+    contact_id    int(8) unsigned      NOT NULL AUTO_INCREMENT,
+    customer_name varchar(100)         NOT NULL,
+    email         varchar(100)         NOT NULL DEFAULT '',
+    phone         varchar(30)          NOT NULL DEFAULT '', -- in international format e.g. +353871234567
+    channels      set ('email', 'sms') NOT NULL DEFAULT 'email',
+    opted_out_at  datetime             NULL,                -- when the customer stopped messages, in UTC
+    opt_out_token varchar(64)          NOT NULL UNIQUE,     -- the link the customer opts out at
+    created_at    TIMESTAMP            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (contact_id),
+    INDEX (customer_name),
+    INDEX (email),
+    INDEX (phone)
+) ENGINE = InnoDB;
+
 -- customers table --
 CREATE TABLE IF NOT EXISTS customers
 (
@@ -69,8 +103,12 @@ CREATE TABLE IF NOT EXISTS customers
     job_report_id      int(6) unsigned NOT NULL,
     customer_name      varchar(50)     NOT NULL,
     customer_complaint varchar(500)    NOT NULL,
+    contact_id         int(8) unsigned NULL,     -- notification preferences the customer is sent messages with
+    created_at         timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at         timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (customer_id),
-    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (contact_id) REFERENCES notification_preferences (contact_id) ON DELETE SET NULL ON UPDATE CASCADE
 ) ENGINE = InnoDB
   AUTO_INCREMENT = 6;
 INSERT INTO customers (job_report_id, customer_name, customer_complaint)
@@ -82,12 +120,502 @@ VALUES (121, 'Freddie Quell', 'The passenger side door will not open.'),
        (651, 'Mick Fanning', 'The hand brake is stuck.');
 COMMIT;
 
+-- attachments table for photos and documents attached to reports --
+-- files are kept in the Blob Store under storage_key --
+CREATE TABLE IF NOT EXISTS attachments
+(
+    attachment_id int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id int(6) unsigned NOT NULL,
+    worker_id     int(5) unsigned NOT NULL, -- worker who uploaded the file
+    kind          enum ('before', 'after', 'diagnostic', 'signed_form', 'other') NOT NULL DEFAULT 'other',
+    file_name     varchar(255)    NOT NULL,
+    content_type  varchar(100)    NOT NULL,
+    size_bytes    bigint unsigned NOT NULL,
+    storage_key   varchar(255)    NOT NULL UNIQUE,
+    created_at    timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (attachment_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- signatures table for customer signatures at handover --
+-- report_hash is the SHA256 of the report when it was signed, a report that no longer matches was modified after signing --
+CREATE TABLE IF NOT EXISTS signatures
+(
+    signature_id   int(6) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id  int(6) unsigned NOT NULL UNIQUE, -- a report is signed once
+    worker_id      int(5) unsigned NOT NULL,        -- worker who took the signature
+    signer_name    varchar(50)     NOT NULL,
+    format         enum ('image', 'strokes') NOT NULL,
+    content_type   varchar(50)     NOT NULL,
+    data           mediumblob      NOT NULL,        -- PNG/JPEG image or JSON strokes
+    signature_hash char(64)        NOT NULL,
+    report_hash    char(64)        NOT NULL,
+    created_at     timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (signature_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- invoices table for invoices and credit notes of job reports, amounts are in cents --
+-- invoice_number is given from invoice_sequences when an invoice is issued, drafts have none --
+CREATE TABLE IF NOT EXISTS invoices
+(
+    invoice_id          int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id       int(6) unsigned NOT NULL,
+    worker_id           int(5) unsigned NOT NULL, -- worker who raised the invoice
+    kind                enum ('invoice', 'credit_note') NOT NULL DEFAULT 'invoice',
+    credited_invoice_id int(8) unsigned,          -- invoice a credit note credits
+    invoice_number      varchar(20) UNIQUE,
+    status              enum ('draft', 'issued', 'paid', 'void') NOT NULL DEFAULT 'draft',
+    bill_to             enum ('customer', 'warranty_provider') NOT NULL DEFAULT 'customer',
+    bill_to_name        varchar(100)    NOT NULL,
+    bill_to_address     varchar(500)    NOT NULL DEFAULT '',
+    currency            char(3)         NOT NULL,
+    subtotal_cents      bigint          NOT NULL DEFAULT 0,
+    vat_cents           bigint          NOT NULL DEFAULT 0,
+    total_cents         bigint          NOT NULL DEFAULT 0,
+    reason              varchar(500)    NOT NULL DEFAULT '',
+    issued_at           timestamp       NULL,
+    due_date            date,
+    created_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (invoice_id),
+    INDEX (status),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    FOREIGN KEY (credited_invoice_id) REFERENCES invoices (invoice_id) ON DELETE RESTRICT ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- invoice_lines table for the labour, parts and other lines of invoices --
+CREATE TABLE IF NOT EXISTS invoice_lines
+(
+    invoice_line_id  int(10) unsigned NOT NULL AUTO_INCREMENT,
+    invoice_id       int(8) unsigned  NOT NULL,
+    line_no          int(4) unsigned  NOT NULL,
+    kind             enum ('labour', 'overtime', 'warranty_labour', 'part', 'other') NOT NULL,
+    description      varchar(255)     NOT NULL,
+    quantity         decimal(10, 2)   NOT NULL,
+    unit_price_cents bigint           NOT NULL,
+    vat_rate         decimal(5, 2)    NOT NULL,
+    net_cents        bigint           NOT NULL,
+    vat_cents        bigint           NOT NULL,
+    PRIMARY KEY (invoice_line_id),
+    UNIQUE KEY (invoice_id, line_no),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- invoice_sequences table for the next number of invoices and credit notes --
+-- the row is locked while a number is taken so numbers have no gaps --
+CREATE TABLE IF NOT EXISTS invoice_sequences
+(
+    name       varchar(20)     NOT NULL,
+    next_value bigint unsigned NOT NULL DEFAULT 1,
+    PRIMARY KEY (name)
+) ENGINE = InnoDB;
+INSERT IGNORE INTO invoice_sequences (name, next_value)
+VALUES ('invoice', 1),
+       ('credit_note', 1);
+COMMIT;
+
+-- payment_ledger table for payments and refunds against invoices, amounts are in cents and refunds are negative --
+-- entries are never changed or deleted, the balance of an invoice is worked out from them --
+CREATE TABLE IF NOT EXISTS payment_ledger
+(
+    payment_id          int(10) unsigned NOT NULL AUTO_INCREMENT,
+    invoice_id          int(8) unsigned  NOT NULL,
+    worker_id           int(5) unsigned  NOT NULL, -- worker who took the payment
+    entry_type          enum ('payment', 'refund') NOT NULL,
+    method              enum ('card', 'cash', 'bank_transfer') NOT NULL,
+    amount_cents        bigint           NOT NULL,
+    currency            char(3)          NOT NULL,
+    provider            varchar(20)      NOT NULL DEFAULT '',
+    provider_ref        varchar(100)     NOT NULL DEFAULT '',
+    refunded_payment_id int(10) unsigned,          -- payment a refund gives back
+    reference           varchar(100)     NOT NULL DEFAULT '',
+    note                varchar(255)     NOT NULL DEFAULT '',
+    created_at          TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (payment_id),
+    INDEX (invoice_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    FOREIGN KEY (refunded_payment_id) REFERENCES payment_ledger (payment_id) ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE = InnoDB;
+DROP TRIGGER IF EXISTS payment_ledger_no_update;
+CREATE TRIGGER payment_ledger_no_update BEFORE UPDATE ON payment_ledger FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payment_ledger is append-only';
+DROP TRIGGER IF EXISTS payment_ledger_no_delete;
+CREATE TRIGGER payment_ledger_no_delete BEFORE DELETE ON payment_ledger FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payment_ledger is append-only';
+
+-- accounting_exports table for each run of an export to an accounting package --
+-- from_time is when the last run of the format was made and up_to when this one was, from_payment_id and --
+-- to_payment_id are the first and last payment it exported --
+CREATE TABLE IF NOT EXISTS accounting_exports
+(
+    export_id       int(8) unsigned  NOT NULL AUTO_INCREMENT,
+    worker_id       int(5) unsigned  NOT NULL,
+    format          varchar(20)      NOT NULL,
+    from_time       timestamp        NULL,
+    up_to           timestamp        NULL,
+    from_payment_id int(10) unsigned NOT NULL DEFAULT 0,
+    to_payment_id   int(10) unsigned NOT NULL DEFAULT 0,
+    documents       int(6) unsigned  NOT NULL DEFAULT 0,
+    payments        int(6) unsigned  NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (export_id),
+    INDEX (worker_id, format),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- accounting_export_formats table with a row for each user and format, locked by a run so two runs cannot cross --
+CREATE TABLE IF NOT EXISTS accounting_export_formats
+(
+    worker_id int(5) unsigned NOT NULL,
+    format    varchar(20)     NOT NULL,
+    PRIMARY KEY (worker_id, format),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- accounting_exported_invoices and accounting_exported_payments tables for what each run exported --
+-- a run exports the documents and payments not yet exported to its format, whenever they were committed --
+CREATE TABLE IF NOT EXISTS accounting_exported_invoices
+(
+    format     varchar(20)     NOT NULL,
+    invoice_id int(8) unsigned NOT NULL,
+    export_id  int(8) unsigned NOT NULL,
+    PRIMARY KEY (format, invoice_id),
+    INDEX (export_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (export_id) REFERENCES accounting_exports (export_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS accounting_exported_payments
+(
+    format     varchar(20)      NOT NULL,
+    payment_id int(10) unsigned NOT NULL,
+    export_id  int(8) unsigned  NOT NULL,
+    PRIMARY KEY (format, payment_id),
+    INDEX (export_id),
+    FOREIGN KEY (payment_id) REFERENCES payment_ledger (payment_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (export_id) REFERENCES accounting_exports (export_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- estimates table for estimates (quotes) given to customers before work starts, amounts are in cents --
+-- approval_token is the token of the link the customer accepts or declines the estimate at --
+CREATE TABLE IF NOT EXISTS estimates
+(
+    estimate_id    int(8) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id      int(5) unsigned NOT NULL,
+    status         enum ('draft', 'sent', 'accepted', 'declined', 'converted') NOT NULL DEFAULT 'draft',
+    customer_name  varchar(100)    NOT NULL,
+    vehicle_model  varchar(100)    NOT NULL DEFAULT '',
+    vehicle_reg    varchar(20)     NOT NULL DEFAULT '',
+    complaint      varchar(1000)   NOT NULL DEFAULT '',
+    currency       char(3)         NOT NULL,
+    subtotal_cents bigint          NOT NULL DEFAULT 0,
+    vat_cents      bigint          NOT NULL DEFAULT 0,
+    total_cents    bigint          NOT NULL DEFAULT 0,
+    expires_on     date,
+    approval_token varchar(64) UNIQUE,
+    decided_by     varchar(100)    NOT NULL DEFAULT '',
+    decision_note  varchar(1000)   NOT NULL DEFAULT '',
+    decided_at     timestamp       NULL,
+    job_report_id  int(6) unsigned,          -- report the estimate was converted to
+    contact_id     int(8) unsigned,          -- notification preferences the customer is sent the estimate with
+    created_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (estimate_id),
+    INDEX (status),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (contact_id) REFERENCES notification_preferences (contact_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- estimate_lines table for the labour, parts and other lines of estimates --
+CREATE TABLE IF NOT EXISTS estimate_lines
+(
+    estimate_line_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+    estimate_id      int(8) unsigned  NOT NULL,
+    line_no          int(4) unsigned  NOT NULL,
+    kind             enum ('labour', 'overtime', 'warranty_labour', 'part', 'other') NOT NULL,
+    description      varchar(255)     NOT NULL,
+    quantity         decimal(10, 2)   NOT NULL,
+    unit_price_cents bigint           NOT NULL,
+    vat_rate         decimal(5, 2)    NOT NULL,
+    net_cents        bigint           NOT NULL,
+    vat_cents        bigint           NOT NULL,
+    PRIMARY KEY (estimate_line_id),
+    UNIQUE KEY (estimate_id, line_no),
+    FOREIGN KEY (estimate_id) REFERENCES estimates (estimate_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- job_assignments table for the history of who jobs were assigned to, claimed by or taken from --
+CREATE TABLE IF NOT EXISTS job_assignments
+(
+    assignment_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id int(6) unsigned  NOT NULL,
+    action        enum ('assigned', 'claimed', 'reassigned', 'unassigned') NOT NULL,
+    from_worker   int(5) unsigned,           -- worker the job was taken from
+    to_worker     int(5) unsigned,           -- worker the job was given to, NULL when put back in the queue
+    changed_by    int(5) unsigned  NOT NULL, -- supervisor or worker who made the change
+    reason        varchar(255)     NOT NULL DEFAULT '',
+    created_at    TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (assignment_id),
+    INDEX (job_report_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (from_worker) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (to_worker) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- appointments table for the workshop diary, times are in UTC --
+CREATE TABLE IF NOT EXISTS appointments
+(
+    appointment_id int(8) unsigned NOT NULL AUTO_INCREMENT,
+    status         enum ('unconfirmed', 'pending', 'booked', 'cancelled', 'converted') NOT NULL DEFAULT 'booked',
+    source         enum ('garage', 'online') NOT NULL DEFAULT 'garage', -- booked by a worker or by the customer
+    starts_at      datetime        NOT NULL,
+    ends_at        datetime        NOT NULL,
+    bay            int(3) unsigned NOT NULL,
+    worker_id      int(5) unsigned,          -- worker at the appointment, NULL if no one has been given it yet
+    created_by     int(5) unsigned,
+    customer_name  varchar(100)    NOT NULL,
+    customer_phone varchar(30)     NOT NULL DEFAULT '',
+    customer_email varchar(100)    NOT NULL DEFAULT '',
+    vehicle_model  varchar(100)    NOT NULL DEFAULT '',
+    vehicle_reg    varchar(20)     NOT NULL DEFAULT '',
+    complaint      varchar(1000)   NOT NULL DEFAULT '',
+    job_report_id  int(6) unsigned,          -- report the appointment was converted to
+    confirm_token  varchar(64) UNIQUE,       -- key to the link a customer confirms an online booking with
+    confirm_by     datetime        NULL,
+    confirmed_at   datetime        NULL,
+    decline_reason varchar(255)    NOT NULL DEFAULT '',
+    created_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (appointment_id),
+    INDEX (starts_at),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- calendar_feeds table for the addresses of calendar feeds of appointments, token is the key to the feed --
+CREATE TABLE IF NOT EXISTS calendar_feeds
+(
+    feed_id    int(8) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id  int(5) unsigned NOT NULL,
+    scope      enum ('worker', 'garage') NOT NULL, -- the worker's appointments or all of them
+    token      varchar(64)     NOT NULL UNIQUE,
+    created_at TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feed_id),
+    UNIQUE KEY (worker_id, scope),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- sla_contracts table for the response times in minutes agreed with customers for breakdowns, 0 for no target --
+CREATE TABLE IF NOT EXISTS sla_contracts
+(
+    contract_id         int(5) unsigned      NOT NULL AUTO_INCREMENT,
+    contract_name       varchar(100)         NOT NULL UNIQUE,
+    customer_name       varchar(100)         NULL, -- customer whose breakdowns are under the contract
+    acknowledge_minutes smallint(5) unsigned NOT NULL DEFAULT 0,
+    arrive_minutes      smallint(5) unsigned NOT NULL DEFAULT 0,
+    resolve_minutes     smallint(5) unsigned NOT NULL DEFAULT 0,
+    active              boolean              NOT NULL DEFAULT 1,
+    created_by          int(5) unsigned,
+    created_at          datetime             NOT NULL,
+    PRIMARY KEY (contract_id),
+    INDEX (customer_name),
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- breakdowns table for breakdown call-outs in the dispatch queue, times are in UTC --
+CREATE TABLE IF NOT EXISTS breakdowns
+(
+    breakdown_id       int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id      int(6) unsigned NOT NULL UNIQUE,
+    priority           tinyint(1) unsigned NOT NULL DEFAULT 3, -- 1 critical, 2 urgent, 3 normal
+    status             enum ('waiting', 'dispatched', 'acknowledged', 'arrived', 'resolved', 'cancelled') NOT NULL DEFAULT 'waiting',
+    latitude           decimal(8, 5)   NOT NULL,
+    longitude          decimal(8, 5)   NOT NULL,
+    worker_id          int(5) unsigned,          -- worker dispatched to the breakdown
+    contract_id        int(5) unsigned,          -- SLA contract the breakdown is under
+    acknowledge_target smallint(5) unsigned NOT NULL DEFAULT 0, -- SLA targets in minutes when it was requested
+    arrive_target      smallint(5) unsigned NOT NULL DEFAULT 0,
+    resolve_target     smallint(5) unsigned NOT NULL DEFAULT 0,
+    created_by         int(5) unsigned,
+    requested_at       datetime        NOT NULL,
+    dispatched_at      datetime        NULL,
+    acknowledged_at    datetime        NULL,
+    arrived_at         datetime        NULL,
+    resolved_at        datetime        NULL,
+    PRIMARY KEY (breakdown_id),
+    INDEX (status, priority, requested_at),
+    INDEX (worker_id),
+    INDEX (requested_at),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (contract_id) REFERENCES sla_contracts (contract_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- worker_positions table for where each worker last reported being --
+CREATE TABLE IF NOT EXISTS worker_positions
+(
+    worker_id   int(5) unsigned NOT NULL,
+    latitude    decimal(8, 5)   NOT NULL,
+    longitude   decimal(8, 5)   NOT NULL,
+    available   boolean         NOT NULL DEFAULT 1, -- whether the worker can take call-outs
+    reported_at datetime        NOT NULL,
+    PRIMARY KEY (worker_id),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- worker_pings table for the GPS pings of workers' devices, kept within the limits in config.ini, times in UTC --
+CREATE TABLE IF NOT EXISTS worker_pings
+(
+    ping_id     bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id   int(5) unsigned     NOT NULL,
+    latitude    decimal(8, 5)       NOT NULL,
+    longitude   decimal(8, 5)       NOT NULL,
+    accuracy_m  decimal(7, 1)       NULL, -- how far off the position could be in metres
+    recorded_at datetime            NOT NULL, -- when the device recorded the position
+    received_at datetime            NOT NULL,
+    PRIMARY KEY (ping_id),
+    INDEX (worker_id, recorded_at),
+    INDEX (recorded_at),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- webhook_endpoints table for the endpoints the events of job reports are sent to --
+CREATE TABLE IF NOT EXISTS webhook_endpoints
+(
+    webhook_id    int(5) unsigned NOT NULL AUTO_INCREMENT,
+    url           varchar(2048)   NOT NULL,
+    secret        varchar(100)    NOT NULL, -- signs the requests sent to the endpoint with HMAC-SHA256
+    events        varchar(255)    NOT NULL, -- events sent, comma separated e.g. report.created,report.completed
+    customer_name varchar(50)     NULL,     -- only the events of the customer's reports are sent when set
+    description   varchar(255)    NULL,
+    active        boolean         NOT NULL DEFAULT 1,
+    created_by    int(5) unsigned,
+    created_at    datetime        NOT NULL,
+    PRIMARY KEY (webhook_id),
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- webhook_events table, the outbox of events recorded with the change to the report, times are in UTC --
+-- reports are not referenced so the events of deleted reports are kept --
+CREATE TABLE IF NOT EXISTS webhook_events
+(
+    event_id      bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event_type    varchar(40)         NOT NULL,
+    job_report_id int(6) unsigned     NOT NULL,
+    payload       mediumtext          NOT NULL, -- the report or breakdown as JSON when the event happened
+    created_at    datetime            NOT NULL,
+    PRIMARY KEY (event_id),
+    INDEX (job_report_id)
+) ENGINE = InnoDB;
+
+-- webhook_deliveries table for each event to send to each endpoint, times are in UTC --
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    delivery_id      bigint(12) unsigned                        NOT NULL AUTO_INCREMENT,
+    webhook_id       int(5) unsigned                            NOT NULL,
+    event_id         bigint(12) unsigned                        NOT NULL,
+    status           enum ('pending', 'delivered', 'failed')    NOT NULL DEFAULT 'pending',
+    attempts         smallint(5) unsigned                       NOT NULL DEFAULT 0,
+    next_attempt_at  datetime                                   NULL, -- when it is next sent, NULL once it is not
+    last_status_code smallint(3) unsigned                       NULL,
+    last_error       varchar(255)                               NULL,
+    delivered_at     datetime                                   NULL,
+    redelivery_of    bigint(12) unsigned                        NULL, -- delivery sent again by a supervisor
+    created_at       datetime                                   NOT NULL,
+    PRIMARY KEY (delivery_id),
+    INDEX (status, next_attempt_at),
+    FOREIGN KEY (webhook_id) REFERENCES webhook_endpoints (webhook_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES webhook_events (event_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (redelivery_of) REFERENCES webhook_deliveries (delivery_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- webhook_attempts table, the log of every attempt to send a delivery, times are in UTC --
+CREATE TABLE IF NOT EXISTS webhook_attempts
+(
+    attempt_id   bigint(12) unsigned  NOT NULL AUTO_INCREMENT,
+    delivery_id  bigint(12) unsigned  NOT NULL,
+    attempted_at datetime             NOT NULL,
+    status_code  smallint(3) unsigned NULL, -- NULL if the endpoint did not answer
+    error        varchar(255)         NULL,
+    response     varchar(1000)        NULL, -- the start of the endpoint's response
+    duration_ms  int(8) unsigned      NOT NULL,
+    PRIMARY KEY (attempt_id),
+    INDEX (delivery_id),
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (delivery_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- sla_alerts table for the stages of breakdowns at risk or breached that events were sent of, times are in UTC --
+CREATE TABLE IF NOT EXISTS sla_alerts
+(
+    breakdown_id int(8) unsigned NOT NULL,
+    stage        enum ('acknowledge', 'arrive', 'resolve') NOT NULL,
+    event_type   varchar(40)     NOT NULL, -- breakdown.sla_at_risk or breakdown.sla_breached
+    created_at   datetime        NOT NULL,
+    PRIMARY KEY (breakdown_id, stage, event_type),
+    FOREIGN KEY (breakdown_id) REFERENCES breakdowns (breakdown_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- report_events table for the live events of job reports pushed to clients, times are in UTC --
+-- reports and workers are not referenced so the events of deleted reports are kept for replay --
+CREATE TABLE IF NOT EXISTS report_events
+(
+    event_id       bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event_type     varchar(40)         NOT NULL,
+    job_report_id  int(6) unsigned     NOT NULL,
+    worker_id      int(5) unsigned     NULL, -- who the report is assigned to after the change, NULL in the queue
+    from_worker_id int(5) unsigned     NULL, -- who the report was assigned to before it moved
+    status         varchar(20)         NOT NULL DEFAULT '', -- what happened for report.status e.g. claimed
+    created_at     datetime            NOT NULL,
+    PRIMARY KEY (event_id),
+    INDEX (created_at)
+) ENGINE = InnoDB;
+
+-- notification_messages table, the log of messages to customers and the outbox they are sent from, times are in UTC --
+-- reports are not referenced so the messages of deleted reports are kept --
+CREATE TABLE IF NOT EXISTS notification_messages
+(
+    message_id      bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event           varchar(40)         NOT NULL,
+    channel         enum ('email', 'sms') NOT NULL,
+    customer_name   varchar(100)        NOT NULL,
+    contact_id      int(8) unsigned     NULL,     -- notification preferences the message was sent with
+    recipient       varchar(100)        NOT NULL, -- email address or phone number
+    job_report_id   int(6) unsigned     NULL,
+    subject         varchar(255)        NOT NULL DEFAULT '',
+    body            text                NOT NULL,
+    status          enum ('pending', 'sent', 'failed', 'skipped') NOT NULL DEFAULT 'pending',
+    attempts        int(3) unsigned     NOT NULL DEFAULT 0,
+    next_attempt_at datetime            NULL,
+    last_error      varchar(255)        NULL,
+    provider_id     varchar(255)        NULL, -- Message-ID of an email or the SMS gateway's ID
+    sent_at         datetime            NULL,
+    created_at      datetime            NOT NULL,
+    PRIMARY KEY (message_id),
+    INDEX (status, next_attempt_at),
+    INDEX (customer_name),
+    INDEX (contact_id),
+    INDEX (job_report_id)
+) ENGINE = InnoDB;
+
 -- session table for login sessions --
 CREATE TABLE session
 (
     id           VARCHAR(255)        NOT NULL, -- UUID
     user         INTEGER(4) unsigned NOT NULL,
     expire_after INT(8)              NOT NULL, -- Unix epoch time store
+    created_at   TIMESTAMP           NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP           NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 
     PRIMARY KEY (id),
     FOREIGN KEY (user) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
@@ -98,3 +626,30 @@ SELECT * FROM jobreports;
 SELECT * FROM customers;
 SELECT * FROM workers;
 SELECT * FROM session;
+SELECT * FROM attachments;
+SELECT * FROM signatures;
+SELECT * FROM invoices;
+SELECT * FROM invoice_lines;
+SELECT * FROM invoice_sequences;
+SELECT * FROM payment_ledger;
+SELECT * FROM accounting_exports;
+SELECT * FROM accounting_export_formats;
+SELECT * FROM accounting_exported_invoices;
+SELECT * FROM accounting_exported_payments;
+SELECT * FROM estimates;
+SELECT * FROM estimate_lines;
+SELECT * FROM job_assignments;
+SELECT * FROM appointments;
+SELECT * FROM calendar_feeds;
+SELECT * FROM sla_contracts;
+SELECT * FROM breakdowns;
+SELECT * FROM worker_positions;
+SELECT * FROM worker_pings;
+SELECT * FROM webhook_endpoints;
+SELECT * FROM webhook_events;
+SELECT * FROM webhook_deliveries;
+SELECT * FROM webhook_attempts;
+SELECT * FROM sla_alerts;
+SELECT * FROM report_events;
+SELECT * FROM notification_preferences;
+SELECT * FROM notification_messages;
@@ -0,0 +1,20 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 001: Odometer Units --
+-- Readings are stored in kilometres with the reading and unit as entered kept alongside it. --
+-- Existing readings were all entered in miles. --
+
+use repotadb;
+
+ALTER TABLE jobreports
+    ADD COLUMN odometer_km      int(20)           NOT NULL DEFAULT 0 AFTER vehicle_location,
+    ADD COLUMN odometer_reading int(20)           NOT NULL DEFAULT 0 AFTER odometer_km,
+    ADD COLUMN odometer_unit    enum ('km', 'mi') NOT NULL DEFAULT 'mi' AFTER odometer_reading;
+
+UPDATE jobreports
+SET odometer_reading = miles_on_vehicle,
+    odometer_unit    = 'mi',
+    odometer_km      = ROUND(miles_on_vehicle * 1.609344);
+
+ALTER TABLE jobreports
+    DROP COLUMN miles_on_vehicle;
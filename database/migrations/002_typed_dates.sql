@@ -0,0 +1,76 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 002: Typed Dates --
+-- date_stamp is changed from varchar(20) to date and every table gets created_at & updated_at. --
+-- Existing dates are in DD-MM-YYYY (or DD/MM/YYYY), dates already in YYYY-MM-DD are kept. --
+
+use repotadb;
+
+-- Any reports listed here have a date_stamp that cannot be converted. --
+-- The migration stops before changing any table while there are some, fix their date_stamp and run it again. --
+SELECT job_report_id, date_stamp
+FROM jobreports
+WHERE CASE
+          WHEN date_stamp REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}'
+              THEN STR_TO_DATE(LEFT(date_stamp, 10), '%Y-%m-%d')
+          WHEN date_stamp REGEXP '^[0-9]{1,2}[-/][0-9]{1,2}[-/][0-9]{4}$'
+              THEN STR_TO_DATE(REPLACE(date_stamp, '/', '-'), '%d-%m-%Y')
+          END IS NULL;
+
+DROP PROCEDURE IF EXISTS check_job_dates;
+DELIMITER //
+CREATE PROCEDURE check_job_dates()
+BEGIN
+    IF EXISTS(SELECT 1
+              FROM jobreports
+              WHERE CASE
+                        WHEN date_stamp REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}'
+                            THEN STR_TO_DATE(LEFT(date_stamp, 10), '%Y-%m-%d')
+                        WHEN date_stamp REGEXP '^[0-9]{1,2}[-/][0-9]{1,2}[-/][0-9]{4}$'
+                            THEN STR_TO_DATE(REPLACE(date_stamp, '/', '-'), '%d-%m-%Y')
+                        END IS NULL) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Some date_stamp cannot be converted, see the reports listed';
+    END IF;
+END //
+DELIMITER ;
+CALL check_job_dates();
+DROP PROCEDURE check_job_dates;
+
+-- Convert date_stamp into a new date column. --
+ALTER TABLE jobreports
+    ADD COLUMN job_date date NULL AFTER date_stamp;
+
+UPDATE jobreports
+SET job_date = CASE
+                   WHEN date_stamp REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}'
+                       THEN STR_TO_DATE(LEFT(date_stamp, 10), '%Y-%m-%d')
+                   WHEN date_stamp REGEXP '^[0-9]{1,2}[-/][0-9]{1,2}[-/][0-9]{4}$'
+                       THEN STR_TO_DATE(REPLACE(date_stamp, '/', '-'), '%d-%m-%Y')
+    END;
+
+ALTER TABLE jobreports
+    DROP COLUMN date_stamp,
+    CHANGE COLUMN job_date date_stamp date NOT NULL,
+    ADD INDEX (date_stamp);
+
+-- created_at & updated_at for every table. --
+ALTER TABLE workers
+    ADD COLUMN created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE jobreports
+    ADD COLUMN created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE customers
+    ADD COLUMN created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE session
+    ADD COLUMN created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+-- Existing reports were created on the day of the job. --
+UPDATE jobreports
+SET created_at = date_stamp,
+    updated_at = date_stamp;
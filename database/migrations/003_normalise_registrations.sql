@@ -0,0 +1,26 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 003: Normalise Registrations --
+-- Registrations were stored as typed, they are now stored upper case with hyphens e.g. "151 dl 2308" as "151-DL-2308". --
+-- Irish registrations typed without any separators and UK registrations are normalised by Horton when next updated. --
+
+use repotadb;
+
+UPDATE jobreports
+SET vehicle_reg = UPPER(REGEXP_REPLACE(TRIM(vehicle_reg), '[[:space:]-]+', '-'))
+WHERE vehicle_reg REGEXP '^[0-9]{2,3}[[:space:]-]+[A-Za-z]{1,2}[[:space:]-]+[0-9]{1,6}$';
+
+-- Registrations are not changed to ones that look valid, they are of real vehicles and signed reports are hashed --
+-- with them. The reports below have registrations Horton refuses, e.g. a county code that was never issued or was --
+-- retired in 2014, and cannot be updated until they are checked against the vehicle and corrected by hand. --
+SELECT job_report_id, vehicle_reg
+FROM (SELECT job_report_id, vehicle_reg, UPPER(REGEXP_REPLACE(vehicle_reg, '[[:space:]-]+', '')) AS compact
+      FROM jobreports) reg
+WHERE (compact REGEXP '^[0-9]'
+    AND compact NOT REGEXP '^[0-9]{2,3}(C|CE|CN|CW|D|DL|G|KE|KK|KY|L|LD|LH|LK|LM|LS|MH|MN|MO|OY|RN|SO|T|TN|TS|W|WD|WH|WW|WX)[1-9][0-9]{0,5}$')
+   OR (compact NOT REGEXP '^[0-9]'
+    AND compact NOT REGEXP '^([A-Z]{2}[0-9]{2}[A-Z]{3}|[A-Z][1-9][0-9]{0,2}[A-Z]{3}|[A-Z]{3}[1-9][0-9]{0,2}[A-Z]|[A-Z]{1,3}[1-9][0-9]{0,3})$')
+   OR compact REGEXP '^(1[4-9]|[2-9][0-9])[12](LK|TN|TS|WD)[1-9]'
+ORDER BY job_report_id;
+
+CREATE INDEX vehicle_reg ON jobreports (vehicle_reg);
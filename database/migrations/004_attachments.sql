@@ -0,0 +1,23 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 004: Attachments --
+-- Photos and documents attached to reports, the files are kept in the Blob Store set up in config.ini. --
+
+use repotadb;
+
+CREATE TABLE IF NOT EXISTS attachments
+(
+    attachment_id int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id int(6) unsigned NOT NULL,
+    worker_id     int(5) unsigned NOT NULL, -- worker who uploaded the file
+    kind          enum ('before', 'after', 'diagnostic', 'signed_form', 'other') NOT NULL DEFAULT 'other',
+    file_name     varchar(255)    NOT NULL,
+    content_type  varchar(100)    NOT NULL,
+    size_bytes    bigint unsigned NOT NULL,
+    storage_key   varchar(255)    NOT NULL UNIQUE,
+    created_at    timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (attachment_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,24 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 005: Signatures --
+-- Customer signatures at handover with the hash of the report when it was signed. --
+
+use repotadb;
+
+CREATE TABLE IF NOT EXISTS signatures
+(
+    signature_id   int(6) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id  int(6) unsigned NOT NULL UNIQUE, -- a report is signed once
+    worker_id      int(5) unsigned NOT NULL,        -- worker who took the signature
+    signer_name    varchar(50)     NOT NULL,
+    format         enum ('image', 'strokes') NOT NULL,
+    content_type   varchar(50)     NOT NULL,
+    data           mediumblob      NOT NULL,        -- PNG/JPEG image or JSON strokes
+    signature_hash char(64)        NOT NULL,
+    report_hash    char(64)        NOT NULL,
+    created_at     timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (signature_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,67 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 006: Invoices --
+-- Invoices and credit notes of job reports with their lines and numbering. --
+
+use repotadb;
+
+-- invoices table for invoices and credit notes of job reports, amounts are in cents --
+-- invoice_number is given from invoice_sequences when an invoice is issued, drafts have none --
+CREATE TABLE IF NOT EXISTS invoices
+(
+    invoice_id          int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id       int(6) unsigned NOT NULL,
+    worker_id           int(5) unsigned NOT NULL, -- worker who raised the invoice
+    kind                enum ('invoice', 'credit_note') NOT NULL DEFAULT 'invoice',
+    credited_invoice_id int(8) unsigned,          -- invoice a credit note credits
+    invoice_number      varchar(20) UNIQUE,
+    status              enum ('draft', 'issued', 'paid', 'void') NOT NULL DEFAULT 'draft',
+    bill_to             enum ('customer', 'warranty_provider') NOT NULL DEFAULT 'customer',
+    bill_to_name        varchar(100)    NOT NULL,
+    bill_to_address     varchar(500)    NOT NULL DEFAULT '',
+    currency            char(3)         NOT NULL,
+    subtotal_cents      bigint          NOT NULL DEFAULT 0,
+    vat_cents           bigint          NOT NULL DEFAULT 0,
+    total_cents         bigint          NOT NULL DEFAULT 0,
+    reason              varchar(500)    NOT NULL DEFAULT '',
+    issued_at           timestamp       NULL,
+    due_date            date,
+    created_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamp       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (invoice_id),
+    INDEX (status),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE RESTRICT ON UPDATE CASCADE,
+    FOREIGN KEY (credited_invoice_id) REFERENCES invoices (invoice_id) ON DELETE RESTRICT ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- invoice_lines table for the labour, parts and other lines of invoices --
+CREATE TABLE IF NOT EXISTS invoice_lines
+(
+    invoice_line_id  int(10) unsigned NOT NULL AUTO_INCREMENT,
+    invoice_id       int(8) unsigned  NOT NULL,
+    line_no          int(4) unsigned  NOT NULL,
+    kind             enum ('labour', 'overtime', 'warranty_labour', 'part', 'other') NOT NULL,
+    description      varchar(255)     NOT NULL,
+    quantity         decimal(10, 2)   NOT NULL,
+    unit_price_cents bigint           NOT NULL,
+    vat_rate         decimal(5, 2)    NOT NULL,
+    net_cents        bigint           NOT NULL,
+    vat_cents        bigint           NOT NULL,
+    PRIMARY KEY (invoice_line_id),
+    UNIQUE KEY (invoice_id, line_no),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- invoice_sequences table for the next number of invoices and credit notes --
+-- the row is locked while a number is taken so numbers have no gaps --
+CREATE TABLE IF NOT EXISTS invoice_sequences
+(
+    name       varchar(20)     NOT NULL,
+    next_value bigint unsigned NOT NULL DEFAULT 1,
+    PRIMARY KEY (name)
+) ENGINE = InnoDB;
+INSERT IGNORE INTO invoice_sequences (name, next_value)
+VALUES ('invoice', 1),
+       ('credit_note', 1);
+COMMIT;
@@ -0,0 +1,36 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 007: Payments --
+-- Append-only ledger of payments and refunds against invoices. --
+
+use repotadb;
+
+-- payment_ledger table for payments and refunds against invoices, amounts are in cents and refunds are negative --
+-- entries are never changed or deleted, the balance of an invoice is worked out from them --
+CREATE TABLE IF NOT EXISTS payment_ledger
+(
+    payment_id          int(10) unsigned NOT NULL AUTO_INCREMENT,
+    invoice_id          int(8) unsigned  NOT NULL,
+    worker_id           int(5) unsigned  NOT NULL, -- worker who took the payment
+    entry_type          enum ('payment', 'refund') NOT NULL,
+    method              enum ('card', 'cash', 'bank_transfer') NOT NULL,
+    amount_cents        bigint           NOT NULL,
+    currency            char(3)          NOT NULL,
+    provider            varchar(20)      NOT NULL DEFAULT '',
+    provider_ref        varchar(100)     NOT NULL DEFAULT '',
+    refunded_payment_id int(10) unsigned,          -- payment a refund gives back
+    reference           varchar(100)     NOT NULL DEFAULT '',
+    note                varchar(255)     NOT NULL DEFAULT '',
+    created_at          TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (payment_id),
+    INDEX (invoice_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    FOREIGN KEY (refunded_payment_id) REFERENCES payment_ledger (payment_id) ON DELETE RESTRICT ON UPDATE RESTRICT
+) ENGINE = InnoDB;
+DROP TRIGGER IF EXISTS payment_ledger_no_update;
+CREATE TRIGGER payment_ledger_no_update BEFORE UPDATE ON payment_ledger FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payment_ledger is append-only';
+DROP TRIGGER IF EXISTS payment_ledger_no_delete;
+CREATE TRIGGER payment_ledger_no_delete BEFORE DELETE ON payment_ledger FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'payment_ledger is append-only';
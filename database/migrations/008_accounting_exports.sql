@@ -0,0 +1,26 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 008: Accounting Exports --
+-- Runs of exports to accounting packages, so each run exports only what is new. --
+
+use repotadb;
+
+-- accounting_exports table for each run of an export to an accounting package --
+-- a run exports the documents issued after from_time up to up_to, and the payments after from_payment_id --
+-- up to to_payment_id, the next run of the format carries on from there --
+CREATE TABLE IF NOT EXISTS accounting_exports
+(
+    export_id       int(8) unsigned  NOT NULL AUTO_INCREMENT,
+    worker_id       int(5) unsigned  NOT NULL,
+    format          varchar(20)      NOT NULL,
+    from_time       timestamp        NULL,
+    up_to           timestamp        NULL,
+    from_payment_id int(10) unsigned NOT NULL DEFAULT 0,
+    to_payment_id   int(10) unsigned NOT NULL DEFAULT 0,
+    documents       int(6) unsigned  NOT NULL DEFAULT 0,
+    payments        int(6) unsigned  NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (export_id),
+    INDEX (worker_id, format),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
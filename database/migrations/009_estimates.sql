@@ -0,0 +1,53 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 009: Estimates --
+-- Estimates given to customers before work starts, with their lines. --
+
+use repotadb;
+
+-- estimates table for estimates (quotes) given to customers before work starts, amounts are in cents --
+-- approval_token is the token of the link the customer accepts or declines the estimate at --
+CREATE TABLE IF NOT EXISTS estimates
+(
+    estimate_id    int(8) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id      int(5) unsigned NOT NULL,
+    status         enum ('draft', 'sent', 'accepted', 'declined', 'converted') NOT NULL DEFAULT 'draft',
+    customer_name  varchar(100)    NOT NULL,
+    vehicle_model  varchar(100)    NOT NULL DEFAULT '',
+    vehicle_reg    varchar(20)     NOT NULL DEFAULT '',
+    complaint      varchar(1000)   NOT NULL DEFAULT '',
+    currency       char(3)         NOT NULL,
+    subtotal_cents bigint          NOT NULL DEFAULT 0,
+    vat_cents      bigint          NOT NULL DEFAULT 0,
+    total_cents    bigint          NOT NULL DEFAULT 0,
+    expires_on     date,
+    approval_token varchar(64) UNIQUE,
+    decided_by     varchar(100)    NOT NULL DEFAULT '',
+    decision_note  varchar(1000)   NOT NULL DEFAULT '',
+    decided_at     timestamp       NULL,
+    job_report_id  int(6) unsigned,          -- report the estimate was converted to
+    created_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (estimate_id),
+    INDEX (status),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- estimate_lines table for the labour, parts and other lines of estimates --
+CREATE TABLE IF NOT EXISTS estimate_lines
+(
+    estimate_line_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+    estimate_id      int(8) unsigned  NOT NULL,
+    line_no          int(4) unsigned  NOT NULL,
+    kind             enum ('labour', 'overtime', 'warranty_labour', 'part', 'other') NOT NULL,
+    description      varchar(255)     NOT NULL,
+    quantity         decimal(10, 2)   NOT NULL,
+    unit_price_cents bigint           NOT NULL,
+    vat_rate         decimal(5, 2)    NOT NULL,
+    net_cents        bigint           NOT NULL,
+    vat_cents        bigint           NOT NULL,
+    PRIMARY KEY (estimate_line_id),
+    UNIQUE KEY (estimate_id, line_no),
+    FOREIGN KEY (estimate_id) REFERENCES estimates (estimate_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
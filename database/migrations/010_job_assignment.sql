@@ -0,0 +1,35 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 010: Job Assignment --
+-- Supervisors create jobs and assign them to workers or leave them in a queue for workers to claim. --
+-- Existing reports stay with the workers who wrote them. --
+
+use repotadb;
+
+ALTER TABLE workers
+    ADD COLUMN role enum ('worker', 'supervisor') NOT NULL DEFAULT 'worker' AFTER hash;
+
+ALTER TABLE jobreports
+    MODIFY COLUMN worker_id int(5) unsigned NULL,
+    ADD COLUMN created_by  int(5) unsigned NULL AFTER worker_id,
+    ADD COLUMN assigned_at timestamp       NULL AFTER created_by,
+    ADD FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- job_assignments table for the history of who jobs were assigned to, claimed by or taken from --
+CREATE TABLE IF NOT EXISTS job_assignments
+(
+    assignment_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id int(6) unsigned  NOT NULL,
+    action        enum ('assigned', 'claimed', 'reassigned', 'unassigned') NOT NULL,
+    from_worker   int(5) unsigned,           -- worker the job was taken from
+    to_worker     int(5) unsigned,           -- worker the job was given to, NULL when put back in the queue
+    changed_by    int(5) unsigned  NOT NULL, -- supervisor or worker who made the change
+    reason        varchar(255)     NOT NULL DEFAULT '',
+    created_at    TIMESTAMP        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (assignment_id),
+    INDEX (job_report_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (from_worker) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (to_worker) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,45 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 011: Appointments --
+-- The workshop diary of appointments and the calendar feeds of it. --
+
+use repotadb;
+
+-- appointments table for the workshop diary, times are in UTC --
+CREATE TABLE IF NOT EXISTS appointments
+(
+    appointment_id int(8) unsigned NOT NULL AUTO_INCREMENT,
+    status         enum ('booked', 'cancelled', 'converted') NOT NULL DEFAULT 'booked',
+    starts_at      datetime        NOT NULL,
+    ends_at        datetime        NOT NULL,
+    bay            int(3) unsigned NOT NULL,
+    worker_id      int(5) unsigned,          -- worker at the appointment, NULL if no one has been given it yet
+    created_by     int(5) unsigned,
+    customer_name  varchar(100)    NOT NULL,
+    customer_phone varchar(30)     NOT NULL DEFAULT '',
+    customer_email varchar(100)    NOT NULL DEFAULT '',
+    vehicle_model  varchar(100)    NOT NULL DEFAULT '',
+    vehicle_reg    varchar(20)     NOT NULL DEFAULT '',
+    complaint      varchar(1000)   NOT NULL DEFAULT '',
+    job_report_id  int(6) unsigned,          -- report the appointment was converted to
+    created_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (appointment_id),
+    INDEX (starts_at),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- calendar_feeds table for the addresses of calendar feeds of appointments, token is the key to the feed --
+CREATE TABLE IF NOT EXISTS calendar_feeds
+(
+    feed_id    int(8) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id  int(5) unsigned NOT NULL,
+    scope      enum ('worker', 'garage') NOT NULL, -- the worker's appointments or all of them
+    token      varchar(64)     NOT NULL UNIQUE,
+    created_at TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feed_id),
+    UNIQUE KEY (worker_id, scope),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
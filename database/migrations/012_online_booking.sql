@@ -0,0 +1,14 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 012: Online Booking --
+-- Customers book appointments online, confirm them by a link and supervisors accept or decline them. --
+
+use repotadb;
+
+ALTER TABLE appointments
+    MODIFY COLUMN status enum ('unconfirmed', 'pending', 'booked', 'cancelled', 'converted') NOT NULL DEFAULT 'booked',
+    ADD COLUMN source         enum ('garage', 'online') NOT NULL DEFAULT 'garage' AFTER status,
+    ADD COLUMN confirm_token  varchar(64)  UNIQUE AFTER job_report_id,
+    ADD COLUMN confirm_by     datetime     NULL AFTER confirm_token,
+    ADD COLUMN confirmed_at   datetime     NULL AFTER confirm_by,
+    ADD COLUMN decline_reason varchar(255) NOT NULL DEFAULT '' AFTER confirmed_at;
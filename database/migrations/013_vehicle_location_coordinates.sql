@@ -0,0 +1,12 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 013: Vehicle Location Coordinates --
+-- Reports store the coordinates of their vehicle location found in the gazetteer. --
+-- Fill them in for existing reports afterwards with: go run main.go geocode --
+
+use repotadb;
+
+ALTER TABLE jobreports
+    ADD COLUMN location_lat decimal(8, 5) NULL AFTER vehicle_location,
+    ADD COLUMN location_lon decimal(8, 5) NULL AFTER location_lat,
+    ADD INDEX (location_lat, location_lon);
@@ -0,0 +1,41 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 014: Breakdown Dispatch --
+-- Breakdowns wait in a dispatch queue for the nearest free worker, workers report where they are. --
+
+use repotadb;
+
+-- breakdowns table for breakdown call-outs in the dispatch queue, times are in UTC --
+CREATE TABLE IF NOT EXISTS breakdowns
+(
+    breakdown_id    int(8) unsigned NOT NULL AUTO_INCREMENT,
+    job_report_id   int(6) unsigned NOT NULL UNIQUE,
+    priority        tinyint(1) unsigned NOT NULL DEFAULT 3, -- 1 critical, 2 urgent, 3 normal
+    status          enum ('waiting', 'dispatched', 'acknowledged', 'arrived', 'cancelled') NOT NULL DEFAULT 'waiting',
+    latitude        decimal(8, 5)   NOT NULL,
+    longitude       decimal(8, 5)   NOT NULL,
+    worker_id       int(5) unsigned,          -- worker dispatched to the breakdown
+    created_by      int(5) unsigned,
+    requested_at    datetime        NOT NULL,
+    dispatched_at   datetime        NULL,
+    acknowledged_at datetime        NULL,
+    arrived_at      datetime        NULL,
+    PRIMARY KEY (breakdown_id),
+    INDEX (status, priority, requested_at),
+    INDEX (worker_id),
+    FOREIGN KEY (job_report_id) REFERENCES jobreports (job_report_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- worker_positions table for where each worker last reported being --
+CREATE TABLE IF NOT EXISTS worker_positions
+(
+    worker_id   int(5) unsigned NOT NULL,
+    latitude    decimal(8, 5)   NOT NULL,
+    longitude   decimal(8, 5)   NOT NULL,
+    available   boolean         NOT NULL DEFAULT 1, -- whether the worker can take call-outs
+    reported_at datetime        NOT NULL,
+    PRIMARY KEY (worker_id),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
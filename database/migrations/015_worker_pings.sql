@@ -0,0 +1,22 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 015: Worker Pings --
+-- Workers' devices send GPS pings, kept with where each worker last reported being. --
+
+use repotadb;
+
+-- worker_pings table for the GPS pings of workers' devices, kept within the limits in config.ini, times in UTC --
+CREATE TABLE IF NOT EXISTS worker_pings
+(
+    ping_id     bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    worker_id   int(5) unsigned     NOT NULL,
+    latitude    decimal(8, 5)       NOT NULL,
+    longitude   decimal(8, 5)       NOT NULL,
+    accuracy_m  decimal(7, 1)       NULL, -- how far off the position could be in metres
+    recorded_at datetime            NOT NULL, -- when the device recorded the position
+    received_at datetime            NOT NULL,
+    PRIMARY KEY (ping_id),
+    INDEX (worker_id, recorded_at),
+    INDEX (recorded_at),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,34 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 016: SLA Contracts --
+-- Breakdowns are held to the response times agreed with customers, and are resolved after a worker arrives. --
+-- Breakdowns before this have no targets. --
+
+use repotadb;
+
+-- sla_contracts table for the response times in minutes agreed with customers for breakdowns, 0 for no target --
+CREATE TABLE IF NOT EXISTS sla_contracts
+(
+    contract_id         int(5) unsigned      NOT NULL AUTO_INCREMENT,
+    contract_name       varchar(100)         NOT NULL UNIQUE,
+    customer_name       varchar(100)         NULL, -- customer whose breakdowns are under the contract
+    acknowledge_minutes smallint(5) unsigned NOT NULL DEFAULT 0,
+    arrive_minutes      smallint(5) unsigned NOT NULL DEFAULT 0,
+    resolve_minutes     smallint(5) unsigned NOT NULL DEFAULT 0,
+    active              boolean              NOT NULL DEFAULT 1,
+    created_by          int(5) unsigned,
+    created_at          datetime             NOT NULL,
+    PRIMARY KEY (contract_id),
+    INDEX (customer_name),
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+ALTER TABLE breakdowns
+    MODIFY COLUMN status enum ('waiting', 'dispatched', 'acknowledged', 'arrived', 'resolved', 'cancelled') NOT NULL DEFAULT 'waiting',
+    ADD COLUMN contract_id        int(5) unsigned AFTER worker_id,
+    ADD COLUMN acknowledge_target smallint(5) unsigned NOT NULL DEFAULT 0 AFTER contract_id,
+    ADD COLUMN arrive_target      smallint(5) unsigned NOT NULL DEFAULT 0 AFTER acknowledge_target,
+    ADD COLUMN resolve_target     smallint(5) unsigned NOT NULL DEFAULT 0 AFTER arrive_target,
+    ADD COLUMN resolved_at        datetime NULL AFTER arrived_at,
+    ADD INDEX (requested_at),
+    ADD FOREIGN KEY (contract_id) REFERENCES sla_contracts (contract_id) ON DELETE SET NULL ON UPDATE CASCADE;
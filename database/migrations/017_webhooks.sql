@@ -0,0 +1,71 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 017: Webhooks --
+-- Events of job reports are recorded in an outbox and sent to the webhook endpoints supervisors add. --
+
+use repotadb;
+
+-- webhook_endpoints table for the endpoints the events of job reports are sent to --
+CREATE TABLE IF NOT EXISTS webhook_endpoints
+(
+    webhook_id    int(5) unsigned NOT NULL AUTO_INCREMENT,
+    url           varchar(2048)   NOT NULL,
+    secret        varchar(100)    NOT NULL, -- signs the requests sent to the endpoint with HMAC-SHA256
+    events        varchar(255)    NOT NULL, -- events sent, comma separated e.g. report.created,report.completed
+    customer_name varchar(50)     NULL,     -- only the events of the customer's reports are sent when set
+    description   varchar(255)    NULL,
+    active        boolean         NOT NULL DEFAULT 1,
+    created_by    int(5) unsigned,
+    created_at    datetime        NOT NULL,
+    PRIMARY KEY (webhook_id),
+    FOREIGN KEY (created_by) REFERENCES workers (worker_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- webhook_events table, the outbox of events recorded with the change to the report, times are in UTC --
+-- reports are not referenced so the events of deleted reports are kept --
+CREATE TABLE IF NOT EXISTS webhook_events
+(
+    event_id      bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event_type    varchar(40)         NOT NULL,
+    job_report_id int(6) unsigned     NOT NULL,
+    payload       mediumtext          NOT NULL, -- the report as JSON when the event happened
+    created_at    datetime            NOT NULL,
+    PRIMARY KEY (event_id),
+    INDEX (job_report_id)
+) ENGINE = InnoDB;
+
+-- webhook_deliveries table for each event to send to each endpoint, times are in UTC --
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    delivery_id      bigint(12) unsigned                        NOT NULL AUTO_INCREMENT,
+    webhook_id       int(5) unsigned                            NOT NULL,
+    event_id         bigint(12) unsigned                        NOT NULL,
+    status           enum ('pending', 'delivered', 'failed')    NOT NULL DEFAULT 'pending',
+    attempts         smallint(5) unsigned                       NOT NULL DEFAULT 0,
+    next_attempt_at  datetime                                   NULL, -- when it is next sent, NULL once it is not
+    last_status_code smallint(3) unsigned                       NULL,
+    last_error       varchar(255)                               NULL,
+    delivered_at     datetime                                   NULL,
+    redelivery_of    bigint(12) unsigned                        NULL, -- delivery sent again by a supervisor
+    created_at       datetime                                   NOT NULL,
+    PRIMARY KEY (delivery_id),
+    INDEX (status, next_attempt_at),
+    FOREIGN KEY (webhook_id) REFERENCES webhook_endpoints (webhook_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES webhook_events (event_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (redelivery_of) REFERENCES webhook_deliveries (delivery_id) ON DELETE SET NULL ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- webhook_attempts table, the log of every attempt to send a delivery, times are in UTC --
+CREATE TABLE IF NOT EXISTS webhook_attempts
+(
+    attempt_id   bigint(12) unsigned  NOT NULL AUTO_INCREMENT,
+    delivery_id  bigint(12) unsigned  NOT NULL,
+    attempted_at datetime             NOT NULL,
+    status_code  smallint(3) unsigned NULL, -- NULL if the endpoint did not answer
+    error        varchar(255)         NULL,
+    response     varchar(1000)        NULL, -- the start of the endpoint's response
+    duration_ms  int(8) unsigned      NOT NULL,
+    PRIMARY KEY (attempt_id),
+    INDEX (delivery_id),
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries (delivery_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
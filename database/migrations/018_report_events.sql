@@ -0,0 +1,21 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 018: Report Events --
+-- Events of job reports are recorded with the change to the report and pushed to clients as they happen. --
+
+use repotadb;
+
+-- report_events table for the live events of job reports pushed to clients, times are in UTC --
+-- reports and workers are not referenced so the events of deleted reports are kept for replay --
+CREATE TABLE IF NOT EXISTS report_events
+(
+    event_id       bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event_type     varchar(40)         NOT NULL,
+    job_report_id  int(6) unsigned     NOT NULL,
+    worker_id      int(5) unsigned     NULL, -- who the report is assigned to after the change, NULL in the queue
+    from_worker_id int(5) unsigned     NULL, -- who the report was assigned to before it moved
+    status         varchar(20)         NOT NULL DEFAULT '', -- what happened for report.status e.g. claimed
+    created_at     datetime            NOT NULL,
+    PRIMARY KEY (event_id),
+    INDEX (created_at)
+) ENGINE = InnoDB;
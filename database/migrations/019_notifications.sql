@@ -0,0 +1,45 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 019: Notifications --
+-- Customers are sent messages about their jobs and bookings by email and SMS, with their preferences and a log. --
+
+use repotadb;
+
+-- notification_preferences table for how each customer is sent messages, matched to their jobs by name --
+CREATE TABLE IF NOT EXISTS notification_preferences
+(
+    customer_name varchar(100)         NOT NULL,
+    email         varchar(100)         NOT NULL DEFAULT '',
+    phone         varchar(30)          NOT NULL DEFAULT '', -- in international format e.g. +353871234567
+    channels      set ('email', 'sms') NOT NULL DEFAULT 'email',
+    opted_out_at  datetime             NULL,                -- when the customer stopped messages, in UTC
+    opt_out_token varchar(64)          NOT NULL UNIQUE,     -- the link the customer opts out at
+    created_at    TIMESTAMP            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (customer_name)
+) ENGINE = InnoDB;
+
+-- notification_messages table, the log of messages to customers and the outbox they are sent from, times are in UTC --
+-- reports are not referenced so the messages of deleted reports are kept --
+CREATE TABLE IF NOT EXISTS notification_messages
+(
+    message_id      bigint(12) unsigned NOT NULL AUTO_INCREMENT,
+    event           varchar(40)         NOT NULL,
+    channel         enum ('email', 'sms') NOT NULL,
+    customer_name   varchar(100)        NOT NULL,
+    recipient       varchar(100)        NOT NULL, -- email address or phone number
+    job_report_id   int(6) unsigned     NULL,
+    subject         varchar(255)        NOT NULL DEFAULT '',
+    body            text                NOT NULL,
+    status          enum ('pending', 'sent', 'failed', 'skipped') NOT NULL DEFAULT 'pending',
+    attempts        int(3) unsigned     NOT NULL DEFAULT 0,
+    next_attempt_at datetime            NULL,
+    last_error      varchar(255)        NULL,
+    provider_id     varchar(255)        NULL, -- Message-ID of an email or the SMS gateway's ID
+    sent_at         datetime            NULL,
+    created_at      datetime            NOT NULL,
+    PRIMARY KEY (message_id),
+    INDEX (status, next_attempt_at),
+    INDEX (customer_name),
+    INDEX (job_report_id)
+) ENGINE = InnoDB;
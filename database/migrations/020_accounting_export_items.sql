@@ -0,0 +1,63 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 020: Accounting Export Items --
+-- Runs of exports record each invoice and payment they export, rather than exporting by time and payment ID. --
+-- Invoices and payments committed after a run had read past them were never exported, now they are by the next run. --
+
+use repotadb;
+
+-- accounting_export_formats table with a row for each user and format, locked by a run so two runs cannot cross --
+CREATE TABLE IF NOT EXISTS accounting_export_formats
+(
+    worker_id int(5) unsigned NOT NULL,
+    format    varchar(20)     NOT NULL,
+    PRIMARY KEY (worker_id, format),
+    FOREIGN KEY (worker_id) REFERENCES workers (worker_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- accounting_exported_invoices and accounting_exported_payments tables for what each run exported --
+-- a run exports the documents and payments not yet exported to its format, whenever they were committed --
+CREATE TABLE IF NOT EXISTS accounting_exported_invoices
+(
+    format     varchar(20)     NOT NULL,
+    invoice_id int(8) unsigned NOT NULL,
+    export_id  int(8) unsigned NOT NULL,
+    PRIMARY KEY (format, invoice_id),
+    INDEX (export_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (invoice_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (export_id) REFERENCES accounting_exports (export_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS accounting_exported_payments
+(
+    format     varchar(20)      NOT NULL,
+    payment_id int(10) unsigned NOT NULL,
+    export_id  int(8) unsigned  NOT NULL,
+    PRIMARY KEY (format, payment_id),
+    INDEX (export_id),
+    FOREIGN KEY (payment_id) REFERENCES payment_ledger (payment_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (export_id) REFERENCES accounting_exports (export_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
+
+-- Record what the runs already made exported, so it is not exported again. --
+INSERT IGNORE INTO accounting_exported_invoices (format, invoice_id, export_id)
+SELECT ae.format, inv.invoice_id, ae.export_id
+FROM accounting_exports ae
+         INNER JOIN jobreports jr ON jr.worker_id = ae.worker_id
+         INNER JOIN invoices inv ON inv.job_report_id = jr.job_report_id
+WHERE inv.invoice_number IS NOT NULL
+  AND (ae.from_time IS NULL OR inv.issued_at > ae.from_time)
+  AND inv.issued_at <= ae.up_to;
+
+INSERT IGNORE INTO accounting_exported_payments (format, payment_id, export_id)
+SELECT ae.format, pl.payment_id, ae.export_id
+FROM accounting_exports ae
+         INNER JOIN jobreports jr ON jr.worker_id = ae.worker_id
+         INNER JOIN invoices inv ON inv.job_report_id = jr.job_report_id
+         INNER JOIN payment_ledger pl ON pl.invoice_id = inv.invoice_id
+WHERE pl.payment_id > ae.from_payment_id
+  AND pl.payment_id <= ae.to_payment_id;
+
+INSERT IGNORE INTO accounting_export_formats (worker_id, format)
+SELECT DISTINCT worker_id, format
+FROM accounting_exports;
@@ -0,0 +1,17 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 021: SLA Alerts --
+-- Webhook endpoints are sent an event once when a stage of a breakdown is at risk of breaching its target or has. --
+
+use repotadb;
+
+-- sla_alerts table for the stages of breakdowns at risk or breached that events were sent of, times are in UTC --
+CREATE TABLE IF NOT EXISTS sla_alerts
+(
+    breakdown_id int(8) unsigned NOT NULL,
+    stage        enum ('acknowledge', 'arrive', 'resolve') NOT NULL,
+    event_type   varchar(40)     NOT NULL, -- breakdown.sla_at_risk or breakdown.sla_breached
+    created_at   datetime        NOT NULL,
+    PRIMARY KEY (breakdown_id, stage, event_type),
+    FOREIGN KEY (breakdown_id) REFERENCES breakdowns (breakdown_id) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,37 @@
+-- REPOTA DATABASE --
+-- repotadb --
+-- Migration 022: Notification Contacts --
+-- Notification preferences are a contact with an ID that reports and estimates are linked to, rather than being --
+-- matched to them by customer name, so customers with the same name do not share an email, phone or opt-out. --
+
+use repotadb;
+
+ALTER TABLE notification_preferences
+    DROP PRIMARY KEY,
+    ADD COLUMN contact_id int(8) unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST,
+    ADD INDEX (customer_name),
+    ADD INDEX (email),
+    ADD INDEX (phone);
+
+ALTER TABLE customers
+    ADD COLUMN contact_id int(8) unsigned NULL AFTER customer_complaint,
+    ADD FOREIGN KEY (contact_id) REFERENCES notification_preferences (contact_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE estimates
+    ADD COLUMN contact_id int(8) unsigned AFTER job_report_id,
+    ADD FOREIGN KEY (contact_id) REFERENCES notification_preferences (contact_id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE notification_messages
+    ADD COLUMN contact_id int(8) unsigned NULL AFTER customer_name,
+    ADD INDEX (contact_id);
+
+-- Link the reports, estimates and messages matched by name until now, names were unique so each has one match. --
+-- Check the contacts of customers who share a name, they were sent each other's messages. --
+UPDATE customers cust INNER JOIN notification_preferences np ON cust.customer_name = np.customer_name
+SET cust.contact_id = np.contact_id;
+
+UPDATE estimates est INNER JOIN notification_preferences np ON est.customer_name = np.customer_name
+SET est.contact_id = np.contact_id;
+
+UPDATE notification_messages nm INNER JOIN notification_preferences np ON nm.customer_name = np.customer_name
+SET nm.contact_id = np.contact_id;
@@ -0,0 +1,144 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Accounting
+ * Exports invoices, credit notes and payments to the import files of accounting packages.
+ * Each package has an Exporter, found by its format with New. Which nominal code (ledger account)
+ * and tax code each amount is posted to is set in Codes, loaded from the package's section of config.ini.
+ */
+
+package accounting
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Exporter writes a Batch to the import file of an accounting package.
+type Exporter interface {
+	// Name is the format the exporter is found by e.g. "xero".
+	Name() string
+	// Export writes every document and payment of batch as CSV to w.
+	// Nothing is written if an amount has no code in codes.
+	Export(w io.Writer, batch Batch, codes Codes) error
+}
+
+// Batch is what is exported in one run.
+type Batch struct {
+	// Documents are issued invoices and credit notes with their lines.
+	Documents []models.Invoice
+	Payments  []Payment
+}
+
+// Payment is a payment or refund with the invoice it was against.
+type Payment struct {
+	models.Payment
+	InvoiceNumber string
+	BillToName    string
+}
+
+// Codes are the nominal and tax codes amounts are posted to.
+type Codes struct {
+	// Sales is the nominal code of each kind of invoice line.
+	Sales map[string]string
+	// Bank is the nominal code of the bank account payments of each method are paid into.
+	Bank map[string]string
+	// Tax is the tax code of each VAT rate, by the rate as it is written e.g. "23" or "13.5".
+	Tax map[string]string
+	// Debtors is the nominal code of the debtors (accounts receivable) control account.
+	Debtors string
+	// NoTax is the tax code of amounts VAT is not charged on, like payments.
+	NoTax string
+}
+
+var exporters = map[string]Exporter{}
+
+func register(exporter Exporter) {
+	exporters[exporter.Name()] = exporter
+}
+
+// New returns the Exporter of format.
+func New(format string) (Exporter, error) {
+	exporter, ok := exporters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown accounting format %q", format)
+	}
+	return exporter, nil
+}
+
+// Formats returns the formats there are Exporters of, in order.
+func Formats() []string {
+	var formats []string
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// SalesCode returns the nominal code of lines of kind.
+func (codes Codes) SalesCode(kind string) (string, error) {
+	return lookup(codes.Sales, kind, "sales of "+kind)
+}
+
+// BankCode returns the nominal code payments by method are paid into.
+func (codes Codes) BankCode(method string) (string, error) {
+	return lookup(codes.Bank, method, "payments by "+method)
+}
+
+// TaxCode returns the tax code of the VAT rate, 0 being zero rated.
+func (codes Codes) TaxCode(rate *float64) (string, error) {
+	if rate == nil {
+		return "", errors.New("a line has no VAT rate")
+	}
+	key := strconv.FormatFloat(*rate, 'f', -1, 64)
+	return lookup(codes.Tax, key, "VAT at "+key+"%")
+}
+
+func lookup(codes map[string]string, key, what string) (string, error) {
+	if code := codes[key]; code != "" {
+		return code, nil
+	}
+	return "", fmt.Errorf("no code is set for %s", what)
+}
+
+// Function to write rows as CSV, only once every row has been made so a failed export writes nothing.
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+// Function to format cents as an amount e.g. -1234 as "-12.34".
+func amount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Function to get the date a document was issued on.
+func issued(doc models.Invoice) string {
+	if doc.IssuedAt == nil {
+		return ""
+	}
+	return doc.IssuedAt.Format(dateLayout)
+}
+
+// Date layout of both Xero and Sage in Ireland and the UK.
+const dateLayout = "02/01/2006"
+
+const creditNote = "credit_note"
@@ -0,0 +1,111 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Sage
+ * Exports to the Sage 50 audit trail transactions import file.
+ * Invoices are SI rows and credit notes SC rows, one for each line with its net, tax code and VAT.
+ * Payments are SA rows (payments on account) to be allocated to their invoices in Sage.
+ * Sage cannot import refunds to customers, they are BP rows (bank payments) to the debtors control account.
+ * Customer account references are made from the customer's name, see SageAccountRef.
+ */
+
+package accounting
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SageExporter writes Sage 50 audit trail transactions.
+type SageExporter struct{}
+
+func init() {
+	register(SageExporter{})
+}
+
+func (SageExporter) Name() string {
+	return "sage"
+}
+
+var sageHeader = []string{"Type", "Account Reference", "Nominal A/C Ref", "Department Code", "Date", "Reference",
+	"Details", "Net Amount", "Tax Code", "Tax Amount", "Exchange Rate", "Extra Reference", "User Name",
+	"Project Refn", "Cost Code Refn"}
+
+func (SageExporter) Export(w io.Writer, batch Batch, codes Codes) error {
+	var rows [][]string
+	row := func(kind, account, nominal, date, reference, details string, net int64, tax string, vat int64,
+		extra string) {
+		rows = append(rows, []string{kind, account, nominal, "0", date, reference, truncate(details, 60), amount(net),
+			tax, amount(vat), "", truncate(extra, 30), "", "", ""})
+	}
+
+	for _, doc := range batch.Documents {
+		kind := "SI"
+		if doc.Kind == creditNote {
+			kind = "SC"
+		}
+		account := SageAccountRef(doc.BillToName)
+		date := issued(doc)
+		extra := "Job Report " + strconv.Itoa(int(doc.JobReportId))
+
+		for _, line := range doc.Lines {
+			nominal, err := codes.SalesCode(line.Kind)
+			if err != nil {
+				return err
+			}
+			tax, err := codes.TaxCode(line.VatRate)
+			if err != nil {
+				return err
+			}
+			row(kind, account, nominal, date, doc.InvoiceNumber, line.Description, line.NetCents, tax, line.VatCents,
+				extra)
+		}
+	}
+
+	for _, payment := range batch.Payments {
+		bank, err := codes.BankCode(payment.Method)
+		if err != nil {
+			return err
+		}
+		date := payment.CreatedAt.Format(dateLayout)
+		extra := payment.Reference
+		if extra == "" {
+			extra = payment.ProviderRef
+		}
+
+		if payment.AmountCents >= 0 {
+			row("SA", SageAccountRef(payment.BillToName), bank, date, payment.InvoiceNumber,
+				"Payment by "+payment.Method, payment.AmountCents, codes.NoTax, 0, extra)
+		} else {
+			row("BP", bank, codes.Debtors, date, payment.InvoiceNumber,
+				"Refund to "+payment.BillToName, -payment.AmountCents, codes.NoTax, 0, extra)
+		}
+	}
+
+	return writeCSV(w, sageHeader, rows)
+}
+
+// SageAccountRef returns the Sage customer account reference of a customer,
+// the letters and digits of their name in upper case up to Sage's limit of 8 e.g. "JOEKENDA".
+func SageAccountRef(name string) string {
+	var ref strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			ref.WriteRune(r)
+			if ref.Len() == 8 {
+				break
+			}
+		}
+	}
+	return ref.String()
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Xero
+ * Exports to the Xero manual journal import file, to be imported with amounts tax exclusive.
+ * Each invoice, credit note, payment and refund is a journal, debits are positive and credits negative.
+ * Sales lines are given their net and tax rate so Xero posts the VAT of each line itself,
+ * with it the lines of each journal add up to zero.
+ */
+
+package accounting
+
+import (
+	"io"
+)
+
+// XeroExporter writes Xero manual journals.
+type XeroExporter struct{}
+
+func init() {
+	register(XeroExporter{})
+}
+
+func (XeroExporter) Name() string {
+	return "xero"
+}
+
+var xeroHeader = []string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount",
+	"TrackingName1", "TrackingOption1", "TrackingName2", "TrackingOption2"}
+
+func (XeroExporter) Export(w io.Writer, batch Batch, codes Codes) error {
+	var rows [][]string
+	row := func(narration, date, description, account, tax string, cents int64) {
+		rows = append(rows, []string{narration, date, description, account, tax, amount(cents), "", "", "", ""})
+	}
+
+	for _, doc := range batch.Documents {
+		// Invoices are debited to the customer, credit notes credited.
+		sign, narration := int64(1), "Invoice "+doc.InvoiceNumber+" - "+doc.BillToName
+		if doc.Kind == creditNote {
+			sign, narration = -1, "Credit Note "+doc.InvoiceNumber+" - "+doc.BillToName
+		}
+		date := issued(doc)
+
+		row(narration, date, doc.BillToName, codes.Debtors, codes.NoTax, sign*doc.TotalCents)
+		for _, line := range doc.Lines {
+			account, err := codes.SalesCode(line.Kind)
+			if err != nil {
+				return err
+			}
+			tax, err := codes.TaxCode(line.VatRate)
+			if err != nil {
+				return err
+			}
+			row(narration, date, line.Description, account, tax, -sign*line.NetCents)
+		}
+	}
+
+	for _, payment := range batch.Payments {
+		bank, err := codes.BankCode(payment.Method)
+		if err != nil {
+			return err
+		}
+		narration := "Payment of " + payment.InvoiceNumber + " - " + payment.BillToName
+		if payment.AmountCents < 0 {
+			narration = "Refund of " + payment.InvoiceNumber + " - " + payment.BillToName
+		}
+		date := payment.CreatedAt.Format(dateLayout)
+		description := payment.Method
+		if payment.Reference != "" {
+			description += " " + payment.Reference
+		}
+
+		row(narration, date, description, bank, codes.NoTax, payment.AmountCents)
+		row(narration, date, description, codes.Debtors, codes.NoTax, -payment.AmountCents)
+	}
+
+	return writeCSV(w, xeroHeader, rows)
+}
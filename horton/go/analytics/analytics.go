@@ -0,0 +1,162 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Analytics
+ * Keeps the results of analytics queries for a short time and works out the figures managers are shown from them.
+ * The aggregates are worked out by the database, results are cached as they are sent so a dashboard refreshing
+ * does not run the queries again.
+ */
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Periods results can be grouped by, All for one group over the whole date range.
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+	Year  = "year"
+	All   = "all"
+)
+
+// Periods is every period results can be grouped by.
+var Periods = []string{Day, Week, Month, Year, All}
+
+// CheckPeriod returns an error if period is not one results can be grouped by.
+func CheckPeriod(period string) error {
+	for _, p := range Periods {
+		if p == period {
+			return nil
+		}
+	}
+	return fmt.Errorf("period must be day, week, month, year or all, not %q", period)
+}
+
+// Rate returns part as a percentage of whole rounded to one decimal place, nil if whole is 0.
+func Rate(part, whole int) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	rate := math.Round(float64(part)*1000/float64(whole)) / 10
+	return &rate
+}
+
+// Quantities written before or after a part, like "2 x Brake pads", "1 DOOR LOCK", "2x wipers" or "BULBS X2".
+var (
+	quantityBefore = regexp.MustCompile(`^\d+\s*(?:[xX×]\s+|\s+)`)
+	quantityAfter  = regexp.MustCompile(`\s+[xX×]\s*\d+$`)
+)
+
+// Parts returns the parts in the comma separated parts of a job, in upper case without their quantities and each
+// once, e.g. "2 CABLES, 2 x Brake pads" is CABLES and BRAKE PADS. A quantity on its own is not a part.
+func Parts(list string) []string {
+	var parts []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(list, ",") {
+		part = strings.Join(strings.Fields(part), " ")
+		part = quantityAfter.ReplaceAllString(quantityBefore.ReplaceAllString(part, ""), "")
+		part = strings.ToUpper(part)
+		if strings.Trim(part, "0123456789") != "" && !seen[part] {
+			seen[part] = true
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// Count is how many times a value was found in a group.
+type Count struct {
+	Group string
+	Value string
+	N     int
+}
+
+// Top returns up to limit of the values found most in each group, groups in order then most found first.
+func Top(counts []Count, limit int) []Count {
+	sorted := append([]Count(nil), counts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Group != sorted[j].Group {
+			return sorted[i].Group < sorted[j].Group
+		}
+		if sorted[i].N != sorted[j].N {
+			return sorted[i].N > sorted[j].N
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	top := []Count{}
+	inGroup := 0
+	for i, c := range sorted {
+		if i == 0 || c.Group != sorted[i-1].Group {
+			inGroup = 0
+		}
+		if inGroup < limit {
+			top = append(top, c)
+		}
+		inGroup++
+	}
+	return top
+}
+
+// Cache keeps results for a time to live, it is safe to share between requests.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[string]entry
+}
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewCache returns a Cache keeping up to max results for ttl each. A ttl of 0 keeps nothing.
+func NewCache(ttl time.Duration, max int) *Cache {
+	return &Cache{ttl: ttl, max: max, entries: map[string]entry{}}
+}
+
+// Get returns the result kept for key at now, false if there is none or it has expired.
+func (c *Cache) Get(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set keeps a result for key from now. When the cache is full expired results are dropped, then the result
+// that expires soonest.
+func (c *Cache) Set(key string, value interface{}, now time.Time) {
+	if c.ttl <= 0 || c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.max {
+		soonest := ""
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			} else if soonest == "" || e.expires.Before(c.entries[soonest].expires) {
+				soonest = k
+			}
+		}
+		if len(c.entries) >= c.max {
+			delete(c.entries, soonest)
+		}
+	}
+	c.entries[key] = entry{value: value, expires: now.Add(c.ttl)}
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
 	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -48,6 +49,7 @@ func Login(c *gin.Context) {
 
 	// Check if user exists in the database and check password is not null.
 	if err := verifyDetails(username, password); err != nil {
+		metrics.LoginFailures.Inc("unknown_user")
 		c.JSON(403, models.Error{Code: 403, Messages: "Username does not exist"})
 		return // Return as there is issues with the username.
 	}
@@ -76,6 +78,7 @@ func Login(c *gin.Context) {
 		}
 	} else {
 		log.Println("Password is incorrect for User", err)
+		metrics.LoginFailures.Inc("wrong_password")
 		c.JSON(401, models.Error{Code: 401, Messages: "Password is incorrect"})
 	}
 	defer db.Close()
@@ -172,7 +175,7 @@ func isValidAccount(username string) bool {
 	//db := mocks.MockDbConn()
 
 	// Check username from workers table.
-	selDB, err := db.Query("SELECT * FROM workers WHERE username=?", username)
+	selDB, err := db.Query("SELECT worker_id, username, worker_name, hash, role FROM workers WHERE username=?", username)
 
 	if err != nil {
 		log.Fatal(err) // error with Query.
@@ -181,7 +184,7 @@ func isValidAccount(username string) bool {
 
 	// Check to see if a true user exists in the table, if not return false.
 	if selDB.Next() {
-		err = selDB.Scan(&wa.Id, &wa.Username, &wa.WorkerName, &wa.Password)
+		err = selDB.Scan(&wa.Id, &wa.Username, &wa.WorkerName, &wa.Password, &wa.Role)
 
 		if err != nil {
 			// No matching username in table (user does not exist).
@@ -0,0 +1,343 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Accounting Export
+ * Handles exporting invoices, credit notes and payments to accounting packages - Export, Get Exports & Download.
+ * Each run exports only what has not been exported to its format before, what each run exported is kept in the
+ * accounting_exported_invoices and accounting_exported_payments tables so a run's file can be downloaded again.
+ * The formats are in the accounting package.
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/accounting"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectAccountingExports is the JOIN Query shared by the functions that get export runs, each adds its own WHERE clause.
+const selectAccountingExports = "SELECT ae.export_id, ae.format, ae.from_time, ae.up_to, ae.from_payment_id, " +
+	"ae.to_payment_id, ae.documents, ae.payments, ae.created_at FROM accounting_exports ae " +
+	"INNER JOIN workers wkr ON ae.worker_id = wkr.worker_id "
+
+// ExportAccounts
+// Works with CheckForCookie, isValidAccount, nextAccountingExport, loadAccountingBatch & recordAccountingExport.
+// If the user has a cookie, export the invoices, credit notes and payments of their reports not yet exported
+// to ?format= (xero or sage) as CSV. Nothing is recorded if there is nothing new or the export fails.
+func ExportAccounts(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to export accounts")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	format := c.Query("format")
+	exporter, err := accounting.New(format)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400,
+			Messages: "format must be one of " + strings.Join(accounting.Formats(), ", ")})
+		return
+	}
+
+	codes, err := config.AccountingCodes(format)
+	if err != nil {
+		log.Println("Failed to load config file for accounting codes.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Exporting Accounts.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	run, err := nextAccountingExport(tx, format)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Exporting Accounts.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	batch, err := loadAccountingBatch(tx, run)
+	if err != nil {
+		log.Println("\nFailed to load Invoices and Payments for export.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if len(batch.Documents) == 0 && len(batch.Payments) == 0 {
+		c.JSON(204, nil) // Nothing new since the last run.
+		return
+	}
+	var file bytes.Buffer
+	if err := exporter.Export(&file, batch, codes); err != nil {
+		log.Println("\nFailed to export accounts.", err)
+		c.JSON(500, models.Error{Code: 500,
+			Messages: fmt.Sprintf("Unable to export, %s in [%s] in config.ini", err, format)})
+		return
+	}
+
+	err = recordAccountingExport(tx, &run, batch)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Recording Accounting Export.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Accounts exported to", format, "- Documents:", run.Documents, "Payments:", run.Payments)
+	sendAccountingExport(c, run, file.Bytes())
+}
+
+// GetAccountingExports
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the runs of their exports to accounting packages, newest first.
+func GetAccountingExports(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Accounting Exports")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectAccountingExports+"WHERE wkr.username = ? ORDER BY ae.export_id DESC", wa.Username)
+	if err != nil {
+		log.Println("\nFailed to process Accounting Exports.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	runs := []models.AccountingExport{}
+	for selDB.Next() {
+		run, err := scanAccountingExport(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Accounting Exports.", err)
+			c.JSON(500, nil)
+			return
+		}
+		runs = append(runs, run)
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetAccountingExport
+// Works with CheckForCookie, isValidAccount & loadAccountingBatch.
+// If the user has a cookie and the run is theirs, download the file of an earlier run again,
+// with the same invoices and payments and the codes now in config.ini.
+func GetAccountingExport(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Accounting Export")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	exportId, _ := strconv.Atoi(c.Params.ByName("exportId"))
+	selDB, err := db.Query(selectAccountingExports+"WHERE ae.export_id = ? AND wkr.username = ?", exportId,
+		wa.Username)
+	if err != nil {
+		log.Println("\nFailed to process Accounting Export.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if !selDB.Next() {
+		selDB.Close()
+		c.JSON(404, models.Error{Code: 404, Messages: "Accounting Export not found"})
+		return
+	}
+	run, err := scanAccountingExport(selDB)
+	selDB.Close()
+	if err != nil {
+		log.Println("\nFailed to load Accounting Export.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	exporter, err := accounting.New(run.Format)
+	if err == nil {
+		var codes accounting.Codes
+		codes, err = config.AccountingCodes(run.Format)
+		if err == nil {
+			var batch accounting.Batch
+			batch, err = loadAccountingBatch(db, run)
+			if err == nil {
+				var file bytes.Buffer
+				if err = exporter.Export(&file, batch, codes); err == nil {
+					sendAccountingExport(c, run, file.Bytes())
+					return
+				}
+			}
+		}
+	}
+	log.Println("\nFailed to export accounts again.", err)
+	c.JSON(500, models.Error{Code: 500, Messages: "Unable to export accounts"})
+}
+
+// Function to start the next run of an export of format. The user's row of the format is locked until tx ends, so
+// two runs cannot export the same invoices and payments.
+func nextAccountingExport(tx *sql.Tx, format string) (models.AccountingExport, error) {
+	run := models.AccountingExport{Format: format, UpTo: time.Now().UTC().Truncate(time.Second)}
+
+	_, err := tx.Exec("INSERT INTO accounting_export_formats (worker_id, format) VALUES (?, ?) "+
+		"ON DUPLICATE KEY UPDATE format = VALUES(format)", wa.Id, format)
+	if err != nil {
+		return run, err
+	}
+
+	var last sql.NullTime
+	err = tx.QueryRow("SELECT MAX(up_to) FROM accounting_exports WHERE worker_id = ? AND format = ?", wa.Id,
+		format).Scan(&last)
+	if last.Valid {
+		run.FromTime = &last.Time
+	}
+	return run, err
+}
+
+// Function to get the invoices, credit notes and payments of a run of an export. A new run, without an ID, gets
+// those of the user's reports not yet exported to its format, whenever they were committed.
+func loadAccountingBatch(db dbExecutor, run models.AccountingExport) (accounting.Batch, error) {
+	var batch accounting.Batch
+	invoices := "INNER JOIN accounting_exported_invoices aei ON aei.invoice_id = inv.invoice_id " +
+		"WHERE aei.export_id = ?"
+	payments := "INNER JOIN accounting_exported_payments aep ON aep.payment_id = pl.payment_id " +
+		"WHERE aep.export_id = ?"
+	args := []interface{}{run.ExportId}
+	if run.ExportId == 0 {
+		invoices = "LEFT JOIN accounting_exported_invoices aei ON aei.invoice_id = inv.invoice_id AND aei.format = ? " +
+			"WHERE aei.invoice_id IS NULL AND wkr.username = ? AND inv.invoice_number IS NOT NULL"
+		payments = "LEFT JOIN accounting_exported_payments aep ON aep.payment_id = pl.payment_id AND aep.format = ? " +
+			"WHERE aep.payment_id IS NULL AND wkr.username = ?"
+		args = []interface{}{run.Format, wa.Username}
+	}
+
+	selDB, err := db.Query(selectInvoices+invoices+" ORDER BY inv.issued_at, inv.invoice_id", args...)
+	if err != nil {
+		return batch, err
+	}
+	for selDB.Next() {
+		doc, err := scanInvoice(selDB)
+		if err != nil {
+			selDB.Close()
+			return batch, err
+		}
+		batch.Documents = append(batch.Documents, doc)
+	}
+	selDB.Close()
+	if err := selDB.Err(); err != nil {
+		return batch, err
+	}
+	for i := range batch.Documents {
+		if batch.Documents[i].Lines, err = loadLines(db, kindInvoice, batch.Documents[i].InvoiceId); err != nil {
+			return batch, err
+		}
+	}
+
+	selDB, err = db.Query("SELECT "+paymentColumns+", COALESCE(inv.invoice_number, ''), inv.bill_to_name"+
+		paymentTables+payments+" ORDER BY pl.payment_id", args...)
+	if err != nil {
+		return batch, err
+	}
+	defer selDB.Close()
+	for selDB.Next() {
+		var payment accounting.Payment
+		payment.Payment, err = scanPayment(selDB, &payment.InvoiceNumber, &payment.BillToName)
+		if err != nil {
+			return batch, err
+		}
+		batch.Payments = append(batch.Payments, payment)
+	}
+	return batch, selDB.Err()
+}
+
+// Function to record a run of an export in tx with the invoices and payments it exported, so they are not
+// exported to its format again.
+func recordAccountingExport(tx *sql.Tx, run *models.AccountingExport, batch accounting.Batch) error {
+	run.Documents, run.Payments = len(batch.Documents), len(batch.Payments)
+	if len(batch.Payments) > 0 {
+		run.FromPaymentId = batch.Payments[0].PaymentId
+		run.ToPaymentId = batch.Payments[len(batch.Payments)-1].PaymentId
+	}
+
+	res, err := tx.Exec("INSERT INTO accounting_exports (worker_id, format, from_time, up_to, from_payment_id, "+
+		"to_payment_id, documents, payments) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", wa.Id, run.Format, run.FromTime,
+		run.UpTo, run.FromPaymentId, run.ToPaymentId, run.Documents, run.Payments)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	run.ExportId = int32(id)
+
+	for _, doc := range batch.Documents {
+		if _, err := tx.Exec("INSERT INTO accounting_exported_invoices (format, invoice_id, export_id) "+
+			"VALUES (?, ?, ?)", run.Format, doc.InvoiceId, run.ExportId); err != nil {
+			return err
+		}
+	}
+	for _, payment := range batch.Payments {
+		if _, err := tx.Exec("INSERT INTO accounting_exported_payments (format, payment_id, export_id) "+
+			"VALUES (?, ?, ?)", run.Format, payment.PaymentId, run.ExportId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to read a record from the selectAccountingExports Query into an AccountingExport object.
+func scanAccountingExport(rows *sql.Rows) (models.AccountingExport, error) {
+	var run models.AccountingExport
+	var from sql.NullTime
+	err := rows.Scan(&run.ExportId, &run.Format, &from, &run.UpTo, &run.FromPaymentId, &run.ToPaymentId,
+		&run.Documents, &run.Payments, &run.CreatedAt)
+	if from.Valid {
+		run.FromTime = &from.Time
+	}
+	return run, err
+}
+
+// Function to send the file of a run of an export.
+func sendAccountingExport(c *gin.Context, run models.AccountingExport, file []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d-%s.csv"`, run.Format, run.ExportId,
+		run.UpTo.Format("2006-01-02")))
+	c.Header("X-Export-Id", strconv.Itoa(int(run.ExportId)))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", file)
+}
@@ -0,0 +1,364 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Analytics
+ * Handles the figures of workshop performance managers are shown - Worker Activity, Common Causes & Parts,
+ * Warranty Work, Hours by Job Type & Completion Rates. Each is an SQL aggregate over the job reports in a date range
+ * (?from= & ?to=), grouped by ?period= and ?by=, and can be limited to a ?worker= or ?customer=.
+ * Results are cached for cache_seconds in config.ini.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/analytics"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// analyticsFrom is the JOIN analytics queries are over, each adds its own columns before and WHERE clause after.
+const analyticsFrom = " FROM jobreports jr INNER JOIN customers cust ON jr.job_report_id = cust.job_report_id " +
+	"LEFT JOIN workers wkr ON jr.worker_id = wkr.worker_id "
+
+// The job type of a report, breakdown call-outs, warranty work or paid work.
+const jobType = "CASE WHEN jr.breakdown = 1 THEN 'breakdown' WHEN jr.warranty = 1 THEN 'warranty' ELSE 'paid' END"
+
+// The columns rows are grouped by for each period and for each ?by=.
+var (
+	periodColumns = map[string]string{
+		analytics.Day:   "DATE_FORMAT(jr.date_stamp, '%Y-%m-%d')",
+		analytics.Week:  "DATE_FORMAT(jr.date_stamp, '%x-W%v')",
+		analytics.Month: "DATE_FORMAT(jr.date_stamp, '%Y-%m')",
+		analytics.Year:  "DATE_FORMAT(jr.date_stamp, '%Y')",
+		analytics.All:   "'all'",
+	}
+	groupColumns = map[string]string{
+		"type":     jobType,
+		"worker":   "COALESCE(wkr.username, '')",
+		"model":    "jr.vehicle_model",
+		"customer": "cust.customer_name",
+		"none":     "''",
+	}
+)
+
+// The most values listed for each vehicle model by GetCauseAnalytics and GetPartsAnalytics.
+const maxCommonValues = 50
+
+// analyticsFilter is the date range, grouping and filters of an analytics request.
+type analyticsFilter struct {
+	from, to   time.Time
+	period, by string
+	limit      int
+	// where is the WHERE clause of the date range and filters, args its arguments.
+	where string
+	args  []interface{}
+}
+
+// GetWorkerAnalytics
+// Works with serveAnalytics.
+// If the user has a cookie and is a supervisor, get the jobs, completed jobs and hours of each worker in each
+// period.
+func GetWorkerAnalytics(c *gin.Context) {
+	serveAnalytics(c, "workers", true, nil, func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+		selDB, err := db.Query("SELECT "+periodColumns[f.period]+", COALESCE(wkr.username, ''), "+
+			"COALESCE(wkr.worker_name, ''), COUNT(*), SUM(jr.job_report_complete), COALESCE(SUM(jr.work_hours), 0)"+
+			analyticsFrom+f.where+" GROUP BY 1, 2, 3 ORDER BY 1, 2", f.args...)
+		if err != nil {
+			return nil, err
+		}
+		defer selDB.Close()
+
+		rows := []models.WorkerActivity{}
+		for selDB.Next() {
+			var row models.WorkerActivity
+			if err := selDB.Scan(&row.Period, &row.Worker, &row.WorkerName, &row.Jobs, &row.Completed,
+				&row.Hours); err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+		return rows, selDB.Err()
+	})
+}
+
+// GetCauseAnalytics
+// Works with serveAnalytics & commonValues.
+// If the user has a cookie and is a supervisor, get the most common causes of the jobs of each vehicle model,
+// ?limit= of them (5 by default), or of ?model=.
+func GetCauseAnalytics(c *gin.Context) {
+	serveAnalytics(c, "causes", false, nil, func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+		return commonValues(db, "TRIM(jr.cause)", nil, f)
+	})
+}
+
+// GetPartsAnalytics
+// Works with serveAnalytics & commonValues.
+// If the user has a cookie and is a supervisor, get the parts most often fitted in the jobs of each vehicle model,
+// ?limit= of them (5 by default), or of ?model=. Each part of a job's list is counted without its quantity.
+func GetPartsAnalytics(c *gin.Context) {
+	serveAnalytics(c, "parts", false, nil, func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+		return commonValues(db, "TRIM(jr.parts)", analytics.Parts, f)
+	})
+}
+
+// GetWarrantyAnalytics
+// Works with serveAnalytics.
+// If the user has a cookie and is a supervisor, get the jobs and hours of warranty and paid work in each period.
+func GetWarrantyAnalytics(c *gin.Context) {
+	serveAnalytics(c, "warranty", true, nil, func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+		selDB, err := db.Query("SELECT "+periodColumns[f.period]+", SUM(jr.warranty = 1), SUM(jr.warranty = 0), "+
+			"COALESCE(SUM(IF(jr.warranty = 1, jr.work_hours, 0)), 0), "+
+			"COALESCE(SUM(IF(jr.warranty = 0, jr.work_hours, 0)), 0)"+analyticsFrom+f.where+" GROUP BY 1 ORDER BY 1", f.args...)
+		if err != nil {
+			return nil, err
+		}
+		defer selDB.Close()
+
+		rows := []models.WarrantySplit{}
+		for selDB.Next() {
+			var row models.WarrantySplit
+			if err := selDB.Scan(&row.Period, &row.WarrantyJobs, &row.PaidJobs, &row.WarrantyHours,
+				&row.PaidHours); err != nil {
+				return nil, err
+			}
+			row.WarrantyPercent = analytics.Rate(row.WarrantyJobs, row.WarrantyJobs+row.PaidJobs)
+			rows = append(rows, row)
+		}
+		return rows, selDB.Err()
+	})
+}
+
+// GetHoursAnalytics
+// Works with serveAnalytics.
+// If the user has a cookie and is a supervisor, get the total and average hours of completed jobs by ?by=,
+// job type (breakdown, warranty or paid, the default), worker, model or customer.
+func GetHoursAnalytics(c *gin.Context) {
+	serveAnalytics(c, "hours", false, []string{"type", "worker", "model", "customer"},
+		func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+			selDB, err := db.Query("SELECT "+groupColumns[f.by]+", COUNT(*), COALESCE(SUM(jr.work_hours), 0), "+
+				"AVG(jr.work_hours)"+analyticsFrom+f.where+" AND jr.job_report_complete = 1 GROUP BY 1 ORDER BY 1",
+				f.args...)
+			if err != nil {
+				return nil, err
+			}
+			defer selDB.Close()
+
+			rows := []models.GroupHours{}
+			for selDB.Next() {
+				var row models.GroupHours
+				var average sql.NullFloat64
+				if err := selDB.Scan(&row.Group, &row.Jobs, &row.TotalHours, &average); err != nil {
+					return nil, err
+				}
+				if average.Valid {
+					rounded := math.Round(average.Float64*10) / 10
+					row.AverageHours = &rounded
+				}
+				rows = append(rows, row)
+			}
+			return rows, selDB.Err()
+		})
+}
+
+// GetCompletionAnalytics
+// Works with serveAnalytics.
+// If the user has a cookie and is a supervisor, get the percentage of jobs complete in each period, overall or
+// by ?by= worker, model, type or customer.
+func GetCompletionAnalytics(c *gin.Context) {
+	serveAnalytics(c, "completion", true, []string{"none", "worker", "model", "type", "customer"},
+		func(db *sql.DB, f analyticsFilter) (interface{}, error) {
+			selDB, err := db.Query("SELECT "+periodColumns[f.period]+", "+groupColumns[f.by]+", COUNT(*), "+
+				"SUM(jr.job_report_complete)"+analyticsFrom+f.where+" GROUP BY 1, 2 ORDER BY 1, 2", f.args...)
+			if err != nil {
+				return nil, err
+			}
+			defer selDB.Close()
+
+			rows := []models.CompletionRate{}
+			for selDB.Next() {
+				var row models.CompletionRate
+				if err := selDB.Scan(&row.Period, &row.Group, &row.Jobs, &row.Completed); err != nil {
+					return nil, err
+				}
+				row.CompletionPercent = analytics.Rate(row.Completed, row.Jobs)
+				rows = append(rows, row)
+			}
+			return rows, selDB.Err()
+		})
+}
+
+// Function to serve an analytics request for supervisors, sending the cached result of the same request if there
+// is one or running the query. periods is whether rows are grouped by ?period=, groups is what rows can be grouped
+// ?by=, the first by default, nil if they are not.
+func serveAnalytics(c *gin.Context, name string, periods bool, groups []string,
+	query func(db *sql.DB, f analyticsFilter) (interface{}, error)) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Analytics")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get analytics") {
+		return
+	}
+
+	settings, err := config.AnalyticsSettings()
+	if err != nil {
+		log.Println("Failed to load config file for analytics.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	filter, err := analyticsRequest(c, settings, periods, groups)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	// Requests asking for the same figures share a result, however the parameters are written.
+	key := fmt.Sprintf("%s|%s|%s|%d|%s|%v", name, filter.period, filter.by, filter.limit, filter.where, filter.args)
+	now := time.Now()
+	if result, ok := settings.Cache.Get(key, now); ok {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	rows, err := query(db, filter)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Getting Analytics.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	result := models.Analytics{From: filter.from.Format("2006-01-02"), To: filter.to.Format("2006-01-02"),
+		Period: filter.period, By: filter.by, GeneratedAt: now.UTC(), Rows: rows}
+	settings.Cache.Set(key, result, now)
+	c.JSON(http.StatusOK, result)
+}
+
+// Function to read the date range, grouping and filters of an analytics request.
+// The date range is the last DefaultMonths months up to today if it is not set, grouped by month.
+func analyticsRequest(c *gin.Context, settings config.Analytics, periods bool, groups []string) (analyticsFilter,
+	error) {
+	y, m, d := time.Now().Date()
+	f := analyticsFilter{
+		from: time.Date(y, m-time.Month(settings.DefaultMonths-1), 1, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+	}
+	for _, param := range []struct {
+		name string
+		date *time.Time
+	}{{"from", &f.from}, {"to", &f.to}} {
+		if value := c.Query(param.name); value != "" {
+			date, err := models.ParseDate(value)
+			if err != nil {
+				return f, fmt.Errorf("%s must be a date like 2020-06-03", param.name)
+			}
+			*param.date = date.Time
+		}
+	}
+	if f.to.Before(f.from) {
+		return f, fmt.Errorf("to must not be before from")
+	}
+	if periods {
+		f.period = c.DefaultQuery("period", analytics.Month)
+		if err := analytics.CheckPeriod(f.period); err != nil {
+			return f, err
+		}
+	}
+	if groups != nil {
+		f.by = c.DefaultQuery("by", groups[0])
+		if !contains(groups, f.by) {
+			return f, fmt.Errorf("by must be %s, not %q", strings.Join(groups, ", "), f.by)
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit < 1 || limit > maxCommonValues {
+		return f, fmt.Errorf("limit must be from 1 to %d", maxCommonValues)
+	}
+	f.limit = limit
+
+	f.where = "WHERE jr.date_stamp >= ? AND jr.date_stamp <= ?"
+	f.args = []interface{}{f.from, f.to}
+	for _, param := range []struct{ name, column string }{
+		{"worker", "wkr.username"}, {"customer", "cust.customer_name"}, {"model", "jr.vehicle_model"},
+	} {
+		if value := c.Query(param.name); value != "" {
+			f.where += " AND " + param.column + " = ?"
+			f.args = append(f.args, value)
+		}
+	}
+	return f, nil
+}
+
+// Function to get the values of a column found most often in the jobs of each vehicle model.
+// When split is set each value is split into the values counted, a job is counted once for each of them.
+func commonValues(db *sql.DB, column string, split func(string) []string, f analyticsFilter) ([]models.CommonValue,
+	error) {
+	selDB, err := db.Query("SELECT jr.vehicle_model, "+column+", COUNT(*)"+analyticsFrom+f.where+" AND "+column+
+		" <> '' GROUP BY 1, 2", f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	var counts []analytics.Count
+	found := map[analytics.Count]int{}
+	for selDB.Next() {
+		var count analytics.Count
+		if err := selDB.Scan(&count.Group, &count.Value, &count.N); err != nil {
+			return nil, err
+		}
+		if split == nil {
+			counts = append(counts, count)
+			continue
+		}
+		for _, value := range split(count.Value) {
+			key := analytics.Count{Group: count.Group, Value: value}
+			if i, ok := found[key]; ok {
+				counts[i].N += count.N
+				continue
+			}
+			found[key] = len(counts)
+			counts = append(counts, analytics.Count{Group: count.Group, Value: value, N: count.N})
+		}
+	}
+	if err := selDB.Err(); err != nil {
+		return nil, err
+	}
+
+	rows := []models.CommonValue{}
+	for _, count := range analytics.Top(counts, f.limit) {
+		rows = append(rows, models.CommonValue{VehicleModel: count.Group, Value: count.Value, Jobs: count.N})
+	}
+	return rows, nil
+}
+
+// Function to check if a list of strings has one.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
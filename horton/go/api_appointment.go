@@ -0,0 +1,811 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Appointment
+ * Handles the workshop diary - Book, Get, Update & Cancel Appointments, the free slots of a day and converting an
+ * appointment to a Job Report. Appointments are booked in a bay, clashes are found by the schedule package.
+ * Calendar feeds of a worker's or the garage's appointments can be subscribed to without logging in (GetCalendarFeed).
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/ical"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/jobqueue"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/schedule"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectAppointments is the Query shared by the functions that get appointments, each adds its own WHERE clause.
+// Columns are read in the order of getAppointments.
+const selectAppointments = "SELECT ap.appointment_id, ap.status, ap.source, ap.starts_at, ap.ends_at, ap.bay, " +
+	"COALESCE(wkr.username, ''), COALESCE(wkr.worker_name, ''), ap.customer_name, ap.customer_phone, " +
+	"ap.customer_email, ap.vehicle_model, ap.vehicle_reg, ap.complaint, COALESCE(ap.job_report_id, 0), " +
+	"ap.created_at, ap.updated_at FROM appointments ap LEFT JOIN workers wkr ON ap.worker_id = wkr.worker_id "
+
+// Scopes of calendar feeds.
+const (
+	feedWorker = "worker"
+	feedGarage = "garage"
+)
+
+// CreateAppointment
+// Works with CheckForCookie, isValidAccount & placeAppointment.
+// If the user has a cookie, book an appointment in the first free bay, or the bay asked for.
+// Appointments outside opening hours or that clash are refused.
+func CreateAppointment(c *gin.Context) {
+	var request models.AppointmentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to book an Appointment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Booking Appointment.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	booking, ok := placeAppointment(c, tx, settings, 0, &request)
+	if !ok {
+		return
+	}
+
+	res, err := tx.Exec("INSERT INTO appointments (status, starts_at, ends_at, bay, worker_id, created_by, "+
+		"customer_name, customer_phone, customer_email, vehicle_model, vehicle_reg, complaint) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", schedule.Booked, booking.Start, booking.End, booking.Bay,
+		nullId(booking.WorkerId), wa.Id, request.CustomerName, request.CustomerPhone, request.CustomerEmail,
+		request.VehicleModel, request.VehicleReg, request.Complaint)
+	if err == nil {
+		var id int64
+		id, _ = res.LastInsertId()
+		booking.AppointmentId = int32(id)
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Booking Appointment.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to book Appointment"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Appointment booked:", booking.AppointmentId)
+	sendAppointment(c, db, 201, booking.AppointmentId)
+}
+
+// GetAppointments
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the garage's appointments from ?from= to ?to= (dates, today and the week after if
+// not set) in order, for one worker with ?worker=. Cancelled and unconfirmed appointments are left out unless
+// asked for with ?status=.
+func GetAppointments(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Appointments")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	from, to, err := dateRange(c, settings.Location, 7)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	where := "WHERE ap.starts_at < ? AND ap.ends_at > ?"
+	args := []interface{}{to, from}
+	if worker := c.Query("worker"); worker != "" {
+		where += " AND wkr.username = ?"
+		args = append(args, worker)
+	}
+	if status := c.Query("status"); status != "" {
+		where += " AND ap.status = ?"
+		args = append(args, status)
+	} else {
+		where += " AND ap.status NOT IN ('cancelled', 'unconfirmed')"
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointments, err := getAppointments(db, where+" ORDER BY ap.starts_at, ap.bay", args...)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, appointments)
+}
+
+// GetAppointment
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get an appointment by its ID.
+func GetAppointment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Appointment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if appointment, ok := requestedAppointment(c, db); ok {
+		c.JSON(http.StatusOK, appointment)
+	}
+}
+
+// UpdateAppointment
+// Works with CheckForCookie, isValidAccount & placeAppointment.
+// If the user has a cookie, move a booked appointment or change its details.
+func UpdateAppointment(c *gin.Context) {
+	var request models.AppointmentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update this Appointment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointment, ok := requestedAppointment(c, db)
+	if !ok {
+		return
+	}
+	if appointment.Status != schedule.Booked {
+		c.JSON(409, models.Error{Code: 409, Messages: "Appointment has been " + appointment.Status})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Appointment.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	booking, ok := placeAppointment(c, tx, settings, appointment.AppointmentId, &request)
+	if !ok {
+		return
+	}
+
+	_, err = tx.Exec("UPDATE appointments SET starts_at = ?, ends_at = ?, bay = ?, worker_id = ?, customer_name = ?, "+
+		"customer_phone = ?, customer_email = ?, vehicle_model = ?, vehicle_reg = ?, complaint = ? "+
+		"WHERE appointment_id = ? AND status = 'booked'", booking.Start, booking.End, booking.Bay,
+		nullId(booking.WorkerId), request.CustomerName, request.CustomerPhone, request.CustomerEmail,
+		request.VehicleModel, request.VehicleReg, request.Complaint, appointment.AppointmentId)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Appointment.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to update Appointment"})
+		return
+	}
+
+	sendAppointment(c, db, http.StatusOK, appointment.AppointmentId)
+}
+
+// CancelAppointment
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, cancel a booked appointment, freeing its bay.
+// It is kept so calendar feeds can tell subscribers it was cancelled.
+func CancelAppointment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to cancel this Appointment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointment, ok := requestedAppointment(c, db)
+	if !ok {
+		return
+	}
+	if appointment.Status != schedule.Booked {
+		c.JSON(409, models.Error{Code: 409, Messages: "Appointment has been " + appointment.Status})
+		return
+	}
+
+	if _, err := db.Exec("UPDATE appointments SET status = 'cancelled' WHERE appointment_id = ? AND status = 'booked'",
+		appointment.AppointmentId); err != nil {
+		log.Println("\nMySQL Error: Error Cancelling Appointment.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(204, nil)
+}
+
+// GetAppointmentSlots
+// Works with CheckForCookie, isValidAccount & loadBookings.
+// If the user has a cookie, get the times on ?date= an appointment of ?minutes= could be booked at,
+// with the number of bays free. ?worker= gets only the times the worker is free.
+func GetAppointmentSlots(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Appointment Slots")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	workerId := 0
+	if worker := c.Query("worker"); worker != "" {
+		var err error
+		if workerId, err = workerIdOf(db, worker); err == sql.ErrNoRows {
+			c.JSON(400, models.Error{Code: 400, Messages: "There is no worker " + worker})
+			return
+		} else if err != nil {
+			log.Println("\nFailed to process Workers.", err)
+			c.JSON(500, nil)
+			return
+		}
+	}
+
+	if slots, ok := daySlots(c, db, workerId, 0); ok {
+		c.JSON(http.StatusOK, slots)
+	}
+}
+
+// ConvertAppointment
+// Works with CheckForCookie, isValidAccount, validateJobReport, checkContact & insertReportTx.
+// If the user has a cookie, create a Job Report of a booked appointment with its customer, complaint and vehicle.
+// The report is the appointment's worker's, given to them as a job if they are not the user, or the user's.
+// ?contact= links the report to the customer's notification preferences, the contact details given with the
+// booking are not trusted to find them.
+func ConvertAppointment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to convert this Appointment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointment, ok := requestedAppointment(c, db)
+	if !ok {
+		return
+	}
+	if appointment.Status != schedule.Booked {
+		c.JSON(409, models.Error{Code: 409, Messages: "Appointment has been " + appointment.Status})
+		return
+	}
+
+	report := settings.ToReport(appointment)
+	if fields := validateJobReport(&report); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Report is invalid", Fields: fields})
+		return
+	}
+	if contact := c.Query("contact"); contact != "" {
+		contactId, err := strconv.ParseInt(contact, 10, 32)
+		if err != nil || contactId < 1 {
+			c.JSON(400, models.Error{Code: 400, Messages: "contact must be the ID of a contact"})
+			return
+		}
+		linked := int32(contactId)
+		report.ContactId = &linked
+	}
+	if !checkContact(c, db, reportContact(report)) {
+		return
+	}
+
+	workerId := wa.Id
+	if appointment.Worker != "" {
+		if workerId, err = workerIdOf(db, appointment.Worker); err != nil {
+			log.Println("\nFailed to process Workers.", err)
+			c.JSON(500, nil)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		var reportId int64
+		reportId, err = insertReportTx(tx, workerId, report)
+		if err == nil && workerId != wa.Id {
+			_, err = tx.Exec("UPDATE jobreports SET created_by = ?, assigned_at = ? WHERE job_report_id = ?",
+				wa.Id, assignedAt(workerId), reportId)
+			if err == nil {
+				err = recordAssignment(tx, reportId, jobqueue.Assigned, 0, workerId, "Appointment")
+			}
+		}
+		if err == nil {
+			_, err = tx.Exec("UPDATE appointments SET status = 'converted', job_report_id = ? "+
+				"WHERE appointment_id = ? AND status = 'booked'", reportId, appointment.AppointmentId)
+		}
+		if err == nil {
+			err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+		}
+		if err == nil {
+			err = queueReportNotification(tx, reportId, notify.JobCreated)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Converting Appointment.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to convert Appointment"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Appointment", appointment.AppointmentId, "converted to a Report")
+	sendAppointment(c, db, 201, appointment.AppointmentId)
+}
+
+// CreateCalendarFeed
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the address of the calendar feed of their appointments (scope worker) or all the
+// garage's (scope garage) for their phone to subscribe to, making it if they have none.
+func CreateCalendarFeed(c *gin.Context) {
+	var request models.CalendarFeedRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+	if request.Scope != feedWorker && request.Scope != feedGarage {
+		c.JSON(400, models.Error{Code: 400, Messages: "scope must be worker or garage"})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to create a Calendar Feed")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	_, publicUrl, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	// A feed already made is kept so phones subscribed to it keep working.
+	_, err = db.Exec("INSERT IGNORE INTO calendar_feeds (worker_id, scope, token) VALUES (?, ?, ?)", wa.Id,
+		request.Scope, uuid.New().String())
+	var token string
+	feed := models.CalendarFeed{Scope: request.Scope}
+	if err == nil {
+		err = db.QueryRow("SELECT token, created_at FROM calendar_feeds WHERE worker_id = ? AND scope = ?", wa.Id,
+			request.Scope).Scan(&token, &feed.CreatedAt)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Creating Calendar Feed.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	feed.Url = publicUrl + "/api/v1/public/calendars/" + token + ".ics"
+	c.JSON(http.StatusOK, feed)
+}
+
+// DeleteCalendarFeed
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, stop the calendar feed of ?scope= working, e.g. when a phone is lost.
+// The next CreateCalendarFeed makes a new address.
+func DeleteCalendarFeed(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete a Calendar Feed")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM calendar_feeds WHERE worker_id = ? AND scope = ?", wa.Id, c.Query("scope"))
+	if err != nil {
+		log.Println("\nMySQL Error: Error Deleting Calendar Feed.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Calendar Feed not found"})
+		return
+	}
+	c.JSON(204, nil)
+}
+
+// GetCalendarFeed
+// Works with ical.Write.
+// Get the iCalendar file of a calendar feed, the appointments of its worker or of the garage from 30 days ago on.
+// No login is needed, the token in the address is the key to the feed.
+func GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Params.ByName("token"), ".ics")
+
+	_, publicUrl, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	garage, err := config.Garage()
+	if err != nil {
+		log.Println("Failed to load config file for garage.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	var scope, workerName string
+	var workerId int
+	err = db.QueryRow("SELECT cf.scope, cf.worker_id, wkr.worker_name FROM calendar_feeds cf "+
+		"INNER JOIN workers wkr ON cf.worker_id = wkr.worker_id WHERE cf.token = ?", token).
+		Scan(&scope, &workerId, &workerName)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Calendar Feed not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Calendar Feed.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	name := garage.Name
+	where := "WHERE ap.ends_at > ? AND ap.status != 'unconfirmed'"
+	args := []interface{}{time.Now().UTC().AddDate(0, 0, -30)}
+	if scope == feedWorker {
+		name += " - " + workerName
+		where += " AND ap.worker_id = ?"
+		args = append(args, workerId)
+	}
+	appointments, err := getAppointments(db, where+" ORDER BY ap.starts_at", args...)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(publicUrl, "https://"), "http://")
+	events := make([]ical.Event, 0, len(appointments))
+	for _, appointment := range appointments {
+		events = append(events, appointmentEvent(appointment, host))
+	}
+
+	var file bytes.Buffer
+	if err := ical.Write(&file, name, events, time.Now()); err != nil {
+		log.Println("\nFailed to write Calendar Feed.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.Data(http.StatusOK, ical.ContentType, file.Bytes())
+}
+
+// Function to check an appointment request and place it in the diary among the appointments booked around it,
+// which are locked until tx ends so two appointments cannot take the same bay at once.
+// Sends the error response and returns false if the appointment is invalid or clashes.
+func placeAppointment(c *gin.Context, tx *sql.Tx, settings schedule.Settings, appointmentId int32,
+	request *models.AppointmentRequest) (schedule.Booking, bool) {
+	if request.VehicleReg != "" {
+		reg, err := plate.Normalise(request.VehicleReg)
+		if err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: "Appointment is invalid", Fields: []models.FieldError{
+				{Field: "vehicleReg", Message: err.Error()}}})
+			return schedule.Booking{}, false
+		}
+		request.VehicleReg = reg
+	}
+
+	minutes := time.Duration(request.Minutes) * time.Minute
+	if request.Minutes == 0 {
+		minutes = settings.Slot
+	}
+	booking := schedule.Booking{AppointmentId: appointmentId, Bay: request.Bay, Start: request.Start.UTC(),
+		End: request.Start.UTC().Add(minutes)}
+
+	if request.Worker != "" {
+		var err error
+		if booking.WorkerId, err = workerIdOf(tx, request.Worker); err == sql.ErrNoRows {
+			c.JSON(400, models.Error{Code: 400, Messages: "Appointment is invalid", Fields: []models.FieldError{
+				{Field: "worker", Message: "there is no worker " + request.Worker}}})
+			return booking, false
+		} else if err != nil {
+			log.Println("\nFailed to process Workers.", err)
+			c.JSON(500, nil)
+			return booking, false
+		}
+	}
+
+	bookings, err := loadBookings(tx, booking.Start, booking.End, true)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return booking, false
+	}
+
+	booking.Bay, err = settings.Place(bookings, booking)
+	switch err {
+	case nil:
+		return booking, true
+	case schedule.ErrNoBay, schedule.ErrBayTaken, schedule.ErrWorkerBusy:
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+	default:
+		c.JSON(400, models.Error{Code: 400, Messages: "Appointment is invalid", Fields: []models.FieldError{
+			{Field: "start", Message: err.Error()}}})
+	}
+	return booking, false
+}
+
+// Function to get the booked appointments, and bookings waiting for a supervisor, at any time from start to end.
+// If forUpdate they are locked, with the gaps between them, until the transaction ends.
+func loadBookings(db dbExecutor, start, end time.Time, forUpdate bool) ([]schedule.Booking, error) {
+	query := "SELECT appointment_id, COALESCE(worker_id, 0), bay, starts_at, ends_at FROM appointments " +
+		"WHERE status IN ('pending', 'booked') AND starts_at < ? AND ends_at > ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	selDB, err := db.Query(query, end, start)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	var bookings []schedule.Booking
+	for selDB.Next() {
+		var b schedule.Booking
+		if err := selDB.Scan(&b.AppointmentId, &b.WorkerId, &b.Bay, &b.Start, &b.End); err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+	return bookings, selDB.Err()
+}
+
+// Function to get the free slots of the day of ?date= for an appointment of ?minutes=, for workerId if not 0.
+// Sends the error response and returns false if the request is invalid, or ?minutes= is more than maxMinutes
+// when it is not 0.
+func daySlots(c *gin.Context, db dbExecutor, workerId int, maxMinutes int) ([]models.AppointmentSlot, bool) {
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return nil, false
+	}
+
+	day, err := models.ParseDate(c.Query("date"))
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "date must be a date like 2020-06-03"})
+		return nil, false
+	}
+	minutes, err := strconv.Atoi(c.DefaultQuery("minutes", "0"))
+	if err != nil || minutes < 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "minutes must be a number of minutes"})
+		return nil, false
+	}
+	if maxMinutes != 0 && minutes > maxMinutes {
+		c.JSON(400, models.Error{Code: 400, Messages: fmt.Sprintf("appointments can be up to %d minutes", maxMinutes)})
+		return nil, false
+	}
+
+	y, m, d := day.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, settings.Location)
+	bookings, err := loadBookings(db, start, start.AddDate(0, 0, 1), false)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return nil, false
+	}
+	return settings.Slots(bookings, start, time.Duration(minutes)*time.Minute, workerId), true
+}
+
+// Function to get the dates ?from= and ?to= of a request as the start of from and the end of to in loc.
+// from is today and to days after from if they are not set.
+func dateRange(c *gin.Context, loc *time.Location, days int) (time.Time, time.Time, error) {
+	y, m, d := time.Now().In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if value := c.Query("from"); value != "" {
+		date, err := models.ParseDate(value)
+		if err != nil {
+			return from, from, fmt.Errorf("from must be a date like 2020-06-03")
+		}
+		y, m, d = date.Date()
+		from = time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	to := from.AddDate(0, 0, days+1)
+	if value := c.Query("to"); value != "" {
+		date, err := models.ParseDate(value)
+		if err != nil {
+			return from, to, fmt.Errorf("to must be a date like 2020-06-03")
+		}
+		y, m, d = date.Date()
+		to = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+// Function to get the appointments of a selectAppointments Query.
+func getAppointments(db dbExecutor, where string, args ...interface{}) ([]models.Appointment, error) {
+	selDB, err := db.Query(selectAppointments+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	appointments := []models.Appointment{}
+	for selDB.Next() {
+		var a models.Appointment
+		if err := selDB.Scan(&a.AppointmentId, &a.Status, &a.Source, &a.Start, &a.End, &a.Bay, &a.Worker, &a.WorkerName,
+			&a.CustomerName, &a.CustomerPhone, &a.CustomerEmail, &a.VehicleModel, &a.VehicleReg, &a.Complaint,
+			&a.JobReportId, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, a)
+	}
+	return appointments, selDB.Err()
+}
+
+// Function to get the appointment requested.
+// Sends the error response and returns false if there is no such appointment.
+func requestedAppointment(c *gin.Context, db dbExecutor) (models.Appointment, bool) {
+	appointmentId, _ := strconv.Atoi(c.Params.ByName("appointmentId"))
+	appointments, err := getAppointments(db, "WHERE ap.appointment_id = ?", appointmentId)
+	if err != nil {
+		log.Println("\nFailed to load Appointment.", err)
+		c.JSON(500, nil)
+		return models.Appointment{}, false
+	}
+	if len(appointments) == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Appointment not found"})
+		return models.Appointment{}, false
+	}
+	return appointments[0], true
+}
+
+// Function to send an appointment as it is now in the database.
+func sendAppointment(c *gin.Context, db dbExecutor, status int, appointmentId int32) {
+	appointments, err := getAppointments(db, "WHERE ap.appointment_id = ?", appointmentId)
+	if err != nil || len(appointments) == 0 {
+		log.Println("\nFailed to load Appointment.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(status, appointments[0])
+}
+
+// Function to make the calendar event of an appointment, tentative while it is an online booking not accepted yet.
+func appointmentEvent(a models.Appointment, host string) ical.Event {
+	summary := strings.TrimSpace(a.VehicleReg + " " + a.VehicleModel)
+	if summary == "" {
+		summary = a.CustomerName
+	} else {
+		summary += " - " + a.CustomerName
+	}
+
+	var details []string
+	if a.Status == schedule.Pending {
+		details = append(details, "Booked online, not accepted yet")
+	}
+	if a.Complaint != "" {
+		details = append(details, a.Complaint)
+	}
+	if a.CustomerPhone != "" {
+		details = append(details, "Phone: "+a.CustomerPhone)
+	}
+	if a.WorkerName != "" {
+		details = append(details, "Worker: "+a.WorkerName)
+	}
+	if a.JobReportId != 0 {
+		details = append(details, fmt.Sprintf("Job Report %d", a.JobReportId))
+	}
+
+	return ical.Event{UID: fmt.Sprintf("appointment-%d@%s", a.AppointmentId, host), Start: a.Start, End: a.End,
+		Summary: summary, Description: strings.Join(details, "\n"), Location: fmt.Sprintf("Bay %d", a.Bay),
+		Cancelled: a.Status == schedule.Cancelled, Tentative: a.Status == schedule.Pending, Modified: a.UpdatedAt}
+}
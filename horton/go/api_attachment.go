@@ -0,0 +1,410 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Attachment
+ * Handles photos and documents attached to reports - Upload, Download, List & Delete.
+ * Files are kept in the Blob Store set up in config.ini and their details in the table attachments.
+ *
+ * References
+ * https://github.com/gin-gonic/gin#single-file
+ * https://golang.org/pkg/net/http/#DetectContentType
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// What an attachment can be.
+var attachmentKinds = map[string]bool{"before": true, "after": true, "diagnostic": true, "signed_form": true,
+	"other": true}
+
+// File extensions attachments are stored with for each MIME type.
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// UploadAttachment
+// Works with CheckForCookie, isValidAccount & ownsReport.
+// If the user has a cookie and owns the report, store the file from the multipart form field "file"
+// in the Blob Store and add its details to the table attachments.
+// The type of file is detected from its contents and must be one of allowed_types in config.ini.
+func UploadAttachment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to upload an Attachment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return
+	}
+
+	maxSize, allowedTypes, err := config.AttachmentRules()
+	if err != nil {
+		log.Println("Failed to load config file for attachments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	kind := c.DefaultPostForm("kind", "other")
+	if !attachmentKinds[kind] {
+		c.JSON(400, models.Error{Code: 400, Messages: "Attachment is invalid", Fields: []models.FieldError{
+			{Field: "kind", Message: "kind must be before, after, diagnostic, signed_form or other"}}})
+		return
+	}
+
+	// Limit the request body so a large upload is refused before it is read.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		log.Println("Failed to read Attachment.", err)
+		c.JSON(400, models.Error{Code: 400, Messages: "Attachment is invalid", Fields: []models.FieldError{
+			{Field: "file", Message: fmt.Sprintf("file is required and must be smaller than %d MB", maxSize>>20)}}})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(413, models.Error{Code: 413, Messages: fmt.Sprintf("Attachment must be smaller than %d MB", maxSize>>20)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Println("Failed to open Attachment.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer file.Close()
+
+	// Detect the type of file from its first 512 bytes, the type sent by the client is not trusted.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		log.Println("Failed to read Attachment.", err)
+		c.JSON(500, nil)
+		return
+	}
+	head = head[:n]
+	contentType := strings.Split(http.DetectContentType(head), ";")[0]
+	if !isAllowedType(contentType, allowedTypes) {
+		c.JSON(415, models.Error{Code: 415, Messages: "Attachments cannot be " + contentType})
+		return
+	}
+
+	store, err := config.BlobStore()
+	if err != nil {
+		log.Println("Failed to set up Blob Store.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	attachment := models.Attachment{
+		JobReportId: int32(reportId),
+		Kind:        kind,
+		FileName:    attachmentFileName(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		UploadedBy:  wa.WorkerName,
+		StorageKey:  fmt.Sprintf("jobReports/%d/%s%s", reportId, uuid.New().String(), attachmentExtensions[contentType]),
+	}
+
+	// Store the file, the bytes used to detect its type are put back in front of the rest of it.
+	if err := store.Put(c, attachment.StorageKey, io.MultiReader(bytes.NewReader(head), file), header.Size,
+		contentType); err != nil {
+		log.Println("Failed to store Attachment.", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to store Attachment"})
+		return
+	}
+
+	result, err := db.Exec("INSERT INTO attachments(job_report_id, worker_id, kind, file_name, content_type, "+
+		"size_bytes, storage_key) VALUES (?, ?, ?, ?, ?, ?, ?)", reportId, wa.Id, attachment.Kind, attachment.FileName,
+		attachment.ContentType, attachment.Size, attachment.StorageKey)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Attachment.\n", err)
+		// Do not leave a file behind that has no record.
+		if err := store.Delete(c, attachment.StorageKey); err != nil {
+			log.Println("Failed to remove stored Attachment.", err)
+		}
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to store Attachment"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	attachment.AttachmentId = int32(id)
+	fmt.Println("\n[INFO] Attachment stored for Report:", reportId, attachment.StorageKey)
+	c.JSON(201, attachment)
+}
+
+// GetAttachments
+// Works with CheckForCookie, isValidAccount & ownsReport.
+// If the user has a cookie and owns the report, get the details of all the files attached to it.
+func GetAttachments(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Attachments")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return
+	}
+
+	selDB, err := db.Query(selectAttachments+"WHERE att.job_report_id = ? ORDER BY att.created_at", reportId)
+	if err != nil {
+		log.Println("\nFailed to process Attachments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	res := []models.Attachment{}
+	for selDB.Next() {
+		attachment, err := scanAttachment(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Attachments.", err)
+			c.JSON(500, nil)
+			return
+		}
+		res = append(res, attachment)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetAttachment
+// Works with CheckForCookie, isValidAccount & ownsReport.
+// If the user has a cookie and owns the report, send the attached file from the Blob Store.
+func GetAttachment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Attachment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	attachment, ok := ownedAttachment(c, db)
+	if !ok {
+		return
+	}
+
+	store, err := config.BlobStore()
+	if err != nil {
+		log.Println("Failed to set up Blob Store.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	file, err := store.Get(c, attachment.StorageKey)
+	if err != nil {
+		log.Println("Failed to get stored Attachment.", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to get Attachment"})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", attachment.FileName),
+	})
+}
+
+// DeleteAttachment
+// Works with CheckForCookie, isValidAccount & ownsReport.
+// If the user has a cookie and owns the report, remove the attached file and its details.
+func DeleteAttachment(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete this Attachment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	attachment, ok := ownedAttachment(c, db)
+	if !ok {
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM attachments WHERE attachment_id = ?", attachment.AttachmentId); err != nil {
+		log.Println("Attachment failed to delete.", err)
+		c.JSON(500, nil)
+		return
+	}
+	removeStoredFiles([]string{attachment.StorageKey})
+	c.JSON(204, nil)
+}
+
+// selectAttachments is the Query shared by the functions that get attachments, each adds its own WHERE clause.
+// Columns are read in the order of scanAttachment.
+const selectAttachments = "SELECT att.attachment_id, att.job_report_id, att.kind, att.file_name, att.content_type, " +
+	"att.size_bytes, att.storage_key, wkr.worker_name, att.created_at FROM attachments att " +
+	"INNER JOIN workers wkr ON att.worker_id = wkr.worker_id "
+
+// Function to read a record from the selectAttachments Query into an Attachment object.
+func scanAttachment(rows *sql.Rows) (models.Attachment, error) {
+	var attachment models.Attachment
+
+	err := rows.Scan(&attachment.AttachmentId, &attachment.JobReportId, &attachment.Kind, &attachment.FileName,
+		&attachment.ContentType, &attachment.Size, &attachment.StorageKey, &attachment.UploadedBy, &attachment.CreatedAt)
+	return attachment, err
+}
+
+// Function to get the report ID from the request and check the logged in user owns the report.
+// Sends the error response and returns false if they do not.
+func ownedReportId(c *gin.Context, db *sql.DB) (int, bool) {
+	reportId, err := strconv.Atoi(c.Params.ByName("jobReportId"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Report not found"})
+		return 0, false
+	}
+
+	owns, err := ownsReport(db, reportId, wa.Username)
+	if err != nil {
+		log.Println("\nFailed to process Report.", err)
+		c.JSON(500, nil)
+		return 0, false
+	}
+	if !owns {
+		c.JSON(404, models.Error{Code: 404, Messages: "Report not found"})
+		return 0, false
+	}
+	return reportId, true
+}
+
+// Function to get the attachment requested from a report the logged in user owns.
+// Sends the error response and returns false if there is no such attachment.
+func ownedAttachment(c *gin.Context, db *sql.DB) (models.Attachment, bool) {
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return models.Attachment{}, false
+	}
+
+	selDB, err := db.Query(selectAttachments+"WHERE att.attachment_id = ? AND att.job_report_id = ?",
+		c.Params.ByName("attachmentId"), reportId)
+	if err != nil {
+		log.Println("\nFailed to process Attachment.", err)
+		c.JSON(500, nil)
+		return models.Attachment{}, false
+	}
+	defer selDB.Close()
+
+	if !selDB.Next() {
+		c.JSON(404, models.Error{Code: 404, Messages: "Attachment not found"})
+		return models.Attachment{}, false
+	}
+	attachment, err := scanAttachment(selDB)
+	if err != nil {
+		log.Println("\nFailed to load Attachment.", err)
+		c.JSON(500, nil)
+		return models.Attachment{}, false
+	}
+	return attachment, true
+}
+
+// Function to get the Blob Store keys of all the files attached to a report.
+// Used before a report is deleted as its attachments are deleted with it.
+func attachmentKeys(db *sql.DB, reportId string) ([]string, error) {
+	selDB, err := db.Query("SELECT storage_key FROM attachments WHERE job_report_id = ?", reportId)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	var keys []string
+	for selDB.Next() {
+		var key string
+		if err := selDB.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, selDB.Err()
+}
+
+// Function to remove files from the Blob Store once their records are deleted.
+// Failures are logged as the records are already gone.
+func removeStoredFiles(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	store, err := config.BlobStore()
+	if err != nil {
+		log.Println("Failed to set up Blob Store.", err)
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Println("Failed to remove stored Attachment", key, err)
+		}
+	}
+}
+
+// Function to check a MIME type is one of the types allowed in config.ini.
+func isAllowedType(contentType string, allowedTypes []string) bool {
+	for _, allowed := range allowedTypes {
+		if strings.TrimSpace(allowed) == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to clean up the name of an uploaded file - no directories and no longer than the column.
+func attachmentFileName(name string) string {
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	if name == "." || name == "/" {
+		name = "attachment"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
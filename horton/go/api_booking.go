@@ -0,0 +1,504 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Booking
+ * Handles customers booking appointments online without logging in - Free Slots, Book, Get & Confirm a Booking.
+ * A booking is an appointment that is unconfirmed until the customer confirms it with the link sent to them,
+ * then pending in the supervisors' queue until one accepts or declines it. The public endpoints are rate limited
+ * by IP address.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/ratelimit"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetBookingSlots
+// Works with limitBooking & daySlots.
+// Get the times on ?date= a customer can book an appointment of ?minutes= at. No login is needed.
+func GetBookingSlots(c *gin.Context) {
+	settings, ok := limitBooking(c, false)
+	if !ok {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	slots, ok := daySlots(c, db, 0, settings.MaxMinutes)
+	if !ok {
+		return
+	}
+
+	// Customers only see times they can book, not how busy the workshop is.
+	now := time.Now()
+	bookable := []models.AppointmentSlot{}
+	for _, slot := range slots {
+		if schedule.CheckNotice(slot.Start, now, settings.MinNotice, settings.MaxAhead) == nil {
+			slot.FreeBays = 0
+			bookable = append(bookable, slot)
+		}
+	}
+	c.JSON(http.StatusOK, bookable)
+}
+
+// CreateBooking
+// Works with limitBooking & sendBookingConfirmation.
+// Book an appointment for a customer in a free slot. It is unconfirmed until the customer follows the link
+// sent to their email to confirm it. No login is needed.
+func CreateBooking(c *gin.Context) {
+	var request models.BookingRequest
+
+	if _, ok := limitBooking(c, false); !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	settings, ok := limitBooking(c, true)
+	if !ok {
+		return
+	}
+	workshop, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	var fields []models.FieldError
+	if err := schedule.CheckNotice(request.Start, time.Now(), settings.MinNotice, settings.MaxAhead); err != nil {
+		fields = append(fields, models.FieldError{Field: "start", Message: err.Error()})
+	}
+	if request.Minutes < 0 || request.Minutes > settings.MaxMinutes {
+		fields = append(fields, models.FieldError{Field: "minutes",
+			Message: fmt.Sprintf("appointments can be up to %d minutes", settings.MaxMinutes)})
+	}
+	if request.VehicleReg != "" {
+		if reg, err := plate.Normalise(request.VehicleReg); err != nil {
+			fields = append(fields, models.FieldError{Field: "vehicleReg", Message: err.Error()})
+		} else {
+			request.VehicleReg = reg
+		}
+	}
+	if len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Booking is invalid", Fields: fields})
+		return
+	}
+
+	minutes := time.Duration(request.Minutes) * time.Minute
+	if request.Minutes == 0 {
+		minutes = workshop.Slot
+	}
+	booking := schedule.Booking{Start: request.Start.UTC(), End: request.Start.UTC().Add(minutes)}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	// The slot is not held until the booking is confirmed, it is checked again then.
+	bookings, err := loadBookings(db, booking.Start, booking.End, false)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if _, err := workshop.Place(bookings, booking); err == schedule.ErrNoBay {
+		c.JSON(409, models.Error{Code: 409, Messages: "That time is no longer free, please choose another"})
+		return
+	} else if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "Booking is invalid", Fields: []models.FieldError{
+			{Field: "start", Message: err.Error()}}})
+		return
+	}
+
+	token := uuid.New().String()
+	confirmBy := time.Now().UTC().Add(settings.ConfirmWithin).Truncate(time.Second)
+	tx, err := db.Begin()
+	if err == nil {
+		var res sql.Result
+		res, err = tx.Exec("INSERT INTO appointments (status, source, starts_at, ends_at, bay, customer_name, "+
+			"customer_phone, customer_email, vehicle_model, vehicle_reg, complaint, confirm_token, confirm_by) "+
+			"VALUES ('unconfirmed', 'online', ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?)", booking.Start, booking.End,
+			request.CustomerName, request.CustomerPhone, request.CustomerEmail, request.VehicleModel,
+			request.VehicleReg, request.Complaint, token, confirmBy)
+		var appointmentId int64
+		if err == nil {
+			appointmentId, err = res.LastInsertId()
+		}
+		if err == nil {
+			err = sendBookingConfirmation(tx, int32(appointmentId), settings.PublicUrl+"/api/v1/public/bookings/"+token)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Booking.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to book"})
+		return
+	}
+
+	c.JSON(202, models.Booking{Status: schedule.Unconfirmed, Start: booking.Start, End: booking.End,
+		CustomerName: request.CustomerName, VehicleModel: request.VehicleModel, VehicleReg: request.VehicleReg,
+		Complaint: request.Complaint, ConfirmBy: &confirmBy})
+}
+
+// GetBooking
+// Works with limitBooking & findBooking.
+// Get a booking by the token of its confirmation link, for the customer. No login is needed.
+func GetBooking(c *gin.Context) {
+	if _, ok := limitBooking(c, false); !ok {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	_, booking, err := findBooking(db, c.Params.ByName("token"), false)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Booking not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Booking.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// ConfirmBooking
+// Works with limitBooking, findBooking & loadBookings.
+// Confirm a booking with the token of its confirmation link, putting it in the supervisors' queue.
+// The slot must still be free. Confirming a confirmed booking again does nothing. No login is needed.
+func ConfirmBooking(c *gin.Context) {
+	if _, ok := limitBooking(c, false); !ok {
+		return
+	}
+
+	workshop, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Confirming Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	appointmentId, booking, err := findBooking(tx, c.Params.ByName("token"), true)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Booking not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Booking.", err)
+		c.JSON(500, nil)
+		return
+	}
+	switch booking.Status {
+	case schedule.Unconfirmed:
+	case schedule.Pending, schedule.Booked:
+		c.JSON(http.StatusOK, booking)
+		return
+	default:
+		c.JSON(409, models.Error{Code: 409, Messages: "Booking has been " + booking.Status})
+		return
+	}
+	if booking.ConfirmBy != nil && time.Now().After(*booking.ConfirmBy) {
+		c.JSON(410, models.Error{Code: 410, Messages: "The time to confirm this booking has passed, please book again"})
+		return
+	}
+
+	bookings, err := loadBookings(tx, booking.Start, booking.End, true)
+	if err != nil {
+		log.Println("\nFailed to load Appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	bay, err := workshop.Place(bookings, schedule.Booking{Start: booking.Start, End: booking.End})
+	if err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: "That time is no longer free, please book another"})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE appointments SET status = 'pending', bay = ?, confirmed_at = ? WHERE appointment_id = ?",
+		bay, time.Now().UTC(), appointmentId)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Confirming Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Booking confirmed:", appointmentId)
+	booking.Status, booking.ConfirmBy = schedule.Pending, nil
+	c.JSON(http.StatusOK, booking)
+}
+
+// GetPendingBookings
+// Works with CheckForCookie, isValidAccount & requireSupervisor.
+// If the user has a cookie and is a supervisor, get the bookings customers have confirmed, oldest first,
+// waiting to be accepted or declined.
+func GetPendingBookings(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Bookings")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get the bookings waiting to be accepted") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointments, err := getAppointments(db, "WHERE ap.status = 'pending' ORDER BY ap.confirmed_at, ap.appointment_id")
+	if err != nil {
+		log.Println("\nFailed to load Bookings.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, appointments)
+}
+
+// AcceptBooking
+// Works with CheckForCookie, isValidAccount, requireSupervisor, placeAppointment & queueBookingNotification.
+// If the user has a cookie and is a supervisor, book a pending booking, giving it to worker in bay if they are set.
+// The customer is sent booking.accepted.
+func AcceptBooking(c *gin.Context) {
+	var decision models.BookingDecision
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&decision); err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+			return
+		}
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to accept this Booking")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "accept bookings") {
+		return
+	}
+
+	settings, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	appointment, ok := requestedAppointment(c, db)
+	if !ok {
+		return
+	}
+	if appointment.Status != schedule.Pending {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only pending bookings can be accepted"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Accepting Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	// The booking keeps its bay unless it is given another.
+	bay := decision.Bay
+	if bay == 0 {
+		bay = appointment.Bay
+	}
+	request := models.AppointmentRequest{Start: appointment.Start, Bay: bay, Worker: decision.Worker,
+		Minutes: int(math.Round(appointment.End.Sub(appointment.Start).Minutes()))}
+	booking, ok := placeAppointment(c, tx, settings, appointment.AppointmentId, &request)
+	if !ok {
+		return
+	}
+
+	res, err := tx.Exec("UPDATE appointments SET status = 'booked', bay = ?, worker_id = ? "+
+		"WHERE appointment_id = ? AND status = 'pending'", booking.Bay, nullId(booking.WorkerId), appointment.AppointmentId)
+	if err == nil {
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			c.JSON(409, models.Error{Code: 409, Messages: "Only pending bookings can be accepted"})
+			return
+		}
+		err = queueBookingNotification(tx, appointment.AppointmentId, notify.BookingAccepted, "", "")
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Accepting Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Booking", appointment.AppointmentId, "accepted by", wa.Username)
+	sendAppointment(c, db, http.StatusOK, appointment.AppointmentId)
+}
+
+// DeclineBooking
+// Works with CheckForCookie, isValidAccount, requireSupervisor & queueBookingNotification.
+// If the user has a cookie and is a supervisor, decline a pending booking with the reason why, freeing its bay.
+// The customer is sent booking.declined with the reason.
+func DeclineBooking(c *gin.Context) {
+	var decision models.BookingDecision
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&decision); err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+			return
+		}
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to decline this Booking")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "decline bookings") {
+		return
+	}
+
+	appointmentId, _ := strconv.Atoi(c.Params.ByName("appointmentId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Declining Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE appointments SET status = 'cancelled', decline_reason = ? "+
+		"WHERE appointment_id = ? AND status = 'pending'", decision.Reason, appointmentId)
+	if err == nil {
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			c.JSON(409, models.Error{Code: 409, Messages: "Only pending bookings can be declined"})
+			return
+		}
+		err = queueBookingNotification(tx, int32(appointmentId), notify.BookingDeclined, "", decision.Reason)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Declining Booking.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Booking", appointmentId, "declined by", wa.Username)
+	sendAppointment(c, db, http.StatusOK, int32(appointmentId))
+}
+
+// Function to count a request to the public booking endpoints by the client's IP address, and a booking if
+// booking. The address is only read from X-Forwarded-For behind trusted_proxies.
+// Sends 429 Too Many Requests and returns false if the client has made too many.
+func limitBooking(c *gin.Context, booking bool) (config.OnlineBooking, bool) {
+	settings, err := config.BookingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for booking.", err)
+		c.JSON(500, nil)
+		return settings, false
+	}
+
+	limiter := settings.Requests
+	if booking {
+		limiter = settings.Bookings
+	}
+	if ok, wait := limiter.Allow(ratelimit.Client(c.Request, settings.TrustedProxies), time.Now()); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(429, models.Error{Code: 429, Messages: "Too many requests, please try again later"})
+		return settings, false
+	}
+	return settings, true
+}
+
+// Function to get a booking by the token of its confirmation link with its appointment ID,
+// locking it until the transaction ends if forUpdate. Returns sql.ErrNoRows if there is no such booking.
+func findBooking(db dbExecutor, token string, forUpdate bool) (int32, models.Booking, error) {
+	var appointmentId int32
+	var booking models.Booking
+	var confirmBy sql.NullTime
+
+	query := "SELECT appointment_id, status, starts_at, ends_at, customer_name, vehicle_model, vehicle_reg, " +
+		"complaint, confirm_by FROM appointments WHERE confirm_token = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	err := db.QueryRow(query, token).Scan(&appointmentId, &booking.Status, &booking.Start, &booking.End,
+		&booking.CustomerName, &booking.VehicleModel, &booking.VehicleReg, &booking.Complaint, &confirmBy)
+	if confirmBy.Valid && booking.Status == schedule.Unconfirmed {
+		booking.ConfirmBy = &confirmBy.Time
+	}
+	return appointmentId, booking, err
+}
+
+// Function to send a customer the link to confirm their booking as booking.confirm, within the transaction that
+// made it. The link is not logged as anyone with it can confirm the booking, it is in the message log.
+func sendBookingConfirmation(tx dbExecutor, appointmentId int32, link string) error {
+	fmt.Println("\n[INFO] Booking", appointmentId, "made online")
+	return queueBookingNotification(tx, appointmentId, notify.BookingConfirm, link, "")
+}
@@ -0,0 +1,790 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Dispatch
+ * Handles breakdown call-outs - Request, Queue, Suggest a Worker, Dispatch, Acknowledge, Arrive & Cancel,
+ * and where workers are. A breakdown is a job flagged breakdown waiting in the dispatch queue by priority then age.
+ * Supervisors send the nearest free worker to it, given to them as a job, who acknowledges the call-out and
+ * arrives. When each happened is kept for tracking response times.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/jobqueue"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/live"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/sla"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// selectBreakdowns is the JOIN Query shared by the functions that get breakdowns, each adds its own WHERE clause.
+// Columns are read in the order of getBreakdowns.
+const selectBreakdowns = "SELECT bd.breakdown_id, bd.job_report_id, bd.priority, bd.status, bd.latitude, " +
+	"bd.longitude, jr.vehicle_location, jr.vehicle_model, jr.vehicle_reg, cust.customer_name, " +
+	"cust.customer_complaint, COALESCE(wkr.username, ''), jr.job_report_complete, bd.requested_at, " +
+	"bd.dispatched_at, bd.acknowledged_at, bd.arrived_at, bd.resolved_at, COALESCE(con.contract_name, ''), " +
+	"bd.acknowledge_target, bd.arrive_target, bd.resolve_target FROM breakdowns bd " +
+	"INNER JOIN jobreports jr ON bd.job_report_id = jr.job_report_id " +
+	"INNER JOIN customers cust ON jr.job_report_id = cust.job_report_id " +
+	"LEFT JOIN workers wkr ON bd.worker_id = wkr.worker_id " +
+	"LEFT JOIN sla_contracts con ON bd.contract_id = con.contract_id "
+
+// Open breakdowns are those not cancelled or resolved whose job is not complete, listed waiting first by priority
+// then age.
+const (
+	openBreakdowns  = "bd.status NOT IN ('cancelled', 'resolved') AND jr.job_report_complete = 0"
+	orderBreakdowns = " ORDER BY bd.status = 'waiting' DESC, bd.priority, bd.requested_at, bd.breakdown_id"
+)
+
+// busyOn is the open breakdowns a worker is on, ending with the worker's ID to compare to.
+// busyWorker is true for a worker in worker_positions wp on an open breakdown.
+const (
+	busyOn = "FROM breakdowns bd INNER JOIN jobreports jr ON bd.job_report_id = jr.job_report_id " +
+		"WHERE bd.status IN ('dispatched', 'acknowledged', 'arrived') AND jr.job_report_complete = 0 AND bd.worker_id = "
+	busyWorker = "EXISTS (SELECT 1 " + busyOn + "wp.worker_id)"
+)
+
+// CreateBreakdown
+// Works with CheckForCookie, isValidAccount, requireSupervisor, breakdownContract & insertReportTx.
+// If the user has a cookie and is a supervisor, put a breakdown in the dispatch queue as a job flagged breakdown.
+// The breakdown is where the report's vehicle location is unless latitude and longitude are set, and is held to
+// the targets of its SLA contract as they are now.
+func CreateBreakdown(c *gin.Context) {
+	var request models.BreakdownRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	request.Report.Breakdown = 1
+	fields := validateJobReport(request.Report)
+	priority, err := dispatch.ParsePriority(request.Priority)
+	if err != nil {
+		fields = append(fields, models.FieldError{Field: "priority", Message: err.Error()})
+	}
+	at, ok := breakdownPoint(request)
+	if !ok {
+		fields = append(fields, models.FieldError{Field: "vehicleLocation",
+			Message: "vehicle location must be a known place, or latitude and longitude set"})
+	}
+	if len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Breakdown is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to request a Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "request breakdowns") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	contractId, targets, ok := breakdownContract(c, db, request)
+	if !ok {
+		return
+	}
+
+	var breakdownId int64
+	tx, err := db.Begin()
+	if err == nil {
+		// The job is inserted as the supervisor's and put in the queue until a worker is dispatched.
+		var reportId int64
+		reportId, err = insertReportTx(tx, wa.Id, *request.Report)
+		if err == nil {
+			_, err = tx.Exec("UPDATE jobreports SET worker_id = NULL, created_by = ?, assigned_at = NULL "+
+				"WHERE job_report_id = ?", wa.Id, reportId)
+		}
+		if err == nil {
+			var res sql.Result
+			res, err = tx.Exec("INSERT INTO breakdowns (job_report_id, priority, status, latitude, longitude, "+
+				"contract_id, acknowledge_target, arrive_target, resolve_target, created_by, requested_at) "+
+				"VALUES (?, ?, 'waiting', ?, ?, ?, ?, ?, ?, ?, ?)", reportId, priority, at.Latitude, at.Longitude,
+				contractId, targets.AcknowledgeMinutes, targets.ArriveMinutes, targets.ResolveMinutes, wa.Id,
+				time.Now().UTC())
+			if err == nil {
+				breakdownId, err = res.LastInsertId()
+			}
+		}
+		if err == nil {
+			err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+		}
+		if err == nil {
+			err = queueReportNotification(tx, reportId, notify.JobCreated)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Breakdown.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to request Breakdown"})
+		return
+	}
+
+	fmt.Println("\n[INFO] New Breakdown:", breakdownId, dispatch.PriorityName(priority))
+	sendBreakdown(c, db, 201, int(breakdownId))
+}
+
+// GetBreakdowns
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the open breakdowns, waiting first by priority then age, or those with ?status=.
+// Supervisors get every breakdown, workers the breakdowns they were dispatched to.
+func GetBreakdowns(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Breakdowns")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	where, args := "WHERE "+openBreakdowns, []interface{}{}
+	if status := c.Query("status"); status != "" {
+		where, args = "WHERE bd.status = ?", append(args, status)
+	}
+	if wa.Role != jobqueue.Supervisor {
+		where, args = where+" AND bd.worker_id = ?", append(args, wa.Id)
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	breakdowns, err := getBreakdowns(db, where+orderBreakdowns, args...)
+	if err != nil {
+		log.Println("\nFailed to load Breakdowns.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, breakdowns)
+}
+
+// GetBreakdown
+// Works with CheckForCookie, isValidAccount & requestedBreakdown.
+// If the user has a cookie and is a supervisor or was dispatched to it, get a breakdown.
+func GetBreakdown(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	breakdown, ok := requestedBreakdown(c, db)
+	if !ok {
+		return
+	}
+	if wa.Role != jobqueue.Supervisor && breakdown.Worker != wa.Username {
+		c.JSON(404, models.Error{Code: 404, Messages: "Breakdown not found"})
+		return
+	}
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetDispatchSuggestions
+// Works with CheckForCookie, isValidAccount, requireSupervisor, requestedBreakdown & loadPositions.
+// If the user has a cookie and is a supervisor, get the free workers nearest to a breakdown, nearest first,
+// by ?distance=road or straight, the distance in config.ini if it is not set.
+func GetDispatchSuggestions(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get workers for this Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "dispatch workers to breakdowns") {
+		return
+	}
+
+	settings, err := config.DispatchSettings()
+	if err != nil {
+		log.Println("Failed to load config file for dispatch.", err)
+		c.JSON(500, nil)
+		return
+	}
+	var distancer dispatch.Distancer
+	switch c.DefaultQuery("distance", settings.Distance) {
+	case config.Road:
+		distancer = settings.Road
+	case config.StraightLine:
+		distancer = settings.Straight
+	default:
+		c.JSON(400, models.Error{Code: 400, Messages: "distance must be road or straight"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	breakdown, ok := requestedBreakdown(c, db)
+	if !ok {
+		return
+	}
+
+	positions, err := loadPositions(db, "")
+	if err != nil {
+		log.Println("\nFailed to load Worker Positions.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	at := dispatch.Point{Latitude: breakdown.Latitude, Longitude: breakdown.Longitude}
+	suggestions := []models.DispatchSuggestion{}
+	for _, s := range dispatch.Suggest(at, positions, distancer, time.Now(), settings.StaleAfter,
+		settings.Suggestions) {
+		suggestions = append(suggestions, models.DispatchSuggestion{WorkerPosition: presentPosition(s.Position),
+			DistanceKm: math.Round(s.DistanceKm*10) / 10})
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// DispatchBreakdown
+// Works with CheckForCookie, isValidAccount, requireSupervisor, lockBreakdown & lockJob.
+// If the user has a cookie and is a supervisor, send a worker to a breakdown, giving them its job.
+// A breakdown can be given to another worker until one arrives. Workers on another breakdown are not sent.
+func DispatchBreakdown(c *gin.Context) {
+	var request models.DispatchRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to dispatch this Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "dispatch workers to breakdowns") {
+		return
+	}
+
+	breakdownId, _ := strconv.Atoi(c.Params.ByName("breakdownId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	workerId, err := workerIdOf(db, request.Worker)
+	if err == sql.ErrNoRows {
+		c.JSON(400, models.Error{Code: 400, Messages: "Dispatch is invalid", Fields: []models.FieldError{
+			{Field: "worker", Message: "there is no worker " + request.Worker}}})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to process Workers.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Dispatching Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	breakdown, ok := lockBreakdown(c, tx, breakdownId)
+	if !ok {
+		return
+	}
+	if err := dispatch.Advance(breakdown.status, dispatch.Dispatched); err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	// Lock the worker so two breakdowns cannot be dispatched to them at once.
+	var busy int
+	err = tx.QueryRow("SELECT worker_id FROM workers WHERE worker_id = ? FOR UPDATE", workerId).Scan(&workerId)
+	if err == nil {
+		err = tx.QueryRow("SELECT COUNT(*) "+busyOn+"?", workerId).Scan(&busy)
+	}
+	if err != nil {
+		log.Println("\nFailed to process Breakdowns.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if busy > 0 {
+		c.JSON(409, models.Error{Code: 409, Messages: request.Worker + " is on a breakdown"})
+		return
+	}
+
+	job, ok := lockJob(c, tx, breakdown.reportId)
+	if !ok {
+		return
+	}
+	action, err := jobqueue.Assign(job.username, request.Worker, job.complete)
+	if err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE jobreports SET worker_id = ?, assigned_at = ? WHERE job_report_id = ?", workerId,
+		assignedAt(workerId), breakdown.reportId)
+	if err == nil {
+		err = recordAssignment(tx, int64(breakdown.reportId), action, job.workerId, workerId, "breakdown dispatch")
+	}
+	if err == nil {
+		err = recordLiveEvent(tx, int64(breakdown.reportId), live.ReportStatus, action, job.workerId)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE breakdowns SET status = 'dispatched', worker_id = ?, dispatched_at = ?, "+
+			"acknowledged_at = NULL WHERE breakdown_id = ?", workerId, time.Now().UTC(), breakdownId)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Dispatching Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Breakdown", breakdownId, "dispatched to", request.Worker, "by", wa.Username)
+	sendBreakdown(c, db, http.StatusOK, breakdownId)
+}
+
+// AcknowledgeBreakdown
+// Works with CheckForCookie, isValidAccount & advanceBreakdown.
+// If the user has a cookie and was dispatched to the breakdown, or is a supervisor, record that the worker
+// has acknowledged the call-out.
+func AcknowledgeBreakdown(c *gin.Context) {
+	advanceBreakdown(c, dispatch.Acknowledged, "acknowledge")
+}
+
+// ArriveBreakdown
+// Works with CheckForCookie, isValidAccount & advanceBreakdown.
+// If the user has a cookie and was dispatched to the breakdown, or is a supervisor, record that the worker
+// has arrived at the breakdown.
+func ArriveBreakdown(c *gin.Context) {
+	advanceBreakdown(c, dispatch.Arrived, "arrive at")
+}
+
+// ResolveBreakdown
+// Works with CheckForCookie, isValidAccount & advanceBreakdown.
+// If the user has a cookie and was dispatched to the breakdown, or is a supervisor, record that the vehicle has
+// been fixed or recovered. The breakdown leaves the queue, its job is completed as usual.
+func ResolveBreakdown(c *gin.Context) {
+	advanceBreakdown(c, dispatch.Resolved, "resolve")
+}
+
+// CancelBreakdown
+// Works with CheckForCookie, isValidAccount, requireSupervisor & lockBreakdown.
+// If the user has a cookie and is a supervisor, take a breakdown out of the dispatch queue before a worker arrives
+// e.g. when the customer got going again. Its job is kept for the supervisor to delete or reassign.
+func CancelBreakdown(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to cancel this Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "cancel breakdowns") {
+		return
+	}
+
+	breakdownId, _ := strconv.Atoi(c.Params.ByName("breakdownId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Cancelling Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	breakdown, ok := lockBreakdown(c, tx, breakdownId)
+	if !ok {
+		return
+	}
+	if err := dispatch.Advance(breakdown.status, dispatch.Cancelled); err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE breakdowns SET status = 'cancelled' WHERE breakdown_id = ?", breakdownId)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Cancelling Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Breakdown", breakdownId, "cancelled by", wa.Username)
+	sendBreakdown(c, db, http.StatusOK, breakdownId)
+}
+
+// UpdateWorkerPosition
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, record where they are and whether they are available for call-outs.
+// The position is kept as a ping from their device (recordPings).
+func UpdateWorkerPosition(c *gin.Context) {
+	var request models.WorkerPositionRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !validCoordinates(*request.Latitude, *request.Longitude) {
+		c.JSON(400, models.Error{Code: 400, Messages: "Position is invalid", Fields: []models.FieldError{
+			{Field: "latitude", Message: fmt.Sprintf("%g, %g is not a position on the map", *request.Latitude,
+				*request.Longitude)}}})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update their Position")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.TrackingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for tracking.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	now := time.Now().UTC()
+	ping := dispatch.Ping{Point: dispatch.Point{Latitude: *request.Latitude, Longitude: *request.Longitude},
+		RecordedAt: now}
+	if err := recordPings(db, wa.Id, []dispatch.Ping{ping}, request.Available, settings); err != nil {
+		log.Println("\nMySQL Error: Error Updating Worker Position.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	positions, err := loadPositions(db, "WHERE wp.worker_id = ?", wa.Id)
+	if err != nil || len(positions) == 0 {
+		log.Println("\nFailed to load Worker Position.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, trackedPosition(positions[0], now, settings))
+}
+
+// GetWorkerPositions
+// Works with CheckForCookie, isValidAccount, requireSupervisor & loadPositions.
+// If the user has a cookie and is a supervisor, get where each worker last reported being and who has gone silent.
+func GetWorkerPositions(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Worker Positions")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get where workers are") {
+		return
+	}
+
+	settings, err := config.TrackingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for tracking.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	positions, err := loadPositions(db, "ORDER BY wkr.username")
+	if err != nil {
+		log.Println("\nFailed to load Worker Positions.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	res := []models.WorkerPosition{}
+	for _, p := range positions {
+		res = append(res, trackedPosition(p, now, settings))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Function to move a breakdown on to acknowledged, arrived or resolved, recording when, for the worker dispatched
+// to it or a supervisor. A worker arriving without acknowledging first acknowledges the call-out as they arrive.
+func advanceBreakdown(c *gin.Context, next, action string) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to " + action + " this Breakdown")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	breakdownId, _ := strconv.Atoi(c.Params.ByName("breakdownId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	breakdown, ok := lockBreakdown(c, tx, breakdownId)
+	if !ok {
+		return
+	}
+	if breakdown.workerId != wa.Id && wa.Role != jobqueue.Supervisor {
+		c.JSON(403, models.Error{Code: 403, Messages: "Only the worker dispatched can " + action + " a breakdown"})
+		return
+	}
+	if err := dispatch.Advance(breakdown.status, next); err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	query := "UPDATE breakdowns SET status = ?, acknowledged_at = COALESCE(acknowledged_at, ?) WHERE breakdown_id = ?"
+	switch next {
+	case dispatch.Arrived:
+		query = "UPDATE breakdowns SET status = ?, acknowledged_at = COALESCE(acknowledged_at, ?), arrived_at = ? " +
+			"WHERE breakdown_id = ?"
+		_, err = tx.Exec(query, next, now, now, breakdownId)
+	case dispatch.Resolved:
+		_, err = tx.Exec("UPDATE breakdowns SET status = ?, resolved_at = ? WHERE breakdown_id = ?", next, now,
+			breakdownId)
+	default:
+		_, err = tx.Exec(query, next, now, breakdownId)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Breakdown.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Breakdown", breakdownId, next, "by", wa.Username)
+	sendBreakdown(c, db, http.StatusOK, breakdownId)
+}
+
+// Function to get where a breakdown is, the coordinates in the request or of the report's vehicle location.
+// Returns false if neither are set.
+func breakdownPoint(request models.BreakdownRequest) (dispatch.Point, bool) {
+	if request.Latitude != nil && request.Longitude != nil {
+		return dispatch.Point{Latitude: *request.Latitude, Longitude: *request.Longitude},
+			validCoordinates(*request.Latitude, *request.Longitude)
+	}
+	if request.Report.Latitude != nil && request.Report.Longitude != nil {
+		return dispatch.Point{Latitude: *request.Report.Latitude, Longitude: *request.Report.Longitude}, true
+	}
+	return dispatch.Point{}, false
+}
+
+// lockedBreakdown is the state of a breakdown while it is locked by lockBreakdown.
+type lockedBreakdown struct {
+	reportId int
+	workerId int
+	status   string
+}
+
+// Function to get the state of a breakdown, locking it until tx ends so it is not changed twice at once.
+// Sends the error response and returns false if there is no such breakdown.
+func lockBreakdown(c *gin.Context, tx *sql.Tx, breakdownId int) (lockedBreakdown, bool) {
+	var breakdown lockedBreakdown
+	var workerId sql.NullInt64
+	err := tx.QueryRow("SELECT job_report_id, worker_id, status FROM breakdowns WHERE breakdown_id = ? FOR UPDATE",
+		breakdownId).Scan(&breakdown.reportId, &workerId, &breakdown.status)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Breakdown not found"})
+		return breakdown, false
+	}
+	if err != nil {
+		log.Println("\nFailed to load Breakdown.", err)
+		c.JSON(500, nil)
+		return breakdown, false
+	}
+	breakdown.workerId = int(workerId.Int64)
+	return breakdown, true
+}
+
+// Function to get the breakdowns of a selectBreakdowns Query, with how they are doing against their SLA targets.
+func getBreakdowns(db dbExecutor, where string, args ...interface{}) ([]models.Breakdown, error) {
+	settings, err := config.SLASettings()
+	if err != nil {
+		return nil, err
+	}
+
+	selDB, err := db.Query(selectBreakdowns+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	now := time.Now().UTC()
+	breakdowns := []models.Breakdown{}
+	for selDB.Next() {
+		var b models.Breakdown
+		var priority int
+		var dispatched, acknowledged, arrived, resolved sql.NullTime
+		var targets sla.Targets
+		if err := selDB.Scan(&b.BreakdownId, &b.JobReportId, &priority, &b.Status, &b.Latitude, &b.Longitude,
+			&b.VehicleLocation, &b.VehicleModel, &b.VehicleReg, &b.CustomerName, &b.Complaint, &b.Worker,
+			&b.JobComplete, &b.RequestedAt, &dispatched, &acknowledged, &arrived, &resolved, &b.Contract,
+			&targets.AcknowledgeMinutes, &targets.ArriveMinutes, &targets.ResolveMinutes); err != nil {
+			return nil, err
+		}
+		b.Priority = dispatch.PriorityName(priority)
+		b.DispatchedAt = timeOf(dispatched)
+		b.AcknowledgedAt = timeOf(acknowledged)
+		b.ArrivedAt = timeOf(arrived)
+		b.ResolvedAt = timeOf(resolved)
+		b.AcknowledgeMinutes = minutesSince(b.RequestedAt, b.AcknowledgedAt)
+		b.ArriveMinutes = minutesSince(b.RequestedAt, b.ArrivedAt)
+		b.ResolveMinutes = minutesSince(b.RequestedAt, b.ResolvedAt)
+		b.SLA = presentSLA(slaResults(b, targets, now), now, settings.WarnBefore)
+		breakdowns = append(breakdowns, b)
+	}
+	return breakdowns, selDB.Err()
+}
+
+// Function to get the breakdown of the ID in the request.
+// Sends the error response and returns false if there is no such breakdown.
+func requestedBreakdown(c *gin.Context, db dbExecutor) (models.Breakdown, bool) {
+	breakdownId, _ := strconv.Atoi(c.Params.ByName("breakdownId"))
+	breakdowns, err := getBreakdowns(db, "WHERE bd.breakdown_id = ?", breakdownId)
+	if err != nil {
+		log.Println("\nFailed to load Breakdown.", err)
+		c.JSON(500, nil)
+		return models.Breakdown{}, false
+	}
+	if len(breakdowns) == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Breakdown not found"})
+		return models.Breakdown{}, false
+	}
+	return breakdowns[0], true
+}
+
+// Function to send a breakdown as it is now in the database.
+func sendBreakdown(c *gin.Context, db dbExecutor, status int, breakdownId int) {
+	breakdowns, err := getBreakdowns(db, "WHERE bd.breakdown_id = ?", breakdownId)
+	if err != nil || len(breakdowns) == 0 {
+		log.Println("\nFailed to load Breakdown.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(status, breakdowns[0])
+}
+
+// Function to get where workers last reported being with whether they are on a breakdown.
+// where is added after the JOIN of worker_positions wp and workers wkr.
+func loadPositions(db dbExecutor, where string, args ...interface{}) ([]dispatch.Position, error) {
+	selDB, err := db.Query("SELECT wp.worker_id, wkr.username, wkr.worker_name, wp.latitude, wp.longitude, "+
+		"wp.available, "+busyWorker+", wp.reported_at FROM worker_positions wp "+
+		"INNER JOIN workers wkr ON wp.worker_id = wkr.worker_id "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	var positions []dispatch.Position
+	for selDB.Next() {
+		var p dispatch.Position
+		if err := selDB.Scan(&p.WorkerId, &p.Username, &p.WorkerName, &p.Latitude, &p.Longitude, &p.Available,
+			&p.Busy, &p.ReportedAt); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, selDB.Err()
+}
+
+// Function to make the position of a worker sent to the client.
+func presentPosition(p dispatch.Position) models.WorkerPosition {
+	return models.WorkerPosition{Worker: p.Username, WorkerName: p.WorkerName, Latitude: p.Latitude,
+		Longitude: p.Longitude, Available: p.Available, Busy: p.Busy, ReportedAt: p.ReportedAt}
+}
+
+// Function to get a time that may be NULL.
+func timeOf(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// Function to get the whole minutes from start to end, nil if end has not happened.
+func minutesSince(start time.Time, end *time.Time) *int {
+	if end == nil {
+		return nil
+	}
+	minutes := int(end.Sub(start).Minutes())
+	return &minutes
+}
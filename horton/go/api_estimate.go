@@ -0,0 +1,725 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Estimate
+ * Handles estimates (quotes) given to customers before work starts - Create, Get, Update, Delete, Send & Convert.
+ * A sent estimate has an approval link the customer can open without logging in to accept or decline it
+ * (GetPublicEstimate, AcceptEstimate & DeclineEstimate) until it expires.
+ * An accepted estimate is converted to a Job Report, its parts are invoiced with the report.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/estimate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Estimates keep their lines in estimate_lines, see loadLines.
+const ownerEstimate = "estimate"
+
+// selectEstimates is the Query shared by the functions that get estimates, each adds its own WHERE clause.
+// Columns are read in the order of scanEstimate.
+const selectEstimates = "SELECT est.estimate_id, est.status, est.customer_name, COALESCE(est.contact_id, 0), " +
+	"est.vehicle_model, est.vehicle_reg, " +
+	"est.complaint, est.currency, est.subtotal_cents, est.vat_cents, est.total_cents, est.expires_on, " +
+	"COALESCE(est.approval_token, ''), est.decided_by, est.decision_note, est.decided_at, " +
+	"COALESCE(est.job_report_id, 0), est.created_at, est.updated_at FROM estimates est " +
+	"INNER JOIN workers wkr ON est.worker_id = wkr.worker_id "
+
+// CreateEstimate
+// Works with CheckForCookie, isValidAccount, calculateEstimate & checkContact.
+// If the user has a cookie, create a draft estimate with its lines.
+func CreateEstimate(c *gin.Context) {
+	var request models.EstimateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to create an Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	est := models.Estimate{Status: estimate.Draft, Currency: settings.Rates.Currency}
+	if !calculateEstimate(c, &est, request, settings.Rates) {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if !checkContact(c, db, est.ContactId) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		var res sql.Result
+		res, err = tx.Exec("INSERT INTO estimates (worker_id, status, customer_name, contact_id, vehicle_model, "+
+			"vehicle_reg, complaint, currency, subtotal_cents, vat_cents, total_cents, expires_on) "+
+			"VALUES (?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?)", wa.Id, est.Status, est.CustomerName,
+			est.ContactId, est.VehicleModel, est.VehicleReg, est.Complaint, est.Currency, est.SubtotalCents,
+			est.VatCents, est.TotalCents, est.ExpiresOn)
+		if err == nil {
+			var id int64
+			id, _ = res.LastInsertId()
+			est.EstimateId = int32(id)
+			err = saveLines(tx, ownerEstimate, est.EstimateId, est.Lines)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Estimate.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to create Estimate"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Estimate created:", est.EstimateId)
+	sendEstimate(c, db, 201, est.EstimateId)
+}
+
+// GetEstimates
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get their estimates newest first, filtered by ?status=.
+func GetEstimates(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Estimates")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	// Estimates are expired when they are read, sent estimates past their expiry date are stored as sent.
+	filter := ""
+	args := []interface{}{wa.Username}
+	switch status := c.Query("status"); status {
+	case "":
+	case estimate.Sent:
+		filter = " AND est.status = 'sent' AND (est.expires_on IS NULL OR est.expires_on >= ?)"
+		args = append(args, models.NewDate(time.Now()))
+	case estimate.Expired:
+		filter = " AND est.status = 'sent' AND est.expires_on < ?"
+		args = append(args, models.NewDate(time.Now()))
+	default:
+		filter = " AND est.status = ?"
+		args = append(args, status)
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectEstimates+"WHERE wkr.username = ?"+filter+" ORDER BY est.estimate_id DESC", args...)
+	if err != nil {
+		log.Println("\nFailed to process Estimates.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	_, publicUrl, err := config.EstimateSettings()
+	if err != nil {
+		log.Println("Failed to load config file for estimates.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	estimates := []models.Estimate{}
+	for selDB.Next() {
+		est, err := scanEstimate(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Estimates.", err)
+			c.JSON(500, nil)
+			return
+		}
+		presentEstimate(&est, publicUrl)
+		estimates = append(estimates, est)
+	}
+	c.JSON(http.StatusOK, estimates)
+}
+
+// GetEstimate
+// Works with CheckForCookie, isValidAccount & findEstimate.
+// If the user has a cookie and owns the estimate, get it with its lines and approval link.
+func GetEstimate(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if est, ok := ownedEstimate(c, db); ok {
+		sendEstimate(c, db, http.StatusOK, est.EstimateId)
+	}
+}
+
+// UpdateEstimate
+// Works with CheckForCookie, isValidAccount, findEstimate, calculateEstimate & checkContact.
+// If the user has a cookie and owns the estimate, replace the details and lines of a draft estimate.
+func UpdateEstimate(c *gin.Context) {
+	var request models.EstimateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update this Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	est, ok := ownedEstimate(c, db)
+	if !ok {
+		return
+	}
+	if est.Status != estimate.Draft {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only draft estimates can be changed"})
+		return
+	}
+	if !calculateEstimate(c, &est, request, settings.Rates) || !checkContact(c, db, est.ContactId) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		_, err = tx.Exec("UPDATE estimates SET customer_name = ?, contact_id = NULLIF(?, 0), vehicle_model = ?, "+
+			"vehicle_reg = ?, complaint = ?, subtotal_cents = ?, vat_cents = ?, total_cents = ?, expires_on = ? "+
+			"WHERE estimate_id = ? AND status = 'draft'", est.CustomerName, est.ContactId, est.VehicleModel,
+			est.VehicleReg, est.Complaint, est.SubtotalCents, est.VatCents, est.TotalCents, est.ExpiresOn,
+			est.EstimateId)
+		if err == nil {
+			err = saveLines(tx, ownerEstimate, est.EstimateId, est.Lines)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Estimate.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to update Estimate"})
+		return
+	}
+
+	sendEstimate(c, db, http.StatusOK, est.EstimateId)
+}
+
+// DeleteEstimate
+// Works with CheckForCookie, isValidAccount & findEstimate.
+// If the user has a cookie and owns the estimate, delete it. Accepted and converted estimates are kept.
+func DeleteEstimate(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete this Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	est, ok := ownedEstimate(c, db)
+	if !ok {
+		return
+	}
+	if est.Status == estimate.Accepted || est.Status == estimate.Converted {
+		c.JSON(409, models.Error{Code: 409, Messages: "Estimate has been accepted and cannot be deleted"})
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM estimates WHERE estimate_id = ? AND status NOT IN ('accepted', 'converted')",
+		est.EstimateId); err != nil {
+		log.Println("\nMySQL Error: Error Deleting Estimate.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(204, nil)
+}
+
+// SendEstimate
+// Works with CheckForCookie, isValidAccount, findEstimate & queueContactNotification.
+// If the user has a cookie and owns the estimate, send a draft estimate to the customer by giving it an approval
+// link and an expiry date (validity_days from today if it has none), and sending them the link as estimate.sent
+// if it has a contact.
+// Sending a sent estimate again gets its link.
+func SendEstimate(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to send this Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	validityDays, publicUrl, err := config.EstimateSettings()
+	if err != nil {
+		log.Println("Failed to load config file for estimates.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	est, ok := ownedEstimate(c, db)
+	if !ok {
+		return
+	}
+	if est.Status == estimate.Sent {
+		sendEstimate(c, db, http.StatusOK, est.EstimateId)
+		return
+	}
+	if est.Status != estimate.Draft {
+		c.JSON(409, models.Error{Code: 409, Messages: "Estimate has already been " + est.Status})
+		return
+	}
+	if len(est.Lines) == 0 {
+		c.JSON(409, models.Error{Code: 409, Messages: "An estimate needs at least one line to be sent"})
+		return
+	}
+
+	expiresOn := models.NewDate(time.Now().AddDate(0, 0, validityDays))
+	if est.ExpiresOn != nil {
+		expiresOn = *est.ExpiresOn
+	}
+	if estimate.IsExpired(expiresOn, time.Now()) {
+		c.JSON(409, models.Error{Code: 409, Messages: "Estimate expired on " + expiresOn.String()})
+		return
+	}
+
+	token, err := estimate.NewToken()
+	var tx *sql.Tx
+	if err == nil {
+		tx, err = db.Begin()
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE estimates SET status = 'sent', approval_token = ?, expires_on = ? "+
+			"WHERE estimate_id = ? AND status = 'draft'", token, expiresOn, est.EstimateId)
+		if err == nil {
+			est.ApprovalToken = token
+			presentEstimate(&est, publicUrl)
+			err = queueContactNotification(tx, notify.EstimateSent, 0, est.ContactId, notify.Data{
+				CustomerName: est.CustomerName, VehicleModel: est.VehicleModel, VehicleReg: est.VehicleReg,
+				Link: est.ApprovalUrl, Total: euro(est.TotalCents) + " " + est.Currency,
+				ExpiresOn: expiresOn.String()})
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Sending Estimate.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Estimate sent:", est.EstimateId)
+	sendEstimate(c, db, http.StatusOK, est.EstimateId)
+}
+
+// ConvertEstimate
+// Works with CheckForCookie, isValidAccount, findEstimate, validateJobReport & insertReportTx.
+// If the user has a cookie and owns the estimate, create a Job Report of an accepted estimate with its vehicle,
+// customer, complaint, parts and hours of labour filled in. The estimate is locked so it is only converted once.
+func ConvertEstimate(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to convert this Estimate")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	est, ok := ownedEstimate(c, db)
+	if !ok {
+		return
+	}
+	if est.Status != estimate.Accepted {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only accepted estimates can be converted to a report"})
+		return
+	}
+
+	report := estimate.ToReport(est, time.Now())
+	if fields := validateJobReport(&report); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Report is invalid", Fields: fields})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Converting Estimate.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the estimate so it can only be converted once.
+	var status string
+	err = tx.QueryRow("SELECT status FROM estimates WHERE estimate_id = ? FOR UPDATE", est.EstimateId).Scan(&status)
+	if err == nil && status != estimate.Accepted {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only accepted estimates can be converted to a report"})
+		return
+	}
+	var reportId int64
+	if err == nil {
+		reportId, err = insertReportTx(tx, wa.Id, report)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE estimates SET status = 'converted', job_report_id = ? WHERE estimate_id = ?",
+			reportId, est.EstimateId)
+	}
+	if err == nil {
+		err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+	}
+	if err == nil {
+		err = queueReportNotification(tx, reportId, notify.JobCreated)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Converting Estimate.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to convert Estimate"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Estimate", est.EstimateId, "converted to a Report")
+	sendEstimate(c, db, 201, est.EstimateId)
+}
+
+// GetPublicEstimate
+// Works with findEstimateByToken.
+// Get the estimate of an approval link with the garage's details, for the customer. No login is needed.
+func GetPublicEstimate(c *gin.Context) {
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	est, err := findEstimateByToken(db, c.Params.ByName("token"), false)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Estimate not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Estimate.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	garage, err := config.Garage()
+	if err != nil {
+		log.Println("Failed to load config file for garage.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, models.PublicEstimate{Garage: garage, Estimate: est})
+}
+
+// AcceptEstimate
+// Works with decideEstimate.
+// Accept the estimate of an approval link for the customer. No login is needed.
+func AcceptEstimate(c *gin.Context) {
+	decideEstimate(c, estimate.Accepted)
+}
+
+// DeclineEstimate
+// Works with decideEstimate.
+// Decline the estimate of an approval link for the customer. No login is needed.
+func DeclineEstimate(c *gin.Context) {
+	decideEstimate(c, estimate.Declined)
+}
+
+// Function to accept or decline the estimate of an approval link with the customer's name and note.
+// The estimate is locked so it can only be decided once.
+func decideEstimate(c *gin.Context, status string) {
+	var decision models.EstimateDecision
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&decision); err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+			return
+		}
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Deciding Estimate.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	est, err := findEstimateByToken(tx, c.Params.ByName("token"), true)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Estimate not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Estimate.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if err := estimate.CanDecide(est, time.Now()); err != nil {
+		code := http.StatusConflict
+		if est.Status == estimate.Expired {
+			code = http.StatusGone
+		}
+		c.JSON(code, models.Error{Code: int32(code), Messages: err.Error()})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE estimates SET status = ?, decided_by = ?, decision_note = ?, decided_at = ? "+
+		"WHERE estimate_id = ?", status, decision.Name, decision.Note, time.Now().UTC(), est.EstimateId)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Deciding Estimate.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Estimate", est.EstimateId, status, "by the customer")
+	est.Status, est.DecidedBy, est.DecisionNote = status, decision.Name, decision.Note
+	c.JSON(http.StatusOK, est)
+}
+
+// Function to fill in an estimate from a request and work out its lines and totals.
+// Sends the error response and returns false if the request is invalid.
+func calculateEstimate(c *gin.Context, est *models.Estimate, request models.EstimateRequest, rates billing.Rates) bool {
+	var fields []models.FieldError
+	if request.VehicleReg != "" {
+		if reg, err := plate.Normalise(request.VehicleReg); err != nil {
+			fields = append(fields, models.FieldError{Field: "vehicleReg", Message: err.Error()})
+		} else {
+			request.VehicleReg = reg
+		}
+	}
+	if request.LabourHours < 0 {
+		fields = append(fields, models.FieldError{Field: "labourHours", Message: "labourHours cannot be negative"})
+	}
+	if request.ExpiresOn != nil && estimate.IsExpired(*request.ExpiresOn, time.Now()) {
+		fields = append(fields, models.FieldError{Field: "expiresOn", Message: "expiresOn cannot be in the past"})
+	}
+
+	est.CustomerName, est.VehicleModel, est.VehicleReg = request.CustomerName, request.VehicleModel, request.VehicleReg
+	est.ContactId, est.Complaint, est.ExpiresOn = request.ContactId, request.Complaint, request.ExpiresOn
+	est.Lines = append(billing.LabourLines(request.LabourHours, 0, false, rates), request.Lines...)
+
+	var err error
+	est.SubtotalCents, est.VatCents, est.TotalCents, err = billing.Calculate(est.Lines, rates)
+	if err != nil {
+		fields = append(fields, models.FieldError{Field: "lines", Message: err.Error()})
+	}
+
+	if len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Estimate is invalid", Fields: fields})
+		return false
+	}
+	return true
+}
+
+// Function to read a record from the selectEstimates Query into an Estimate object.
+// Sent estimates past their expiry date are read as expired.
+func scanEstimate(rows *sql.Rows) (models.Estimate, error) {
+	var est models.Estimate
+	var expiresOn models.Date
+	var decidedAt sql.NullTime
+
+	err := rows.Scan(&est.EstimateId, &est.Status, &est.CustomerName, &est.ContactId, &est.VehicleModel,
+		&est.VehicleReg, &est.Complaint, &est.Currency, &est.SubtotalCents, &est.VatCents, &est.TotalCents, &expiresOn,
+		&est.ApprovalToken, &est.DecidedBy, &est.DecisionNote, &decidedAt, &est.JobReportId, &est.CreatedAt,
+		&est.UpdatedAt)
+	if !expiresOn.IsZero() {
+		est.ExpiresOn = &expiresOn
+	}
+	if decidedAt.Valid {
+		est.DecidedAt = &decidedAt.Time
+	}
+	est.Status = estimate.Status(est, time.Now())
+	return est, err
+}
+
+// Function to get an estimate of a user with its lines.
+// Returns sql.ErrNoRows if the user has no such estimate.
+func findEstimate(db dbExecutor, estimateId int, username string) (models.Estimate, error) {
+	return getEstimate(db, "WHERE est.estimate_id = ? AND wkr.username = ?", estimateId, username)
+}
+
+// Function to get the estimate of an approval link with its lines, locking it until the transaction ends
+// if forUpdate. Returns sql.ErrNoRows if there is no such estimate.
+func findEstimateByToken(db dbExecutor, token string, forUpdate bool) (models.Estimate, error) {
+	where := "WHERE est.approval_token = ?"
+	if forUpdate {
+		where += " FOR UPDATE"
+	}
+	if token == "" {
+		return models.Estimate{}, sql.ErrNoRows
+	}
+	return getEstimate(db, where, token)
+}
+
+// Function to get the estimate of a selectEstimates Query with its lines.
+func getEstimate(db dbExecutor, where string, args ...interface{}) (models.Estimate, error) {
+	selDB, err := db.Query(selectEstimates+where, args...)
+	if err != nil {
+		return models.Estimate{}, err
+	}
+	if !selDB.Next() {
+		selDB.Close()
+		if err := selDB.Err(); err != nil {
+			return models.Estimate{}, err
+		}
+		return models.Estimate{}, sql.ErrNoRows
+	}
+	est, err := scanEstimate(selDB)
+	selDB.Close()
+	if err != nil {
+		return est, err
+	}
+
+	est.Lines, err = loadLines(db, ownerEstimate, est.EstimateId)
+	return est, err
+}
+
+// Function to get the estimate requested from the logged in user's estimates.
+// Sends the error response and returns false if there is no such estimate.
+func ownedEstimate(c *gin.Context, db *sql.DB) (models.Estimate, bool) {
+	estimateId, err := strconv.Atoi(c.Params.ByName("estimateId"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Estimate not found"})
+		return models.Estimate{}, false
+	}
+
+	est, err := findEstimate(db, estimateId, wa.Username)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Estimate not found"})
+		return models.Estimate{}, false
+	}
+	if err != nil {
+		log.Println("\nFailed to load Estimate.", err)
+		c.JSON(500, nil)
+		return models.Estimate{}, false
+	}
+	return est, true
+}
+
+// Function to send an estimate to the user with its lines and approval link, as it is now in the database.
+func sendEstimate(c *gin.Context, db *sql.DB, status int, estimateId int32) {
+	est, err := findEstimate(db, int(estimateId), wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Estimate.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	_, publicUrl, err := config.EstimateSettings()
+	if err != nil {
+		log.Println("Failed to load config file for estimates.", err)
+		c.JSON(500, nil)
+		return
+	}
+	presentEstimate(&est, publicUrl)
+	c.JSON(status, est)
+}
+
+// Function to set the approval link of a sent estimate.
+func presentEstimate(est *models.Estimate, publicUrl string) {
+	if est.ApprovalToken != "" {
+		est.ApprovalUrl = publicUrl + "/api/v1/public/estimates/" + est.ApprovalToken
+	}
+}
+
+// Function to get the parts and other lines of the estimate a report was converted from, to invoice the report with.
+func estimatedLines(db dbExecutor, reportId int) ([]models.InvoiceLine, error) {
+	var estimateId int32
+	err := db.QueryRow("SELECT estimate_id FROM estimates WHERE job_report_id = ? ORDER BY estimate_id DESC LIMIT 1",
+		reportId).Scan(&estimateId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := loadLines(db, ownerEstimate, estimateId)
+	if err != nil {
+		return nil, err
+	}
+	var parts []models.InvoiceLine
+	for _, line := range lines {
+		if line.Kind == billing.Part || line.Kind == billing.Other {
+			parts = append(parts, line)
+		}
+	}
+	return parts, nil
+}
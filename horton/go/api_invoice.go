@@ -0,0 +1,772 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Invoice
+ * Handles invoices of completed Job Reports - Create, Get, Update, Delete, Change Status & Credit Notes.
+ * Labour is charged from the report's work hours at the rates in config.ini, parts and other lines are added
+ * by the user. Warranty jobs are invoiced to the warranty provider instead of the customer.
+ * An invoice is a draft until it is issued, when it gets the next number of a sequence with no gaps.
+ * Issued invoices cannot be changed, they are corrected with credit notes.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of invoice.
+const (
+	kindInvoice    = "invoice"
+	kindCreditNote = "credit_note"
+)
+
+// selectInvoices is the JOIN Query shared by the functions that get invoices, each adds its own WHERE clause.
+// Columns are read in the order of scanInvoice.
+const selectInvoices = "SELECT inv.invoice_id, inv.job_report_id, inv.kind, COALESCE(inv.credited_invoice_id, 0), " +
+	"COALESCE(inv.invoice_number, ''), inv.status, inv.bill_to, inv.bill_to_name, inv.bill_to_address, inv.currency, " +
+	"inv.subtotal_cents, inv.vat_cents, inv.total_cents, inv.reason, inv.issued_at, inv.due_date, inv.created_at, " +
+	"inv.updated_at FROM invoices inv INNER JOIN jobreports jr ON inv.job_report_id = jr.job_report_id " +
+	"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id "
+
+// CreateInvoice
+// Works with CheckForCookie, isValidAccount, findReport, invoiceLines & insertInvoice.
+// If the user has a cookie and owns the report, create a draft invoice for it from its work hours
+// and the parts and other lines sent, or those of the estimate it was converted from if none are sent.
+// Only completed reports can be invoiced, once.
+func CreateInvoice(c *gin.Context) {
+	var request models.InvoiceRequest
+
+	// The request body is optional, labour alone can be invoiced.
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+			return
+		}
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to invoice this Report")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return
+	}
+	report, err := findReport(db, reportId, wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if report.JobComplete == 0 {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only completed reports can be invoiced"})
+		return
+	}
+
+	invoice := models.Invoice{JobReportId: int32(reportId), Kind: kindInvoice, Status: billing.Draft,
+		BillTo: billing.Customer, BillToName: report.CustomerName, BillToAddress: request.BillToAddress,
+		Currency: settings.Rates.Currency}
+	if report.Warranty != 0 {
+		invoice.BillTo = billing.WarrantyProvider
+		invoice.BillToName = settings.WarrantyProviderName
+		invoice.BillToAddress = settings.WarrantyProviderAddress
+	}
+	if request.BillToName != "" {
+		invoice.BillToName = request.BillToName
+	}
+
+	if len(request.Lines) == 0 {
+		if request.Lines, err = estimatedLines(db, reportId); err != nil {
+			log.Println("\nFailed to load Estimate.", err)
+			c.JSON(500, nil)
+			return
+		}
+	}
+
+	if !invoiceLines(c, &invoice, report, request, settings.Rates) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Invoice.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	status, err := insertInvoice(tx, &invoice)
+	if err = endTx(tx, err); err != nil {
+		if status != 0 {
+			c.JSON(status, models.Error{Code: int32(status), Messages: err.Error()})
+			return
+		}
+		log.Println("\nMySQL Error: Error Inserting Invoice.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to create Invoice"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Invoice created:", invoice.InvoiceId, "for Report:", reportId)
+	sendInvoice(c, db, 201, invoice.InvoiceId)
+}
+
+// GetInvoices
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the invoices and credit notes of their reports, newest first.
+// Filtered by ?status=, ?kind= and ?jobReportId=.
+func GetInvoices(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Invoices")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	filter := ""
+	args := []interface{}{wa.Username}
+	for param, column := range map[string]string{"status": "inv.status", "kind": "inv.kind",
+		"jobReportId": "inv.job_report_id"} {
+		if value := c.Query(param); value != "" {
+			filter += " AND " + column + " = ?"
+			args = append(args, value)
+		}
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectInvoices+"WHERE wkr.username = ?"+filter+" ORDER BY inv.invoice_id DESC", args...)
+	if err != nil {
+		log.Println("\nFailed to process Invoices.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	invoices := []models.Invoice{}
+	for selDB.Next() {
+		invoice, err := scanInvoice(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Invoices.", err)
+			c.JSON(500, nil)
+			return
+		}
+		invoices = append(invoices, invoice)
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+// GetInvoice
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, get the invoice with its lines.
+func GetInvoice(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// UpdateInvoice
+// Works with CheckForCookie, isValidAccount, findInvoice & invoiceLines.
+// If the user has a cookie and owns the invoice's report, replace the parts and other lines (and who it is billed to)
+// of a draft. Labour is worked out again from the report's work hours and overtimeHours at the rates in config.ini.
+func UpdateInvoice(c *gin.Context) {
+	var request models.InvoiceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	if invoice.Status != billing.Draft {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only draft invoices can be changed, raise a credit note instead"})
+		return
+	}
+
+	report, err := findReport(db, int(invoice.JobReportId), wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if !invoiceLines(c, &invoice, report, request, settings.Rates) {
+		return
+	}
+	if request.BillToName != "" {
+		invoice.BillToName = request.BillToName
+	}
+	if request.BillToAddress != "" {
+		invoice.BillToAddress = request.BillToAddress
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		_, err = tx.Exec("UPDATE invoices SET bill_to_name = ?, bill_to_address = ?, subtotal_cents = ?, vat_cents = ?, "+
+			"total_cents = ? WHERE invoice_id = ? AND status = 'draft'", invoice.BillToName, invoice.BillToAddress,
+			invoice.SubtotalCents, invoice.VatCents, invoice.TotalCents, invoice.InvoiceId)
+		if err == nil {
+			err = saveLines(tx, kindInvoice, invoice.InvoiceId, invoice.Lines)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Invoice.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to update Invoice"})
+		return
+	}
+
+	sendInvoice(c, db, http.StatusOK, invoice.InvoiceId)
+}
+
+// DeleteInvoice
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, delete a draft invoice. Issued invoices are voided instead.
+func DeleteInvoice(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	if invoice.Status != billing.Draft {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only draft invoices can be deleted, void it instead"})
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM invoices WHERE invoice_id = ? AND status = 'draft'", invoice.InvoiceId); err != nil {
+		log.Println("\nMySQL Error: Error Deleting Invoice.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(204, nil)
+}
+
+// SetInvoiceStatus
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, move the invoice to another status.
+// An invoice issued is given the next invoice number and its due date from payment_terms_days.
+func SetInvoiceStatus(c *gin.Context) {
+	var request models.InvoiceStatus
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to change this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Changing Invoice.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	status, err := setInvoiceStatus(tx, invoice, request.Status, settings)
+	if err = endTx(tx, err); err != nil {
+		if status != 0 {
+			c.JSON(status, models.Error{Code: int32(status), Messages: err.Error()})
+			return
+		}
+		log.Println("\nMySQL Error: Error Changing Invoice.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Invoice", invoice.InvoiceId, "is now", request.Status)
+	sendInvoice(c, db, http.StatusOK, invoice.InvoiceId)
+}
+
+// CreateCreditNote
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, raise a credit note against an issued invoice.
+// Without lines every line of the invoice is credited. Credit notes are issued straight away with the next
+// credit note number and cannot credit more than is left of the invoice.
+func CreateCreditNote(c *gin.Context) {
+	var request models.InvoiceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to credit this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if request.Reason == "" {
+		c.JSON(400, models.Error{Code: 400, Messages: "Credit note is invalid", Fields: []models.FieldError{
+			{Field: "reason", Message: "reason is required"}}})
+		return
+	}
+
+	settings, err := config.InvoiceSettings()
+	if err != nil {
+		log.Println("Failed to load config file for invoicing.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	if invoice.Kind != kindInvoice || (invoice.Status != billing.Issued && invoice.Status != billing.Paid) {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only issued invoices can be credited"})
+		return
+	}
+
+	credit := models.Invoice{JobReportId: invoice.JobReportId, Kind: kindCreditNote,
+		CreditedInvoiceId: invoice.InvoiceId, Status: billing.Issued, BillTo: invoice.BillTo,
+		BillToName: invoice.BillToName, BillToAddress: invoice.BillToAddress, Currency: invoice.Currency,
+		Reason: request.Reason, Lines: request.Lines}
+	if len(credit.Lines) == 0 {
+		credit.Lines = invoice.Lines
+	}
+	if !calculateInvoice(c, &credit, settings.Rates) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Creating Credit Note.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	status, err := insertCreditNote(tx, &credit, invoice, settings)
+	if err = endTx(tx, err); err != nil {
+		if status != 0 {
+			c.JSON(status, models.Error{Code: int32(status), Messages: err.Error()})
+			return
+		}
+		log.Println("\nMySQL Error: Error Creating Credit Note.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to create Credit Note"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Credit Note", credit.InvoiceNumber, "raised against Invoice", invoice.InvoiceNumber)
+	sendInvoice(c, db, 201, credit.InvoiceId)
+}
+
+// Function to read a record from the selectInvoices Query into an Invoice object.
+func scanInvoice(rows *sql.Rows) (models.Invoice, error) {
+	var invoice models.Invoice
+	var issuedAt sql.NullTime
+	var dueDate models.Date
+
+	err := rows.Scan(&invoice.InvoiceId, &invoice.JobReportId, &invoice.Kind, &invoice.CreditedInvoiceId,
+		&invoice.InvoiceNumber, &invoice.Status, &invoice.BillTo, &invoice.BillToName, &invoice.BillToAddress,
+		&invoice.Currency, &invoice.SubtotalCents, &invoice.VatCents, &invoice.TotalCents, &invoice.Reason, &issuedAt,
+		&dueDate, &invoice.CreatedAt, &invoice.UpdatedAt)
+	if issuedAt.Valid {
+		invoice.IssuedAt = &issuedAt.Time
+	}
+	if !dueDate.IsZero() {
+		invoice.DueDate = &dueDate
+	}
+	return invoice, err
+}
+
+// Function to get an invoice with its lines, of a report belonging to a user.
+// Returns sql.ErrNoRows if the user has no such invoice.
+func findInvoice(db dbExecutor, invoiceId int, username string) (models.Invoice, error) {
+	selDB, err := db.Query(selectInvoices+"WHERE inv.invoice_id = ? AND wkr.username = ?", invoiceId, username)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	if !selDB.Next() {
+		selDB.Close()
+		if err := selDB.Err(); err != nil {
+			return models.Invoice{}, err
+		}
+		return models.Invoice{}, sql.ErrNoRows
+	}
+	invoice, err := scanInvoice(selDB)
+	selDB.Close()
+	if err != nil {
+		return invoice, err
+	}
+
+	invoice.Lines, err = loadLines(db, kindInvoice, invoice.InvoiceId)
+	return invoice, err
+}
+
+// Function to get the lines of an invoice or estimate in order.
+// owner is "invoice" or "estimate", their lines are kept in the tables invoice_lines and estimate_lines.
+func loadLines(db dbExecutor, owner string, ownerId int32) ([]models.InvoiceLine, error) {
+	selDB, err := db.Query("SELECT kind, description, quantity, unit_price_cents, vat_rate, net_cents, vat_cents "+
+		"FROM "+owner+"_lines WHERE "+owner+"_id = ? ORDER BY line_no", ownerId)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	lines := []models.InvoiceLine{}
+	for selDB.Next() {
+		var line models.InvoiceLine
+		if err := selDB.Scan(&line.Kind, &line.Description, &line.Quantity, &line.UnitPriceCents, &line.VatRate,
+			&line.NetCents, &line.VatCents); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, selDB.Err()
+}
+
+// Function to get the invoice requested from the reports the logged in user owns.
+// Sends the error response and returns false if there is no such invoice.
+func ownedInvoice(c *gin.Context, db *sql.DB) (models.Invoice, bool) {
+	invoiceId, err := strconv.Atoi(c.Params.ByName("invoiceId"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Invoice not found"})
+		return models.Invoice{}, false
+	}
+
+	invoice, err := findInvoice(db, invoiceId, wa.Username)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Invoice not found"})
+		return models.Invoice{}, false
+	}
+	if err != nil {
+		log.Println("\nFailed to load Invoice.", err)
+		c.JSON(500, nil)
+		return models.Invoice{}, false
+	}
+	return invoice, true
+}
+
+// Function to send an invoice to the client with its lines, as it is now in the database.
+func sendInvoice(c *gin.Context, db *sql.DB, status int, invoiceId int32) {
+	invoice, err := findInvoice(db, int(invoiceId), wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Invoice.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(status, invoice)
+}
+
+// Function to work out the lines and totals of an invoice.
+// Sends the error response and returns false if a line is invalid.
+func calculateInvoice(c *gin.Context, invoice *models.Invoice, rates billing.Rates) bool {
+	if len(invoice.Lines) == 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Invoice is invalid", Fields: []models.FieldError{
+			{Field: "lines", Message: "an invoice needs at least one line"}}})
+		return false
+	}
+
+	var err error
+	invoice.SubtotalCents, invoice.VatCents, invoice.TotalCents, err = billing.Calculate(invoice.Lines, rates)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "Invoice is invalid", Fields: []models.FieldError{
+			{Field: "lines", Message: err.Error()}}})
+		return false
+	}
+	return true
+}
+
+// Function to set the lines of an invoice to the labour of its report and the parts and other lines requested, and
+// work out its totals. Labour cannot be sent, it is always charged from the report's hours at the rates.
+// Sends the error response and returns false if the overtime hours or a line is invalid.
+func invoiceLines(c *gin.Context, invoice *models.Invoice, report models.JobReport, request models.InvoiceRequest,
+	rates billing.Rates) bool {
+	hours := float64(report.WorkHours)
+	if request.OvertimeHours < 0 || request.OvertimeHours > hours {
+		c.JSON(400, models.Error{Code: 400, Messages: "Invoice is invalid", Fields: []models.FieldError{
+			{Field: "overtimeHours", Message: "overtimeHours must be between 0 and the report's workHours"}}})
+		return false
+	}
+	for _, line := range request.Lines {
+		if billing.IsLabour(line.Kind) {
+			c.JSON(400, models.Error{Code: 400, Messages: "Invoice is invalid", Fields: []models.FieldError{
+				{Field: "lines", Message: "labour is charged from the report's workHours, send overtimeHours instead"}}})
+			return false
+		}
+	}
+
+	invoice.Lines = append(billing.LabourLines(hours-request.OvertimeHours, request.OvertimeHours,
+		report.Warranty != 0, rates), request.Lines...)
+	return calculateInvoice(c, invoice, rates)
+}
+
+// Function to insert a draft invoice in tx, locking its report first so it cannot be invoiced twice at once.
+// Returns the status code to send with the error if the report has already been invoiced.
+func insertInvoice(tx *sql.Tx, invoice *models.Invoice) (int, error) {
+	var reportId int32
+	if err := tx.QueryRow("SELECT job_report_id FROM jobreports WHERE job_report_id = ? FOR UPDATE",
+		invoice.JobReportId).Scan(&reportId); err != nil {
+		return 0, err
+	}
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM invoices WHERE job_report_id = ? AND kind = 'invoice' "+
+		"AND status != 'void'", reportId).Scan(&existing); err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 409, errors.New("report has already been invoiced")
+	}
+
+	res, err := tx.Exec("INSERT INTO invoices (job_report_id, worker_id, kind, status, bill_to, bill_to_name, "+
+		"bill_to_address, currency, subtotal_cents, vat_cents, total_cents) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		reportId, wa.Id, invoice.Kind, invoice.Status, invoice.BillTo, invoice.BillToName, invoice.BillToAddress,
+		invoice.Currency, invoice.SubtotalCents, invoice.VatCents, invoice.TotalCents)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	invoice.InvoiceId = int32(id)
+	return 0, saveLines(tx, kindInvoice, invoice.InvoiceId, invoice.Lines)
+}
+
+// Function to replace the lines of an invoice or estimate, owner is "invoice" or "estimate" as for loadLines.
+func saveLines(tx *sql.Tx, owner string, ownerId int32, lines []models.InvoiceLine) error {
+	if _, err := tx.Exec("DELETE FROM "+owner+"_lines WHERE "+owner+"_id = ?", ownerId); err != nil {
+		return err
+	}
+	for i, line := range lines {
+		_, err := tx.Exec("INSERT INTO "+owner+"_lines ("+owner+"_id, line_no, kind, description, quantity, "+
+			"unit_price_cents, vat_rate, net_cents, vat_cents) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", ownerId, i+1,
+			line.Kind, line.Description, line.Quantity, line.UnitPriceCents, line.VatRate, line.NetCents, line.VatCents)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to move an invoice to another status in tx, locking it first so two changes cannot cross.
+// Returns the status code to send with the error if the change is not allowed.
+func setInvoiceStatus(tx *sql.Tx, invoice models.Invoice, status string, settings config.Invoicing) (int, error) {
+	var current string
+	if err := tx.QueryRow("SELECT status FROM invoices WHERE invoice_id = ? FOR UPDATE",
+		invoice.InvoiceId).Scan(&current); err != nil {
+		return 0, err
+	}
+	if !billing.CanTransition(current, status) {
+		return 409, fmt.Errorf("an invoice that is %s cannot be made %s", current, status)
+	}
+
+	// Once payments are recorded against an invoice its balance decides if it is paid.
+	if current != billing.Draft {
+		balance, err := invoiceBalance(tx, invoice.InvoiceId)
+		if err != nil {
+			return 0, err
+		}
+		if status == billing.Void && balance.PaidCents != 0 {
+			return 409, errors.New("an invoice with payments cannot be voided, refund them first")
+		}
+		if status == billing.Paid && balance.OutstandingCents > 0 {
+			return 409, fmt.Errorf("%d cents are outstanding, record a payment instead", balance.OutstandingCents)
+		}
+		if current == billing.Paid && status == billing.Issued && balance.OutstandingCents <= 0 {
+			return 409, errors.New("nothing is owed on the invoice, refund a payment instead")
+		}
+	}
+
+	if current == billing.Draft && status == billing.Issued {
+		number, err := nextInvoiceNumber(tx, kindInvoice, settings.InvoicePrefix)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("UPDATE invoices SET status = ?, invoice_number = ?, issued_at = ?, due_date = ? "+
+			"WHERE invoice_id = ?", status, number, time.Now().UTC(),
+			models.NewDate(time.Now().AddDate(0, 0, settings.PaymentTermsDays)), invoice.InvoiceId)
+		return 0, err
+	}
+
+	_, err := tx.Exec("UPDATE invoices SET status = ? WHERE invoice_id = ?", status, invoice.InvoiceId)
+	return 0, err
+}
+
+// Function to insert an issued credit note in tx, checking it does not credit more than is left of the invoice.
+// Returns the status code to send with the error if the credit note is not allowed.
+func insertCreditNote(tx *sql.Tx, credit *models.Invoice, invoice models.Invoice, settings config.Invoicing) (int, error) {
+	// Lock the invoice so two credit notes cannot be raised against it at once.
+	var status string
+	if err := tx.QueryRow("SELECT status FROM invoices WHERE invoice_id = ? FOR UPDATE",
+		invoice.InvoiceId).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status != billing.Issued && status != billing.Paid {
+		return 409, errors.New("only issued invoices can be credited")
+	}
+
+	var credited int64
+	if err := tx.QueryRow("SELECT COALESCE(SUM(total_cents), 0) FROM invoices WHERE credited_invoice_id = ? "+
+		"AND kind = 'credit_note' AND status != 'void'", invoice.InvoiceId).Scan(&credited); err != nil {
+		return 0, err
+	}
+	if credited+credit.TotalCents > invoice.TotalCents {
+		return 400, fmt.Errorf("credit notes cannot credit more than the invoice total, %d cents are left",
+			invoice.TotalCents-credited)
+	}
+
+	number, err := nextInvoiceNumber(tx, kindCreditNote, settings.CreditNotePrefix)
+	if err != nil {
+		return 0, err
+	}
+	credit.InvoiceNumber = number
+
+	res, err := tx.Exec("INSERT INTO invoices (job_report_id, worker_id, kind, credited_invoice_id, invoice_number, "+
+		"status, bill_to, bill_to_name, bill_to_address, currency, subtotal_cents, vat_cents, total_cents, reason, "+
+		"issued_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", credit.JobReportId, wa.Id, credit.Kind,
+		credit.CreditedInvoiceId, credit.InvoiceNumber, credit.Status, credit.BillTo, credit.BillToName,
+		credit.BillToAddress, credit.Currency, credit.SubtotalCents, credit.VatCents, credit.TotalCents, credit.Reason,
+		time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	credit.InvoiceId = int32(id)
+	return 0, saveLines(tx, kindInvoice, credit.InvoiceId, credit.Lines)
+}
+
+// Function to take the next number of a sequence in tx. The sequence's row stays locked until tx ends,
+// so a number is only used if the invoice is saved and numbers have no gaps.
+func nextInvoiceNumber(tx *sql.Tx, sequence, prefix string) (string, error) {
+	var next int64
+	if err := tx.QueryRow("SELECT next_value FROM invoice_sequences WHERE name = ? FOR UPDATE",
+		sequence).Scan(&next); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE invoice_sequences SET next_value = next_value + 1 WHERE name = ?", sequence); err != nil {
+		return "", err
+	}
+	return billing.FormatNumber(prefix, next), nil
+}
+
+// Function to check if a report has any invoices or credit notes, void or not.
+func hasInvoices(db dbExecutor, reportId string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM invoices WHERE job_report_id = ?", reportId).Scan(&count)
+	return count > 0, err
+}
+
+// Function to end a transaction, committing it if err is nil or rolling it back if not.
+// Returns err, or the error committing.
+func endTx(tx *sql.Tx, err error) error {
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,500 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Job Queue
+ * Handles jobs supervisors create for workers - Create, Assign, Claim & Getting the assigned and unassigned jobs.
+ * A job is a report created by a supervisor, it is assigned to a worker or left in the queue for a worker to claim.
+ * Once assigned the job is one of the worker's reports. Each change is kept in the job_assignments table.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/jobqueue"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/live"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// selectJobs is the JOIN Query shared by the functions that get jobs, each adds its own WHERE clause.
+// Columns are read in the order of scanJob, jobs in the queue have no worker.
+const selectJobs = "SELECT DISTINCT " + reportFields + ", COALESCE(wkr.username, ''), COALESCE(sup.username, ''), " +
+	"jr.assigned_at FROM jobreports jr INNER JOIN customers cust ON jr.job_report_id = cust.job_report_id " +
+	"LEFT JOIN workers wkr ON jr.worker_id = wkr.worker_id " +
+	"LEFT JOIN workers sup ON jr.created_by = sup.worker_id " +
+	"LEFT JOIN signatures sig ON jr.job_report_id = sig.job_report_id "
+
+// Open jobs are listed breakdowns first, then oldest first.
+const orderJobs = " ORDER BY jr.breakdown DESC, jr.date_stamp, jr.job_report_id"
+
+// CreateJob
+// Works with CheckForCookie, isValidAccount, requireSupervisor & insertReportTx.
+// If the user has a cookie and is a supervisor, create a job and assign it to the worker assignTo,
+// or leave it in the queue if there is no assignTo.
+func CreateJob(c *gin.Context) {
+	var request models.JobRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if fields := validateJobReport(request.Report); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Job is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to create a Job")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "create jobs") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	workerId, ok := assigneeId(c, db, request.AssignTo)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err == nil {
+		// The report is inserted as the supervisor's and then given to the worker, or to no one.
+		var reportId int64
+		reportId, err = insertReportTx(tx, wa.Id, *request.Report)
+		if err == nil {
+			_, err = tx.Exec("UPDATE jobreports SET worker_id = ?, created_by = ?, assigned_at = ? "+
+				"WHERE job_report_id = ?", nullId(workerId), wa.Id, assignedAt(workerId), reportId)
+		}
+		if err == nil && workerId != 0 {
+			err = recordAssignment(tx, reportId, jobqueue.Assigned, 0, workerId, "")
+		}
+		if err == nil {
+			request.Report.JobReportId = int32(reportId)
+		}
+		if err == nil {
+			err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+		}
+		if err == nil {
+			err = queueReportNotification(tx, reportId, notify.JobCreated)
+		}
+		err = endTx(tx, err)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Job.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to create Job"})
+		return
+	}
+
+	fmt.Println("\n[INFO] New Job:", request.Report.JobReportId)
+	sendJob(c, db, 201, request.Report.JobReportId)
+}
+
+// GetAssignedJobs
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the open jobs assigned to them.
+// Supervisors can get the jobs of another worker with ?worker=, e.g. to reassign them when the worker is off sick.
+func GetAssignedJobs(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Jobs")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	worker := wa.Username
+	if other := c.Query("worker"); other != "" && other != wa.Username {
+		if !requireSupervisor(c, "get the jobs of other workers") {
+			return
+		}
+		worker = other
+	}
+
+	getJobs(c, "WHERE wkr.username = ? AND jr.assigned_at IS NOT NULL AND jr.job_report_complete = 0", worker)
+}
+
+// GetUnassignedJobs
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the jobs in the queue waiting to be claimed or assigned.
+func GetUnassignedJobs(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Jobs")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	getJobs(c, "WHERE jr.worker_id IS NULL AND jr.job_report_complete = 0")
+}
+
+// ClaimJob
+// Works with CheckForCookie, isValidAccount & lockJob.
+// If the user has a cookie, assign a job in the queue to them. Only one worker can claim a job.
+func ClaimJob(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to claim this Job")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	reportId, _ := strconv.Atoi(c.Params.ByName("jobReportId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Claiming Job.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	job, ok := lockJob(c, tx, reportId)
+	if !ok {
+		return
+	}
+	if err := jobqueue.Claim(job.username, wa.Username, job.complete); err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE jobreports SET worker_id = ?, assigned_at = ? WHERE job_report_id = ?", wa.Id,
+		assignedAt(wa.Id), reportId)
+	if err == nil {
+		err = recordAssignment(tx, int64(reportId), jobqueue.Claimed, 0, wa.Id, "")
+	}
+	if err == nil {
+		err = recordLiveEvent(tx, int64(reportId), live.ReportStatus, jobqueue.Claimed, 0)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Claiming Job.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Job", reportId, "claimed by", wa.Username)
+	sendJob(c, db, http.StatusOK, int32(reportId))
+}
+
+// AssignJob
+// Works with CheckForCookie, isValidAccount, requireSupervisor & lockJob.
+// If the user has a cookie and is a supervisor, give a job to the worker assignTo, whether it is in the queue or
+// assigned to another worker, or put it back in the queue if assignTo is empty. Complete jobs are not moved.
+func AssignJob(c *gin.Context) {
+	var request models.JobAssignmentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to assign this Job")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "assign jobs") {
+		return
+	}
+
+	reportId, _ := strconv.Atoi(c.Params.ByName("jobReportId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	workerId, ok := assigneeId(c, db, request.AssignTo)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Assigning Job.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	job, ok := lockJob(c, tx, reportId)
+	if !ok {
+		return
+	}
+	action, err := jobqueue.Assign(job.username, request.AssignTo, job.complete)
+	if err != nil {
+		c.JSON(409, models.Error{Code: 409, Messages: err.Error()})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE jobreports SET worker_id = ?, assigned_at = ? WHERE job_report_id = ?",
+		nullId(workerId), assignedAt(workerId), reportId)
+	if err == nil {
+		err = recordAssignment(tx, int64(reportId), action, job.workerId, workerId, request.Reason)
+	}
+	if err == nil {
+		err = recordLiveEvent(tx, int64(reportId), live.ReportStatus, action, job.workerId)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Assigning Job.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Job", reportId, action, "by", wa.Username)
+	sendJob(c, db, http.StatusOK, int32(reportId))
+}
+
+// GetJobAssignments
+// Works with CheckForCookie, isValidAccount & ownsReport.
+// If the user has a cookie and is a supervisor or has the job, get the history of who it was assigned to.
+func GetJobAssignments(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get the history of this Job")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	reportId, _ := strconv.Atoi(c.Params.ByName("jobReportId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if wa.Role != jobqueue.Supervisor {
+		owned, err := ownsReport(db, reportId, wa.Username)
+		if err != nil {
+			log.Println("\nFailed to process Report.", err)
+			c.JSON(500, nil)
+			return
+		}
+		if !owned {
+			c.JSON(404, models.Error{Code: 404, Messages: "Job not found"})
+			return
+		}
+	}
+
+	selDB, err := db.Query("SELECT ja.action, COALESCE(fw.username, ''), COALESCE(tw.username, ''), cb.username, "+
+		"ja.reason, ja.created_at FROM job_assignments ja LEFT JOIN workers fw ON ja.from_worker = fw.worker_id "+
+		"LEFT JOIN workers tw ON ja.to_worker = tw.worker_id INNER JOIN workers cb ON ja.changed_by = cb.worker_id "+
+		"WHERE ja.job_report_id = ? ORDER BY ja.assignment_id", reportId)
+	if err != nil {
+		log.Println("\nFailed to process Job Assignments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	history := []models.JobAssignment{}
+	for selDB.Next() {
+		var change models.JobAssignment
+		if err := selDB.Scan(&change.Action, &change.FromWorker, &change.ToWorker, &change.ChangedBy, &change.Reason,
+			&change.CreatedAt); err != nil {
+			log.Println("\nFailed to load Job Assignments.", err)
+			c.JSON(500, nil)
+			return
+		}
+		history = append(history, change)
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// Function to check the logged in user is a supervisor before they do something only supervisors can.
+// Sends the error response and returns false if they are not.
+func requireSupervisor(c *gin.Context, action string) bool {
+	if wa.Role != jobqueue.Supervisor {
+		c.JSON(403, models.Error{Code: 403, Messages: "Only supervisors can " + action})
+		return false
+	}
+	return true
+}
+
+// Function to get the ID of the worker a job is to be assigned to, 0 for none.
+// Sends the error response and returns false if there is no such worker.
+func assigneeId(c *gin.Context, db dbExecutor, username string) (int, bool) {
+	if username == "" {
+		return 0, true
+	}
+
+	workerId, err := workerIdOf(db, username)
+	if err == sql.ErrNoRows {
+		c.JSON(400, models.Error{Code: 400, Messages: "Job is invalid", Fields: []models.FieldError{
+			{Field: "assignTo", Message: "there is no worker " + username}}})
+		return 0, false
+	}
+	if err != nil {
+		log.Println("\nFailed to process Workers.", err)
+		c.JSON(500, nil)
+		return 0, false
+	}
+	return workerId, true
+}
+
+// Function to get the ID of a worker by their username.
+// Returns sql.ErrNoRows if there is no such worker.
+func workerIdOf(db dbExecutor, username string) (int, error) {
+	var workerId int
+	err := db.QueryRow("SELECT worker_id FROM workers WHERE username = ?", username).Scan(&workerId)
+	return workerId, err
+}
+
+// lockedJob is who a job is assigned to while it is locked by lockJob.
+type lockedJob struct {
+	workerId int
+	username string
+	complete bool
+}
+
+// Function to get who a job is assigned to, locking it until tx ends so it is not assigned twice at once.
+// Sends the error response and returns false if there is no such job.
+func lockJob(c *gin.Context, tx *sql.Tx, reportId int) (lockedJob, bool) {
+	var job lockedJob
+	var workerId sql.NullInt64
+	err := tx.QueryRow("SELECT jr.worker_id, COALESCE(wkr.username, ''), jr.job_report_complete FROM jobreports jr "+
+		"LEFT JOIN workers wkr ON jr.worker_id = wkr.worker_id WHERE jr.job_report_id = ? FOR UPDATE", reportId).
+		Scan(&workerId, &job.username, &job.complete)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Job not found"})
+		return job, false
+	}
+	if err != nil {
+		log.Println("\nFailed to load Job.", err)
+		c.JSON(500, nil)
+		return job, false
+	}
+	job.workerId = int(workerId.Int64)
+	return job, true
+}
+
+// Function to add a change of who a job is assigned to, to its history. Worker IDs are 0 for the queue.
+func recordAssignment(tx *sql.Tx, reportId int64, action string, fromWorker, toWorker int, reason string) error {
+	_, err := tx.Exec("INSERT INTO job_assignments (job_report_id, action, from_worker, to_worker, changed_by, reason) "+
+		"VALUES (?, ?, ?, ?, ?, ?)", reportId, action, nullId(fromWorker), nullId(toWorker), wa.Id, reason)
+	return err
+}
+
+// Function to store a worker ID of 0 as NULL, for jobs in the queue.
+func nullId(workerId int) interface{} {
+	if workerId == 0 {
+		return nil
+	}
+	return workerId
+}
+
+// Function to get the time a job given to a worker is assigned at, NULL for jobs in the queue.
+func assignedAt(workerId int) interface{} {
+	if workerId == 0 {
+		return nil
+	}
+	return time.Now().UTC()
+}
+
+// Function to read a record from the selectJobs Query into a Job object.
+func scanJob(rows *sql.Rows) (models.Job, error) {
+	var job models.Job
+	var assigned sql.NullTime
+	var err error
+	job.JobReport, err = scanReport(rows, &job.AssignedTo, &job.CreatedBy, &assigned)
+	if assigned.Valid {
+		job.AssignedAt = &assigned.Time
+	}
+	return job, err
+}
+
+// Function to send the jobs of a selectJobs Query, open jobs in the order they should be done.
+func getJobs(c *gin.Context, where string, args ...interface{}) {
+	unit, err := requestedOdometerUnit(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectJobs+where+orderJobs, args...)
+	if err != nil {
+		log.Println("\nFailed to process Jobs.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	jobs := []models.Job{}
+	for selDB.Next() {
+		job, err := scanJob(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Jobs.", err)
+			c.JSON(500, nil)
+			return
+		}
+		presentReport(&job.JobReport, unit)
+		jobs = append(jobs, job)
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// Function to send a job as it is now in the database.
+func sendJob(c *gin.Context, db *sql.DB, status int, reportId int32) {
+	selDB, err := db.Query(selectJobs+"WHERE jr.job_report_id = ?", reportId)
+	if err == nil {
+		defer selDB.Close()
+		if selDB.Next() {
+			var job models.Job
+			if job, err = scanJob(selDB); err == nil {
+				presentReport(&job.JobReport, "")
+				c.JSON(status, job)
+				return
+			}
+		}
+	}
+	log.Println("\nFailed to load Job.", err)
+	c.JSON(500, nil)
+}
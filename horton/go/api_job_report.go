@@ -16,16 +16,85 @@
 package openapi
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
 	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/odometer"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
 	"log"
 	"net/http"
+	"strconv"
 )
 
+// reportFields are the columns of a report read by scanReport, from the tables jobreports jr, customers cust,
+// workers wkr & signatures sig.
+const reportFields = "jr.job_report_id, jr.date_stamp, jr.vehicle_model, " +
+	"jr.vehicle_reg, jr.odometer_km, jr.odometer_reading, jr.odometer_unit, jr.vehicle_location, jr.location_lat, " +
+	"jr.location_lon, jr.warranty, jr.breakdown, cust.customer_name, cust.contact_id, " +
+	"cust.customer_complaint, jr.cause, jr.correction, jr.parts, jr.work_hours, " +
+	"COALESCE(wkr.worker_name, ''), jr.job_report_complete, jr.created_at, jr.updated_at, COALESCE(sig.report_hash, '')"
+
+// selectReports is the JOIN Query shared by the functions that get reports, each adds its own WHERE clause.
+// Columns are read in the order of scanReport.
+const selectReports = "SELECT DISTINCT " + reportFields + " " +
+	"FROM jobreports jr INNER JOIN customers cust " +
+	"ON jr.job_report_id = cust.job_report_id " +
+	"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id " +
+	"LEFT JOIN signatures sig ON jr.job_report_id = sig.job_report_id "
+
+// Function to read a record from the selectReports Query into a JobReport object.
+// extra are read from any columns selected after reportFields.
+func scanReport(rows *sql.Rows, extra ...interface{}) (models.JobReport, error) {
+	var report models.JobReport
+
+	err := rows.Scan(append([]interface{}{&report.JobReportId, &report.Date, &report.VehicleModel, &report.VehicleReg,
+		&report.OdometerKm, &report.OdometerReading, &report.OdometerUnit, &report.VehicleLocation, &report.Latitude,
+		&report.Longitude, &report.Warranty, &report.Breakdown, &report.CustomerName, &report.ContactId,
+		&report.Complaint, &report.Cause, &report.Correction, &report.Parts, &report.WorkHours, &report.WorkerName,
+		&report.JobComplete, &report.CreatedAt, &report.UpdatedAt, &report.SignedReportHash}, extra...)...)
+	return report, err
+}
+
+// Function to get a report belonging to a user by its ID.
+// Returns sql.ErrNoRows if the user has no such report.
+func findReport(db *sql.DB, reportId int, username string) (models.JobReport, error) {
+	selDB, err := db.Query(selectReports+"WHERE jr.job_report_id = ? AND wkr.username = ?", reportId, username)
+	if err != nil {
+		return models.JobReport{}, err
+	}
+	defer selDB.Close()
+
+	if !selDB.Next() {
+		if err := selDB.Err(); err != nil {
+			return models.JobReport{}, err
+		}
+		return models.JobReport{}, sql.ErrNoRows
+	}
+	return scanReport(selDB)
+}
+
+// dbExecutor is a *sql.DB or a *sql.Tx, so the same query can be run in a transaction or not.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Function to check a report belongs to a user.
+// Used by the functions for things attached to reports.
+func ownsReport(db dbExecutor, reportId int, username string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM jobreports jr INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id "+
+		"WHERE jr.job_report_id = ? AND wkr.username = ?", reportId, username).Scan(&count)
+	return count > 0, err
+}
+
 // CreateReport
 // Works with CheckForCookie & InsertJobReport.
 // If the user has a cookie call InsertJobReport to create a report from user input data.
@@ -33,9 +102,16 @@ func CreateReport(c *gin.Context) {
 	var report models.JobReport
 
 	// Bind entered JobReport data from user to object, else throw error.
-	if err := c.BindJSON(&report); err != nil {
+	if err := c.ShouldBindJSON(&report); err != nil {
 		fmt.Println(err.Error()) // Failed to Bind data.
-		c.JSON(500, nil)
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	// Validate the report - normalises the odometer reading and registration.
+	if fields := validateJobReport(&report); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Report is invalid", Fields: fields})
+		return
 	}
 
 	// Check for user's cookie - if they do not have one abort the request.
@@ -48,7 +124,7 @@ func CreateReport(c *gin.Context) {
 	// Call InsertJobReport to create the report.
 	if err := InsertJobReport(c, report, wa.Username); err == nil {
 		c.JSON(201, models.Error{Code: 201, Messages: "Report created successfully"})
-	} else {
+	} else if !c.Writer.Written() {
 		c.JSON(401, models.Error{Code: 401, Messages: "Not able to create Report"})
 	}
 }
@@ -56,61 +132,97 @@ func CreateReport(c *gin.Context) {
 // InsertJobReport
 // Function that creates a new report by starting and committing a MySQL transaction
 // with data inputted by user to insert into the tables, jobreports and customers.
+// A contact the report is linked to that does not exist is answered with a 400 by checkContact.
 func InsertJobReport(c *gin.Context, report models.JobReport, username string) error {
 	db := config.DbConn()
 	//db := mocks.MockDbConn() // mock db for testing
+	defer db.Close()
 
 	fmt.Println("\n[INFO] Processing Report Details...")
 
-	// Insert into the table jobreports.
-	insertReport, err := db.Prepare(
-		"INSERT INTO jobreports(worker_id, date_stamp, vehicle_model, vehicle_reg, vehicle_location, " +
-			"miles_on_vehicle, warranty, breakdown, cause, correction, parts, work_hours, job_report_complete) " +
-			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	// Check logged in user - mainly for selecting the user's 'Worker Name' to add it to the report.
+	if !isValidAccount(username) {
+		log.Println("\nUser is not logged in")
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return errors.New("error creating Report")
+	}
 
-	// Insert into the table customers.
-	insertCustomer, err := db.Prepare("INSERT INTO customers (job_report_id, customer_name, customer_complaint)" +
-		" VALUES (LAST_INSERT_ID(), ?, ?)")
+	if !checkContact(c, db, reportContact(report)) {
+		return errors.New("error creating Report")
+	}
 
+	// Begin MySQL transaction to create a new report with input data from user.
+	tx, err := db.Begin()
 	if err != nil {
 		c.JSON(500, nil)
 		log.Println("\nMySQL Error: Error Preparing new Report:\n", err)
 		return errors.New("error creating Report")
 	}
-
-	// Check logged in user - mainly for selecting the user's 'Worker Name' to add it to the report.
-	if !isValidAccount(username) {
-		log.Println("\nUser is not logged in")
-		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
-		return errors.New("error creating Report")
+	reportId, err := insertReportTx(tx, wa.Id, report)
+	if err == nil {
+		err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+	}
+	if err == nil {
+		err = queueReportNotification(tx, reportId, notify.JobCreated)
+	}
+	if err == nil {
+		err = tx.Commit() // Commit MySQL transaction.
+	} else {
+		tx.Rollback()
 	}
-
-	// Begin MySQL transition to create a new report with input data from user.
-	_, err = db.Query("BEGIN")
-	// Execute insert into the table jobreports.
-	reportResult, err := insertReport.Exec(wa.Id, report.Date, report.VehicleModel, report.VehicleReg, report.VehicleLocation,
-		report.MilesOnVehicle, report.Warranty, report.Breakdown, report.Cause, report.Correction, report.Parts,
-		report.WorkHours, report.JobComplete)
-	// Execute insert into the table customers.
-	customerResult, err := insertCustomer.Exec(report.CustomerName, report.Complaint)
-	_, err = db.Query("COMMIT") // Commit MySQL transition.
 
 	if err != nil {
 		log.Println("\nMySQL Error: Error Inserting Report Details.\n", err)
 		c.JSON(500, nil)
 		return errors.New("error creating Report")
 	}
-	fmt.Println("\n[INFO] Printing MySQL Results for new Report...\n", reportResult, customerResult)
-
-	defer db.Close()
+	fmt.Println("\n[INFO] New Report:", reportId)
 	return nil
 }
 
+// Function to insert a report into the tables jobreports and customers within a transaction.
+// Returns the ID of the new report.
+func insertReportTx(tx *sql.Tx, workerId int, report models.JobReport) (int64, error) {
+	// Insert into the table jobreports.
+	reportResult, err := tx.Exec(
+		"INSERT INTO jobreports(worker_id, date_stamp, vehicle_model, vehicle_reg, vehicle_location, location_lat, "+
+			"location_lon, odometer_km, odometer_reading, odometer_unit, warranty, breakdown, cause, correction, parts, "+
+			"work_hours, job_report_complete) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		workerId, report.Date, report.VehicleModel, report.VehicleReg, report.VehicleLocation, report.Latitude,
+		report.Longitude, report.OdometerKm, report.OdometerReading, report.OdometerUnit, report.Warranty, report.Breakdown, report.Cause,
+		report.Correction, report.Parts, report.WorkHours, report.JobComplete)
+	if err != nil {
+		return 0, err
+	}
+	reportId, err := reportResult.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// Insert into the table customers.
+	_, err = tx.Exec("INSERT INTO customers (job_report_id, customer_name, customer_complaint, contact_id) "+
+		"VALUES (?, ?, ?, NULLIF(?, 0))", reportId, report.CustomerName, report.Complaint, report.ContactId)
+	return reportId, err
+}
+
 // GetReportById
 // Works with CheckForCookie & isValidAccount.
 // If the user has a cookie and owns the report,
 // get it in the database with a JOIN QUERY by its requested ID and logged in user's username.
+// Sends a PDF job sheet instead if the ID ends in ".pdf" or the client accepts application/pdf.
 func GetReportById(c *gin.Context) {
+	// /jobReports/export shares this route, gin does not allow a fixed path beside :jobReportId.
+	if c.Params.ByName("jobReportId") == "export" {
+		ExportReports(c)
+		return
+	}
+
+	// The report as a PDF job sheet instead of JSON.
+	if wantsReportPdf(c) {
+		GetReportPdf(c)
+		return
+	}
+
 	db := config.DbConn()
 	//db := mocks.MockDbConn()
 
@@ -126,6 +238,13 @@ func GetReportById(c *gin.Context) {
 	reportId := c.Params.ByName("jobReportId")
 	fmt.Printf("Get Report with ID: " + reportId)
 
+	// Unit the client wants odometer readings in, if any.
+	unit, err := requestedOdometerUnit(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
 	// Check the user (worker) to put it in the SELECT Query.
 	if !isValidAccount(worker) {
 		log.Println("\nUser does not own this report.")
@@ -134,13 +253,7 @@ func GetReportById(c *gin.Context) {
 	}
 
 	// JOIN Query to get report by requested ID and username.
-	selDB, err := db.Query("SELECT DISTINCT jr.job_report_id, jr.date_stamp, jr.vehicle_model, "+
-		"jr.vehicle_reg, jr.miles_on_vehicle, jr.vehicle_location, jr.warranty, jr.breakdown, "+
-		"cust.customer_name, cust.customer_complaint, jr.cause, jr.correction, jr.parts, jr.work_hours, "+
-		"wkr.worker_name, jr.job_report_complete FROM jobreports jr INNER JOIN customers cust "+
-		"ON jr.job_report_id = cust.job_report_id "+
-		"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id "+
-		"WHERE jr.job_report_id = ? AND wkr.username = ?", reportId, worker)
+	selDB, err := db.Query(selectReports+"WHERE jr.job_report_id = ? AND wkr.username = ?", reportId, worker)
 
 	if err != nil {
 		log.Println("\nFailed to process Report.", err)
@@ -150,16 +263,13 @@ func GetReportById(c *gin.Context) {
 
 	// Run through each record and read values - Get the requested report from the database.
 	for selDB.Next() {
-		var report models.JobReport
-
-		err = selDB.Scan(&report.JobReportId, &report.Date, &report.VehicleModel, &report.VehicleReg, &report.MilesOnVehicle,
-			&report.VehicleLocation, &report.Warranty, &report.Breakdown, &report.CustomerName, &report.Complaint, &report.Cause,
-			&report.Correction, &report.Parts, &report.WorkHours, &report.WorkerName, &report.JobComplete)
+		report, err := scanReport(selDB)
 
 		if err != nil {
 			log.Println("\nFailed to load Report.")
 			c.JSON(500, nil)
 		}
+		presentReport(&report, unit)
 		// Add each record to array.
 		res = append(res, report)
 		log.Printf(string(report.JobReportId))
@@ -180,7 +290,6 @@ func GetReports(c *gin.Context) {
 
 	worker := wa.Username
 	var res []models.JobReport
-	var report models.JobReport
 
 	if !CheckForCookie(c) {
 		log.Println("User is unauthorized to get these Reports")
@@ -193,13 +302,22 @@ func GetReports(c *gin.Context) {
 		return
 	}
 
+	// Unit the client wants odometer readings in, if any.
+	unit, err := requestedOdometerUnit(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	// Filters and order the client wants the reports in.
+	filter, args, err := reportListFilter(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
 	// JOIN Query to get user's job reports.
-	selDB, err := db.Query("SELECT DISTINCT jr.job_report_id, jr.date_stamp, jr.vehicle_model, "+
-		"jr.vehicle_reg, jr.miles_on_vehicle, jr.vehicle_location, jr.warranty, jr.breakdown, "+
-		"cust.customer_name, cust.customer_complaint, jr.cause, jr.correction, jr.parts, jr.work_hours, "+
-		"wkr.worker_name, jr.job_report_complete FROM jobreports jr INNER JOIN customers cust "+
-		"ON jr.job_report_id = cust.job_report_id "+
-		"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id WHERE wkr.username = ?", worker)
+	selDB, err := db.Query(selectReports+"WHERE wkr.username = ?"+filter, append([]interface{}{worker}, args...)...)
 
 	if err != nil {
 		log.Println("\nFailed to process Reports.")
@@ -209,14 +327,13 @@ func GetReports(c *gin.Context) {
 
 	// Run through each record and read values - get the user's reports.
 	for selDB.Next() {
-		err = selDB.Scan(&report.JobReportId, &report.Date, &report.VehicleModel, &report.VehicleReg, &report.MilesOnVehicle,
-			&report.VehicleLocation, &report.Warranty, &report.Breakdown, &report.CustomerName, &report.Complaint, &report.Cause,
-			&report.Correction, &report.Parts, &report.WorkHours, &report.WorkerName, &report.JobComplete)
+		report, err := scanReport(selDB)
 
 		if err != nil {
 			log.Println("\nFailed to load Reports.")
 			c.JSON(500, nil)
 		}
+		presentReport(&report, unit)
 		// Add each record to array.
 		res = append(res, report)
 		log.Printf(string(report.JobReportId))
@@ -228,8 +345,9 @@ func GetReports(c *gin.Context) {
 }
 
 // UpdateReport
-// Works with CheckForCookie.
+// Works with CheckForCookie, checkContact & recordReportEvents.
 // If the user has a cookie allow them to update/edit report in the database by its requested ID.
+// The update is recorded for webhooks in the same transaction.
 func UpdateReport(c *gin.Context) {
 	db := config.DbConn()
 	//db := mocks.MockDbConn()
@@ -245,17 +363,36 @@ func UpdateReport(c *gin.Context) {
 	}
 
 	// Bind JobReport data to object, else throw error.
-	if err := c.BindJSON(&report); err != nil {
+	if err := c.ShouldBindJSON(&report); err != nil {
 		fmt.Println(err.Error())
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	// Validate the report - normalises the odometer reading and registration.
+	if fields := validateJobReport(&report); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Report is invalid", Fields: fields})
+		return
+	}
+	if !checkContact(c, db, reportContact(report)) {
+		return
 	}
 
 	// Read in values from client request and build object - update the report with the user's inputted data.
-	update, err := db.Exec("UPDATE jobreports jr SET jr.date_stamp = ?, jr.vehicle_model = ?, "+
-		"jr.vehicle_reg = ?, jr.vehicle_location = ?, jr.miles_on_vehicle = ?, jr.warranty = ?, "+
-		"jr.breakdown = ?, jr.cause = ?, jr.correction = ?, jr.parts = ?, jr.work_hours = ?, "+
-		"jr.job_report_complete = ? WHERE jr.job_report_id = ?", report.Date, report.VehicleModel, report.VehicleReg,
-		report.VehicleLocation, report.MilesOnVehicle, report.Warranty, report.Breakdown, report.Cause, report.Correction,
-		report.Parts, report.WorkHours, report.JobComplete, reportId)
+	id, _ := strconv.ParseInt(reportId, 10, 64)
+	var update sql.Result
+	tx, err := db.Begin()
+	if err == nil {
+		var wasComplete bool
+		wasComplete, err = reportComplete(tx, id)
+		if err == nil {
+			update, err = updateReport(tx, reportId, report)
+		}
+		if err == nil {
+			err = recordReportEvents(tx, id, webhook.ReportUpdated, wasComplete)
+		}
+		err = endTx(tx, err)
+	}
 
 	if err != nil {
 		log.Println("\nMySQL Error: Error Updating Report:\n", err)
@@ -270,8 +407,9 @@ func UpdateReport(c *gin.Context) {
 }
 
 // DeleteReport
-// Works with CheckForCookie.
+// Works with CheckForCookie & recordReportDeleted.
 // If the user has a cookie allow them to delete a report in the database by its requested ID.
+// The report as it was is recorded for webhooks in the same transaction.
 func DeleteReport(c *gin.Context) {
 	db := config.DbConn()
 	//db := mocks.MockDbConn()
@@ -285,19 +423,184 @@ func DeleteReport(c *gin.Context) {
 		return
 	}
 
+	// Invoiced reports are kept for the accounts, their invoices must be voided and kept too.
+	invoiced, err := hasInvoices(db, reportId)
+	if err != nil {
+		log.Println("Failed to get Invoices of Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if invoiced {
+		c.JSON(409, models.Error{Code: 409, Messages: "Report has been invoiced and cannot be deleted"})
+		return
+	}
+
+	// Get the report's attachments before they are deleted with it.
+	keys, err := attachmentKeys(db, reportId)
+	if err != nil {
+		log.Println("Failed to get Attachments of Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+
 	// Create query to delete the report with its requested ID.
-	res, err := db.Exec("DELETE FROM jobreports WHERE job_report_id=?", reportId)
+	id, _ := strconv.ParseInt(reportId, 10, 64)
+	var res sql.Result
+	tx, err := db.Begin()
+	if err == nil {
+		err = recordReportDeleted(tx, id)
+		if err == nil {
+			res, err = tx.Exec("DELETE FROM jobreports WHERE job_report_id=?", reportId)
+		}
+		err = endTx(tx, err)
+	}
 	if err != nil {
 		log.Printf("Report failed to delete.")
 		c.JSON(500, nil)
+		return
 	}
 
 	affectedRows, err := res.RowsAffected()
 	if err != nil {
 		log.Printf("Report failed to delete.")
 		c.JSON(500, nil)
+		return
 	}
 
 	fmt.Printf("\nThe statement affected %d rows\n", affectedRows)
+	// Remove the report's attached files from the Blob Store.
+	removeStoredFiles(keys)
 	c.JSON(204, nil) // Report has been deleted successfully.
 }
+
+// Function to update a report in the table jobreports with the user's inputted data.
+func updateReport(db dbExecutor, reportId string, report models.JobReport) (sql.Result, error) {
+	return db.Exec("UPDATE jobreports jr INNER JOIN customers cust ON jr.job_report_id = cust.job_report_id "+
+		"SET jr.date_stamp = ?, jr.vehicle_model = ?, "+
+		"jr.vehicle_reg = ?, jr.vehicle_location = ?, jr.location_lat = ?, jr.location_lon = ?, jr.odometer_km = ?, "+
+		"jr.odometer_reading = ?, jr.odometer_unit = ?, jr.warranty = ?, jr.breakdown = ?, jr.cause = ?, "+
+		"jr.correction = ?, jr.parts = ?, jr.work_hours = ?, jr.job_report_complete = ?, "+
+		"cust.contact_id = IF(? IS NULL, cust.contact_id, NULLIF(?, 0)) WHERE jr.job_report_id = ?",
+		report.Date, report.VehicleModel, report.VehicleReg, report.VehicleLocation, report.Latitude, report.Longitude,
+		report.OdometerKm, report.OdometerReading, report.OdometerUnit,
+		report.Warranty, report.Breakdown, report.Cause, report.Correction, report.Parts, report.WorkHours,
+		report.JobComplete, report.ContactId, report.ContactId, reportId)
+}
+
+// Function to build the filters for listing reports from the query parameters of a request.
+// "from" and "to" limit reports to a date range (inclusive), "reg" to a vehicle registration,
+// "near" to vehicle locations "within" km of a place (nearWithin) and "order" sorts them by date, "desc" (default)
+// or "asc".
+// Returns the clause to add after the WHERE clause of selectReports and its arguments.
+func reportListFilter(c *gin.Context) (string, []interface{}, error) {
+	var filter string
+	var args []interface{}
+
+	if from := c.Query("from"); from != "" {
+		date, err := models.ParseDate(from)
+		if err != nil {
+			return "", nil, err
+		}
+		filter += " AND jr.date_stamp >= ?"
+		args = append(args, date)
+	}
+	if reg := c.Query("reg"); reg != "" {
+		// Match the registration as it is stored, registrations that do not parse are matched as entered.
+		if normalised, err := plate.Normalise(reg); err == nil {
+			reg = normalised
+		}
+		filter += " AND jr.vehicle_reg = ?"
+		args = append(args, reg)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := models.ParseDate(to)
+		if err != nil {
+			return "", nil, err
+		}
+		filter += " AND jr.date_stamp <= ?"
+		args = append(args, date)
+	}
+	if near := c.Query("near"); near != "" {
+		clause, nearArgs, err := nearWithin(near, c.Query("within"))
+		if err != nil {
+			return "", nil, err
+		}
+		filter += clause
+		args = append(args, nearArgs...)
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+		filter += " ORDER BY jr.date_stamp DESC, jr.job_report_id DESC"
+	case "asc":
+		filter += " ORDER BY jr.date_stamp ASC, jr.job_report_id ASC"
+	default:
+		return "", nil, errors.New("order must be asc or desc")
+	}
+	return filter, args, nil
+}
+
+// Function to set the odometer reading of a report from user input.
+// The reading is entered as odometerReading with an odometerUnit of km or mi,
+// older clients only send milesOnVehicle which is taken as a reading in miles.
+// The reading is kept as entered and converted to kilometres for storing.
+func setOdometer(report *models.JobReport) error {
+	if report.OdometerReading == 0 && report.MilesOnVehicle != 0 {
+		report.OdometerReading = report.MilesOnVehicle
+		report.OdometerUnit = string(odometer.Miles)
+	}
+
+	unit, err := odometer.ParseUnit(report.OdometerUnit)
+	if err != nil {
+		return err
+	}
+	if report.OdometerReading < 0 {
+		return errors.New("odometer reading cannot be negative")
+	}
+
+	report.OdometerUnit = string(unit)
+	report.OdometerKm = int32(odometer.ToKilometres(int64(report.OdometerReading), unit))
+	return nil
+}
+
+// Function to set the fields of a report read from the database that are worked out before sending it to the client.
+func presentReport(report *models.JobReport, unit odometer.Unit) {
+	// Compare to the signed hash before any fields are changed for the client.
+	if report.SignedReportHash != "" {
+		report.Signed = true
+		report.ModifiedAfterSigning = reportHash(*report) != report.SignedReportHash
+	}
+
+	presentOdometer(report, unit)
+
+	// Registrations that do not parse were stored before plates were validated.
+	if p, err := plate.Parse(report.VehicleReg); err == nil {
+		report.VehicleRegYear = int32(p.Year)
+		report.VehicleRegCounty = p.County
+	}
+}
+
+// Function to set the odometer fields of a report read from the database before sending it to the client.
+// The reading as entered is kept in originalOdometerReading & originalOdometerUnit and odometerReading is
+// given in the unit requested by the client, or as entered if no unit was requested.
+// milesOnVehicle is kept for older clients.
+func presentOdometer(report *models.JobReport, unit odometer.Unit) {
+	report.OriginalOdometerReading = report.OdometerReading
+	report.OriginalOdometerUnit = report.OdometerUnit
+	report.MilesOnVehicle = int32(odometer.FromKilometres(int64(report.OdometerKm), odometer.Miles))
+
+	if unit != "" && string(unit) != report.OdometerUnit {
+		report.OdometerReading = int32(odometer.FromKilometres(int64(report.OdometerKm), unit))
+		report.OdometerUnit = string(unit)
+	}
+}
+
+// Function to get the unit the client wants odometer readings in from the "unit" query parameter.
+// Returns an empty unit if none was requested.
+func requestedOdometerUnit(c *gin.Context) (odometer.Unit, error) {
+	unit := c.Query("unit")
+	if unit == "" {
+		return "", nil
+	}
+	return odometer.ParseUnit(unit)
+}
@@ -0,0 +1,237 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Live
+ * Handles pushing the events of job reports to clients as they happen - Report Events.
+ * Events are recorded in report_events in the same transaction as the change to the report, and each client's
+ * stream reads those after the last it was sent. Clients that reconnect send the ID of the last event they were sent
+ * and are sent the events they missed while events are kept.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/jobqueue"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/live"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"strconv"
+	"time"
+)
+
+// The most events read for a client at once, the rest are read on the next check.
+const maxLiveEvents = 1000
+
+// GetReportEvents
+// Works with CheckForCookie, isValidAccount & streamReportEvents.
+// If the user has a cookie, stream the events of the job reports they can see as Server-Sent Events, or over a
+// WebSocket if the request asks to be upgraded. Supervisors are sent every event, workers the events of their own
+// reports, reports moved away from them and reports in the queue. The Last-Event-ID header, or lastEventId for
+// WebSockets, is the last event the client was sent before reconnecting.
+func GetReportEvents(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get the Report Events")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+	scope := live.Scope{All: wa.Role == jobqueue.Supervisor, WorkerId: wa.Id}
+	username := wa.Username
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+	var last int64
+	if lastEventId != "" {
+		var err error
+		if last, err = strconv.ParseInt(lastEventId, 10, 64); err != nil || last < 0 {
+			c.JSON(400, models.Error{Code: 400, Messages: "Last-Event-ID must be the ID of an event"})
+			return
+		}
+	}
+
+	settings, err := config.LiveSettings()
+	if err != nil {
+		log.Println("Failed to load config file for live events.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	cursor, missed, err := liveCursor(db, last, settings)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Getting Report Events.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	if live.IsWebSocket(c.Request) {
+		conn, err := live.Upgrade(c.Writer, c.Request, settings.Origins)
+		if err != nil {
+			log.Println("\nFailed to upgrade Report Events to a WebSocket.", err)
+			return
+		}
+		defer conn.Close()
+		fmt.Println("\n[INFO] Report Events streamed over a WebSocket to", username)
+		send := func(id int64, event string, data []byte) error { return conn.WriteText(data) }
+		streamReportEvents(db, settings, cursor, scope, missed, send, conn.Ping, conn.Done())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	if err := live.WriteRetry(c.Writer, settings.Retry); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	fmt.Println("\n[INFO] Report Events streamed to", username)
+	send := func(id int64, event string, data []byte) error {
+		if err := live.WriteEvent(c.Writer, id, event, data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	keepAlive := func() error {
+		// A comment keeps the connection open through proxies.
+		if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	streamReportEvents(db, settings, cursor, scope, missed, send, keepAlive, c.Request.Context().Done())
+}
+
+// Function to prune the events kept past replay_hours and find where a client's stream starts, after last or the
+// newest event if last is 0. missed is true if events after last were pruned, the stream starts at the newest.
+func liveCursor(db *sql.DB, last int64, settings config.Live) (*live.Cursor, bool, error) {
+	_, err := db.Exec("DELETE FROM report_events WHERE created_at < ?", time.Now().UTC().Add(-settings.Replay))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var oldest, newest int64
+	err = db.QueryRow("SELECT COALESCE(MIN(event_id), 0), COALESCE(MAX(event_id), 0) FROM report_events").
+		Scan(&oldest, &newest)
+	if err != nil {
+		return nil, false, err
+	}
+	missed := live.Missed(last, oldest)
+	if last == 0 || missed {
+		last = newest
+	}
+	return live.NewCursor(last, settings.Settle), missed, nil
+}
+
+// Function to send a client the events in its scope as they are recorded, checking every poll_seconds until done
+// is closed or an event cannot be sent. A client that missed events is sent reset first.
+func streamReportEvents(db *sql.DB, settings config.Live, cursor *live.Cursor, scope live.Scope, missed bool,
+	send func(id int64, event string, data []byte) error, keepAlive func() error, done <-chan struct{}) {
+	if missed {
+		if send(0, live.Reset, []byte(`{"type":"`+live.Reset+`"}`)) != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(settings.PollInterval)
+	defer ticker.Stop()
+	lastSent := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		events, err := loadReportEvents(db, cursor.After())
+		if err != nil {
+			log.Println("\nMySQL Error: Error Getting Report Events.\n", err)
+			return
+		}
+		now := time.Now().UTC()
+		for _, event := range events {
+			e := live.Event{Id: event.Id, Type: event.Type, JobReportId: event.JobReportId,
+				WorkerId: event.workerId, FromWorkerId: event.fromWorkerId, CreatedAt: event.CreatedAt}
+			if !cursor.Fresh(e) || !scope.Allows(e) {
+				continue
+			}
+			data, err := json.Marshal(event.ReportEvent)
+			if err == nil {
+				err = send(event.Id, event.Type, data)
+			}
+			if err != nil {
+				return
+			}
+			lastSent = time.Now()
+		}
+		cursor.Settle(now)
+
+		if time.Since(lastSent) >= settings.KeepAlive {
+			if keepAlive() != nil {
+				return
+			}
+			lastSent = time.Now()
+		}
+	}
+}
+
+// reportEvent is an event as it is sent, with the IDs of the workers it is scoped by.
+type reportEvent struct {
+	models.ReportEvent
+	workerId     int
+	fromWorkerId int
+}
+
+// Function to get up to maxLiveEvents events after an event, in order.
+func loadReportEvents(db dbExecutor, after int64) ([]reportEvent, error) {
+	selDB, err := db.Query("SELECT e.event_id, e.event_type, e.job_report_id, e.status, "+
+		"COALESCE(e.worker_id, 0), COALESCE(e.from_worker_id, 0), COALESCE(wkr.username, ''), "+
+		"COALESCE(prev.username, ''), e.created_at FROM report_events e "+
+		"LEFT JOIN workers wkr ON e.worker_id = wkr.worker_id "+
+		"LEFT JOIN workers prev ON e.from_worker_id = prev.worker_id "+
+		"WHERE e.event_id > ? ORDER BY e.event_id LIMIT ?", after, maxLiveEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	var events []reportEvent
+	for selDB.Next() {
+		var e reportEvent
+		err := selDB.Scan(&e.Id, &e.Type, &e.JobReportId, &e.Status, &e.workerId, &e.fromWorkerId, &e.Worker,
+			&e.PreviousWorker, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, selDB.Err()
+}
+
+// Function to record a live event of a report within the transaction that changed it, with who it is assigned to
+// now. fromWorker is who it was assigned to before it moved, 0 if it did not. Nothing is recorded for a report
+// that does not exist.
+func recordLiveEvent(tx dbExecutor, reportId int64, event, status string, fromWorker int) error {
+	_, err := tx.Exec("INSERT INTO report_events (event_type, job_report_id, worker_id, from_worker_id, status, "+
+		"created_at) SELECT ?, job_report_id, worker_id, ?, ?, ? FROM jobreports WHERE job_report_id = ?", event,
+		nullId(fromWorker), status, time.Now().UTC(), reportId)
+	return err
+}
@@ -0,0 +1,137 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Location
+ * Handles the coordinates of vehicle locations - Suggesting Places & Finding Reports near a Place.
+ * Locations are found in the gazetteer (go/gazetteer) offline, reports store the coordinates beside the text.
+ */
+
+package openapi
+
+import (
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The most places suggested at once.
+const maxSuggestions = 50
+
+// GetLocations
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, suggest the towns and townlands starting with ?q=, what has been typed of a location.
+func GetLocations(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Locations")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > maxSuggestions {
+		c.JSON(400, models.Error{Code: 400, Messages: fmt.Sprintf("limit must be from 1 to %d", maxSuggestions)})
+		return
+	}
+
+	places, err := config.Gazetteer()
+	if err != nil {
+		log.Println("Failed to load the gazetteer.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	locations := []models.Location{}
+	for _, p := range places.Suggest(c.Query("q"), limit) {
+		locations = append(locations, models.Location{Label: p.Label(), Name: p.Name, County: p.County,
+			Kind: string(p.Kind), Latitude: p.Latitude, Longitude: p.Longitude})
+	}
+	c.JSON(http.StatusOK, locations)
+}
+
+// Function to set the coordinates of a report's vehicle location from the gazetteer.
+// They are cleared when the location is not a place the gazetteer knows, a report is saved without them
+// if the gazetteer cannot be read.
+func locateReport(report *models.JobReport) {
+	report.Latitude, report.Longitude = nil, nil
+	if strings.TrimSpace(report.VehicleLocation) == "" {
+		return
+	}
+
+	places, err := config.Gazetteer()
+	if err != nil {
+		log.Println("Failed to load the gazetteer.", err)
+		return
+	}
+	if place, ok := places.Locate(report.VehicleLocation); ok {
+		report.Latitude, report.Longitude = &place.Latitude, &place.Longitude
+	}
+}
+
+// Function to build the filter for reports with vehicle locations within km of near, a place in the gazetteer
+// or coordinates like "53.27,-9.05". The default distance is set in config.ini.
+// Returns the clause to add after the WHERE clause of selectReports and its arguments.
+func nearWithin(near, within string) (string, []interface{}, error) {
+	defaultKm, maxKm, err := config.DistanceRules()
+	if err != nil {
+		log.Println("Failed to load config file for geocoding.", err)
+		return "", nil, err
+	}
+
+	km := defaultKm
+	if within != "" {
+		if km, err = strconv.ParseFloat(within, 64); err != nil || km <= 0 || km > maxKm {
+			return "", nil, fmt.Errorf("within must be a distance in km up to %g", maxKm)
+		}
+	}
+
+	lat, lon, ok := parseCoordinates(near)
+	if !ok {
+		places, err := config.Gazetteer()
+		if err != nil {
+			log.Println("Failed to load the gazetteer.", err)
+			return "", nil, err
+		}
+		place, found := places.Locate(near)
+		if !found {
+			return "", nil, fmt.Errorf("near must be a town or townland, %q is not one or could be more than one", near)
+		}
+		lat, lon = place.Latitude, place.Longitude
+	}
+
+	// The box narrows the reports down with the index before the distance to each is worked out.
+	minLat, maxLat, minLon, maxLon := gazetteer.Bounds(lat, lon, km)
+	return " AND jr.location_lat BETWEEN ? AND ? AND jr.location_lon BETWEEN ? AND ?" +
+			" AND 12742 * ASIN(SQRT(POW(SIN(RADIANS(jr.location_lat - ?) / 2), 2) + COS(RADIANS(?)) *" +
+			" COS(RADIANS(jr.location_lat)) * POW(SIN(RADIANS(jr.location_lon - ?) / 2), 2))) <= ?",
+		[]interface{}{minLat, maxLat, minLon, maxLon, lat, lat, lon, km}, nil
+}
+
+// Function to read coordinates written as "latitude,longitude".
+func parseCoordinates(text string) (float64, float64, bool) {
+	parts := strings.Split(text, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if latErr != nil || lonErr != nil || !validCoordinates(lat, lon) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Function to check coordinates are a position on the map.
+func validCoordinates(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
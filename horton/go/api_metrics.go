@@ -0,0 +1,117 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Metrics
+ * Serves the metrics of Horton for Prometheus - Requests by Route, Database Queries, Sessions, Logins & Back4App.
+ * /metrics is served on the API's port to requests with the token in config.ini and on the internal port without.
+ */
+
+package openapi
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetMetrics
+// Works with metricsAuthorized & writeMetrics.
+// If the request has the metrics token, send the metrics of Horton in the Prometheus text exposition format.
+func GetMetrics(c *gin.Context) {
+	settings, err := config.MetricsSettings()
+	if err != nil {
+		log.Println("Failed to load config file for metrics.", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to load metrics settings"})
+		return
+	}
+
+	// Without a token metrics can only be read on the internal port.
+	if settings.Token == "" {
+		c.JSON(404, models.Error{Code: 404, Messages: "Metrics are not served on this port"})
+		return
+	}
+	if !metricsAuthorized(c.GetHeader("Authorization"), settings.Token) {
+		c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+		c.JSON(401, models.Error{Code: 401, Messages: "Metrics token is missing or incorrect"})
+		return
+	}
+
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	writeMetrics(c.Writer)
+}
+
+// ServeMetrics starts serving /metrics without a token on the internal port in config.ini, if one is set, at its
+// internal address, 127.0.0.1 unless another is set. Returns an error if the port cannot be listened on.
+func ServeMetrics() error {
+	settings, err := config.MetricsSettings()
+	if err != nil {
+		return err
+	}
+	if settings.InternalPort == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(settings.InternalAddress, settings.InternalPort))
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		writeMetrics(w)
+	})
+
+	fmt.Println("[INFO] Metrics are served on", listener.Addr())
+	go func() {
+		log.Println("Metrics server stopped", http.Serve(listener, mux))
+	}()
+	return nil
+}
+
+// Function to count the sessions that have not expired, then write every metric.
+func writeMetrics(w io.Writer) {
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	var active int
+	err := db.QueryRow("SELECT COUNT(*) FROM session WHERE created_at + INTERVAL expire_after SECOND > NOW()").
+		Scan(&active)
+	if err != nil {
+		log.Println("\nMySQL Error: Error counting active sessions\n", err)
+	} else {
+		metrics.ActiveSessions.Set(float64(active))
+	}
+
+	if err := metrics.Default.Write(w); err != nil {
+		log.Println("Unable to write metrics", err)
+	}
+}
+
+// Function to check an Authorization header has the metrics token, compared in constant time.
+func metricsAuthorized(header, token string) bool {
+	given := strings.TrimPrefix(header, "Bearer ")
+	return given != header && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// Function to record the requests of a route and how long they took by the route's Name.
+func instrumentRoute(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		handler(c)
+		metrics.RequestDuration.Observe(time.Since(start).Seconds(), name)
+		metrics.Requests.Inc(name, strconv.Itoa(c.Writer.Status()))
+	}
+}
@@ -0,0 +1,832 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Notification
+ * Handles the messages customers are sent about their jobs and bookings - Preferences, Opt-out & the Message Log.
+ * Messages are filled in from their templates and added to notification_messages in the same transaction as the
+ * change they are about, then sent by email or SMS in the background and tried again with backoff until they are
+ * sent or fail for good. Customers are sent messages by the contact their report or estimate is linked to, a booking
+ * only by the contact details given with it.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectPreferences is the Query shared by the functions that get notification preferences, columns are read by
+// getPreferences.
+const selectPreferences = "SELECT contact_id, customer_name, email, phone, channels, opted_out_at, updated_at " +
+	"FROM notification_preferences "
+
+// selectMessages is the Query shared by the functions that get the message log, columns are read by getMessages.
+const selectMessages = "SELECT message_id, event, channel, customer_name, COALESCE(contact_id, 0), recipient, " +
+	"job_report_id, subject, body, " +
+	"status, attempts, next_attempt_at, COALESCE(last_error, ''), COALESCE(provider_id, ''), sent_at, created_at " +
+	"FROM notification_messages "
+
+// The most messages listed at once, and how many are listed if no limit is asked for.
+const (
+	maxMessagesListed     = 1000
+	defaultMessagesListed = 100
+)
+
+// GetNotificationPreferences
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getPreferences.
+// If the user has a cookie and is a supervisor, get every contact's notification preferences.
+func GetNotificationPreferences(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Notification Preferences")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get notification preferences") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	preferences, err := getPreferences(db, "ORDER BY customer_name, contact_id")
+	if err != nil {
+		log.Println("\nFailed to load Notification Preferences.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, preferences)
+}
+
+// CreateNotificationPreferences
+// Works with CheckForCookie, isValidAccount, requireSupervisor & requestedPreferences.
+// If the user has a cookie and is a supervisor, add a contact for how a customer is sent messages. Reports and
+// estimates are linked to it by its contactId. Channels left out are default_channels.
+func CreateNotificationPreferences(c *gin.Context) {
+	preferences, ok := requestedPreferences(c, "create")
+	if !ok {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	now := time.Now().UTC()
+	res, err := db.Exec("INSERT INTO notification_preferences (customer_name, email, phone, channels, opted_out_at, "+
+		"opt_out_token) VALUES (?, ?, ?, ?, IF(?, ?, NULL), ?)", preferences.CustomerName, preferences.Email,
+		preferences.Phone, strings.Join(preferences.Channels, ","), preferences.OptedOut, now, uuid.New().String())
+	var contactId int64
+	if err == nil {
+		contactId, err = res.LastInsertId()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Creating Notification Preferences.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to create Notification Preferences"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Notification Preferences created for contact", contactId, "by", wa.Username)
+	sendPreferences(c, db, 201, contactId)
+}
+
+// UpdateNotificationPreferences
+// Works with CheckForCookie, isValidAccount, requireSupervisor & requestedPreferences.
+// If the user has a cookie and is a supervisor, set how a contact is sent messages. Channels left out are
+// default_channels. Opting a contact out skips their pending messages.
+func UpdateNotificationPreferences(c *gin.Context) {
+	preferences, ok := requestedPreferences(c, "update")
+	if !ok {
+		return
+	}
+	contactId, _ := strconv.ParseInt(c.Params.ByName("contactId"), 10, 64)
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err == nil {
+		var found int64
+		err = tx.QueryRow("SELECT contact_id FROM notification_preferences WHERE contact_id = ? FOR UPDATE",
+			contactId).Scan(&found)
+		if err == nil {
+			now := time.Now().UTC()
+			_, err = tx.Exec("UPDATE notification_preferences SET customer_name = ?, email = ?, phone = ?, "+
+				"channels = ?, opted_out_at = IF(?, COALESCE(opted_out_at, ?), NULL) WHERE contact_id = ?",
+				preferences.CustomerName, preferences.Email, preferences.Phone, strings.Join(preferences.Channels, ","),
+				preferences.OptedOut, now, contactId)
+		}
+		if err == nil && preferences.OptedOut {
+			err = skipPendingMessages(tx, int32(contactId))
+		}
+		err = endTx(tx, err)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Notification Preferences not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Notification Preferences.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to update Notification Preferences"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Notification Preferences updated for contact", contactId, "by", wa.Username)
+	sendPreferences(c, db, http.StatusOK, contactId)
+}
+
+// DeleteNotificationPreferences
+// Works with CheckForCookie, isValidAccount & requireSupervisor.
+// If the user has a cookie and is a supervisor, remove a contact. Their reports and estimates are unlinked and
+// sent no messages, their bookings are sent messages by default_channels to the contact details given with them.
+func DeleteNotificationPreferences(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete Notification Preferences")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "delete notification preferences") {
+		return
+	}
+
+	contactId, _ := strconv.ParseInt(c.Params.ByName("contactId"), 10, 64)
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM notification_preferences WHERE contact_id = ?", contactId)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Deleting Notification Preferences.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Notification Preferences not found"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Notification Preferences deleted for contact", contactId, "by", wa.Username)
+	c.JSON(204, nil)
+}
+
+// GetNotifications
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getMessages.
+// If the user has a cookie and is a supervisor, get the message log newest first.
+// ?customer=, ?contact=, ?status= (pending, sent, failed or skipped), ?event=, ?channel= and ?report= filter it,
+// ?limit= (100) limits it.
+func GetNotifications(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Notifications")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get the message log") {
+		return
+	}
+
+	where := []string{}
+	args := []interface{}{}
+	for _, filter := range []struct{ param, column string }{
+		{"customer", "customer_name"}, {"contact", "contact_id"}, {"status", "status"}, {"event", "event"},
+		{"channel", "channel"}, {"report", "job_report_id"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			where, args = append(where, filter.column+" = ?"), append(args, value)
+		}
+	}
+	if status := c.Query("status"); status != "" && status != notify.Pending && status != notify.Sent &&
+		status != notify.Failed && status != notify.Skipped {
+		c.JSON(400, models.Error{Code: 400, Messages: "status must be pending, sent, failed or skipped"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMessagesListed)))
+	if err != nil || limit < 1 || limit > maxMessagesListed {
+		c.JSON(400, models.Error{Code: 400,
+			Messages: fmt.Sprintf("limit must be a number from 1 to %d", maxMessagesListed)})
+		return
+	}
+
+	query := ""
+	if len(where) > 0 {
+		query = "WHERE " + strings.Join(where, " AND ") + " "
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	messages, err := getMessages(db, query+"ORDER BY message_id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		log.Println("\nFailed to load Notifications.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// GetNotification
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getMessages.
+// If the user has a cookie and is a supervisor, get a message in the message log.
+func GetNotification(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Notification")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get the message log") {
+		return
+	}
+
+	messageId, _ := strconv.ParseInt(c.Params.ByName("messageId"), 10, 64)
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	messages, err := getMessages(db, "WHERE message_id = ?", messageId)
+	if err != nil {
+		log.Println("\nFailed to load Notification.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if len(messages) == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Notification not found"})
+		return
+	}
+	c.JSON(http.StatusOK, messages[0])
+}
+
+// OptOutNotifications
+// Works with limitBooking & skipPendingMessages.
+// Stop messages to the contact whose opt-out link has the token, skipping their pending messages. Opting out
+// again does nothing. No login is needed.
+func OptOutNotifications(c *gin.Context) {
+	if _, ok := limitBooking(c, false); !ok {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	var contactId int32
+	var customerName string
+	tx, err := db.Begin()
+	if err == nil {
+		err = tx.QueryRow("SELECT contact_id, customer_name FROM notification_preferences WHERE opt_out_token = ? "+
+			"FOR UPDATE", c.Params.ByName("token")).Scan(&contactId, &customerName)
+		if err == nil {
+			_, err = tx.Exec("UPDATE notification_preferences SET opted_out_at = COALESCE(opted_out_at, ?) "+
+				"WHERE contact_id = ?", time.Now().UTC(), contactId)
+		}
+		if err == nil {
+			err = skipPendingMessages(tx, contactId)
+		}
+		err = endTx(tx, err)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Opt-out link not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Opting Out of Notifications.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Notifications opted out of by", customerName)
+	c.JSON(http.StatusOK, models.NotificationOptOut{CustomerName: customerName, OptedOut: true})
+}
+
+// StartNotifications starts sending the pending messages to customers in the background, checking every
+// poll_seconds in config.ini. Returns an error if the settings cannot be loaded.
+func StartNotifications() error {
+	settings, err := config.NotificationsSettings()
+	if err != nil {
+		return err
+	}
+	senders := notificationSenders(settings)
+
+	go func() {
+		ticker := time.NewTicker(settings.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			db := config.DbConn()
+			//db := mocks.MockDbConn()
+			if err := sendNotifications(db, settings, senders); err != nil {
+				log.Println("\nMySQL Error: Error Sending Notifications.\n", err)
+			}
+			db.Close()
+		}
+	}()
+	return nil
+}
+
+// Function to get the senders of the channels set up in config.ini, email if smtp_host is set and SMS if sms_url is.
+func notificationSenders(settings config.Notifications) map[string]notify.Sender {
+	senders := map[string]notify.Sender{}
+	if settings.SMTP.Addr != "" {
+		senders[notify.Email] = settings.SMTP
+	}
+	if settings.SMS.URL != "" {
+		gateway := settings.SMS
+		gateway.Client = &http.Client{Timeout: settings.Timeout}
+		senders[notify.SMS] = gateway
+	}
+	return senders
+}
+
+// pendingMessage is a message claimed to be sent, with how many times it has been attempted.
+type pendingMessage struct {
+	notify.Message
+	channel  string
+	attempts int
+}
+
+// Function to send the messages that are due, up to batch_size.
+// An email can take timeout_seconds to connect to the SMTP server and again to be sent, so the batch is claimed with
+// claimOutbox for twice the timeout of each message. Customers are not sent a message twice by two Hortons unless
+// the one sending it stops before recording it.
+func sendNotifications(db *sql.DB, settings config.Notifications, senders map[string]notify.Sender) error {
+	now := time.Now().UTC()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	messages, err := dueMessages(tx, now, settings.BatchSize)
+	if err == nil && len(messages) > 0 {
+		ids := make([]int64, len(messages))
+		for i, m := range messages {
+			ids[i] = m.Id
+		}
+		err = claimOutbox(tx, "notification_messages", "message_id", ids, now, 2*settings.Timeout)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		var providerId string
+		sendErr := fmt.Errorf("%s is not set up", m.channel)
+		if sender, ok := senders[m.channel]; ok {
+			providerId, sendErr = sender.Send(m.Message)
+		}
+		if err := recordMessageAttempt(db, m, providerId, sendErr, settings.Backoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to lock the pending messages that are due at now, oldest first.
+func dueMessages(tx *sql.Tx, now time.Time, limit int) ([]pendingMessage, error) {
+	rows, err := tx.Query("SELECT message_id, channel, recipient, subject, body, attempts FROM notification_messages "+
+		"WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, message_id LIMIT ? FOR UPDATE",
+		notify.Pending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []pendingMessage
+	for rows.Next() {
+		var m pendingMessage
+		if err := rows.Scan(&m.Id, &m.channel, &m.To, &m.Subject, &m.Body, &m.attempts); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// Function to record an attempt to send a message and set when it is next tried, if it is.
+func recordMessageAttempt(db *sql.DB, m pendingMessage, providerId string, sendErr error,
+	backoff webhook.Backoff) error {
+	now := time.Now().UTC()
+	attempts := m.attempts + 1
+	status, outcome := notify.Pending, "retry"
+	var next, sentAt *time.Time
+	lastError := ""
+	if sendErr == nil {
+		status, outcome, sentAt = notify.Sent, "sent", &now
+	} else {
+		lastError = sendErr.Error()
+		if len(lastError) > 255 {
+			lastError = lastError[:255]
+		}
+		if wait, ok := backoff.Next(attempts); ok {
+			at := now.Add(wait)
+			next = &at
+		} else {
+			status, outcome = notify.Failed, "failed"
+		}
+	}
+	metrics.NotificationMessages.Inc(m.channel, outcome)
+
+	// A message skipped while it was being sent keeps its status.
+	_, err := db.Exec("UPDATE notification_messages SET status = IF(status = ?, ?, status), attempts = ?, "+
+		"next_attempt_at = IF(status = ?, ?, NULL), last_error = NULLIF(?, ''), provider_id = NULLIF(?, ''), "+
+		"sent_at = ? WHERE message_id = ?", notify.Pending, status, attempts, notify.Pending, next, lastError,
+		providerId, sentAt, m.Id)
+	return err
+}
+
+// Function to skip a contact's pending messages once they opt out.
+func skipPendingMessages(tx dbExecutor, contactId int32) error {
+	_, err := tx.Exec("UPDATE notification_messages SET status = ?, next_attempt_at = NULL, "+
+		"last_error = 'customer opted out' WHERE contact_id = ? AND status = ?", notify.Skipped, contactId,
+		notify.Pending)
+	return err
+}
+
+// contact is the notification preferences a message is sent with.
+type contact struct {
+	id           int32
+	email, phone string
+	channels     []string
+	optedOut     bool
+	optOutToken  string
+}
+
+// recipient is an address a message may be sent to by a channel, with the contact it belongs to if it has one.
+type recipient struct {
+	channel, address string
+	contact          *contact
+}
+
+// Function to get a contact by the where clause of a Query, nil if there is none.
+func findContact(tx dbExecutor, where string, args ...interface{}) (*contact, error) {
+	var found contact
+	var channels string
+	err := tx.QueryRow("SELECT contact_id, email, phone, channels, opted_out_at IS NOT NULL, opt_out_token "+
+		"FROM notification_preferences "+where, args...).Scan(&found.id, &found.email, &found.phone, &channels,
+		&found.optedOut, &found.optOutToken)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	found.channels = splitChannels(channels)
+	return &found, nil
+}
+
+// Function to check the contact a report or estimate is linked to exists, 0 is none.
+// Sends the error response and returns false if it does not.
+func checkContact(c *gin.Context, db dbExecutor, contactId int32) bool {
+	if err := contactError(db, contactId); err != nil {
+		c.JSON(int(err.Code), err)
+		return false
+	}
+	return true
+}
+
+// Function to get the error of linking to a contact that does not exist, nil if it exists or is 0.
+func contactError(db dbExecutor, contactId int32) *models.Error {
+	if contactId == 0 {
+		return nil
+	}
+	found, err := findContact(db, "WHERE contact_id = ?", contactId)
+	if err != nil {
+		log.Println("\nFailed to load Notification Preferences.", err)
+		return &models.Error{Code: 500, Messages: "Unable to process request"}
+	}
+	if found == nil {
+		return &models.Error{Code: 400, Messages: "Contact not found",
+			Fields: []models.FieldError{{Field: "contactId", Message: "must be the ID of a contact"}}}
+	}
+	return nil
+}
+
+// Function to get the contact a report is linked to, 0 if it is linked to none or does not say.
+func reportContact(report models.JobReport) int32 {
+	if report.ContactId == nil {
+		return 0
+	}
+	return *report.ContactId
+}
+
+// Function to add the messages about a report to the contact it is linked to, within the transaction that
+// changed it. Nothing is sent for a report that does not exist or has no contact.
+func queueReportNotification(tx dbExecutor, reportId int64, event string) error {
+	report, err := outboxReport(tx, reportId)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return queueContactNotification(tx, event, reportId, reportContact(report), notify.Data{
+		CustomerName: report.CustomerName, JobReportId: reportId, VehicleModel: report.VehicleModel,
+		VehicleReg: report.VehicleReg})
+}
+
+// Function to add the messages of an event to a contact, by the channels they chose to the details they gave.
+// Nothing is sent if contactId is 0 or the contact has been deleted.
+func queueContactNotification(tx dbExecutor, event string, reportId int64, contactId int32, data notify.Data) error {
+	if contactId == 0 {
+		return nil
+	}
+	to, err := findContact(tx, "WHERE contact_id = ?", contactId)
+	if to == nil || err != nil {
+		return err
+	}
+	return queueNotification(tx, event, reportId, []recipient{
+		{channel: notify.Email, address: to.email, contact: to}, {channel: notify.SMS, address: to.phone, contact: to},
+	}, data)
+}
+
+// Function to add the messages about a booking to its customer, within the transaction that changed it, sent to
+// the email and phone given with the booking. link is where it is confirmed, reason why it was declined.
+// Anyone can book, so each address is only matched to a contact with that same email or phone, whose choices
+// and opt-out link are used for it. The customer's name is not used to find them.
+func queueBookingNotification(tx dbExecutor, appointmentId int32, event, link, reason string) error {
+	workshop, _, err := config.AppointmentSettings()
+	if err != nil {
+		log.Println("Failed to load config file for appointments.", err)
+		return nil
+	}
+
+	var email, phone string
+	data := notify.Data{Link: link, Reason: reason}
+	err = tx.QueryRow("SELECT customer_name, customer_email, customer_phone, vehicle_model, vehicle_reg, starts_at "+
+		"FROM appointments WHERE appointment_id = ?", appointmentId).Scan(&data.CustomerName, &email, &phone,
+		&data.VehicleModel, &data.VehicleReg, &data.Start)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data.Start = data.Start.In(workshop.Location)
+	if phone != "" {
+		// Phone numbers given with bookings are not checked, one that is not in international format is not texted.
+		phone, _ = notify.NormalisePhone(phone)
+	}
+
+	recipients := []recipient{{channel: notify.Email, address: email}, {channel: notify.SMS, address: phone}}
+	for i, r := range recipients {
+		if r.address == "" {
+			continue
+		}
+		// A contact that opted out is matched first, so an address shared by contacts stays opted out.
+		column := map[string]string{notify.Email: "email", notify.SMS: "phone"}[r.channel]
+		recipients[i].contact, err = findContact(tx, "WHERE "+column+" = ? "+
+			"ORDER BY opted_out_at IS NULL, contact_id LIMIT 1", r.address)
+		if err != nil {
+			return err
+		}
+	}
+	return queueNotification(tx, event, 0, recipients, data)
+}
+
+// Function to add the messages of an event to its recipients, one for each channel that is set up and has a
+// template. A recipient's contact chooses their channels, default_channels is used for an address with none, and
+// their messages carry the opt-out link of that contact only. Messages to a contact that opted out are logged as
+// skipped, except booking.confirm which they asked for and is sent by every channel.
+// A missing config file or broken template is logged rather than stopping the change the message is about.
+func queueNotification(tx dbExecutor, event string, reportId int64, recipients []recipient, data notify.Data) error {
+	settings, err := config.NotificationsSettings()
+	if err != nil {
+		log.Println("Failed to load config file for notifications.", err)
+		return nil
+	}
+	templates, err := notify.LoadTemplates(settings.Templates)
+	if err != nil {
+		log.Println("Failed to load notification templates.", err)
+		return nil
+	}
+	if garage, err := config.Garage(); err == nil {
+		data.GarageName, data.GaragePhone = garage.Name, garage.Phone
+	}
+
+	var jobReportId interface{}
+	if reportId != 0 {
+		jobReportId = reportId
+	}
+	senders := notificationSenders(settings)
+	now := time.Now().UTC()
+	for _, to := range recipients {
+		channels, optedOut := settings.DefaultChannels, false
+		var contactId interface{}
+		data.OptOutLink = ""
+		if to.contact != nil {
+			channels, optedOut, contactId = to.contact.channels, to.contact.optedOut, to.contact.id
+			data.OptOutLink = settings.PublicUrl + "/api/v1/public/notifications/" + to.contact.optOutToken + "/optOut"
+		}
+		if event == notify.BookingConfirm {
+			channels, optedOut = notify.Channels, false
+		}
+		if _, ok := senders[to.channel]; !ok || to.address == "" || !hasChannel(channels, to.channel) {
+			continue
+		}
+		subject, body, ok, err := templates.Render(event, to.channel, data)
+		if err != nil {
+			log.Println("Failed to fill in the", event, to.channel, "template.", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		status, next, lastError := notify.Pending, &now, ""
+		if optedOut {
+			status, next, lastError = notify.Skipped, nil, "customer opted out"
+		}
+		if _, err := tx.Exec("INSERT INTO notification_messages (event, channel, customer_name, contact_id, "+
+			"recipient, job_report_id, subject, body, status, next_attempt_at, last_error, created_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)", event, to.channel, data.CustomerName,
+			contactId, to.address, jobReportId, subject, body, status, next, lastError, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to check whether a channel is one of channels.
+func hasChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to get the preferences in a request to create or update them, once they are valid and the user is a
+// supervisor. Sends the error response and returns false if not.
+func requestedPreferences(c *gin.Context, action string) (models.NotificationPreferences, bool) {
+	var preferences models.NotificationPreferences
+
+	if err := c.ShouldBindJSON(&preferences); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return preferences, false
+	}
+	preferences.CustomerName = strings.TrimSpace(preferences.CustomerName)
+
+	settings, err := config.NotificationsSettings()
+	if err != nil {
+		log.Println("Failed to load config file for notifications.", err)
+		c.JSON(500, nil)
+		return preferences, false
+	}
+	if preferences.Channels == nil {
+		preferences.Channels = settings.DefaultChannels
+	}
+
+	if fields := validatePreferences(&preferences); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Notification Preferences are invalid", Fields: fields})
+		return preferences, false
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to " + action + " Notification Preferences")
+		return preferences, false
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return preferences, false
+	}
+
+	return preferences, requireSupervisor(c, action+" notification preferences")
+}
+
+// Function to send a contact's preferences as they are saved.
+func sendPreferences(c *gin.Context, db dbExecutor, status int, contactId int64) {
+	saved, err := getPreferences(db, "WHERE contact_id = ?", contactId)
+	if err != nil || len(saved) == 0 {
+		log.Println("\nFailed to load Notification Preferences.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(status, saved[0])
+}
+
+// Function to validate a customer's preferences, putting their phone number in international format.
+func validatePreferences(preferences *models.NotificationPreferences) []models.FieldError {
+	var fields []models.FieldError
+	if preferences.CustomerName == "" || len(preferences.CustomerName) > 100 {
+		fields = append(fields, models.FieldError{Field: "customerName", Message: "must be 1 to 100 characters"})
+	}
+	if preferences.Email != "" {
+		if err := notify.CheckEmail(preferences.Email); err != nil || len(preferences.Email) > 100 {
+			fields = append(fields, models.FieldError{Field: "email",
+				Message: "must be an email address of up to 100 characters"})
+		}
+	}
+	if preferences.Phone != "" {
+		phone, err := notify.NormalisePhone(preferences.Phone)
+		if err != nil {
+			fields = append(fields, models.FieldError{Field: "phone", Message: err.Error()})
+		}
+		preferences.Phone = phone
+	}
+	if err := notify.CheckChannels(preferences.Channels); err != nil {
+		fields = append(fields, models.FieldError{Field: "channels", Message: err.Error()})
+	}
+	for _, channel := range preferences.Channels {
+		if channel == notify.Email && preferences.Email == "" {
+			fields = append(fields, models.FieldError{Field: "email", Message: "is needed to be sent email"})
+		}
+		if channel == notify.SMS && preferences.Phone == "" {
+			fields = append(fields, models.FieldError{Field: "phone", Message: "is needed to be sent text messages"})
+		}
+	}
+	return fields
+}
+
+// Function to get the notification preferences of a selectPreferences Query.
+func getPreferences(db dbExecutor, where string, args ...interface{}) ([]models.NotificationPreferences, error) {
+	selDB, err := db.Query(selectPreferences+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	preferences := []models.NotificationPreferences{}
+	for selDB.Next() {
+		var p models.NotificationPreferences
+		var channels string
+		if err := selDB.Scan(&p.ContactId, &p.CustomerName, &p.Email, &p.Phone, &channels, &p.OptedOutAt,
+			&p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Channels = splitChannels(channels)
+		p.OptedOut = p.OptedOutAt != nil
+		preferences = append(preferences, p)
+	}
+	return preferences, selDB.Err()
+}
+
+// Function to get the messages of a selectMessages Query.
+func getMessages(db dbExecutor, where string, args ...interface{}) ([]models.NotificationMessage, error) {
+	selDB, err := db.Query(selectMessages+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	messages := []models.NotificationMessage{}
+	for selDB.Next() {
+		var m models.NotificationMessage
+		if err := selDB.Scan(&m.MessageId, &m.Event, &m.Channel, &m.CustomerName, &m.ContactId, &m.Recipient,
+			&m.JobReportId, &m.Subject, &m.Body, &m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.ProviderId,
+			&m.SentAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, selDB.Err()
+}
+
+// Function to split the channels of a SET column, none if it is empty.
+func splitChannels(channels string) []string {
+	if channels == "" {
+		return []string{}
+	}
+	return strings.Split(channels, ",")
+}
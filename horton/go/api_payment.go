@@ -0,0 +1,630 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Payment
+ * Handles payments against invoices - Record, Refund, Get Payments, Balances & Aged Debtors.
+ * Card payments are taken by the payment provider in config.ini, cash and bank transfers are only recorded.
+ * Payments and refunds are written to the payment_ledger table and never changed, an invoice is paid
+ * once its payments cover its total less its credit notes, and back to issued if a refund leaves it owing.
+ */
+
+package openapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/export"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/payments"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// selectBalances is the JOIN Query shared by the functions that get balances, each adds its own WHERE clause.
+// Columns are read in the order of scanBalance.
+const selectBalances = "SELECT inv.invoice_id, COALESCE(inv.invoice_number, ''), inv.status, inv.bill_to_name, " +
+	"inv.currency, inv.total_cents, (SELECT COALESCE(SUM(cn.total_cents), 0) FROM invoices cn " +
+	"WHERE cn.credited_invoice_id = inv.invoice_id AND cn.kind = 'credit_note' AND cn.status != 'void'), " +
+	"(SELECT COALESCE(SUM(pl.amount_cents), 0) FROM payment_ledger pl WHERE pl.invoice_id = inv.invoice_id), " +
+	"inv.due_date FROM invoices inv INNER JOIN jobreports jr ON inv.job_report_id = jr.job_report_id " +
+	"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id WHERE inv.kind = 'invoice' "
+
+// selectPayments is the JOIN Query shared by the functions that get payments, each adds its own WHERE clause.
+// Columns are read in the order of scanPayment.
+const selectPayments = "SELECT " + paymentColumns + paymentTables
+
+const paymentColumns = "pl.payment_id, pl.invoice_id, pl.entry_type, pl.method, pl.amount_cents, pl.currency, " +
+	"pl.provider, pl.provider_ref, COALESCE(pl.refunded_payment_id, 0), pl.reference, pl.note, pl.created_at"
+
+const paymentTables = " FROM payment_ledger pl INNER JOIN invoices inv ON pl.invoice_id = inv.invoice_id " +
+	"INNER JOIN jobreports jr ON inv.job_report_id = jr.job_report_id " +
+	"INNER JOIN workers wkr ON jr.worker_id = wkr.worker_id "
+
+// Columns of the aged debtors report when it is exported.
+var agedDebtorColumns = []export.Column{{Name: "billToName"}, {Name: "invoices", Numeric: true},
+	{Name: "current", Numeric: true}, {Name: "days1To30", Numeric: true}, {Name: "days31To60", Numeric: true},
+	{Name: "days61To90", Numeric: true}, {Name: "over90Days", Numeric: true}, {Name: "total", Numeric: true}}
+
+// RecordPayment
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, take a card payment or record a cash or
+// bank transfer payment against an issued invoice. Part payments are allowed, paying more than is owed is not.
+func RecordPayment(c *gin.Context) {
+	var request models.PaymentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to pay this Invoice")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if fields := validatePayment(request); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Payment is invalid", Fields: fields})
+		return
+	}
+
+	provider, err := config.PaymentProvider()
+	if err != nil {
+		log.Println("Failed to set up payment provider.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	if invoice.Kind != kindInvoice {
+		c.JSON(409, models.Error{Code: 409, Messages: "Payments can only be taken against invoices"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Recording Payment.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	payment := models.Payment{InvoiceId: invoice.InvoiceId, Type: payments.Payment, Method: request.Method,
+		AmountCents: request.AmountCents, Currency: invoice.Currency, Reference: request.Reference, Note: request.Note}
+	status, err := insertPayment(c, tx, &payment, invoice, request.CardToken, provider)
+	if err = endTx(tx, err); err != nil {
+		// A card payment taken but not recorded is given back.
+		if payment.ProviderRef != "" {
+			if _, refundErr := provider.Refund(context.Background(), payment.ProviderRef, payment.AmountCents); refundErr != nil {
+				log.Println("\nFailed to refund unrecorded payment", payment.ProviderRef, refundErr)
+			}
+		}
+		if status != 0 {
+			c.JSON(status, models.Error{Code: int32(status), Messages: err.Error()})
+			return
+		}
+		log.Println("\nMySQL Error: Error Recording Payment.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to record Payment"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Payment recorded:", payment.PaymentId, "of", payment.AmountCents, "against Invoice",
+		invoice.InvoiceNumber)
+	c.JSON(201, payment)
+}
+
+// RefundPayment
+// Works with CheckForCookie, isValidAccount & findPayment.
+// If the user has a cookie and owns the payment's invoice, refund all or part of a payment.
+// Card payments are refunded through the provider that took them.
+func RefundPayment(c *gin.Context) {
+	var request models.RefundRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to refund this Payment")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if request.AmountCents < 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Refund is invalid", Fields: []models.FieldError{
+			{Field: "amountCents", Message: "amountCents cannot be negative"}}})
+		return
+	}
+
+	provider, err := config.PaymentProvider()
+	if err != nil {
+		log.Println("Failed to set up payment provider.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	paymentId, err := strconv.Atoi(c.Params.ByName("paymentId"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Payment not found"})
+		return
+	}
+	payment, err := findPayment(db, paymentId, wa.Username)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Payment not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Payment.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if payment.Type != payments.Payment {
+		c.JSON(409, models.Error{Code: 409, Messages: "Only payments can be refunded"})
+		return
+	}
+	if payment.Method == payments.Card && payment.Provider != provider.Name() {
+		c.JSON(409, models.Error{Code: 409,
+			Messages: fmt.Sprintf("Payment was taken by %s and must be refunded there", payment.Provider)})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Refunding Payment.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	refund := models.Payment{InvoiceId: payment.InvoiceId, Type: payments.Refund, Method: payment.Method,
+		AmountCents: -request.AmountCents, Currency: payment.Currency, RefundedPaymentId: payment.PaymentId,
+		Note: request.Reason}
+	status, err := insertRefund(c, tx, &refund, payment, provider)
+	if err = endTx(tx, err); err != nil {
+		if refund.ProviderRef != "" {
+			// The card has been refunded but the ledger does not show it, it must be put right by hand.
+			log.Println("\n[ERROR] Refund", refund.ProviderRef, "of Payment", payment.PaymentId, "was not recorded.", err)
+		}
+		if status != 0 {
+			c.JSON(status, models.Error{Code: int32(status), Messages: err.Error()})
+			return
+		}
+		log.Println("\nMySQL Error: Error Refunding Payment.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to refund Payment"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Payment", payment.PaymentId, "refunded:", -refund.AmountCents)
+	c.JSON(201, refund)
+}
+
+// GetPayments
+// Works with CheckForCookie, isValidAccount & findInvoice.
+// If the user has a cookie and owns the invoice's report, get the invoice's balance with its payments and refunds.
+func GetPayments(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Payments")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	invoice, ok := ownedInvoice(c, db)
+	if !ok {
+		return
+	}
+	if invoice.Kind != kindInvoice {
+		c.JSON(404, models.Error{Code: 404, Messages: "Invoice not found"})
+		return
+	}
+
+	balance, err := invoiceBalance(db, invoice.InvoiceId)
+	if err != nil {
+		log.Println("\nFailed to load Invoice balance.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	selDB, err := db.Query(selectPayments+"WHERE pl.invoice_id = ? ORDER BY pl.payment_id", invoice.InvoiceId)
+	if err != nil {
+		log.Println("\nFailed to process Payments.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	balance.Payments = []models.Payment{}
+	for selDB.Next() {
+		payment, err := scanPayment(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Payments.", err)
+			c.JSON(500, nil)
+			return
+		}
+		balance.Payments = append(balance.Payments, payment)
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+// GetBalances
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get the balances of their issued invoices that are not settled, oldest first.
+// ?billToName= limits them to one customer, ?overdue=true to those past their due date.
+func GetBalances(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get these Balances")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	filter := ""
+	args := []interface{}{wa.Username}
+	if name := c.Query("billToName"); name != "" {
+		filter += " AND inv.bill_to_name = ?"
+		args = append(args, name)
+	}
+	overdue := c.Query("overdue") == "true"
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectBalances+"AND inv.status IN ('issued', 'paid') AND wkr.username = ?"+filter+
+		" ORDER BY inv.due_date, inv.invoice_id", args...)
+	if err != nil {
+		log.Println("\nFailed to process Balances.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	today := models.NewDate(time.Now())
+	balances := []models.InvoiceBalance{}
+	for selDB.Next() {
+		balance, err := scanBalance(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Balances.", err)
+			c.JSON(500, nil)
+			return
+		}
+		if balance.OutstandingCents == 0 {
+			continue
+		}
+		if overdue && (balance.OutstandingCents < 0 || balance.DueDate == nil || !balance.DueDate.Before(today.Time)) {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+	c.JSON(http.StatusOK, balances)
+}
+
+// GetAgedDebtors
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, get what each customer owes on their invoices split by how long it is overdue,
+// as of today or ?asOf=. Sent as JSON, or as a spreadsheet with ?format=csv|xlsx.
+func GetAgedDebtors(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Aged Debtors")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	asOf := models.NewDate(time.Now())
+	if value := c.Query("asOf"); value != "" {
+		date, err := models.ParseDate(value)
+		if err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: "asOf: " + err.Error()})
+			return
+		}
+		asOf = date
+	}
+
+	format := c.Query("format")
+	contentType, ok := export.ContentTypes[format]
+	if format != "" && !ok {
+		c.JSON(400, models.Error{Code: 400, Messages: "format must be csv or xlsx"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectBalances+"AND inv.status = 'issued' AND wkr.username = ? "+
+		"AND DATE(inv.issued_at) <= ? ORDER BY inv.bill_to_name", wa.Username, asOf)
+	if err != nil {
+		log.Println("\nFailed to process Aged Debtors.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	var debts []payments.Debt
+	for selDB.Next() {
+		balance, err := scanBalance(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Aged Debtors.", err)
+			c.JSON(500, nil)
+			return
+		}
+		debt := payments.Debt{BillToName: balance.BillToName, OutstandingCents: balance.OutstandingCents}
+		if balance.DueDate != nil {
+			debt.DueDate = balance.DueDate.Time
+		}
+		debts = append(debts, debt)
+	}
+	if err := selDB.Err(); err != nil {
+		log.Println("\nFailed to load Aged Debtors.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	report := models.AgedDebtors{AsOf: asOf}
+	report.Debtors, report.Totals = payments.AgeDebts(debts, asOf.Time)
+	if format == "" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="aged-debtors-%s.%s"`, asOf, format))
+	c.Status(http.StatusOK)
+	w, err := export.NewWriter(format, c.Writer, agedDebtorColumns)
+	if err != nil {
+		log.Println("\nFailed to start Aged Debtors export.", err)
+		return
+	}
+	for _, debtor := range append(report.Debtors, report.Totals) {
+		err = w.WriteRow([]string{debtor.BillToName, strconv.Itoa(debtor.Invoices), euro(debtor.CurrentCents),
+			euro(debtor.Days1To30Cents), euro(debtor.Days31To60Cents), euro(debtor.Days61To90Cents),
+			euro(debtor.Over90DaysCents), euro(debtor.TotalCents)})
+		if err != nil {
+			log.Println("\nFailed to export Aged Debtors.", err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Println("\nFailed to finish Aged Debtors export.", err)
+	}
+}
+
+// Function to check a payment before it is taken.
+// Card payments need the card's token and bank transfers their reference.
+func validatePayment(request models.PaymentRequest) []models.FieldError {
+	var fields []models.FieldError
+	if !payments.IsMethod(request.Method) {
+		fields = append(fields, models.FieldError{Field: "method", Message: "method must be card, cash or bank_transfer"})
+	}
+	if request.AmountCents <= 0 {
+		fields = append(fields, models.FieldError{Field: "amountCents", Message: "amountCents must be more than 0"})
+	}
+	if request.Method == payments.Card && request.CardToken == "" {
+		fields = append(fields, models.FieldError{Field: "cardToken", Message: "cardToken is required for card payments"})
+	}
+	if request.Method == payments.BankTransfer && request.Reference == "" {
+		fields = append(fields, models.FieldError{Field: "reference",
+			Message: "reference is required for bank transfers"})
+	}
+	return fields
+}
+
+// Function to take a payment and write it to the ledger in tx, locking the invoice so two payments cannot cross.
+// The invoice is marked paid once nothing is owed.
+// Returns the status code to send with the error if the payment is not allowed.
+func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, invoice models.Invoice, cardToken string,
+	provider payments.Provider) (int, error) {
+	var status string
+	if err := tx.QueryRow("SELECT status FROM invoices WHERE invoice_id = ? FOR UPDATE",
+		invoice.InvoiceId).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status != billing.Issued {
+		return 409, fmt.Errorf("only issued invoices can be paid, this invoice is %s", status)
+	}
+
+	balance, err := invoiceBalance(tx, invoice.InvoiceId)
+	if err != nil {
+		return 0, err
+	}
+	if payment.AmountCents > balance.OutstandingCents {
+		return 400, fmt.Errorf("payment is more than is owed, %d cents are outstanding", balance.OutstandingCents)
+	}
+
+	if payment.Method == payments.Card {
+		payment.ProviderRef, err = provider.Charge(ctx, payment.AmountCents, payment.Currency, cardToken,
+			"Invoice "+invoice.InvoiceNumber)
+		if err == payments.ErrDeclined {
+			return http.StatusPaymentRequired, err
+		}
+		if err != nil {
+			log.Println("\nPayment provider failed to take Payment.", err)
+			return http.StatusBadGateway, errors.New("the payment provider could not take the payment")
+		}
+		payment.Provider = provider.Name()
+	}
+
+	if err := insertLedgerEntry(tx, payment); err != nil {
+		return 0, err
+	}
+	if payment.AmountCents == balance.OutstandingCents {
+		if _, err := tx.Exec("UPDATE invoices SET status = ? WHERE invoice_id = ?", billing.Paid,
+			invoice.InvoiceId); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// Function to refund a payment and write the refund to the ledger in tx, locking the invoice so two refunds
+// cannot cross. A refund of 0 gives back all that is left of the payment. A paid invoice left owing is issued again.
+// Returns the status code to send with the error if the refund is not allowed.
+func insertRefund(ctx context.Context, tx *sql.Tx, refund *models.Payment, payment models.Payment,
+	provider payments.Provider) (int, error) {
+	var status string
+	if err := tx.QueryRow("SELECT status FROM invoices WHERE invoice_id = ? FOR UPDATE",
+		payment.InvoiceId).Scan(&status); err != nil {
+		return 0, err
+	}
+
+	var refunded int64
+	if err := tx.QueryRow("SELECT COALESCE(-SUM(amount_cents), 0) FROM payment_ledger WHERE refunded_payment_id = ?",
+		payment.PaymentId).Scan(&refunded); err != nil {
+		return 0, err
+	}
+	left := payment.AmountCents - refunded
+	if left <= 0 {
+		return 409, errors.New("payment has already been refunded")
+	}
+	if refund.AmountCents == 0 {
+		refund.AmountCents = -left
+	}
+	if -refund.AmountCents > left {
+		return 400, fmt.Errorf("refund is more than is left of the payment, %d cents can be refunded", left)
+	}
+
+	if payment.Method == payments.Card {
+		reference, err := provider.Refund(ctx, payment.ProviderRef, -refund.AmountCents)
+		if err != nil {
+			log.Println("\nPayment provider failed to refund Payment.", err)
+			return http.StatusBadGateway, errors.New("the payment provider could not refund the payment")
+		}
+		refund.Provider, refund.ProviderRef = provider.Name(), reference
+	}
+
+	if err := insertLedgerEntry(tx, refund); err != nil {
+		return 0, err
+	}
+	if status == billing.Paid {
+		if _, err := tx.Exec("UPDATE invoices SET status = ? WHERE invoice_id = ?", billing.Issued,
+			payment.InvoiceId); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// Function to append a payment or refund to the ledger.
+func insertLedgerEntry(tx *sql.Tx, entry *models.Payment) error {
+	var refunded interface{}
+	if entry.RefundedPaymentId != 0 {
+		refunded = entry.RefundedPaymentId
+	}
+	res, err := tx.Exec("INSERT INTO payment_ledger (invoice_id, worker_id, entry_type, method, amount_cents, currency, "+
+		"provider, provider_ref, refunded_payment_id, reference, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		entry.InvoiceId, wa.Id, entry.Type, entry.Method, entry.AmountCents, entry.Currency, entry.Provider,
+		entry.ProviderRef, refunded, entry.Reference, entry.Note)
+	if err != nil {
+		return err
+	}
+	id, _ := res.LastInsertId()
+	entry.PaymentId = int32(id)
+	entry.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Function to get the balance of an invoice, without its payments.
+func invoiceBalance(db dbExecutor, invoiceId int32) (models.InvoiceBalance, error) {
+	selDB, err := db.Query(selectBalances+"AND inv.invoice_id = ?", invoiceId)
+	if err != nil {
+		return models.InvoiceBalance{}, err
+	}
+	defer selDB.Close()
+
+	if !selDB.Next() {
+		if err := selDB.Err(); err != nil {
+			return models.InvoiceBalance{}, err
+		}
+		return models.InvoiceBalance{}, sql.ErrNoRows
+	}
+	return scanBalance(selDB)
+}
+
+// Function to read a record from the selectBalances Query into an InvoiceBalance object.
+func scanBalance(rows *sql.Rows) (models.InvoiceBalance, error) {
+	var balance models.InvoiceBalance
+	var dueDate models.Date
+
+	err := rows.Scan(&balance.InvoiceId, &balance.InvoiceNumber, &balance.Status, &balance.BillToName,
+		&balance.Currency, &balance.TotalCents, &balance.CreditedCents, &balance.PaidCents, &dueDate)
+	balance.OutstandingCents = payments.Outstanding(balance.TotalCents, balance.CreditedCents, balance.PaidCents)
+	if !dueDate.IsZero() {
+		balance.DueDate = &dueDate
+	}
+	return balance, err
+}
+
+// Function to read a record from the selectPayments Query into a Payment object.
+// Columns selected after those of selectPayments are read into extra.
+func scanPayment(rows *sql.Rows, extra ...interface{}) (models.Payment, error) {
+	var payment models.Payment
+	err := rows.Scan(append([]interface{}{&payment.PaymentId, &payment.InvoiceId, &payment.Type, &payment.Method,
+		&payment.AmountCents, &payment.Currency, &payment.Provider, &payment.ProviderRef, &payment.RefundedPaymentId,
+		&payment.Reference, &payment.Note, &payment.CreatedAt}, extra...)...)
+	return payment, err
+}
+
+// Function to get a payment or refund against an invoice of a report belonging to a user.
+// Returns sql.ErrNoRows if the user has no such payment.
+func findPayment(db *sql.DB, paymentId int, username string) (models.Payment, error) {
+	selDB, err := db.Query(selectPayments+"WHERE pl.payment_id = ? AND wkr.username = ?", paymentId, username)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	defer selDB.Close()
+
+	if !selDB.Next() {
+		if err := selDB.Err(); err != nil {
+			return models.Payment{}, err
+		}
+		return models.Payment{}, sql.ErrNoRows
+	}
+	return scanPayment(selDB)
+}
+
+// Function to format cents as an amount e.g. 1234 as "12.34", for spreadsheets.
+func euro(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
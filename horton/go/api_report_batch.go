@@ -0,0 +1,292 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Report Batch
+ * Handles creating, updating and deleting many Job Reports in one request - POST /jobReports:batch.
+ * Used by devices syncing reports made while offline, every operation shares one database connection.
+ * "atomic" batches apply all the operations in one transaction or none of them,
+ * "bestEffort" batches apply each operation in its own transaction and carry on past failures.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Most operations allowed in a batch.
+const maxBatchOperations = 500
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "bestEffort"
+)
+
+// BatchReports
+// Works with CheckForCookie, isValidAccount, validateJobReport, contactError & ownsReport.
+// If the user has a cookie, apply the creates, updates and deletes of the batch to their reports.
+// Each operation is validated as CreateReport/UpdateReport would before any are applied,
+// the result of each is returned in the order they were sent with the IDs of new reports.
+func BatchReports(c *gin.Context) {
+	var batch models.BatchRequest
+
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to batch Reports")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if batch.Mode == "" {
+		batch.Mode = batchAtomic
+	}
+	if batch.Mode != batchAtomic && batch.Mode != batchBestEffort {
+		c.JSON(400, models.Error{Code: 400, Messages: "mode must be atomic or bestEffort"})
+		return
+	}
+	if len(batch.Operations) == 0 || len(batch.Operations) > maxBatchOperations {
+		c.JSON(400, models.Error{Code: 400,
+			Messages: fmt.Sprintf("a batch must have between 1 and %d operations", maxBatchOperations)})
+		return
+	}
+
+	result := models.BatchResult{Mode: batch.Mode, Results: make([]models.BatchItemResult, len(batch.Operations))}
+	invalid := 0
+	for i, op := range batch.Operations {
+		result.Results[i] = models.BatchItemResult{Index: i, Op: op.Op, ClientId: op.ClientId,
+			JobReportId: op.JobReportId}
+		if err := validateBatchOperation(&batch.Operations[i]); err != nil {
+			result.Results[i].Status, result.Results[i].Error = 400, err
+			invalid++
+		}
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	// The contacts of valid operations are checked before any are applied, one that does not exist is a 400.
+	for i, op := range batch.Operations {
+		if result.Results[i].Status == 0 && op.Op != "delete" {
+			if err := contactError(db, reportContact(*op.Report)); err != nil {
+				result.Results[i].Status, result.Results[i].Error = int(err.Code), err
+				invalid++
+			}
+		}
+	}
+
+	var removedFiles []string
+	if batch.Mode == batchAtomic {
+		if invalid == 0 {
+			removedFiles = applyAtomicBatch(db, batch.Operations, result.Results)
+		}
+	} else {
+		removedFiles = applyBestEffortBatch(db, batch.Operations, result.Results)
+	}
+
+	// Operations left without a status were valid but not applied as another operation of the atomic batch failed.
+	for i := range result.Results {
+		item := &result.Results[i]
+		if item.Status == 0 {
+			item.Status = http.StatusFailedDependency
+			item.Error = &models.Error{Code: http.StatusFailedDependency,
+				Messages: "Not applied, another operation in the batch failed"}
+		}
+		if item.Status < 300 {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Applied = result.Succeeded > 0 || result.Failed == 0
+
+	// Remove the attached files of deleted reports only once the deletes are committed.
+	removeStoredFiles(removedFiles)
+
+	fmt.Println("\n[INFO] Batch Processed:", batch.Mode, "Succeeded:", result.Succeeded, "Failed:", result.Failed)
+	if batch.Mode == batchAtomic && !result.Applied {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Function to check an operation of a batch before it is applied.
+// The report of a create or update is validated with validateJobReport, which normalises it.
+func validateBatchOperation(op *models.BatchOperation) *models.Error {
+	switch op.Op {
+	case "create", "update":
+		if op.Op == "update" && op.JobReportId <= 0 {
+			return &models.Error{Code: 400, Messages: "jobReportId is required"}
+		}
+		if op.Report == nil {
+			return &models.Error{Code: 400, Messages: "report is required"}
+		}
+		if fields := validateJobReport(op.Report); len(fields) > 0 {
+			return &models.Error{Code: 400, Messages: "Report is invalid", Fields: fields}
+		}
+	case "delete":
+		if op.JobReportId <= 0 {
+			return &models.Error{Code: 400, Messages: "jobReportId is required"}
+		}
+	default:
+		return &models.Error{Code: 400, Messages: "op must be create, update or delete"}
+	}
+	return nil
+}
+
+// Function to apply every operation in one transaction, stopping at the first that fails.
+// The failed operation gets its status, the others are left without one as nothing was applied.
+// Returns the storage keys of the attached files of deleted reports.
+func applyAtomicBatch(db *sql.DB, ops []models.BatchOperation, results []models.BatchItemResult) []string {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("\nMySQL Error: Error Starting Batch.\n", err)
+		results[0].Status, results[0].Error = 500, &models.Error{Code: 500, Messages: "Unable to process request"}
+		return nil
+	}
+
+	var keys []string
+	applied := make([]models.BatchItemResult, len(ops))
+	for i, op := range ops {
+		applied[i] = results[i]
+		opKeys, ok := applyBatchOperation(db, tx, op, &applied[i])
+		if !ok {
+			tx.Rollback()
+			results[i] = applied[i]
+			return nil
+		}
+		keys = append(keys, opKeys...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("\nMySQL Error: Error Committing Batch.\n", err)
+		results[0].Status, results[0].Error = 500, &models.Error{Code: 500, Messages: "Unable to process request"}
+		return nil
+	}
+	copy(results, applied)
+	return keys
+}
+
+// Function to apply each valid operation in its own transaction.
+// Returns the storage keys of the attached files of deleted reports.
+func applyBestEffortBatch(db *sql.DB, ops []models.BatchOperation, results []models.BatchItemResult) []string {
+	var keys []string
+	for i, op := range ops {
+		if results[i].Status != 0 {
+			continue // Invalid.
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Println("\nMySQL Error: Error Starting Batch Operation.\n", err)
+			results[i].Status, results[i].Error = 500, &models.Error{Code: 500, Messages: "Unable to process request"}
+			continue
+		}
+		opKeys, ok := applyBatchOperation(db, tx, op, &results[i])
+		if !ok {
+			tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			log.Println("\nMySQL Error: Error Committing Batch Operation.\n", err)
+			results[i].Status, results[i].Error = 500, &models.Error{Code: 500, Messages: "Unable to process request"}
+			results[i].JobReportId = op.JobReportId
+			continue
+		}
+		keys = append(keys, opKeys...)
+	}
+	return keys
+}
+
+// Function to apply an operation of a batch in tx, setting its status in result.
+// Reports can only be updated or deleted by the worker who owns them.
+// Returns the storage keys of the attached files of a deleted report, and false if the operation failed.
+func applyBatchOperation(db *sql.DB, tx *sql.Tx, op models.BatchOperation, result *models.BatchItemResult) ([]string, bool) {
+	fail := func(status int, message string, err error) ([]string, bool) {
+		if err != nil {
+			log.Println("\nMySQL Error: Error Applying Batch Operation.\n", err)
+		}
+		result.Status, result.Error = status, &models.Error{Code: int32(status), Messages: message}
+		return nil, false
+	}
+
+	if op.Op == "create" {
+		reportId, err := insertReportTx(tx, wa.Id, *op.Report)
+		if err == nil {
+			err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+		}
+		if err == nil {
+			err = queueReportNotification(tx, reportId, notify.JobCreated)
+		}
+		if err != nil {
+			return fail(500, "Not able to create Report", err)
+		}
+		result.Status, result.JobReportId = http.StatusCreated, int32(reportId)
+		return nil, true
+	}
+
+	owns, err := ownsReport(tx, int(op.JobReportId), wa.Username)
+	if err != nil {
+		return fail(500, "Unable to process request", err)
+	}
+	if !owns {
+		return fail(404, "Report not found", nil)
+	}
+	reportId := strconv.Itoa(int(op.JobReportId))
+
+	if op.Op == "update" {
+		wasComplete, err := reportComplete(tx, int64(op.JobReportId))
+		if err == nil {
+			_, err = updateReport(tx, reportId, *op.Report)
+		}
+		if err == nil {
+			err = recordReportEvents(tx, int64(op.JobReportId), webhook.ReportUpdated, wasComplete)
+		}
+		if err != nil {
+			return fail(500, "Error Updating Report", err)
+		}
+		result.Status = http.StatusOK
+		return nil, true
+	}
+
+	invoiced, err := hasInvoices(tx, reportId)
+	if err != nil {
+		return fail(500, "Unable to process request", err)
+	}
+	if invoiced {
+		return fail(409, "Report has been invoiced and cannot be deleted", nil)
+	}
+
+	// Get the report's attachments before they are deleted with it.
+	keys, err := attachmentKeys(db, reportId)
+	if err != nil {
+		return fail(500, "Unable to process request", err)
+	}
+	if err := recordReportDeleted(tx, int64(op.JobReportId)); err != nil {
+		return fail(500, "Report failed to delete", err)
+	}
+	if _, err := tx.Exec("DELETE FROM jobreports WHERE job_report_id = ?", op.JobReportId); err != nil {
+		return fail(500, "Report failed to delete", err)
+	}
+	result.Status = http.StatusNoContent
+	return keys, true
+}
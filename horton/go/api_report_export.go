@@ -0,0 +1,204 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Report Export
+ * Handles exporting Job Reports to a spreadsheet - GET /jobReports/export?format=csv|xlsx.
+ * Takes the same filters as GetReports, the columns can be chosen with ?columns= or set in config.ini.
+ * Each report is written to the response as it is read from the database, reports are never all held in memory.
+ *
+ * References
+ * https://golang.org/pkg/database/sql/#Rows
+ */
+
+package openapi
+
+import (
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/export"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rows written between each flush of the response.
+const exportFlushRows = 200
+
+// reportColumn is a column that can be exported and how to get its value from a report.
+type reportColumn struct {
+	numeric bool
+	value   func(report models.JobReport) string
+}
+
+func itoa(n int32) string { return strconv.Itoa(int(n)) }
+
+// Columns that can be exported by their name in the JSON of a report.
+var reportColumns = map[string]reportColumn{
+	"jobReportId":          {true, func(r models.JobReport) string { return itoa(r.JobReportId) }},
+	"date":                 {false, func(r models.JobReport) string { return r.Date.String() }},
+	"vehicleModel":         {false, func(r models.JobReport) string { return r.VehicleModel }},
+	"vehicleReg":           {false, func(r models.JobReport) string { return r.VehicleReg }},
+	"vehicleRegYear":       {true, func(r models.JobReport) string { return itoa(r.VehicleRegYear) }},
+	"vehicleRegCounty":     {false, func(r models.JobReport) string { return r.VehicleRegCounty }},
+	"vehicleLocation":      {false, func(r models.JobReport) string { return r.VehicleLocation }},
+	"odometerReading":      {true, func(r models.JobReport) string { return itoa(r.OdometerReading) }},
+	"odometerUnit":         {false, func(r models.JobReport) string { return r.OdometerUnit }},
+	"odometerKm":           {true, func(r models.JobReport) string { return itoa(r.OdometerKm) }},
+	"warranty":             {true, func(r models.JobReport) string { return itoa(r.Warranty) }},
+	"breakdown":            {true, func(r models.JobReport) string { return itoa(r.Breakdown) }},
+	"customerName":         {false, func(r models.JobReport) string { return r.CustomerName }},
+	"complaint":            {false, func(r models.JobReport) string { return r.Complaint }},
+	"cause":                {false, func(r models.JobReport) string { return r.Cause }},
+	"correction":           {false, func(r models.JobReport) string { return r.Correction }},
+	"parts":                {false, func(r models.JobReport) string { return r.Parts }},
+	"workHours":            {true, func(r models.JobReport) string { return itoa(r.WorkHours) }},
+	"workerName":           {false, func(r models.JobReport) string { return r.WorkerName }},
+	"jobComplete":          {true, func(r models.JobReport) string { return itoa(r.JobComplete) }},
+	"signed":               {false, func(r models.JobReport) string { return strconv.FormatBool(r.Signed) }},
+	"modifiedAfterSigning": {false, func(r models.JobReport) string { return strconv.FormatBool(r.ModifiedAfterSigning) }},
+	"createdAt":            {false, func(r models.JobReport) string { return r.CreatedAt.UTC().Format(time.RFC3339) }},
+	"updatedAt":            {false, func(r models.JobReport) string { return r.UpdatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// ExportReports
+// Works with CheckForCookie, isValidAccount & reportListFilter.
+// If the user has a cookie, stream their reports matching the filters of GetReports as a CSV or XLSX file.
+// Called by GetReportById for /jobReports/export, as the route is shared with the report's ID.
+func ExportReports(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to export these Reports")
+		return
+	}
+
+	worker := wa.Username
+	if !isValidAccount(worker) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User does not own these reports"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	contentType, ok := export.ContentTypes[format]
+	if !ok {
+		c.JSON(400, models.Error{Code: 400, Messages: "format must be csv or xlsx"})
+		return
+	}
+
+	names, err := exportColumnNames(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	unit, err := requestedOdometerUnit(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	filter, args, err := reportListFilter(c)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	selDB, err := db.Query(selectReports+"WHERE wkr.username = ?"+filter, append([]interface{}{worker}, args...)...)
+	if err != nil {
+		log.Println("\nFailed to process Reports.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	columns := make([]export.Column, len(names))
+	for i, name := range names {
+		columns[i] = export.Column{Name: name, Numeric: reportColumns[name].numeric}
+	}
+
+	// From here the response has started, errors can only be logged and the file left unfinished.
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="job-reports-%s.%s"`,
+		time.Now().Format("2006-01-02"), format))
+	c.Status(http.StatusOK)
+
+	w, err := export.NewWriter(format, c.Writer, columns)
+	if err != nil {
+		log.Println("\nFailed to start Report export.", err)
+		return
+	}
+
+	rows := 0
+	values := make([]string, len(names))
+	for selDB.Next() {
+		report, err := scanReport(selDB)
+		if err != nil {
+			log.Println("\nFailed to load Report for export.", err)
+			return
+		}
+		presentReport(&report, unit)
+
+		for i, name := range names {
+			values[i] = reportColumns[name].value(report)
+		}
+		if err := w.WriteRow(values); err != nil {
+			log.Println("\nFailed to export Report.", err)
+			return
+		}
+
+		if rows++; rows%exportFlushRows == 0 {
+			if err := w.Flush(); err != nil {
+				log.Println("\nFailed to send Report export.", err)
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+	if err := selDB.Err(); err != nil {
+		log.Println("\nFailed to load Reports for export.", err)
+		return
+	}
+
+	if err := w.Close(); err != nil {
+		log.Println("\nFailed to finish Report export.", err)
+		return
+	}
+	fmt.Println("\n[INFO] Reports Exported:", rows)
+}
+
+// Function to get the columns to export, from ?columns= (a comma separated list) or the defaults in config.ini.
+func exportColumnNames(c *gin.Context) ([]string, error) {
+	var names []string
+	if requested := c.Query("columns"); requested != "" {
+		names = strings.Split(requested, ",")
+	} else {
+		defaults, err := config.ExportColumns()
+		if err != nil {
+			return nil, err
+		}
+		names = defaults
+	}
+
+	var columns []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := reportColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to export")
+	}
+	return columns, nil
+}
@@ -0,0 +1,281 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Report Import
+ * Handles importing historical Job Reports from a CSV file - POST /jobReports/import.
+ * Each row is checked with the same rules as CreateReport (validateJobReport), valid rows are saved in batches,
+ * one transaction for each batch. Rejected rows can be downloaded as a CSV error report to be fixed and imported again.
+ * A dry run checks every row without saving anything. The same import can be run from the command line
+ * with "go run main.go import" (import_command.go).
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/blobstore"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/reportimport"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ImportReports
+// Works with CheckForCookie, isValidAccount & importReports.
+// If the user has a cookie, import the reports in the CSV file of the multipart form field "file" as their own.
+// "mapping" is a JSON object of the CSV column for each report field, e.g. {"date": "Job Date"},
+// without it the columns must be named as the fields. Send dryRun=true to check the file without saving.
+// Shares the route of /jobReports/:jobReportId, gin does not allow a fixed path beside it.
+func ImportReports(c *gin.Context) {
+	if c.Params.ByName("jobReportId") != "import" {
+		c.JSON(404, models.Error{Code: 404, Messages: "Not found"})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to import Reports")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	batchSize, maxSize, err := config.ImportRules()
+	if err != nil {
+		log.Println("Failed to load config file for imports.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	// Limit the request body so a large upload is refused before it is read.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "Import is invalid", Fields: []models.FieldError{
+			{Field: "file", Message: fmt.Sprintf("file is required and must be smaller than %d MB", maxSize>>20)}}})
+		return
+	}
+
+	var mapping reportimport.Mapping
+	if m := c.PostForm("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			c.JSON(400, models.Error{Code: 400, Messages: "Import is invalid", Fields: []models.FieldError{
+				{Field: "mapping", Message: "mapping must be a JSON object of report fields to CSV columns"}}})
+			return
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dryRun", c.Query("dryRun")))
+
+	file, err := header.Open()
+	if err != nil {
+		log.Println("Failed to open Import.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer file.Close()
+
+	reader, err := reportimport.NewReader(file, mapping)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "Import is invalid", Fields: []models.FieldError{
+			{Field: "file", Message: err.Error()}}})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	result, rejected, err := importReports(db, wa.Id, reader, dryRun, batchSize)
+	if err != nil {
+		log.Println("\nFailed to import Reports.", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to import Reports"})
+		return
+	}
+
+	// Keep the rejected rows for the user to download.
+	if len(rejected) > 0 {
+		store, err := config.BlobStore()
+		if err == nil {
+			var id string
+			id, err = storeImportErrors(c, store, wa.Id, reader.Header(), rejected)
+			result.ErrorReport = "/api/v1/imports/" + id + "/errors"
+		}
+		if err != nil {
+			log.Println("\nFailed to store Import error report.", err)
+		}
+	}
+
+	fmt.Println("\n[INFO] Reports Imported:", result.Imported, "Rejected:", result.Rejected, "Dry Run:", dryRun)
+	c.JSON(http.StatusOK, result)
+}
+
+// GetImportErrors
+// Works with CheckForCookie & isValidAccount.
+// If the user has a cookie, download the error report of rejected rows of one of their imports as CSV.
+func GetImportErrors(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Import")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	importId, err := uuid.Parse(c.Params.ByName("importId"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Import not found"})
+		return
+	}
+
+	store, err := config.BlobStore()
+	if err != nil {
+		log.Println("Failed to set up Blob Store.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	// Error reports are kept under the ID of the worker who imported them, so only they can get them.
+	file, err := store.Get(c, importErrorsKey(wa.Id, importId.String()))
+	if err == blobstore.ErrNotFound {
+		c.JSON(404, models.Error{Code: 404, Messages: "Import not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Import error report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, importId))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		log.Println("\nFailed to send Import error report.", err)
+	}
+}
+
+// Function to import the reports read by reader for a worker.
+// Rows are checked with validateJobReport and valid rows are saved batchSize at a time, each batch in a transaction.
+// If a batch fails its rows are saved one by one so only the rows the database refuses are rejected.
+// Returns the result and the rejected rows. Nothing is saved when dryRun is true.
+func importReports(db *sql.DB, workerId int, reader *reportimport.Reader, dryRun bool,
+	batchSize int) (models.ImportResult, []reportimport.Row, error) {
+	result := models.ImportResult{DryRun: dryRun, Errors: []models.ImportRowError{}}
+	var rejected []reportimport.Row
+	var batch []reportimport.Row
+
+	reject := func(row reportimport.Row) {
+		rejected = append(rejected, row)
+		result.Rejected++
+		result.Errors = append(result.Errors, models.ImportRowError{Row: row.Number, Fields: row.Errors})
+	}
+
+	save := func() {
+		if len(batch) == 0 {
+			return
+		}
+		saved, err := saveImportBatch(db, workerId, batch)
+		if err != nil {
+			log.Println("\nMySQL Error: Import batch failed, saving its rows one by one.\n", err)
+			saved = 0
+			for _, row := range batch {
+				if _, err := saveImportBatch(db, workerId, []reportimport.Row{row}); err != nil {
+					row.Errors = append(row.Errors, models.FieldError{Field: "row", Message: "not saved: " + err.Error()})
+					reject(row)
+					continue
+				}
+				saved++
+			}
+		}
+		result.Imported += saved
+		batch = batch[:0]
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, rejected, err
+		}
+		result.Rows++
+
+		if len(row.Errors) == 0 {
+			row.Errors = validateJobReport(&row.Report)
+		}
+		if len(row.Errors) > 0 {
+			reject(row)
+			continue
+		}
+		result.Valid++
+
+		if dryRun {
+			continue
+		}
+		if batch = append(batch, row); len(batch) >= batchSize {
+			save()
+		}
+	}
+	save()
+
+	return result, rejected, nil
+}
+
+// Function to save a batch of imported reports in one transaction, none are saved if any fail.
+// Their events are recorded but their customers are not sent job.created, imports are of jobs from before Horton and
+// customers would be told of jobs long done.
+func saveImportBatch(db *sql.DB, workerId int, rows []reportimport.Row) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		reportId, err := insertReportTx(tx, workerId, row.Report)
+		if err == nil {
+			err = recordReportEvents(tx, reportId, webhook.ReportCreated, false)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// Function to store the error report of an import in the Blob Store, returns the ID of the import.
+func storeImportErrors(ctx context.Context, store blobstore.Store, workerId int, header []string,
+	rows []reportimport.Row) (string, error) {
+	var report bytes.Buffer
+	if err := reportimport.WriteErrors(&report, header, rows); err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	err := store.Put(ctx, importErrorsKey(workerId, id), &report, int64(report.Len()), "text/csv")
+	return id, err
+}
+
+func importErrorsKey(workerId int, importId string) string {
+	return fmt.Sprintf("imports/%d/%s-errors.csv", workerId, importId)
+}
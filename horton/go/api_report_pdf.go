@@ -0,0 +1,102 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Report PDF
+ * Handles getting a Job Report as a PDF job sheet - GET /jobReports/{id}.pdf or Accept: application/pdf.
+ * The sheet is rendered on the server by the reportpdf package with the garage's details in config.ini.
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/reportpdf"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const mimePDF = "application/pdf"
+
+// Function to check if a request for a report wants it as a PDF, by a ".pdf" on the ID or the Accept header.
+func wantsReportPdf(c *gin.Context) bool {
+	if strings.HasSuffix(c.Params.ByName("jobReportId"), ".pdf") {
+		return true
+	}
+	return c.GetHeader("Accept") != "" && c.NegotiateFormat(gin.MIMEJSON, mimePDF) == mimePDF
+}
+
+// GetReportPdf
+// Works with CheckForCookie, isValidAccount, findReport & loadSignature.
+// If the user has a cookie and owns the report, send the report as a PDF job sheet.
+// Called by GetReportById when the client asks for a PDF.
+func GetReportPdf(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Report")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User does not own this report"})
+		return
+	}
+
+	reportId, err := strconv.Atoi(strings.TrimSuffix(c.Params.ByName("jobReportId"), ".pdf"))
+	if err != nil {
+		c.JSON(404, models.Error{Code: 404, Messages: "Report not found"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	report, err := findReport(db, reportId, wa.Username)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Report not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	presentReport(&report, "")
+
+	var signature *models.Signature
+	if report.Signed {
+		sig, err := loadSignature(db, reportId)
+		if err != nil {
+			log.Println("\nFailed to load Signature.", err)
+			c.JSON(500, nil)
+			return
+		}
+		signature = &sig
+	}
+
+	garage, err := config.Garage()
+	if err != nil {
+		log.Println("\nFailed to load Garage details.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	// Render to a buffer first so a failure can still be sent as a JSON error.
+	var pdf bytes.Buffer
+	if err := reportpdf.Render(&pdf, report, garage, signature); err != nil {
+		log.Println("\nFailed to render Report PDF.", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to render Report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="job-report-%d.pdf"`, reportId))
+	c.Data(http.StatusOK, mimePDF, pdf.Bytes())
+	fmt.Println("\n[INFO] Report PDF Processed:", reportId)
+}
@@ -0,0 +1,254 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Signature
+ * Handles the customer's signature on a report at handover - Sign & Get Signature.
+ * The signature is stored with a SHA256 hash of the report's contents when it was signed,
+ * any later change to the report (UpdateReport) no longer matches the hash and is shown as modified after signing.
+ *
+ * References
+ * https://golang.org/pkg/crypto/sha256/
+ */
+
+package openapi
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Largest signature image allowed in bytes.
+	maxSignatureImage = 512 << 10
+	// Most points allowed across all the strokes of a signature.
+	maxSignaturePoints = 10000
+)
+
+// SignReport
+// Works with CheckForCookie, isValidAccount & findReport.
+// If the user has a cookie and owns the report, store the customer's signature (an image or strokes)
+// with the hash of the report as it is now. A report can only be signed once.
+func SignReport(c *gin.Context) {
+	var signature models.Signature
+
+	if err := c.ShouldBindJSON(&signature); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to sign this Report")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	format, contentType, data, fields := signatureData(signature)
+	if strings.TrimSpace(signature.SignerName) == "" {
+		fields = append(fields, models.FieldError{Field: "signerName", Message: "signerName is required"})
+	}
+	if len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Signature is invalid", Fields: fields})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return
+	}
+
+	report, err := findReport(db, reportId, wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if report.SignedReportHash != "" {
+		c.JSON(409, models.Error{Code: 409, Messages: "Report has already been signed"})
+		return
+	}
+
+	dataHash := sha256.Sum256(data)
+	signature.JobReportId = int32(reportId)
+	signature.ReportHash = reportHash(report)
+	signature.SignatureHash = hex.EncodeToString(dataHash[:])
+
+	_, err = db.Exec("INSERT INTO signatures(job_report_id, worker_id, signer_name, format, content_type, data, "+
+		"signature_hash, report_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", reportId, wa.Id, signature.SignerName, format,
+		contentType, data, signature.SignatureHash, signature.ReportHash)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Signature.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to store Signature"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Report signed:", reportId, "Report Hash:", signature.ReportHash)
+	c.JSON(201, models.Signature{JobReportId: signature.JobReportId, SignerName: signature.SignerName,
+		ReportHash: signature.ReportHash, SignatureHash: signature.SignatureHash, CurrentHash: signature.ReportHash,
+		SignedBy: wa.WorkerName, SignedAt: time.Now().UTC()})
+}
+
+// GetSignature
+// Works with CheckForCookie, isValidAccount & findReport.
+// If the user has a cookie and owns the report, get the customer's signature and check the report against
+// the hash stored when it was signed.
+func GetSignature(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Signature")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	reportId, ok := ownedReportId(c, db)
+	if !ok {
+		return
+	}
+
+	signature, err := loadSignature(db, reportId)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Report has not been signed"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Signature.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	// Check the report still matches what the customer signed.
+	report, err := findReport(db, reportId, wa.Username)
+	if err != nil {
+		log.Println("\nFailed to load Report.", err)
+		c.JSON(500, nil)
+		return
+	}
+	signature.CurrentHash = reportHash(report)
+	signature.ModifiedAfterSigning = signature.CurrentHash != signature.ReportHash
+
+	c.JSON(http.StatusOK, signature)
+}
+
+// Function to load the signature of a report, the image as a data URL or the strokes.
+// Returns sql.ErrNoRows if the report has not been signed.
+func loadSignature(db *sql.DB, reportId int) (models.Signature, error) {
+	var signature models.Signature
+	var format, contentType string
+	var data []byte
+	err := db.QueryRow("SELECT sig.signature_id, sig.job_report_id, sig.signer_name, sig.format, sig.content_type, "+
+		"sig.data, sig.signature_hash, sig.report_hash, wkr.worker_name, sig.created_at FROM signatures sig "+
+		"INNER JOIN workers wkr ON sig.worker_id = wkr.worker_id WHERE sig.job_report_id = ?", reportId).Scan(
+		&signature.SignatureId, &signature.JobReportId, &signature.SignerName, &format, &contentType, &data,
+		&signature.SignatureHash, &signature.ReportHash, &signature.SignedBy, &signature.SignedAt)
+	if err != nil {
+		return models.Signature{}, err
+	}
+
+	if format == "strokes" {
+		if err := json.Unmarshal(data, &signature.Strokes); err != nil {
+			log.Println("\nFailed to read Signature strokes.", err)
+		}
+	} else {
+		signature.Image = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+	return signature, nil
+}
+
+// Function to get the hash of the contents of a report.
+// Only the fields the customer signed for are hashed, not the fields worked out for the client or timestamps.
+func reportHash(report models.JobReport) string {
+	contents, _ := json.Marshal(struct {
+		JobReportId     int32  `json:"jobReportId"`
+		Date            string `json:"date"`
+		VehicleModel    string `json:"vehicleModel"`
+		VehicleReg      string `json:"vehicleReg"`
+		VehicleLocation string `json:"vehicleLocation"`
+		OdometerKm      int32  `json:"odometerKm"`
+		OdometerReading int32  `json:"odometerReading"`
+		OdometerUnit    string `json:"odometerUnit"`
+		Warranty        int32  `json:"warranty"`
+		Breakdown       int32  `json:"breakdown"`
+		CustomerName    string `json:"customerName"`
+		Complaint       string `json:"complaint"`
+		Cause           string `json:"cause"`
+		Correction      string `json:"correction"`
+		Parts           string `json:"parts"`
+		WorkHours       int32  `json:"workHours"`
+		JobComplete     int32  `json:"jobComplete"`
+	}{report.JobReportId, report.Date.String(), report.VehicleModel, report.VehicleReg, report.VehicleLocation,
+		report.OdometerKm, report.OdometerReading, report.OdometerUnit, report.Warranty, report.Breakdown,
+		report.CustomerName, report.Complaint, report.Cause, report.Correction, report.Parts, report.WorkHours,
+		report.JobComplete})
+
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// Function to get the data to store for a signature from the image or strokes sent by the client.
+// Returns the format ("image" or "strokes"), its content type and data, or an error for each invalid field.
+func signatureData(signature models.Signature) (string, string, []byte, []models.FieldError) {
+	if signature.Image != "" && len(signature.Strokes) > 0 {
+		return "", "", nil, []models.FieldError{{Field: "image", Message: "send either an image or strokes, not both"}}
+	}
+
+	if signature.Image != "" {
+		// Remove the data URL prefix if there is one.
+		encoded := signature.Image
+		if i := strings.Index(encoded, ";base64,"); strings.HasPrefix(encoded, "data:") && i > 0 {
+			encoded = encoded[i+len(";base64,"):]
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", "", nil, []models.FieldError{{Field: "image", Message: "image must be base64"}}
+		}
+		if len(data) > maxSignatureImage {
+			return "", "", nil, []models.FieldError{{Field: "image", Message: "image must be smaller than 512 KB"}}
+		}
+		contentType := http.DetectContentType(data)
+		if contentType != "image/png" && contentType != "image/jpeg" {
+			return "", "", nil, []models.FieldError{{Field: "image", Message: "image must be a PNG or JPEG"}}
+		}
+		return "image", contentType, data, nil
+	}
+
+	if len(signature.Strokes) > 0 {
+		points := 0
+		for _, stroke := range signature.Strokes {
+			points += len(stroke)
+		}
+		if points < 2 || points > maxSignaturePoints {
+			return "", "", nil, []models.FieldError{{Field: "strokes",
+				Message: fmt.Sprintf("strokes must have between 2 and %d points", maxSignaturePoints)}}
+		}
+		data, _ := json.Marshal(signature.Strokes)
+		return "strokes", "application/json", data, nil
+	}
+
+	return "", "", nil, []models.FieldError{{Field: "image", Message: "an image or strokes is required"}}
+}
@@ -0,0 +1,608 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API SLA
+ * Handles the response times agreed with customers for breakdowns - SLA Contracts, Breaches & Compliance Reports.
+ * Breakdowns keep the targets of their contract from when they were requested, so a contract changing does not
+ * change how breakdowns before it did.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/export"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/sla"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectContracts is the Query shared by the functions that get SLA contracts, columns are read by getContracts.
+const selectContracts = "SELECT contract_id, contract_name, COALESCE(customer_name, ''), acknowledge_minutes, " +
+	"arrive_minutes, resolve_minutes, active FROM sla_contracts "
+
+// The longest target, a week, breakdowns keep their targets in minutes in a smallint.
+const maxTargetMinutes = 7 * 24 * 60
+
+// Columns of the export of the breakdowns in a compliance report, a target, minutes and outcome for each stage.
+var slaExportColumns = []export.Column{
+	{Name: "breakdownId", Numeric: true}, {Name: "jobReportId", Numeric: true}, {Name: "contract"},
+	{Name: "customerName"}, {Name: "vehicleReg"}, {Name: "priority"}, {Name: "status"}, {Name: "worker"},
+	{Name: "requestedAt"}, {Name: "acknowledgedAt"}, {Name: "arrivedAt"}, {Name: "resolvedAt"},
+	{Name: "acknowledgeTarget", Numeric: true}, {Name: "acknowledgeMinutes", Numeric: true},
+	{Name: "acknowledgeOutcome"}, {Name: "arriveTarget", Numeric: true}, {Name: "arriveMinutes", Numeric: true},
+	{Name: "arriveOutcome"}, {Name: "resolveTarget", Numeric: true}, {Name: "resolveMinutes", Numeric: true},
+	{Name: "resolveOutcome"},
+}
+
+// GetSLAContracts
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getContracts.
+// If the user has a cookie and is a supervisor, get the SLA contracts by name.
+func GetSLAContracts(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get SLA Contracts")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get SLA contracts") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	contracts, err := getContracts(db, "ORDER BY contract_name")
+	if err != nil {
+		log.Println("\nFailed to load SLA Contracts.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, contracts)
+}
+
+// CreateSLAContract
+// Works with CheckForCookie, isValidAccount, requireSupervisor & validateContract.
+// If the user has a cookie and is a supervisor, add an SLA contract. It is active unless active is false.
+func CreateSLAContract(c *gin.Context) {
+	var contract models.SLAContract
+
+	if err := c.ShouldBindJSON(&contract); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if fields := validateContract(contract); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Contract is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to add an SLA Contract")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "add SLA contracts") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if existing, err := getContracts(db, "WHERE contract_name = ?", contract.Name); err != nil {
+		log.Println("\nFailed to load SLA Contracts.", err)
+		c.JSON(500, nil)
+		return
+	} else if len(existing) > 0 {
+		c.JSON(409, models.Error{Code: 409, Messages: "There is already a contract named " + contract.Name})
+		return
+	}
+
+	active := contract.Active == nil || *contract.Active
+	res, err := db.Exec("INSERT INTO sla_contracts (contract_name, customer_name, acknowledge_minutes, "+
+		"arrive_minutes, resolve_minutes, active, created_by, created_at) VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?)",
+		contract.Name, contract.CustomerName, contract.AcknowledgeMinutes, contract.ArriveMinutes,
+		contract.ResolveMinutes, active, wa.Id, time.Now().UTC())
+	var contractId int64
+	if err == nil {
+		contractId, err = res.LastInsertId()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting SLA Contract.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to add SLA Contract"})
+		return
+	}
+
+	fmt.Println("\n[INFO] New SLA Contract:", contract.Name)
+	sendContract(c, db, http.StatusCreated, int(contractId))
+}
+
+// UpdateSLAContract
+// Works with CheckForCookie, isValidAccount, requireSupervisor & validateContract.
+// If the user has a cookie and is a supervisor, change an SLA contract's targets or whether it is active.
+// Breakdowns already under the contract keep the targets they were requested with.
+func UpdateSLAContract(c *gin.Context) {
+	var contract models.SLAContract
+
+	if err := c.ShouldBindJSON(&contract); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if fields := validateContract(contract); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Contract is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update this SLA Contract")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "change SLA contracts") {
+		return
+	}
+
+	contractId, _ := strconv.Atoi(c.Params.ByName("contractId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	existing, err := getContracts(db, "WHERE contract_id = ? OR contract_name = ?", contractId, contract.Name)
+	if err != nil {
+		log.Println("\nFailed to load SLA Contracts.", err)
+		c.JSON(500, nil)
+		return
+	}
+	found := false
+	for _, e := range existing {
+		if int(e.ContractId) == contractId {
+			found = true
+		} else {
+			c.JSON(409, models.Error{Code: 409, Messages: "There is already a contract named " + contract.Name})
+			return
+		}
+	}
+	if !found {
+		c.JSON(404, models.Error{Code: 404, Messages: "SLA Contract not found"})
+		return
+	}
+
+	_, err = db.Exec("UPDATE sla_contracts SET contract_name = ?, customer_name = NULLIF(?, ''), "+
+		"acknowledge_minutes = ?, arrive_minutes = ?, resolve_minutes = ?, active = COALESCE(?, active) "+
+		"WHERE contract_id = ?", contract.Name, contract.CustomerName, contract.AcknowledgeMinutes,
+		contract.ArriveMinutes, contract.ResolveMinutes, contract.Active, contractId)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating SLA Contract.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] SLA Contract", contract.Name, "updated by", wa.Username)
+	sendContract(c, db, http.StatusOK, contractId)
+}
+
+// GetSLABreaches
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getBreakdowns.
+// If the user has a cookie and is a supervisor, get the open breakdowns that have breached a target or will within
+// warn_before_minutes, oldest first.
+func GetSLABreaches(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get SLA Breaches")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get SLA breaches") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	breakdowns, err := getBreakdowns(db, "WHERE "+openBreakdowns+" ORDER BY bd.requested_at, bd.breakdown_id")
+	if err != nil {
+		log.Println("\nFailed to load Breakdowns.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	res := []models.Breakdown{}
+	for _, b := range breakdowns {
+		for _, r := range b.SLA {
+			if r.Outcome == sla.Breached || r.AtRisk {
+				res = append(res, b)
+				break
+			}
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// StartSLAAlerts starts checking open breakdowns in the background every alert_check_seconds in config.ini, sending
+// endpoints breakdown.sla_at_risk when a stage is due soon and breakdown.sla_breached when it is overdue or was
+// reached late. Returns an error if the settings cannot be loaded.
+func StartSLAAlerts() error {
+	settings, err := config.SLASettings()
+	if err != nil {
+		return err
+	}
+	if settings.AlertInterval <= 0 {
+		return fmt.Errorf("alert_check_seconds must be more than 0")
+	}
+
+	go func() {
+		ticker := time.NewTicker(settings.AlertInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			db := config.DbConn()
+			//db := mocks.MockDbConn()
+			if err := checkSLAAlerts(db); err != nil {
+				log.Println("\nMySQL Error: Error Checking SLA Alerts.\n", err)
+			}
+			db.Close()
+		}
+	}()
+	return nil
+}
+
+// Function to record the events of the stages of open breakdowns that are at risk or breached.
+// Each alert of a stage is recorded in sla_alerts with its event, so it is only sent once however often breakdowns
+// are checked or however many instances of Horton check them.
+func checkSLAAlerts(db *sql.DB) error {
+	breakdowns, err := getBreakdowns(db, "WHERE "+openBreakdowns+" ORDER BY bd.requested_at, bd.breakdown_id")
+	if err != nil {
+		return err
+	}
+
+	for _, b := range breakdowns {
+		for _, r := range b.SLA {
+			event := ""
+			if r.Outcome == sla.Breached {
+				event = webhook.BreakdownSLABreached
+			} else if r.AtRisk {
+				event = webhook.BreakdownSLAAtRisk
+			}
+			if event == "" {
+				continue
+			}
+			if err := recordSLAAlert(db, b, r.Stage, event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Function to record an alert of a stage of a breakdown and its event, unless it has been already.
+func recordSLAAlert(db *sql.DB, b models.Breakdown, stage, event string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec("INSERT IGNORE INTO sla_alerts (breakdown_id, stage, event_type, created_at) "+
+		"VALUES (?, ?, ?, ?)", b.BreakdownId, stage, event, time.Now().UTC())
+	if err == nil {
+		var rows int64
+		if rows, err = res.RowsAffected(); err == nil && rows == 1 {
+			err = recordOutboxEvent(tx, event, int64(b.JobReportId), b.CustomerName,
+				models.SLAAlert{Stage: stage, Breakdown: b})
+			if err == nil {
+				fmt.Println("\n[INFO] Breakdown", b.BreakdownId, stage, "SLA alert recorded:", event)
+			}
+		}
+	}
+	return endTx(tx, err)
+}
+
+// GetSLAReport
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getBreakdowns.
+// If the user has a cookie and is a supervisor, get the compliance with their targets of the breakdowns requested
+// in a month like 2020-06, by contract. ?contract= limits it to one contract, "none" for the default targets.
+// ?format=csv or xlsx exports each breakdown with how it did against each target.
+func GetSLAReport(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get SLA Reports")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get SLA reports") {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := export.ContentTypes[format]
+	if !ok && format != "json" {
+		c.JSON(400, models.Error{Code: 400, Messages: "format must be json, csv or xlsx"})
+		return
+	}
+
+	settings, err := config.SLASettings()
+	if err != nil {
+		log.Println("Failed to load config file for SLAs.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	month := c.Params.ByName("month")
+	from, to, err := sla.Month(month, settings.Location)
+	if err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	// Cancelled breakdowns were never answered so are left out.
+	where := "WHERE bd.status <> 'cancelled' AND bd.requested_at >= ? AND bd.requested_at < ?"
+	args := []interface{}{from.UTC(), to.UTC()}
+	switch contract := c.Query("contract"); contract {
+	case "":
+	case "none":
+		where += " AND bd.contract_id IS NULL"
+	default:
+		where, args = where+" AND con.contract_name = ?", append(args, contract)
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	breakdowns, err := getBreakdowns(db, where+" ORDER BY bd.requested_at, bd.breakdown_id", args...)
+	if err != nil {
+		log.Println("\nFailed to load Breakdowns.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, slaReport(month, breakdowns))
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sla-report-%s.%s"`, month, format))
+	c.Status(http.StatusOK)
+
+	w, err := export.NewWriter(format, c.Writer, slaExportColumns)
+	if err != nil {
+		log.Println("\nFailed to start SLA Report export.", err)
+		return
+	}
+	for _, b := range breakdowns {
+		if err := w.WriteRow(slaExportRow(b)); err != nil {
+			log.Println("\nFailed to export SLA Report.", err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Println("\nFailed to finish SLA Report export.", err)
+	}
+}
+
+// Function to find the SLA contract a new breakdown is under and its targets, the contract named in the request,
+// or the active contract of the report's customer, or none with the default targets in config.ini.
+// Sends the error response and returns false if the contract named is not active.
+func breakdownContract(c *gin.Context, db dbExecutor, request models.BreakdownRequest) (sql.NullInt64, sla.Targets,
+	bool) {
+	where, args := "WHERE active = 1 AND customer_name = ? ORDER BY contract_id LIMIT 1",
+		[]interface{}{request.Report.CustomerName}
+	if request.Contract != "" {
+		where, args = "WHERE active = 1 AND contract_name = ?", []interface{}{request.Contract}
+	}
+
+	contracts, err := getContracts(db, where, args...)
+	if err != nil {
+		log.Println("\nFailed to load SLA Contracts.", err)
+		c.JSON(500, nil)
+		return sql.NullInt64{}, sla.Targets{}, false
+	}
+	if len(contracts) > 0 {
+		contract := contracts[0]
+		return sql.NullInt64{Int64: int64(contract.ContractId), Valid: true}, sla.Targets{
+			AcknowledgeMinutes: contract.AcknowledgeMinutes,
+			ArriveMinutes:      contract.ArriveMinutes,
+			ResolveMinutes:     contract.ResolveMinutes,
+		}, true
+	}
+	if request.Contract != "" {
+		c.JSON(400, models.Error{Code: 400, Messages: "Breakdown is invalid", Fields: []models.FieldError{
+			{Field: "contract", Message: fmt.Sprintf("%q is not an active SLA contract", request.Contract)}}})
+		return sql.NullInt64{}, sla.Targets{}, false
+	}
+
+	settings, err := config.SLASettings()
+	if err != nil {
+		log.Println("Failed to load config file for SLAs.", err)
+		c.JSON(500, nil)
+		return sql.NullInt64{}, sla.Targets{}, false
+	}
+	return sql.NullInt64{}, settings.Default, true
+}
+
+// Function to validate an SLA contract's targets.
+func validateContract(contract models.SLAContract) []models.FieldError {
+	var fields []models.FieldError
+	if strings.TrimSpace(contract.Name) == "" || len(contract.Name) > 100 {
+		fields = append(fields, models.FieldError{Field: "name", Message: "name must be 1 to 100 characters"})
+	}
+	if len(contract.CustomerName) > 100 {
+		fields = append(fields, models.FieldError{Field: "customerName",
+			Message: "customer name must be at most 100 characters"})
+	}
+	targets := sla.Targets{AcknowledgeMinutes: contract.AcknowledgeMinutes, ArriveMinutes: contract.ArriveMinutes,
+		ResolveMinutes: contract.ResolveMinutes}
+	if err := targets.Check(); err != nil {
+		fields = append(fields, models.FieldError{Field: "targets", Message: err.Error()})
+	}
+	for _, stage := range sla.Stages {
+		if targets.Minutes(stage) > maxTargetMinutes {
+			fields = append(fields, models.FieldError{Field: stage + "Minutes",
+				Message: fmt.Sprintf("%s target must be at most %d minutes", stage, maxTargetMinutes)})
+		}
+	}
+	return fields
+}
+
+// Function to get the SLA contracts of a selectContracts Query.
+func getContracts(db dbExecutor, where string, args ...interface{}) ([]models.SLAContract, error) {
+	selDB, err := db.Query(selectContracts+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	contracts := []models.SLAContract{}
+	for selDB.Next() {
+		var contract models.SLAContract
+		var active bool
+		if err := selDB.Scan(&contract.ContractId, &contract.Name, &contract.CustomerName,
+			&contract.AcknowledgeMinutes, &contract.ArriveMinutes, &contract.ResolveMinutes, &active); err != nil {
+			return nil, err
+		}
+		contract.Active = &active
+		contracts = append(contracts, contract)
+	}
+	return contracts, selDB.Err()
+}
+
+// Function to send an SLA contract as it is now in the database.
+func sendContract(c *gin.Context, db dbExecutor, status int, contractId int) {
+	contracts, err := getContracts(db, "WHERE contract_id = ?", contractId)
+	if err != nil || len(contracts) == 0 {
+		log.Println("\nFailed to load SLA Contract.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(status, contracts[0])
+}
+
+// Function to work out how a breakdown is doing against its targets at now.
+// Cancelled breakdowns have none, they were never answered.
+func slaResults(b models.Breakdown, targets sla.Targets, now time.Time) []sla.Result {
+	if b.Status == "cancelled" {
+		return nil
+	}
+	return sla.Evaluate(targets, sla.Times{Requested: b.RequestedAt, Acknowledged: b.AcknowledgedAt,
+		Arrived: b.ArrivedAt, Resolved: b.ResolvedAt}, now)
+}
+
+// Function to make the results of a breakdown against its targets sent to the client, stages due within warn of
+// now are at risk.
+func presentSLA(results []sla.Result, now time.Time, warn time.Duration) []models.SLAResult {
+	res := []models.SLAResult{}
+	for _, r := range results {
+		res = append(res, models.SLAResult{Stage: r.Stage, TargetMinutes: r.TargetMinutes, Due: r.Due,
+			Minutes: r.Minutes, Outcome: r.Outcome, AtRisk: r.DueWithin(now, warn)})
+	}
+	return res
+}
+
+// Function to tally the results of breakdowns into a compliance report, overall and by contract.
+func slaReport(month string, breakdowns []models.Breakdown) models.SLAReport {
+	report := models.SLAReport{Month: month, Breakdowns: len(breakdowns), Contracts: []models.SLAContractReport{}}
+
+	var all [][]sla.Result
+	byContract := map[string][][]sla.Result{}
+	var contracts []string
+	for _, b := range breakdowns {
+		results := make([]sla.Result, len(b.SLA))
+		for i, r := range b.SLA {
+			results[i] = sla.Result{Stage: r.Stage, TargetMinutes: r.TargetMinutes, Due: r.Due, Minutes: r.Minutes,
+				Outcome: r.Outcome}
+		}
+		all = append(all, results)
+		if _, ok := byContract[b.Contract]; !ok {
+			contracts = append(contracts, b.Contract)
+		}
+		byContract[b.Contract] = append(byContract[b.Contract], results)
+	}
+
+	report.Stages = presentStages(sla.Summarise(all))
+	for _, contract := range contracts {
+		report.Contracts = append(report.Contracts, models.SLAContractReport{Contract: contract,
+			Breakdowns: len(byContract[contract]), Stages: presentStages(sla.Summarise(byContract[contract]))})
+	}
+	return report
+}
+
+// Function to make the tallies of a compliance report sent to the client.
+func presentStages(tallies []sla.Tally) []models.SLAStage {
+	stages := []models.SLAStage{}
+	for _, t := range tallies {
+		stages = append(stages, models.SLAStage{Stage: t.Stage, Total: t.Total, Met: t.Met, Breached: t.Breached,
+			Pending: t.Pending, CompliancePercent: roundTenth(t.Compliance()),
+			AverageMinutes: roundTenth(t.AverageMinutes())})
+	}
+	return stages
+}
+
+// Function to round a number that may not be set to one decimal place.
+func roundTenth(n *float64) *float64 {
+	if n == nil {
+		return nil
+	}
+	rounded := math.Round(*n*10) / 10
+	return &rounded
+}
+
+// Function to make the row of a breakdown in the export of a compliance report.
+func slaExportRow(b models.Breakdown) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	row := []string{strconv.Itoa(int(b.BreakdownId)), strconv.Itoa(int(b.JobReportId)), b.Contract,
+		b.CustomerName, b.VehicleReg, b.Priority, b.Status, b.Worker, formatTime(&b.RequestedAt),
+		formatTime(b.AcknowledgedAt), formatTime(b.ArrivedAt), formatTime(b.ResolvedAt)}
+	for _, stage := range sla.Stages {
+		target, minutes, outcome := "", "", ""
+		for _, r := range b.SLA {
+			if r.Stage == stage {
+				target, outcome = strconv.Itoa(r.TargetMinutes), r.Outcome
+				if r.Minutes != nil {
+					minutes = strconv.Itoa(*r.Minutes)
+				}
+			}
+		}
+		row = append(row, target, minutes, outcome)
+	}
+	return row
+}
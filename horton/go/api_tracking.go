@@ -0,0 +1,399 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Tracking
+ * Handles the GPS pings workers' devices send - Recording Pings, Listing Pings, the Live Feed of where Workers
+ * are & Silent Workers. Pings are kept in worker_pings within the limits in config.ini, the latest moves the
+ * worker's position in worker_positions.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The most pings listed at once.
+const maxPingsListed = 10000
+
+// RecordWorkerPings
+// Works with CheckForCookie, isValidAccount & recordPings.
+// If the user has a cookie, keep the pings their device has recorded and move their position to the latest.
+func RecordWorkerPings(c *gin.Context) {
+	var request models.WorkerPingsRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to record Worker Pings")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	settings, err := config.TrackingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for tracking.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	if len(request.Pings) > settings.MaxBatch {
+		c.JSON(413, models.Error{Code: 413, Messages: fmt.Sprintf("Send at most %d pings at once",
+			settings.MaxBatch)})
+		return
+	}
+
+	now := time.Now().UTC()
+	pings := make([]dispatch.Ping, len(request.Pings))
+	var fields []models.FieldError
+	for i, p := range request.Pings {
+		pings[i] = dispatch.Ping{Point: dispatch.Point{Latitude: *p.Latitude, Longitude: *p.Longitude},
+			AccuracyM: p.AccuracyM, RecordedAt: now}
+		if p.RecordedAt != nil {
+			pings[i].RecordedAt = p.RecordedAt.UTC()
+		}
+		if err := settings.Rules.Check(pings[i], now); err != nil {
+			fields = append(fields, models.FieldError{Field: fmt.Sprintf("pings[%d]", i), Message: err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Pings are invalid", Fields: fields})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	if err := recordPings(db, wa.Id, pings, request.Available, settings); err != nil {
+		log.Println("\nMySQL Error: Error Recording Worker Pings.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	positions, err := loadPositions(db, "WHERE wp.worker_id = ?", wa.Id)
+	if err != nil || len(positions) == 0 {
+		log.Println("\nFailed to load Worker Position.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusCreated, trackedPosition(positions[0], now, settings))
+}
+
+// GetWorkerPings
+// Works with CheckForCookie, isValidAccount & workerIdOf.
+// If the user has a cookie, get the pings kept of their device oldest first, or of ?worker= for supervisors.
+// ?since= and ?until= are times like 2020-06-03T09:00:00Z, the last day if they are not set.
+func GetWorkerPings(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Worker Pings")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	username := c.DefaultQuery("worker", wa.Username)
+	if username != wa.Username && !requireSupervisor(c, "get where other workers have been") {
+		return
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-24 * time.Hour)
+	for _, param := range []struct {
+		name string
+		time *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		if value := c.Query(param.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(400, models.Error{Code: 400,
+					Messages: param.name + " must be a time like 2020-06-03T09:00:00Z"})
+				return
+			}
+			*param.time = t.UTC()
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > maxPingsListed {
+		c.JSON(400, models.Error{Code: 400, Messages: fmt.Sprintf("limit must be from 1 to %d", maxPingsListed)})
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	workerId, err := workerIdOf(db, username)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Worker " + username + " not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nFailed to load Worker.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	selDB, err := db.Query("SELECT latitude, longitude, accuracy_m, recorded_at FROM worker_pings "+
+		"WHERE worker_id = ? AND recorded_at >= ? AND recorded_at < ? ORDER BY recorded_at, ping_id LIMIT ?",
+		workerId, since, until, limit)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Getting Worker Pings.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer selDB.Close()
+
+	pings := []models.WorkerPing{}
+	for selDB.Next() {
+		var lat, lon float64
+		var accuracy sql.NullFloat64
+		var recordedAt time.Time
+		if err := selDB.Scan(&lat, &lon, &accuracy, &recordedAt); err != nil {
+			log.Println("\nMySQL Error: Error Getting Worker Pings.\n", err)
+			c.JSON(500, nil)
+			return
+		}
+		ping := models.WorkerPing{Worker: username, Latitude: &lat, Longitude: &lon, RecordedAt: &recordedAt}
+		if accuracy.Valid {
+			ping.AccuracyM = &accuracy.Float64
+		}
+		pings = append(pings, ping)
+	}
+	if err := selDB.Err(); err != nil {
+		log.Println("\nMySQL Error: Error Getting Worker Pings.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, pings)
+}
+
+// GetSilentWorkers
+// Works with CheckForCookie, isValidAccount, requireSupervisor & loadPositions.
+// If the user has a cookie and is a supervisor, get the workers on a call-out who have not pinged for longer than
+// silent_alert_minutes, longest silent first.
+func GetSilentWorkers(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Silent Workers")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get silent workers") {
+		return
+	}
+
+	settings, err := config.TrackingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for tracking.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	positions, err := loadPositions(db, "ORDER BY wp.reported_at, wkr.username")
+	if err != nil {
+		log.Println("\nFailed to load Worker Positions.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	res := []models.WorkerPosition{}
+	for _, p := range positions {
+		if p.Silent(now, settings.SilentAfter) {
+			res = append(res, trackedPosition(p, now, settings))
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetWorkerPositionFeed
+// Works with CheckForCookie, isValidAccount, requireSupervisor & loadPositions.
+// If the user has a cookie and is a supervisor, stream where workers are as Server-Sent Events.
+// Every position is sent first as a "position" event, then each that changes. "silent" is sent when a worker on a
+// call-out goes silent, "heard" when they are no longer silent.
+func GetWorkerPositionFeed(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get the Worker Position Feed")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "watch where workers are") {
+		return
+	}
+
+	settings, err := config.TrackingSettings()
+	if err != nil {
+		log.Println("Failed to load config file for tracking.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	feed := dispatch.NewFeed(settings.SilentAfter)
+	ticker := time.NewTicker(settings.FeedInterval)
+	defer ticker.Stop()
+	first := true
+	c.Stream(func(w io.Writer) bool {
+		if !first {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-ticker.C:
+			}
+		}
+		first = false
+
+		positions, err := loadPositions(db, "ORDER BY wkr.username")
+		if err != nil {
+			log.Println("\nFailed to load Worker Positions.", err)
+			return false
+		}
+		now := time.Now().UTC()
+		changed, silent, heard := feed.Update(positions, now)
+		for _, event := range []struct {
+			name      string
+			positions []dispatch.Position
+		}{{"position", changed}, {"silent", silent}, {"heard", heard}} {
+			for _, p := range event.positions {
+				c.SSEvent(event.name, trackedPosition(p, now, settings))
+			}
+		}
+		if len(silent) > 0 {
+			fmt.Println("\n[INFO] Workers silent on a call-out:", usernames(silent))
+		}
+		if len(changed)+len(silent)+len(heard) == 0 {
+			// A comment keeps the connection open through proxies.
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		}
+		return err == nil
+	})
+}
+
+// Function to keep a worker's pings, moving their position to the latest if it is newer than where they last
+// reported being, then prune pings past the limits in config.ini. available is unchanged if it is nil.
+func recordPings(db *sql.DB, workerId int, pings []dispatch.Ping, available *bool,
+	settings config.Tracking) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	values := make([]string, len(pings))
+	var args []interface{}
+	for i, p := range pings {
+		values[i] = "(?, ?, ?, ?, ?, ?)"
+		args = append(args, workerId, p.Latitude, p.Longitude, p.AccuracyM, p.RecordedAt, now)
+	}
+	_, err = tx.Exec("INSERT INTO worker_pings (worker_id, latitude, longitude, accuracy_m, recorded_at, "+
+		"received_at) VALUES "+strings.Join(values, ", "), args...)
+	if err != nil {
+		return err
+	}
+
+	// Pings sent late, after the device was out of coverage, do not move the worker back to where they were.
+	// Workers are available when they first report where they are, until they say they are not.
+	latest := dispatch.Latest(pings)
+	_, err = tx.Exec("INSERT INTO worker_positions (worker_id, latitude, longitude, available, reported_at) "+
+		"VALUES (?, ?, ?, COALESCE(?, 1), ?) ON DUPLICATE KEY UPDATE "+
+		"latitude = IF(VALUES(reported_at) >= reported_at, VALUES(latitude), latitude), "+
+		"longitude = IF(VALUES(reported_at) >= reported_at, VALUES(longitude), longitude), "+
+		"available = COALESCE(?, available), reported_at = GREATEST(reported_at, VALUES(reported_at))",
+		workerId, latest.Latitude, latest.Longitude, available, latest.RecordedAt, available)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	prunePings(db, workerId, now, settings)
+	return nil
+}
+
+// Function to delete pings older than they are kept for, a batch at a time, and the worker's oldest pings over
+// the most kept for each worker. Pings left are pruned on later pings, failures are only logged.
+func prunePings(db *sql.DB, workerId int, now time.Time, settings config.Tracking) {
+	if _, err := db.Exec("DELETE FROM worker_pings WHERE recorded_at < ? LIMIT 1000",
+		now.Add(-settings.Rules.Retention)); err != nil {
+		log.Println("\nMySQL Error: Error Pruning Worker Pings.\n", err)
+		return
+	}
+
+	// The newest ping over the limit, it and those older are deleted.
+	var pingId int64
+	var recordedAt time.Time
+	err := db.QueryRow("SELECT ping_id, recorded_at FROM worker_pings WHERE worker_id = ? "+
+		"ORDER BY recorded_at DESC, ping_id DESC LIMIT 1 OFFSET ?", workerId, settings.MaxPings).
+		Scan(&pingId, &recordedAt)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err == nil {
+		_, err = db.Exec("DELETE FROM worker_pings WHERE worker_id = ? AND "+
+			"(recorded_at < ? OR (recorded_at = ? AND ping_id <= ?))", workerId, recordedAt, recordedAt, pingId)
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Pruning Worker Pings.\n", err)
+	}
+}
+
+// Function to make the position of a worker sent to supervisors, with whether they have gone silent.
+func trackedPosition(p dispatch.Position, now time.Time, settings config.Tracking) models.WorkerPosition {
+	position := presentPosition(p)
+	position.Silent = p.Silent(now, settings.SilentAfter)
+	return position
+}
+
+// Function to list the usernames of workers for the log.
+func usernames(positions []dispatch.Position) string {
+	names := make([]string, len(positions))
+	for i, p := range positions {
+		names[i] = p.Username
+	}
+	return strings.Join(names, ", ")
+}
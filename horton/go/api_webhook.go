@@ -0,0 +1,826 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * API Webhook
+ * Handles the endpoints the events of job reports are sent to - Webhooks, the Delivery Log & Redelivery.
+ * Events are recorded in an outbox, webhook_events with a delivery in webhook_deliveries for each endpoint, in the
+ * same transaction as the change to the report, so an event is sent if and only if its change was committed.
+ * Deliveries are sent in the background and tried again with backoff until they are delivered or fail for good.
+ * An event can be sent more than once, endpoints can tell by its id.
+ */
+
+package openapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/live"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectWebhooks is the Query shared by the functions that get webhook endpoints, columns are read by getWebhooks.
+const selectWebhooks = "SELECT webhook_id, url, events, COALESCE(customer_name, ''), COALESCE(description, ''), " +
+	"active, created_at FROM webhook_endpoints "
+
+// selectDeliveries is the JOIN Query shared by the functions that get deliveries, columns are read by
+// getDeliveries.
+const selectDeliveries = "SELECT d.delivery_id, d.webhook_id, w.url, e.event_id, e.event_type, e.job_report_id, " +
+	"d.status, d.attempts, d.next_attempt_at, d.last_status_code, COALESCE(d.last_error, ''), d.delivered_at, " +
+	"d.redelivery_of, d.created_at FROM webhook_deliveries d " +
+	"INNER JOIN webhook_endpoints w ON d.webhook_id = w.webhook_id " +
+	"INNER JOIN webhook_events e ON d.event_id = e.event_id "
+
+// The most deliveries listed at once, and how many are listed if no limit is asked for.
+const (
+	maxDeliveriesListed     = 1000
+	defaultDeliveriesListed = 100
+)
+
+// GetWebhooks
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getWebhooks.
+// If the user has a cookie and is a supervisor, get the webhook endpoints. Their secrets are not sent.
+func GetWebhooks(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Webhooks")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get webhooks") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	webhooks, err := getWebhooks(db, "ORDER BY webhook_id")
+	if err != nil {
+		log.Println("\nFailed to load Webhooks.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook
+// Works with CheckForCookie, isValidAccount, requireSupervisor, validateWebhook & checkWebhookAddress.
+// If the user has a cookie and is a supervisor, add an endpoint to send events to.
+// The secret its requests are signed with is sent back, it cannot be got again.
+func CreateWebhook(c *gin.Context) {
+	var hook models.Webhook
+
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if fields := validateWebhook(hook); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Webhook is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to add a Webhook")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "add webhooks") {
+		return
+	}
+
+	if !checkWebhookAddress(c, hook.URL) {
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.Println("Unable to generate Webhook secret.", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	active := hook.Active == nil || *hook.Active
+	res, err := db.Exec("INSERT INTO webhook_endpoints (url, secret, events, customer_name, description, active, "+
+		"created_by, created_at) VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)", hook.URL, secret,
+		strings.Join(hook.Events, ","), hook.CustomerName, hook.Description, active, wa.Id, time.Now().UTC())
+	var webhookId int64
+	if err == nil {
+		webhookId, err = res.LastInsertId()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Webhook.\n", err)
+		c.JSON(500, models.Error{Code: 500, Messages: "Unable to add Webhook"})
+		return
+	}
+
+	webhooks, err := getWebhooks(db, "WHERE webhook_id = ?", webhookId)
+	if err != nil || len(webhooks) == 0 {
+		log.Println("\nFailed to load Webhook.", err)
+		c.JSON(500, nil)
+		return
+	}
+	webhooks[0].Secret = secret
+
+	fmt.Println("\n[INFO] New Webhook:", hook.URL)
+	c.JSON(http.StatusCreated, webhooks[0])
+}
+
+// UpdateWebhook
+// Works with CheckForCookie, isValidAccount, requireSupervisor, validateWebhook & checkWebhookAddress.
+// If the user has a cookie and is a supervisor, change where an endpoint is, the events it is sent or whether it is
+// active. Its secret is kept.
+func UpdateWebhook(c *gin.Context) {
+	var hook models.Webhook
+
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: err.Error()})
+		return
+	}
+
+	if fields := validateWebhook(hook); len(fields) > 0 {
+		c.JSON(400, models.Error{Code: 400, Messages: "Webhook is invalid", Fields: fields})
+		return
+	}
+
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to update this Webhook")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "change webhooks") {
+		return
+	}
+
+	if !checkWebhookAddress(c, hook.URL) {
+		return
+	}
+
+	webhookId, _ := strconv.Atoi(c.Params.ByName("webhookId"))
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	_, err := db.Exec("UPDATE webhook_endpoints SET url = ?, events = ?, customer_name = NULLIF(?, ''), "+
+		"description = NULLIF(?, ''), active = COALESCE(?, active) WHERE webhook_id = ?", hook.URL,
+		strings.Join(hook.Events, ","), hook.CustomerName, hook.Description, hook.Active, webhookId)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Updating Webhook.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	webhooks, err := getWebhooks(db, "WHERE webhook_id = ?", webhookId)
+	if err != nil {
+		log.Println("\nFailed to load Webhook.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if len(webhooks) == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Webhook not found"})
+		return
+	}
+
+	fmt.Println("\n[INFO] Webhook", webhookId, "updated by", wa.Username)
+	c.JSON(http.StatusOK, webhooks[0])
+}
+
+// DeleteWebhook
+// Works with CheckForCookie, isValidAccount & requireSupervisor.
+// If the user has a cookie and is a supervisor, remove an endpoint with its deliveries.
+// Set active to false instead to stop sending events to it and keep its delivery log.
+func DeleteWebhook(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to delete this Webhook")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "remove webhooks") {
+		return
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM webhook_endpoints WHERE webhook_id = ?", c.Params.ByName("webhookId"))
+	var affected int64
+	if err == nil {
+		affected, err = res.RowsAffected()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Deleting Webhook.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	if affected == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Webhook not found"})
+		return
+	}
+	c.JSON(204, nil)
+}
+
+// GetWebhookDeliveries
+// Works with CheckForCookie, isValidAccount, requireSupervisor & getDeliveries.
+// If the user has a cookie and is a supervisor, get the deliveries of events newest first.
+// ?webhook=, ?status= (pending, delivered or failed), ?event= and ?report= filter them, ?limit= (100) limits them.
+func GetWebhookDeliveries(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get Webhook Deliveries")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get webhook deliveries") {
+		return
+	}
+
+	where := []string{}
+	args := []interface{}{}
+	for _, filter := range []struct{ param, column string }{
+		{"webhook", "d.webhook_id"}, {"status", "d.status"}, {"event", "e.event_type"}, {"report", "e.job_report_id"},
+	} {
+		if value := c.Query(filter.param); value != "" {
+			where, args = append(where, filter.column+" = ?"), append(args, value)
+		}
+	}
+	if status := c.Query("status"); status != "" && status != webhook.Pending && status != webhook.Delivered &&
+		status != webhook.Failed {
+		c.JSON(400, models.Error{Code: 400, Messages: "status must be pending, delivered or failed"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveriesListed)))
+	if err != nil || limit < 1 || limit > maxDeliveriesListed {
+		c.JSON(400, models.Error{Code: 400,
+			Messages: fmt.Sprintf("limit must be a number from 1 to %d", maxDeliveriesListed)})
+		return
+	}
+
+	query := ""
+	if len(where) > 0 {
+		query = "WHERE " + strings.Join(where, " AND ") + " "
+	}
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	deliveries, err := getDeliveries(db, query+"ORDER BY d.delivery_id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		log.Println("\nFailed to load Webhook Deliveries.", err)
+		c.JSON(500, nil)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery
+// Works with CheckForCookie, isValidAccount, requireSupervisor & sendDelivery.
+// If the user has a cookie and is a supervisor, get a delivery with the log of every attempt to send it.
+func GetWebhookDelivery(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to get this Webhook Delivery")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "get webhook deliveries") {
+		return
+	}
+
+	deliveryId, _ := strconv.ParseInt(c.Params.ByName("deliveryId"), 10, 64)
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	sendDelivery(c, db, http.StatusOK, deliveryId)
+}
+
+// RedeliverWebhook
+// Works with CheckForCookie, isValidAccount, requireSupervisor & sendDelivery.
+// If the user has a cookie and is a supervisor, send the event of a delivery to its endpoint again as a new
+// delivery, whether the first was delivered or not. The endpoint must be active.
+func RedeliverWebhook(c *gin.Context) {
+	if !CheckForCookie(c) {
+		log.Println("User is unauthorized to redeliver this Webhook Delivery")
+		return
+	}
+
+	if !isValidAccount(wa.Username) {
+		c.JSON(401, models.Error{Code: 401, Messages: "User is not logged in"})
+		return
+	}
+
+	if !requireSupervisor(c, "redeliver webhooks") {
+		return
+	}
+
+	deliveryId, _ := strconv.ParseInt(c.Params.ByName("deliveryId"), 10, 64)
+
+	db := config.DbConn()
+	//db := mocks.MockDbConn()
+	defer db.Close()
+
+	var webhookId, eventId int64
+	var active bool
+	err := db.QueryRow("SELECT d.webhook_id, d.event_id, w.active FROM webhook_deliveries d INNER JOIN "+
+		"webhook_endpoints w ON d.webhook_id = w.webhook_id WHERE d.delivery_id = ?", deliveryId).
+		Scan(&webhookId, &eventId, &active)
+	if err == sql.ErrNoRows {
+		c.JSON(404, models.Error{Code: 404, Messages: "Webhook Delivery not found"})
+		return
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Getting Webhook Delivery.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	if !active {
+		c.JSON(409, models.Error{Code: 409, Messages: "Webhook is not active"})
+		return
+	}
+
+	now := time.Now().UTC()
+	res, err := db.Exec("INSERT INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at, "+
+		"redelivery_of, created_at) VALUES (?, ?, ?, ?, ?, ?)", webhookId, eventId, webhook.Pending, now, deliveryId,
+		now)
+	var redeliveryId int64
+	if err == nil {
+		redeliveryId, err = res.LastInsertId()
+	}
+	if err != nil {
+		log.Println("\nMySQL Error: Error Inserting Webhook Delivery.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+
+	fmt.Println("\n[INFO] Webhook Delivery", deliveryId, "redelivered by", wa.Username)
+	sendDelivery(c, db, http.StatusAccepted, redeliveryId)
+}
+
+// StartWebhooks starts sending the deliveries in the outbox in the background, checking every poll_seconds in
+// config.ini. Returns an error if the settings cannot be loaded.
+func StartWebhooks() error {
+	settings, err := config.WebhooksSettings()
+	if err != nil {
+		return err
+	}
+
+	client := webhook.NewClient(settings.Timeout, settings.AllowPrivate)
+
+	go func() {
+		ticker := time.NewTicker(settings.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			db := config.DbConn()
+			//db := mocks.MockDbConn()
+			if err := deliverWebhooks(db, settings, client); err != nil {
+				log.Println("\nMySQL Error: Error Delivering Webhooks.\n", err)
+			}
+			db.Close()
+		}
+	}()
+	return nil
+}
+
+// Function to send the deliveries that are due, up to batch_size.
+// The batch is claimed with claimOutbox for as long as its endpoints can take to answer one after another, so a
+// delivery is only sent again by another Horton once the claim runs out if this one stops before recording it.
+func deliverWebhooks(db *sql.DB, settings config.Webhooks, client *http.Client) error {
+	now := time.Now().UTC()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	ids, err := dueDeliveries(tx, now, settings.BatchSize)
+	if err == nil && len(ids) > 0 {
+		err = claimOutbox(tx, "webhook_deliveries", "delivery_id", ids, now, settings.Timeout)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	messages, attempts, err := deliveryMessages(db, ids)
+	if err != nil {
+		return err
+	}
+	for i, m := range messages {
+		attempt := webhook.Send(client, m, time.Now().UTC())
+		if err := recordAttempt(db, m.DeliveryId, attempts[i]+1, attempt, settings.Backoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to lock the deliveries to active endpoints that are due at now, oldest first.
+func dueDeliveries(tx *sql.Tx, now time.Time, limit int) ([]int64, error) {
+	rows, err := tx.Query("SELECT d.delivery_id FROM webhook_deliveries d INNER JOIN webhook_endpoints w "+
+		"ON d.webhook_id = w.webhook_id WHERE d.status = ? AND w.active = 1 AND d.next_attempt_at <= ? "+
+		"ORDER BY d.next_attempt_at, d.delivery_id LIMIT ? FOR UPDATE", webhook.Pending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Function to make the messages of deliveries, with how many times each has been attempted.
+func deliveryMessages(db *sql.DB, ids []int64) ([]webhook.Message, []int, error) {
+	args := []interface{}{}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := db.Query("SELECT d.delivery_id, d.attempts, e.event_id, e.event_type, e.job_report_id, e.payload, "+
+		"e.created_at, w.url, w.secret FROM webhook_deliveries d "+
+		"INNER JOIN webhook_endpoints w ON d.webhook_id = w.webhook_id "+
+		"INNER JOIN webhook_events e ON d.event_id = e.event_id WHERE d.delivery_id IN ("+placeholders(len(ids))+
+		") ORDER BY d.next_attempt_at, d.delivery_id", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var messages []webhook.Message
+	var attempts []int
+	for rows.Next() {
+		var m webhook.Message
+		var event models.WebhookEvent
+		var tries int
+		var payload []byte
+		if err := rows.Scan(&m.DeliveryId, &tries, &event.EventId, &event.Type, &event.JobReportId, &payload,
+			&event.CreatedAt, &m.URL, &m.Secret); err != nil {
+			return nil, nil, err
+		}
+		event.Data = payload
+		if m.Body, err = json.Marshal(event); err != nil {
+			return nil, nil, err
+		}
+		m.Event = event.Type
+		messages, attempts = append(messages, m), append(attempts, tries)
+	}
+	return messages, attempts, rows.Err()
+}
+
+// Function to log an attempt to send a delivery and set when it is next tried, if it is.
+// attempts is how many times it has been attempted counting this one.
+func recordAttempt(db *sql.DB, deliveryId int64, attempts int, attempt webhook.Attempt, backoff webhook.Backoff) error {
+	now := time.Now().UTC()
+	status, outcome := webhook.Pending, "retry"
+	var next, deliveredAt *time.Time
+	if attempt.Delivered() {
+		status, outcome, deliveredAt = webhook.Delivered, "delivered", &now
+	} else if wait, ok := backoff.Next(attempts); ok {
+		at := now.Add(wait)
+		next = &at
+	} else {
+		status, outcome = webhook.Failed, "failed"
+	}
+	metrics.WebhookDeliveries.Inc(outcome)
+
+	lastError := attempt.Error
+	if len(lastError) > 255 {
+		lastError = lastError[:255]
+	}
+	if _, err := db.Exec("INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, response, "+
+		"duration_ms) VALUES (?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?)", deliveryId, now,
+		attempt.StatusCode, lastError, attempt.Response, attempt.Duration.Milliseconds()); err != nil {
+		return err
+	}
+	_, err := db.Exec("UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, "+
+		"last_status_code = NULLIF(?, 0), last_error = NULLIF(?, ''), delivered_at = ? WHERE delivery_id = ?",
+		status, attempts, next, attempt.StatusCode, lastError, deliveredAt, deliveryId)
+	return err
+}
+
+// Function to record the events of a report being created or updated in the outbox and as live events, within the
+// transaction that changed it. event is report.created or report.updated, report.completed is also recorded if the
+// report is now complete and was not before. An update that completes or reopens a report is also a live
+// report.status, and one that completes it sends the customer job.completed. Nothing is recorded for a report that
+// does not exist.
+func recordReportEvents(tx dbExecutor, reportId int64, event string, wasComplete bool) error {
+	report, err := outboxReport(tx, reportId)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordEvent(tx, event, report); err != nil {
+		return err
+	}
+	if err := recordLiveEvent(tx, reportId, event, "", 0); err != nil {
+		return err
+	}
+	complete := report.JobComplete == 1
+	if event == webhook.ReportUpdated && complete != wasComplete {
+		status := live.Completed
+		if !complete {
+			status = live.Reopened
+		}
+		if err := recordLiveEvent(tx, reportId, live.ReportStatus, status, 0); err != nil {
+			return err
+		}
+	}
+	if complete && !wasComplete {
+		if event == webhook.ReportUpdated {
+			if err := queueReportNotification(tx, reportId, notify.JobCompleted); err != nil {
+				return err
+			}
+		}
+		return recordEvent(tx, webhook.ReportCompleted, report)
+	}
+	return nil
+}
+
+// Function to record a report being deleted in the outbox and as a live event, within the transaction that deletes
+// it before it is deleted. Nothing is recorded for a report that does not exist.
+func recordReportDeleted(tx dbExecutor, reportId int64) error {
+	report, err := outboxReport(tx, reportId)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordLiveEvent(tx, reportId, live.ReportDeleted, "", 0); err != nil {
+		return err
+	}
+	return recordEvent(tx, webhook.ReportDeleted, report)
+}
+
+// Function to get whether a report is complete, locking it until the transaction ends.
+// Returns false for a report that does not exist.
+func reportComplete(tx dbExecutor, reportId int64) (bool, error) {
+	var complete bool
+	err := tx.QueryRow("SELECT job_report_complete FROM jobreports WHERE job_report_id = ? FOR UPDATE", reportId).
+		Scan(&complete)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return complete, err
+}
+
+// Function to get a report as it is sent to endpoints, whoever it is assigned to.
+// Returns sql.ErrNoRows if there is no such report.
+func outboxReport(tx dbExecutor, reportId int64) (models.JobReport, error) {
+	selDB, err := tx.Query("SELECT "+reportFields+" FROM jobreports jr INNER JOIN customers cust "+
+		"ON jr.job_report_id = cust.job_report_id LEFT JOIN workers wkr ON jr.worker_id = wkr.worker_id "+
+		"LEFT JOIN signatures sig ON jr.job_report_id = sig.job_report_id WHERE jr.job_report_id = ?", reportId)
+	if err != nil {
+		return models.JobReport{}, err
+	}
+	defer selDB.Close()
+
+	if !selDB.Next() {
+		if err := selDB.Err(); err != nil {
+			return models.JobReport{}, err
+		}
+		return models.JobReport{}, sql.ErrNoRows
+	}
+	report, err := scanReport(selDB)
+	if err != nil {
+		return models.JobReport{}, err
+	}
+	presentReport(&report, "")
+	return report, nil
+}
+
+// Function to record an event of a report with a delivery to each active endpoint sent it.
+// The event is not recorded if no endpoint is sent it.
+func recordEvent(tx dbExecutor, event string, report models.JobReport) error {
+	return recordOutboxEvent(tx, event, int64(report.JobReportId), report.CustomerName, report)
+}
+
+// Function to record an event in the outbox with data as its payload, for the endpoints active now that are sent
+// the event and the events of the customer. Nothing is recorded if no endpoint is sent it.
+func recordOutboxEvent(tx dbExecutor, event string, reportId int64, customerName string, data interface{}) error {
+	match := "FROM webhook_endpoints WHERE active = 1 AND FIND_IN_SET(?, events) > 0 " +
+		"AND (customer_name IS NULL OR customer_name = ?)"
+	var endpoints int
+	if err := tx.QueryRow("SELECT COUNT(*) "+match, event, customerName).Scan(&endpoints); err != nil {
+		return err
+	}
+	if endpoints == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := tx.Exec("INSERT INTO webhook_events (event_type, job_report_id, payload, created_at) "+
+		"VALUES (?, ?, ?, ?)", event, reportId, payload, now)
+	if err != nil {
+		return err
+	}
+	eventId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at, created_at) "+
+		"SELECT webhook_id, ?, ?, ?, ? "+match, eventId, webhook.Pending, now, now, event, customerName)
+	return err
+}
+
+// Function to check an endpoint's URL is not on this host or a private network, unless allowed in config.ini.
+// Sends 400 if it is, it is checked once the user is known as its host is looked up.
+func checkWebhookAddress(c *gin.Context, rawURL string) bool {
+	settings, err := config.WebhooksSettings()
+	if err != nil {
+		log.Println("\nUnable to load Webhook settings.", err)
+		c.JSON(500, nil)
+		return false
+	}
+	if settings.AllowPrivate {
+		return true
+	}
+	if err := webhook.CheckURL(rawURL); err != nil {
+		c.JSON(400, models.Error{Code: 400, Messages: "Webhook is invalid",
+			Fields: []models.FieldError{{Field: "url", Message: "url must be public, " + err.Error()}}})
+		return false
+	}
+	return true
+}
+
+// Function to validate a webhook endpoint, its URL must be http or https.
+func validateWebhook(hook models.Webhook) []models.FieldError {
+	var fields []models.FieldError
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		len(hook.URL) > 2048 {
+		fields = append(fields, models.FieldError{Field: "url",
+			Message: "url must be an http or https URL of at most 2048 characters"})
+	}
+	if err := webhook.CheckEvents(hook.Events); err != nil {
+		fields = append(fields, models.FieldError{Field: "events", Message: err.Error()})
+	}
+	if len(hook.CustomerName) > 50 {
+		fields = append(fields, models.FieldError{Field: "customerName",
+			Message: "customer name must be at most 50 characters"})
+	}
+	if len(hook.Description) > 255 {
+		fields = append(fields, models.FieldError{Field: "description",
+			Message: "description must be at most 255 characters"})
+	}
+	return fields
+}
+
+// Function to get the webhook endpoints of a selectWebhooks Query.
+func getWebhooks(db dbExecutor, where string, args ...interface{}) ([]models.Webhook, error) {
+	selDB, err := db.Query(selectWebhooks+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	webhooks := []models.Webhook{}
+	for selDB.Next() {
+		var hook models.Webhook
+		var events string
+		var active bool
+		if err := selDB.Scan(&hook.WebhookId, &hook.URL, &events, &hook.CustomerName, &hook.Description, &active,
+			&hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hook.Events = strings.Split(events, ",")
+		hook.Active = &active
+		webhooks = append(webhooks, hook)
+	}
+	return webhooks, selDB.Err()
+}
+
+// Function to get the deliveries of a selectDeliveries Query.
+func getDeliveries(db dbExecutor, where string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	selDB, err := db.Query(selectDeliveries+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer selDB.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for selDB.Next() {
+		var d models.WebhookDelivery
+		if err := selDB.Scan(&d.DeliveryId, &d.WebhookId, &d.URL, &d.EventId, &d.Event, &d.JobReportId, &d.Status,
+			&d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.RedeliveryOf,
+			&d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, selDB.Err()
+}
+
+// Function to send a delivery with the log of its attempts as it is now in the database.
+func sendDelivery(c *gin.Context, db *sql.DB, status int, deliveryId int64) {
+	deliveries, err := getDeliveries(db, "WHERE d.delivery_id = ?", deliveryId)
+	if err != nil {
+		log.Println("\nFailed to load Webhook Delivery.", err)
+		c.JSON(500, nil)
+		return
+	}
+	if len(deliveries) == 0 {
+		c.JSON(404, models.Error{Code: 404, Messages: "Webhook Delivery not found"})
+		return
+	}
+	delivery := deliveries[0]
+
+	rows, err := db.Query("SELECT attempted_at, status_code, COALESCE(error, ''), COALESCE(response, ''), "+
+		"duration_ms FROM webhook_attempts WHERE delivery_id = ? ORDER BY attempt_id", deliveryId)
+	if err != nil {
+		log.Println("\nMySQL Error: Error Getting Webhook Attempts.\n", err)
+		c.JSON(500, nil)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.WebhookAttempt
+		if err := rows.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &a.Response, &a.DurationMs); err != nil {
+			log.Println("\nMySQL Error: Error Getting Webhook Attempts.\n", err)
+			c.JSON(500, nil)
+			return
+		}
+		delivery.Log = append(delivery.Log, a)
+	}
+	c.JSON(status, delivery)
+}
+
+// Function to claim the rows of an outbox table by their ids until a batch of them has been sent, by moving when
+// they are next attempted past the time the batch can take. The rows are sent one after another and each may take
+// up to timeout, so the claim covers them all with a minute to spare.
+func claimOutbox(tx *sql.Tx, table, idColumn string, ids []int64, now time.Time, timeout time.Duration) error {
+	args := []interface{}{now.Add(time.Duration(len(ids))*timeout + time.Minute)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	_, err := tx.Exec("UPDATE "+table+" SET next_attempt_at = ? WHERE "+idColumn+" IN ("+placeholders(len(ids))+")",
+		args...)
+	return err
+}
+
+// Function to make the placeholders of an IN list of n values e.g. "?, ?, ?".
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,162 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Billing
+ * Works out the lines and totals of invoices from labour hours and parts.
+ * Money is kept in cents so totals add up exactly, VAT is worked out and rounded on each line.
+ * Labour is charged at the standard, overtime or warranty rate, warranty jobs are billed to the warranty provider.
+ *
+ * References
+ * https://www.revenue.ie/en/vat/vat-rates/search-vat-rates/current-vat-rates.aspx
+ */
+
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Kinds of invoice line.
+const (
+	Labour         = "labour"
+	Overtime       = "overtime"
+	WarrantyLabour = "warranty_labour"
+	Part           = "part"
+	Other          = "other"
+)
+
+// Statuses of an invoice.
+const (
+	Draft  = "draft"
+	Issued = "issued"
+	Paid   = "paid"
+	Void   = "void"
+)
+
+// Who an invoice is billed to.
+const (
+	Customer         = "customer"
+	WarrantyProvider = "warranty_provider"
+)
+
+// Rates are the labour rates in cents per hour and VAT rates as percentages.
+type Rates struct {
+	Standard int64
+	Overtime int64
+	Warranty int64
+	// LabourVAT is the VAT rate of labour e.g. 13.5, PartsVAT the rate of parts and other lines e.g. 23.
+	LabourVAT float64
+	PartsVAT  float64
+	Currency  string
+}
+
+// Which statuses an invoice can move to from each status.
+var transitions = map[string][]string{
+	Draft:  {Issued, Void},
+	Issued: {Paid, Void},
+	Paid:   {Issued}, // A refunded payment puts a paid invoice back to issued.
+	Void:   {},
+}
+
+// ErrInvalidLine is returned for lines with a negative quantity or price, or no description.
+var ErrInvalidLine = errors.New("invoice lines need a description and a quantity and price of 0 or more")
+
+// CanTransition reports whether an invoice can move from status from to status to.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// LabourLines returns the labour lines of a job, hours at the standard rate and overtimeHours at the overtime rate.
+// For warranty jobs every hour is charged at the warranty rate.
+func LabourLines(hours, overtimeHours float64, warranty bool, rates Rates) []models.InvoiceLine {
+	if warranty {
+		if hours+overtimeHours <= 0 {
+			return nil
+		}
+		return []models.InvoiceLine{{Kind: WarrantyLabour, Description: "Labour (warranty)",
+			Quantity: hours + overtimeHours, UnitPriceCents: rates.Warranty, VatRate: Rate(rates.LabourVAT)}}
+	}
+
+	var lines []models.InvoiceLine
+	if hours > 0 {
+		lines = append(lines, models.InvoiceLine{Kind: Labour, Description: "Labour",
+			Quantity: hours, UnitPriceCents: rates.Standard, VatRate: Rate(rates.LabourVAT)})
+	}
+	if overtimeHours > 0 {
+		lines = append(lines, models.InvoiceLine{Kind: Overtime, Description: "Labour (overtime)",
+			Quantity: overtimeHours, UnitPriceCents: rates.Overtime, VatRate: Rate(rates.LabourVAT)})
+	}
+	return lines
+}
+
+// Rate returns a VAT rate to set on a line.
+func Rate(rate float64) *float64 {
+	return &rate
+}
+
+// IsLabour reports whether a kind of line is labour, worked out from a report's hours rather than sent by the user.
+func IsLabour(kind string) bool {
+	return kind == Labour || kind == Overtime || kind == WarrantyLabour
+}
+
+// Calculate works out the net and VAT of each line and returns the invoice's subtotal, VAT and total.
+// Lines without a VAT rate get the rate of their kind, lines with a rate of 0 are zero rated.
+func Calculate(lines []models.InvoiceLine, rates Rates) (subtotal, vat, total int64, err error) {
+	for i := range lines {
+		line := &lines[i]
+		if line.Description == "" || line.Quantity < 0 || line.UnitPriceCents < 0 ||
+			(line.VatRate != nil && *line.VatRate < 0) {
+			return 0, 0, 0, ErrInvalidLine
+		}
+		if line.Kind == "" {
+			line.Kind = Other
+		}
+		if !isKind(line.Kind) {
+			return 0, 0, 0, fmt.Errorf("%q is not a kind of invoice line", line.Kind)
+		}
+		if line.VatRate == nil {
+			line.VatRate = Rate(rateOf(line.Kind, rates))
+		}
+
+		line.NetCents = round(line.Quantity * float64(line.UnitPriceCents))
+		line.VatCents = round(float64(line.NetCents) * *line.VatRate / 100)
+		subtotal += line.NetCents
+		vat += line.VatCents
+	}
+	return subtotal, vat, subtotal + vat, nil
+}
+
+func isKind(kind string) bool {
+	switch kind {
+	case Labour, Overtime, WarrantyLabour, Part, Other:
+		return true
+	}
+	return false
+}
+
+func rateOf(kind string, rates Rates) float64 {
+	if IsLabour(kind) {
+		return rates.LabourVAT
+	}
+	return rates.PartsVAT
+}
+
+// Function to round to the nearest cent, halves away from zero.
+func round(cents float64) int64 {
+	return int64(math.Round(cents))
+}
+
+// FormatNumber returns an invoice or credit note number from its prefix and place in the sequence e.g. "INV-000042".
+func FormatNumber(prefix string, n int64) string {
+	return fmt.Sprintf("%s%06d", prefix, n)
+}
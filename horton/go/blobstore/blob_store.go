@@ -0,0 +1,29 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Blob Store
+ * Stores files attached to reports (photos, diagnostic printouts, signed forms).
+ * Files are kept on the local filesystem by default or in an S3 compatible bucket (AWS S3, MinIO).
+ */
+
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is where files are kept, each file is stored under a key e.g. "jobReports/121/photo.jpg".
+type Store interface {
+	// Put stores size bytes from r under key, replacing any file already stored under it.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key, the caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key, deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -17,17 +17,20 @@ package openapi
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
 	"github.com/gin-gonic/gin"
 	"gopkg.in/ini.v1"
 	"io"
 	"log"
 	"net/http"
+	"time"
 )
 
 // GetCarApiData
 // Function to get Vehicle Data from Back4App (3rd Party API) and send to Client.
 // Load config file for API access, set up the request, set the auth headers from config file,
 // do the request and then send the data from Back4App to client.
+// How long Back4App takes to answer and requests that fail are recorded for /metrics.
 func GetCarApiData(c *gin.Context) {
 	// Load config file.
 	cfg, err := ini.Load("go/config/config.ini")
@@ -61,27 +64,37 @@ func GetCarApiData(c *gin.Context) {
 
 	// Do GET request - get data from Back4App.
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.Back4AppDuration.Observe(time.Since(start).Seconds())
 	if err != nil {
+		metrics.Back4AppFailures.Inc("request")
 		log.Println("Unable to do request", err)
 		c.JSON(500, nil)
+		return
+	}
+	// Close the response body.
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Println("Failed to close body from request.")
+		}
+	}(resp.Body)
+	if resp.StatusCode >= 300 {
+		metrics.Back4AppFailures.Inc("status")
+		log.Println("Back4App responded with", resp.Status)
 	}
 
 	// Decode JSON from response body.
 	var data map[string]interface{}
 	err = json.NewDecoder(resp.Body).Decode(&data)
 	if err != nil {
+		metrics.Back4AppFailures.Inc("decode")
 		log.Println("Unable to decode", err)
 		c.JSON(500, nil)
+		return
 	}
 
 	// Send data to client.
 	c.JSON(http.StatusOK, data)
-	// Close the response body.
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Println("Failed to close body from request.")
-		}
-	}(resp.Body)
 }
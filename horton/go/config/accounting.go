@@ -0,0 +1,43 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Accounting
+ * Loads the nominal and tax codes of each accounting package from its section of config.ini.
+ */
+
+package config
+
+import (
+	"strings"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/accounting"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/payments"
+	"gopkg.in/ini.v1"
+)
+
+// AccountingCodes use the config.ini file to get the codes amounts are posted to in the package of format.
+func AccountingCodes(format string) (accounting.Codes, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return accounting.Codes{}, err
+	}
+	section := cfg.Section(format)
+
+	codes := accounting.Codes{Sales: map[string]string{}, Bank: map[string]string{}, Tax: map[string]string{},
+		Debtors: section.Key("debtors").String(), NoTax: section.Key("no_tax").String()}
+	for _, kind := range []string{billing.Labour, billing.Overtime, billing.WarrantyLabour, billing.Part, billing.Other} {
+		codes.Sales[kind] = section.Key(kind).String()
+	}
+	for _, method := range []string{payments.Card, payments.Cash, payments.BankTransfer} {
+		codes.Bank[method] = section.Key(method).String()
+	}
+	for _, key := range section.Keys() {
+		if rate := strings.TrimPrefix(key.Name(), "tax_"); rate != key.Name() {
+			codes.Tax[rate] = key.String()
+		}
+	}
+	return codes, nil
+}
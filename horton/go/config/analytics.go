@@ -0,0 +1,52 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Analytics
+ * Loads the date range analytics cover by default and how long their results are cached from config.ini.
+ */
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/analytics"
+	"gopkg.in/ini.v1"
+)
+
+// Analytics is the analytics settings in config.ini.
+type Analytics struct {
+	// DefaultMonths is how many months back analytics cover when no date range is asked for.
+	DefaultMonths int
+
+	// Cache keeps the results of analytics, shared by every request.
+	Cache *analytics.Cache
+}
+
+// The cache is shared by every request, it is made with the settings in config.ini when first used.
+var (
+	analyticsCacheMade sync.Once
+	analyticsCache     *analytics.Cache
+)
+
+// AnalyticsSettings use the config.ini file to get the settings of analytics.
+func AnalyticsSettings() (Analytics, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Analytics{}, err
+	}
+	section := cfg.Section("analytics")
+
+	analyticsCacheMade.Do(func() {
+		analyticsCache = analytics.NewCache(time.Duration(section.Key("cache_seconds").MustInt(60))*time.Second,
+			section.Key("cache_entries").MustInt(200))
+	})
+
+	return Analytics{
+		DefaultMonths: section.Key("default_months").MustInt(12),
+		Cache:         analyticsCache,
+	}, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Appointments
+ * Loads the opening hours and bays of the workshop and the address of calendar feeds from config.ini.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/schedule"
+	"gopkg.in/ini.v1"
+)
+
+var weekdays = map[string]time.Weekday{"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday}
+
+// AppointmentSettings use the config.ini file to get the opening hours and bays appointments are booked in,
+// and the public address of Horton that calendar feeds start with.
+func AppointmentSettings() (schedule.Settings, string, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return schedule.Settings{}, "", err
+	}
+	section := cfg.Section("appointments")
+
+	settings := schedule.Settings{
+		Bays: section.Key("bays").MustInt(1),
+		Slot: time.Duration(section.Key("slot_minutes").MustInt(30)) * time.Minute,
+		Days: map[time.Weekday]bool{},
+	}
+	if settings.Bays < 1 || settings.Slot <= 0 {
+		return settings, "", fmt.Errorf("bays and slot_minutes must be more than 0")
+	}
+	if settings.Opens, err = timeOfDay(section.Key("opens").MustString("08:00")); err != nil {
+		return settings, "", err
+	}
+	if settings.Closes, err = timeOfDay(section.Key("closes").MustString("18:00")); err != nil {
+		return settings, "", err
+	}
+	for _, day := range section.Key("days").Strings(",") {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return settings, "", fmt.Errorf("unknown day %q in days", day)
+		}
+		settings.Days[weekday] = true
+	}
+	if settings.Location, err = time.LoadLocation(section.Key("timezone").MustString("Europe/Dublin")); err != nil {
+		return settings, "", err
+	}
+
+	publicUrl := strings.TrimSuffix(section.Key("public_url").MustString("http://localhost:8080"), "/")
+	return settings, publicUrl, nil
+}
+
+// Function to read a time of day such as 08:30 as the time since midnight.
+func timeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("times of day must be like 08:30, not %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,52 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Blob Store Connection
+ * Sets up the Blob Store for attachments with the details in config.ini.
+ * store = local keeps files in dir, store = s3 keeps them in the bucket in the [s3] section.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/blobstore"
+	"gopkg.in/ini.v1"
+)
+
+// BlobStore use the config.ini file to set up the store for attachments.
+func BlobStore() (blobstore.Store, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return nil, err
+	}
+	attachments := cfg.Section("attachments")
+
+	switch store := attachments.Key("store").MustString("local"); store {
+	case "local":
+		return blobstore.NewLocalStore(attachments.Key("dir").MustString("attachments"))
+	case "s3":
+		s3 := cfg.Section("s3")
+		return blobstore.NewS3Store(s3.Key("endpoint").String(), s3.Key("bucket").String(),
+			s3.Key("region").String(), s3.Key("access_key").String(), s3.Key("secret_key").String())
+	default:
+		return nil, fmt.Errorf("unknown attachment store %q", store)
+	}
+}
+
+// AttachmentRules use the config.ini file to get the largest attachment allowed in bytes
+// and the MIME types attachments can be.
+func AttachmentRules() (int64, []string, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return 0, nil, err
+	}
+	attachments := cfg.Section("attachments")
+
+	maxSize := attachments.Key("max_size_mb").MustInt64(10) << 20
+	allowedTypes := attachments.Key("allowed_types").Strings(",")
+	return maxSize, allowedTypes, nil
+}
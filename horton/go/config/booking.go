@@ -0,0 +1,76 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Booking
+ * Loads how far ahead customers can book online, how long they have to confirm and how often they can
+ * use the booking endpoints from config.ini.
+ */
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/ratelimit"
+	"gopkg.in/ini.v1"
+)
+
+// OnlineBooking is the online booking settings in config.ini.
+type OnlineBooking struct {
+	// Bookings must start MinNotice from now or later and no more than MaxAhead from now.
+	MinNotice time.Duration
+	MaxAhead  time.Duration
+
+	// MaxMinutes is the longest appointment customers can book.
+	MaxMinutes int
+
+	// ConfirmWithin is how long customers have to confirm a booking.
+	ConfirmWithin time.Duration
+
+	// PublicUrl is the address of Horton that confirmation links start with.
+	PublicUrl string
+
+	// Requests limits the requests to the booking endpoints by each IP address,
+	// Bookings limits the bookings made by each IP address.
+	Requests *ratelimit.Limiter
+	Bookings *ratelimit.Limiter
+
+	// TrustedProxies are the proxies in front of Horton whose X-Forwarded-For is read for the client's address.
+	TrustedProxies []string
+}
+
+// The limiters are shared by every request, they are made with the limits in config.ini when first used.
+var (
+	bookingLimits   sync.Once
+	requestLimiter  *ratelimit.Limiter
+	bookingsLimiter *ratelimit.Limiter
+)
+
+// BookingSettings use the config.ini file to get the settings of online booking.
+func BookingSettings() (OnlineBooking, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return OnlineBooking{}, err
+	}
+	section := cfg.Section("booking")
+
+	bookingLimits.Do(func() {
+		requestLimiter = ratelimit.New(section.Key("requests_per_minute").MustInt(30), time.Minute)
+		bookingsLimiter = ratelimit.New(section.Key("bookings_per_hour").MustInt(5), time.Hour)
+	})
+
+	_, publicUrl, err := AppointmentSettings()
+	return OnlineBooking{
+		MinNotice:      time.Duration(section.Key("min_notice_hours").MustInt(2)) * time.Hour,
+		MaxAhead:       time.Duration(section.Key("max_days_ahead").MustInt(60)) * 24 * time.Hour,
+		MaxMinutes:     section.Key("max_minutes").MustInt(240),
+		ConfirmWithin:  time.Duration(section.Key("confirm_within_minutes").MustInt(60)) * time.Minute,
+		PublicUrl:      publicUrl,
+		Requests:       requestLimiter,
+		Bookings:       bookingsLimiter,
+		TrustedProxies: section.Key("trusted_proxies").Strings(","),
+	}, err
+}
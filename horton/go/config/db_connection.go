@@ -14,12 +14,18 @@ package config
 import (
 	"database/sql"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
+	"github.com/go-sql-driver/mysql"
 	"gopkg.in/ini.v1"
 	"log"
 	"os"
 )
 
+// The MySQL driver wrapped to record the latency and errors of queries, see ./go/metrics/database.go
+func init() {
+	sql.Register("mysql-metrics", metrics.WrapDriver(mysql.MySQLDriver{}))
+}
+
 // DbConn use the config.ini file to log into MySQL for database access.
 func DbConn() (db *sql.DB) {
 	// Load config file.
@@ -35,7 +41,7 @@ func DbConn() (db *sql.DB) {
 	password := cfg.Section("database").Key("password")
 
 	// Log into MySQL driver with details from config file.
-	db, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:3306)/%s", username, password, ip, dbName))
+	db, err = sql.Open("mysql-metrics", fmt.Sprintf("%s:%s@tcp(%s:3306)/%s?parseTime=true", username, password, ip, dbName))
 
 	if err != nil {
 		panic(err.Error())
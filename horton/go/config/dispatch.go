@@ -0,0 +1,82 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Dispatch
+ * Loads how workers are suggested for breakdowns from config.ini, and the road graph distances by road are
+ * worked out on.
+ */
+
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+	"gopkg.in/ini.v1"
+)
+
+// Dispatch is the breakdown dispatch settings in config.ini.
+type Dispatch struct {
+	// Distance is how far workers are from breakdowns by default, Road or StraightLine.
+	Distance string
+
+	// Road works out distances by road, Straight as the crow flies.
+	Road     dispatch.Distancer
+	Straight dispatch.Distancer
+
+	// StaleAfter is how old a worker's position can be for them to be suggested.
+	StaleAfter time.Duration
+
+	// Suggestions is how many workers are suggested for a breakdown.
+	Suggestions int
+}
+
+// Ways of working out how far workers are from breakdowns.
+const (
+	Road         = "road"
+	StraightLine = "straight"
+)
+
+// The road graph is shared by every request, it is read when first used.
+var (
+	roadsRead sync.Once
+	roads     *dispatch.RoadGraph
+	roadsErr  error
+)
+
+// DispatchSettings use the config.ini file to get the settings of breakdown dispatch.
+func DispatchSettings() (Dispatch, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Dispatch{}, err
+	}
+	section := cfg.Section("dispatch")
+
+	settings := Dispatch{
+		Distance:    section.Key("distance").In(Road, []string{Road, StraightLine}),
+		Straight:    dispatch.StraightLine{},
+		StaleAfter:  time.Duration(section.Key("position_stale_minutes").MustInt(30)) * time.Minute,
+		Suggestions: section.Key("suggestions").MustInt(5),
+	}
+
+	roadsRead.Do(func() {
+		places, err := Gazetteer()
+		if err != nil {
+			roadsErr = err
+			return
+		}
+		file, err := os.Open(section.Key("roads").MustString("go/dispatch/ie_roads.csv"))
+		if err != nil {
+			roadsErr = err
+			return
+		}
+		defer file.Close()
+		roads, roadsErr = dispatch.ReadRoads(file, places)
+	})
+	settings.Road = roads
+	return settings, roadsErr
+}
@@ -0,0 +1,30 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Estimates
+ * Loads how long estimates are valid for and the address their approval links are sent with from config.ini.
+ */
+
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// EstimateSettings use the config.ini file to get the days an estimate is valid for once it is sent,
+// and the public address of Horton that approval links start with.
+func EstimateSettings() (int, string, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return 0, "", err
+	}
+	estimates := cfg.Section("estimates")
+
+	validityDays := estimates.Key("validity_days").MustInt(30)
+	publicUrl := strings.TrimSuffix(estimates.Key("public_url").MustString("http://localhost:8080"), "/")
+	return validityDays, publicUrl, nil
+}
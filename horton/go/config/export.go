@@ -0,0 +1,21 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Export
+ * Loads the default columns of report exports in config.ini.
+ */
+
+package config
+
+import "gopkg.in/ini.v1"
+
+// ExportColumns use the config.ini file to get the columns exported when the client does not choose them.
+func ExportColumns() ([]string, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Section("export").Key("columns").Strings(","), nil
+}
@@ -0,0 +1,33 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Garage
+ * Loads the garage's details in config.ini for printed documents.
+ */
+
+package config
+
+import (
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"gopkg.in/ini.v1"
+)
+
+// Garage use the config.ini file to get the garage's details.
+func Garage() (models.Garage, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return models.Garage{}, err
+	}
+	garage := cfg.Section("garage")
+
+	return models.Garage{
+		Name:      garage.Key("name").MustString("Repota"),
+		Address:   garage.Key("address").String(),
+		Phone:     garage.Key("phone").String(),
+		Email:     garage.Key("email").String(),
+		VatNumber: garage.Key("vat_number").String(),
+		LogoPath:  garage.Key("logo").String(),
+	}, nil
+}
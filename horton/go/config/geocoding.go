@@ -0,0 +1,57 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Geocoding
+ * Loads the gazetteer vehicle locations are found in, from the file in config.ini.
+ */
+
+package config
+
+import (
+	"os"
+	"sync"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+	"gopkg.in/ini.v1"
+)
+
+// The gazetteer is shared by every request, it is read when first used.
+var (
+	gazetteerRead sync.Once
+	places        *gazetteer.Gazetteer
+	placesErr     error
+)
+
+// Gazetteer use the config.ini file to read the gazetteer of places vehicle locations are found in.
+func Gazetteer() (*gazetteer.Gazetteer, error) {
+	gazetteerRead.Do(func() {
+		// Load config file.
+		cfg, err := ini.Load("go/config/config.ini")
+		if err != nil {
+			placesErr = err
+			return
+		}
+
+		file, err := os.Open(cfg.Section("geocoding").Key("gazetteer").MustString("go/gazetteer/ie_places.csv"))
+		if err != nil {
+			placesErr = err
+			return
+		}
+		defer file.Close()
+		places, placesErr = gazetteer.Read(file)
+	})
+	return places, placesErr
+}
+
+// DistanceRules use the config.ini file to get the distance in km reports are found within of a place
+// when none is asked for, and the furthest that can be asked for.
+func DistanceRules() (float64, float64, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return 0, 0, err
+	}
+	geocoding := cfg.Section("geocoding")
+	return geocoding.Key("default_within_km").MustFloat64(20), geocoding.Key("max_within_km").MustFloat64(500), nil
+}
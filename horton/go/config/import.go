@@ -0,0 +1,24 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Import
+ * Loads the settings for importing reports from CSV in config.ini.
+ */
+
+package config
+
+import "gopkg.in/ini.v1"
+
+// ImportRules use the config.ini file to get the number of reports saved in each transaction of an import
+// and the largest CSV file that can be uploaded in bytes.
+func ImportRules() (int, int64, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return 0, 0, err
+	}
+	section := cfg.Section("import")
+
+	return section.Key("batch_size").MustInt(100), section.Key("max_size_mb").MustInt64(20) << 20, nil
+}
@@ -0,0 +1,59 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Invoicing
+ * Loads the labour rates, VAT rates and invoice numbering in config.ini.
+ */
+
+package config
+
+import (
+	"math"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"gopkg.in/ini.v1"
+)
+
+// Invoicing is the invoicing settings in config.ini.
+type Invoicing struct {
+	Rates            billing.Rates
+	InvoicePrefix    string
+	CreditNotePrefix string
+	PaymentTermsDays int
+	// WarrantyProvider is who warranty jobs are invoiced to.
+	WarrantyProviderName    string
+	WarrantyProviderAddress string
+}
+
+// InvoiceSettings use the config.ini file to get the rates and numbering of invoices.
+func InvoiceSettings() (Invoicing, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Invoicing{}, err
+	}
+	invoicing := cfg.Section("invoicing")
+	provider := cfg.Section("warranty_provider")
+
+	return Invoicing{
+		Rates: billing.Rates{
+			Standard:  cents(invoicing.Key("standard_rate").MustFloat64(0)),
+			Overtime:  cents(invoicing.Key("overtime_rate").MustFloat64(0)),
+			Warranty:  cents(invoicing.Key("warranty_rate").MustFloat64(0)),
+			LabourVAT: invoicing.Key("labour_vat").MustFloat64(13.5),
+			PartsVAT:  invoicing.Key("parts_vat").MustFloat64(23),
+			Currency:  invoicing.Key("currency").MustString("EUR"),
+		},
+		InvoicePrefix:           invoicing.Key("invoice_prefix").MustString("INV-"),
+		CreditNotePrefix:        invoicing.Key("credit_note_prefix").MustString("CN-"),
+		PaymentTermsDays:        invoicing.Key("payment_terms_days").MustInt(30),
+		WarrantyProviderName:    provider.Key("name").MustString("Warranty Provider"),
+		WarrantyProviderAddress: provider.Key("address").String(),
+	}, nil
+}
+
+// Function to convert an amount in euro (or pounds) to cents.
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,55 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Live
+ * Loads how often live events of job reports are checked for and how long they are kept for replay from config.ini.
+ */
+
+package config
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// Live is the live events settings in config.ini.
+type Live struct {
+	// PollInterval is how often each client's new events are checked for.
+	PollInterval time.Duration
+
+	// KeepAlive is how long a client can go without being sent anything before it is sent a keep-alive.
+	KeepAlive time.Duration
+
+	// Settle is how long an event can take to be committed, recent events are read again until it has passed.
+	Settle time.Duration
+
+	// Replay is how long events are kept for clients that reconnect.
+	Replay time.Duration
+
+	// Retry is how long clients are told to wait before reconnecting.
+	Retry time.Duration
+
+	// Origins are the origins of the pages allowed to connect over a WebSocket, like http://localhost:8080.
+	Origins []string
+}
+
+// LiveSettings use the config.ini file to get the settings of live events.
+func LiveSettings() (Live, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Live{}, err
+	}
+	live := cfg.Section("live")
+
+	return Live{
+		PollInterval: time.Duration(live.Key("poll_seconds").MustInt(2)) * time.Second,
+		KeepAlive:    time.Duration(live.Key("keep_alive_seconds").MustInt(15)) * time.Second,
+		Settle:       time.Duration(live.Key("settle_seconds").MustInt(10)) * time.Second,
+		Replay:       time.Duration(live.Key("replay_hours").MustInt(24)) * time.Hour,
+		Retry:        time.Duration(live.Key("retry_seconds").MustInt(3)) * time.Second,
+		Origins:      live.Key("allowed_origins").Strings(","),
+	}, nil
+}
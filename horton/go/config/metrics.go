@@ -0,0 +1,41 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Metrics
+ * Loads who can read the metrics of Horton at /metrics from config.ini.
+ */
+
+package config
+
+import "gopkg.in/ini.v1"
+
+// Metrics is the metrics settings in config.ini.
+type Metrics struct {
+	// Token is sent by Prometheus as "Authorization: Bearer <token>" to read /metrics on the API's port,
+	// empty if metrics are not served there.
+	Token string
+
+	// InternalPort is the port /metrics is also served on without a token, empty if it is not.
+	InternalPort string
+
+	// InternalAddress is the address the internal port is listened on, 127.0.0.1 by default so it is only reachable
+	// from the host unless the monitoring network's interface is set.
+	InternalAddress string
+}
+
+// MetricsSettings use the config.ini file to get the settings of metrics.
+func MetricsSettings() (Metrics, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Metrics{}, err
+	}
+	section := cfg.Section("metrics")
+
+	return Metrics{
+		Token:           section.Key("token").String(),
+		InternalPort:    section.Key("internal_port").String(),
+		InternalAddress: section.Key("internal_address").MustString("127.0.0.1"),
+	}, nil
+}
@@ -0,0 +1,88 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Notifications
+ * Loads where customers' messages are sent from, their templates and how failed messages are retried from config.ini.
+ */
+
+package config
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"gopkg.in/ini.v1"
+)
+
+// Notifications is the customer notifications settings in config.ini.
+type Notifications struct {
+	// Templates is the directory of the message templates.
+	Templates string
+
+	// PublicUrl is what the links customers opt out at start with.
+	PublicUrl string
+
+	// DefaultChannels are the channels customers without preferences are sent messages by.
+	DefaultChannels []string
+
+	// SMTP is the server email is sent through, email is not sent if its Addr is empty.
+	SMTP notify.SMTP
+
+	// SMS is the gateway text messages are sent through, they are not sent if its URL is empty.
+	SMS notify.SMSGateway
+
+	// PollInterval is how often messages are checked for, up to BatchSize at a time.
+	PollInterval time.Duration
+	BatchSize    int
+
+	// Timeout is how long the SMTP server or SMS gateway has to answer.
+	Timeout time.Duration
+
+	// Backoff is how long to wait before trying failed messages again.
+	Backoff webhook.Backoff
+}
+
+// NotificationsSettings use the config.ini file to get the settings of customer notifications.
+// SMS.Client is left for the caller to set.
+func NotificationsSettings() (Notifications, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Notifications{}, err
+	}
+	section := cfg.Section("notifications")
+
+	timeout := time.Duration(section.Key("timeout_seconds").MustInt(10)) * time.Second
+	settings := Notifications{
+		Templates:       section.Key("templates").MustString("go/config/notifications"),
+		PublicUrl:       strings.TrimSuffix(section.Key("public_url").MustString("http://localhost:8080"), "/"),
+		DefaultChannels: section.Key("default_channels").Strings(","),
+		SMTP: notify.SMTP{
+			Username: section.Key("smtp_username").String(),
+			Password: section.Key("smtp_password").String(),
+			From:     section.Key("smtp_from").String(),
+			Timeout:  timeout,
+		},
+		SMS: notify.SMSGateway{
+			URL:   section.Key("sms_url").String(),
+			Token: section.Key("sms_token").String(),
+			From:  section.Key("sms_from").String(),
+		},
+		PollInterval: time.Duration(section.Key("poll_seconds").MustInt(10)) * time.Second,
+		BatchSize:    section.Key("batch_size").MustInt(50),
+		Timeout:      timeout,
+		Backoff: webhook.Backoff{
+			Base:        time.Duration(section.Key("retry_base_seconds").MustInt(60)) * time.Second,
+			Max:         time.Duration(section.Key("retry_max_minutes").MustInt(360)) * time.Minute,
+			MaxAttempts: section.Key("max_attempts").MustInt(8),
+		},
+	}
+	if host := strings.TrimSpace(section.Key("smtp_host").String()); host != "" {
+		settings.SMTP.Addr = net.JoinHostPort(host, section.Key("smtp_port").MustString("25"))
+	}
+	return settings, notify.CheckChannels(settings.DefaultChannels)
+}
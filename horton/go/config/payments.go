@@ -0,0 +1,35 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Payments
+ * Sets up the payment provider for card payments with the details in config.ini.
+ * provider = fake takes card payments in memory without charging anyone.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/payments"
+	"gopkg.in/ini.v1"
+)
+
+// The FakeProvider is shared by every request so payments it takes can be refunded.
+var fakeProvider = payments.NewFakeProvider()
+
+// PaymentProvider use the config.ini file to set up the provider for card payments.
+func PaymentProvider() (payments.Provider, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return nil, err
+	}
+
+	switch provider := cfg.Section("payments").Key("provider").MustString("fake"); provider {
+	case "fake":
+		return fakeProvider, nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", provider)
+	}
+}
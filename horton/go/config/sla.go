@@ -0,0 +1,57 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * SLA
+ * Loads the response times breakdowns are held to when they are not under a contract from config.ini.
+ */
+
+package config
+
+import (
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/sla"
+	"gopkg.in/ini.v1"
+)
+
+// SLA is the service level agreement settings in config.ini.
+type SLA struct {
+	// Default is the targets of breakdowns not under a contract.
+	Default sla.Targets
+
+	// WarnBefore is how long before a target breakdowns are listed as at risk of breaching it.
+	WarnBefore time.Duration
+
+	// AlertInterval is how often open breakdowns are checked for stages at risk or breached to send events of.
+	AlertInterval time.Duration
+
+	// Location is the time zone compliance reports are by month in.
+	Location *time.Location
+}
+
+// SLASettings use the config.ini file to get the settings of service level agreements.
+func SLASettings() (SLA, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return SLA{}, err
+	}
+	section := cfg.Section("sla")
+
+	loc, err := time.LoadLocation(section.Key("timezone").MustString("Europe/Dublin"))
+	if err != nil {
+		return SLA{}, err
+	}
+	settings := SLA{
+		Default: sla.Targets{
+			AcknowledgeMinutes: section.Key("default_acknowledge_minutes").MustInt(0),
+			ArriveMinutes:      section.Key("default_arrive_minutes").MustInt(0),
+			ResolveMinutes:     section.Key("default_resolve_minutes").MustInt(0),
+		},
+		WarnBefore:    time.Duration(section.Key("warn_before_minutes").MustInt(10)) * time.Minute,
+		AlertInterval: time.Duration(section.Key("alert_check_seconds").MustInt(60)) * time.Second,
+		Location:      loc,
+	}
+	return settings, settings.Default.Check()
+}
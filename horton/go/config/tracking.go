@@ -0,0 +1,53 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Tracking
+ * Loads how long workers' GPS pings are kept and when supervisors are alerted to silent workers from config.ini.
+ */
+
+package config
+
+import (
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+	"gopkg.in/ini.v1"
+)
+
+// Tracking is the worker tracking settings in config.ini.
+type Tracking struct {
+	// Pings are kept for Rules.Retention, and up to MaxPings for each worker.
+	Rules    dispatch.PingRules
+	MaxPings int
+
+	// MaxBatch is the most pings a device can send at once.
+	MaxBatch int
+
+	// SilentAfter is how long a worker on a call-out can go without a ping before supervisors are alerted.
+	SilentAfter time.Duration
+
+	// FeedInterval is how often the live feed of where workers are is checked for changes.
+	FeedInterval time.Duration
+}
+
+// TrackingSettings use the config.ini file to get the settings of worker tracking.
+func TrackingSettings() (Tracking, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Tracking{}, err
+	}
+	tracking := cfg.Section("tracking")
+
+	return Tracking{
+		Rules: dispatch.PingRules{
+			Retention: time.Duration(tracking.Key("ping_retention_days").MustInt(30)) * 24 * time.Hour,
+			Skew:      time.Duration(tracking.Key("clock_skew_seconds").MustInt(120)) * time.Second,
+		},
+		MaxPings:     tracking.Key("max_pings_per_worker").MustInt(20000),
+		MaxBatch:     tracking.Key("max_pings_per_request").MustInt(100),
+		SilentAfter:  time.Duration(tracking.Key("silent_alert_minutes").MustInt(15)) * time.Minute,
+		FeedInterval: time.Duration(tracking.Key("feed_interval_seconds").MustInt(5)) * time.Second,
+	}, nil
+}
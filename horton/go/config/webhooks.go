@@ -0,0 +1,56 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Webhooks
+ * Loads how often events are sent to webhook endpoints and how failed deliveries are retried from config.ini.
+ */
+
+package config
+
+import (
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+	"gopkg.in/ini.v1"
+)
+
+// Webhooks is the webhooks settings in config.ini.
+type Webhooks struct {
+	// PollInterval is how often the outbox is checked for deliveries that are due.
+	PollInterval time.Duration
+
+	// BatchSize is the most deliveries sent each time the outbox is checked.
+	BatchSize int
+
+	// Timeout is how long an endpoint has to answer.
+	Timeout time.Duration
+
+	// Backoff is how long to wait before trying failed deliveries again.
+	Backoff webhook.Backoff
+
+	// AllowPrivate is whether endpoints can be on this host or a private network, for testing.
+	AllowPrivate bool
+}
+
+// WebhooksSettings use the config.ini file to get the settings of webhooks.
+func WebhooksSettings() (Webhooks, error) {
+	// Load config file.
+	cfg, err := ini.Load("go/config/config.ini")
+	if err != nil {
+		return Webhooks{}, err
+	}
+	section := cfg.Section("webhooks")
+
+	return Webhooks{
+		PollInterval: time.Duration(section.Key("poll_seconds").MustInt(10)) * time.Second,
+		BatchSize:    section.Key("batch_size").MustInt(50),
+		Timeout:      time.Duration(section.Key("timeout_seconds").MustInt(10)) * time.Second,
+		Backoff: webhook.Backoff{
+			Base:        time.Duration(section.Key("retry_base_seconds").MustInt(30)) * time.Second,
+			Max:         time.Duration(section.Key("retry_max_minutes").MustInt(360)) * time.Minute,
+			MaxAttempts: section.Key("max_attempts").MustInt(10),
+		},
+		AllowPrivate: section.Key("allow_private_addresses").MustBool(false),
+	}, nil
+}
@@ -0,0 +1,163 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Dispatch
+ * Works out the order breakdowns are answered in and the workers nearest to them.
+ * Breakdowns wait in a queue by priority then age until a worker is dispatched, who acknowledges the call-out,
+ * arrives and resolves it. Workers report where they are and whether they are available, the nearest free worker
+ * with a recent position is suggested for each breakdown.
+ */
+
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+)
+
+// Priorities of breakdowns, the lowest is answered first.
+const (
+	Critical = 1
+	Urgent   = 2
+	Normal   = 3
+)
+
+var priorityNames = map[int]string{Critical: "critical", Urgent: "urgent", Normal: "normal"}
+
+// Statuses of breakdowns.
+const (
+	Waiting      = "waiting"
+	Dispatched   = "dispatched"
+	Acknowledged = "acknowledged"
+	Arrived      = "arrived"
+	Resolved     = "resolved"
+	Cancelled    = "cancelled"
+)
+
+// ErrClosed is returned for changes to breakdowns that have been cancelled, ErrResolved to those resolved.
+var (
+	ErrClosed   = errors.New("breakdown has been cancelled")
+	ErrResolved = errors.New("breakdown has been resolved")
+)
+
+// ParsePriority returns the priority named e.g. "critical", normal if the name is empty.
+func ParsePriority(name string) (int, error) {
+	if name == "" {
+		return Normal, nil
+	}
+	for priority, n := range priorityNames {
+		if strings.EqualFold(name, n) {
+			return priority, nil
+		}
+	}
+	return 0, fmt.Errorf("priority must be critical, urgent or normal, not %q", name)
+}
+
+// PriorityName returns the name of a priority.
+func PriorityName(priority int) string {
+	return priorityNames[priority]
+}
+
+// Advance returns an error if a breakdown cannot move from status to next. Workers can be dispatched again
+// until one arrives, a worker arriving without acknowledging first acknowledges the call-out as they arrive.
+// A breakdown is resolved once the vehicle is fixed or recovered after a worker arrives.
+func Advance(status, next string) error {
+	if status == Cancelled {
+		return ErrClosed
+	}
+	if status == Resolved {
+		return ErrResolved
+	}
+	switch next {
+	case Dispatched:
+		if status == Arrived {
+			return errors.New("a worker has already arrived at the breakdown")
+		}
+	case Acknowledged:
+		if status != Dispatched {
+			return fmt.Errorf("only dispatched breakdowns can be acknowledged, it is %s", status)
+		}
+	case Arrived:
+		if status != Dispatched && status != Acknowledged {
+			return fmt.Errorf("only dispatched breakdowns can be arrived at, it is %s", status)
+		}
+	case Resolved:
+		if status != Arrived {
+			return fmt.Errorf("only breakdowns arrived at can be resolved, it is %s", status)
+		}
+	case Cancelled:
+		if status == Arrived {
+			return errors.New("a worker has already arrived at the breakdown")
+		}
+	default:
+		return fmt.Errorf("unknown status %q", next)
+	}
+	return nil
+}
+
+// Point is a place on the map.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Distancer works out how far a worker has to go from one point to another, in kilometres.
+type Distancer interface {
+	Distance(from, to Point) float64
+}
+
+// StraightLine is the distance as the crow flies.
+type StraightLine struct{}
+
+// Distance returns the straight-line distance between two points.
+func (StraightLine) Distance(from, to Point) float64 {
+	return gazetteer.Distance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
+
+// Position is where a worker last reported being.
+type Position struct {
+	WorkerId   int
+	Username   string
+	WorkerName string
+	Point
+	// Available is whether the worker said they can take call-outs, Busy whether they are on a breakdown.
+	Available  bool
+	Busy       bool
+	ReportedAt time.Time
+}
+
+// Free returns whether the worker can be sent to a breakdown at now, positions older than staleAfter are
+// not trusted.
+func (p Position) Free(now time.Time, staleAfter time.Duration) bool {
+	return p.Available && !p.Busy && now.Sub(p.ReportedAt) <= staleAfter
+}
+
+// Suggestion is a worker who could be sent to a breakdown with how far away they are.
+type Suggestion struct {
+	Position
+	DistanceKm float64
+}
+
+// Suggest returns up to limit of the free workers nearest to a breakdown at, nearest first.
+func Suggest(at Point, positions []Position, d Distancer, now time.Time, staleAfter time.Duration,
+	limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	for _, p := range positions {
+		if p.Free(now, staleAfter) {
+			suggestions = append(suggestions, Suggestion{Position: p, DistanceKm: d.Distance(p.Point, at)})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].DistanceKm < suggestions[j].DistanceKm
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
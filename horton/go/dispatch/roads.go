@@ -0,0 +1,157 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Roads
+ * Works out distances by road from a graph of the roads between towns, offline.
+ * Roads are read from a CSV file with the columns from, to & km, the towns at each end written as locations
+ * found in the gazetteer e.g. "Gort, Co. Galway". Workers and breakdowns join the graph at their nearest town.
+ *
+ * References
+ * https://en.wikipedia.org/wiki/Dijkstra%27s_algorithm
+ */
+
+package dispatch
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+)
+
+// RoadGraph is the towns joined by roads, it is safe to share between requests once read.
+type RoadGraph struct {
+	towns []Point
+	roads map[int][]road
+}
+
+type road struct {
+	to int
+	km float64
+}
+
+// ReadRoads reads a road graph from CSV with a header row, finding the towns in places.
+func ReadRoads(r io.Reader, places *gazetteer.Gazetteer) (*RoadGraph, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &RoadGraph{roads: map[int][]road{}}
+	towns := map[string]int{}
+	town := func(line int, location string) (int, error) {
+		place, ok := places.Locate(location)
+		if !ok {
+			return 0, fmt.Errorf("roads line %d: %q is not in the gazetteer", line, location)
+		}
+		label := place.Label()
+		if i, ok := towns[label]; ok {
+			return i, nil
+		}
+		towns[label] = len(g.towns)
+		g.towns = append(g.towns, Point{place.Latitude, place.Longitude})
+		return towns[label], nil
+	}
+
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 3 {
+			return nil, fmt.Errorf("roads line %d: want 3 columns, got %d", i+1, len(row))
+		}
+		from, err := town(i+1, row[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := town(i+1, row[1])
+		if err != nil {
+			return nil, err
+		}
+		km, err := strconv.ParseFloat(row[2], 64)
+		if err != nil || km <= 0 {
+			return nil, fmt.Errorf("roads line %d: %q is not a distance", i+1, row[2])
+		}
+		g.roads[from] = append(g.roads[from], road{to, km})
+		g.roads[to] = append(g.roads[to], road{from, km})
+	}
+	return g, nil
+}
+
+// Distance returns the distance by road between two points, going straight to and from the nearest town
+// on the graph at each end. It is the straight-line distance if the towns are not joined by road or the points
+// are nearer each other than to a town.
+func (g *RoadGraph) Distance(from, to Point) float64 {
+	straight := StraightLine{}.Distance(from, to)
+	start, toStart := g.nearest(from)
+	end, fromEnd := g.nearest(to)
+	if start < 0 || start == end || straight <= toStart+fromEnd {
+		return straight
+	}
+
+	km, ok := g.shortest(start, end)
+	if !ok {
+		return straight
+	}
+	return math.Max(toStart+km+fromEnd, straight)
+}
+
+// Function to find the town on the graph nearest a point, -1 if there are none.
+func (g *RoadGraph) nearest(p Point) (int, float64) {
+	best, bestKm := -1, math.Inf(1)
+	for i, town := range g.towns {
+		if km := (StraightLine{}).Distance(p, town); km < bestKm {
+			best, bestKm = i, km
+		}
+	}
+	return best, bestKm
+}
+
+// Function to find the length of the shortest route by road between two towns.
+// Returns false if they are not joined by road.
+func (g *RoadGraph) shortest(start, end int) (float64, bool) {
+	distances := map[int]float64{start: 0}
+	queue := &routeQueue{{start, 0}}
+	for queue.Len() > 0 {
+		next := heap.Pop(queue).(route)
+		if next.town == end {
+			return next.km, true
+		}
+		if next.km > distances[next.town] {
+			continue
+		}
+		for _, r := range g.roads[next.town] {
+			km := next.km + r.km
+			if known, ok := distances[r.to]; !ok || km < known {
+				distances[r.to] = km
+				heap.Push(queue, route{r.to, km})
+			}
+		}
+	}
+	return 0, false
+}
+
+// route is how far a town is along the roads searched so far.
+type route struct {
+	town int
+	km   float64
+}
+
+// routeQueue is the towns to search from, nearest first.
+type routeQueue []route
+
+func (q routeQueue) Len() int            { return len(q) }
+func (q routeQueue) Less(i, j int) bool  { return q[i].km < q[j].km }
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(route)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,100 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Tracking
+ * Checks the GPS pings workers' devices send and works out what a supervisor watching where workers are is sent.
+ * A worker on a call-out whose device has not pinged for a while has gone silent, supervisors are alerted so
+ * someone working alone can be checked on.
+ */
+
+package dispatch
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ping is a position recorded by a worker's device.
+type Ping struct {
+	Point
+	// AccuracyM is how far off the position could be in metres, nil if the device did not say.
+	AccuracyM  *float64
+	RecordedAt time.Time
+}
+
+// PingRules are the limits on the pings a device can send.
+type PingRules struct {
+	// Pings recorded before Retention ago are not kept, nor those more than Skew after now.
+	Retention time.Duration
+	Skew      time.Duration
+}
+
+// Check returns an error if a ping cannot be kept at now.
+func (r PingRules) Check(p Ping, now time.Time) error {
+	if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+		return fmt.Errorf("%g, %g is not a position on the map", p.Latitude, p.Longitude)
+	}
+	if p.AccuracyM != nil && *p.AccuracyM < 0 {
+		return fmt.Errorf("accuracy cannot be negative")
+	}
+	if p.RecordedAt.After(now.Add(r.Skew)) {
+		return fmt.Errorf("recorded at %s, after now", p.RecordedAt.Format(time.RFC3339))
+	}
+	if p.RecordedAt.Before(now.Add(-r.Retention)) {
+		return fmt.Errorf("recorded at %s, older than pings are kept", p.RecordedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Latest returns the most recently recorded of pings, which must not be empty.
+func Latest(pings []Ping) Ping {
+	latest := pings[0]
+	for _, p := range pings[1:] {
+		if p.RecordedAt.After(latest.RecordedAt) {
+			latest = p
+		}
+	}
+	return latest
+}
+
+// Silent returns whether a worker on a call-out has not been heard from for longer than after at now.
+func (p Position) Silent(now time.Time, after time.Duration) bool {
+	return p.Busy && now.Sub(p.ReportedAt) > after
+}
+
+// Feed works out what has changed in where workers are since a supervisor was last sent it.
+type Feed struct {
+	SilentAfter time.Duration
+
+	sent   map[int]Position
+	silent map[int]bool
+}
+
+// NewFeed returns a feed that has sent nothing, workers go silent after silentAfter without a ping.
+func NewFeed(silentAfter time.Duration) *Feed {
+	return &Feed{SilentAfter: silentAfter, sent: map[int]Position{}, silent: map[int]bool{}}
+}
+
+// Update returns the positions that are new or have changed since the last update, the workers who have gone
+// silent since and those who were silent and have been heard from, each in order of worker ID.
+func (f *Feed) Update(positions []Position, now time.Time) (changed, silent, heard []Position) {
+	for _, p := range positions {
+		if sent, ok := f.sent[p.WorkerId]; !ok || sent != p {
+			changed = append(changed, p)
+			f.sent[p.WorkerId] = p
+		}
+		isSilent := p.Silent(now, f.SilentAfter)
+		if isSilent && !f.silent[p.WorkerId] {
+			silent = append(silent, p)
+		} else if !isSilent && f.silent[p.WorkerId] {
+			heard = append(heard, p)
+		}
+		f.silent[p.WorkerId] = isSilent
+	}
+	for _, list := range [][]Position{changed, silent, heard} {
+		sort.Slice(list, func(i, j int) bool { return list[i].WorkerId < list[j].WorkerId })
+	}
+	return changed, silent, heard
+}
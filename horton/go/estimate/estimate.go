@@ -0,0 +1,100 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Estimate
+ * Works out the statuses of estimates (quotes) given to customers before work starts, and turns an accepted
+ * estimate into a job report. An estimate is sent to the customer as a link with a token that cannot be guessed,
+ * the customer accepts or declines it there until it expires.
+ */
+
+package estimate
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Statuses of an estimate.
+const (
+	Draft     = "draft"
+	Sent      = "sent"
+	Accepted  = "accepted"
+	Declined  = "declined"
+	Expired   = "expired"
+	Converted = "converted"
+)
+
+// NewToken returns a random token for the approval link of an estimate.
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsExpired reports whether an estimate expiring on expiresOn has expired on the day of now.
+// Estimates can be accepted up to the end of the day they expire on.
+func IsExpired(expiresOn models.Date, now time.Time) bool {
+	return !expiresOn.IsZero() && models.NewDate(now).After(expiresOn.Time)
+}
+
+// Status returns the status of an estimate on the day of now, a sent estimate past its expiry date has expired.
+func Status(e models.Estimate, now time.Time) string {
+	if e.Status == Sent && e.ExpiresOn != nil && IsExpired(*e.ExpiresOn, now) {
+		return Expired
+	}
+	return e.Status
+}
+
+// CanDecide returns an error if the customer cannot accept or decline an estimate on the day of now.
+func CanDecide(e models.Estimate, now time.Time) error {
+	switch status := Status(e, now); status {
+	case Sent:
+		return nil
+	case Expired:
+		return fmt.Errorf("estimate expired on %s", e.ExpiresOn)
+	default:
+		return fmt.Errorf("estimate has already been %s", status)
+	}
+}
+
+// ToReport returns the job report of an accepted estimate dated on the day of now, with its vehicle,
+// customer and complaint. The part lines are listed as the report's parts and its hours of labour
+// are rounded up to whole hours.
+func ToReport(e models.Estimate, now time.Time) models.JobReport {
+	report := models.JobReport{Date: models.NewDate(now), VehicleModel: e.VehicleModel, VehicleReg: e.VehicleReg,
+		CustomerName: e.CustomerName, Complaint: e.Complaint}
+	if e.ContactId != 0 {
+		report.ContactId = &e.ContactId
+	}
+
+	var parts []string
+	var hours float64
+	for _, line := range e.Lines {
+		switch line.Kind {
+		case billing.Labour, billing.Overtime, billing.WarrantyLabour:
+			hours += line.Quantity
+		case billing.Part:
+			parts = append(parts, fmt.Sprintf("%s x %s", formatQuantity(line.Quantity), line.Description))
+		}
+	}
+	report.Parts = strings.Join(parts, ", ")
+	report.WorkHours = int32(math.Ceil(hours - 1e-9))
+	return report
+}
+
+func formatQuantity(quantity float64) string {
+	if quantity == math.Trunc(quantity) {
+		return fmt.Sprintf("%.0f", quantity)
+	}
+	return fmt.Sprintf("%g", quantity)
+}
@@ -0,0 +1,77 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * CSV Export
+ * Writes an export as CSV (RFC 4180), starting with a byte order mark so Excel reads it as UTF-8.
+ *
+ * References
+ * https://golang.org/pkg/encoding/csv/
+ */
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+const byteOrderMark = "\ufeff"
+
+// CSVWriter writes an export as CSV.
+type CSVWriter struct {
+	csv     *csv.Writer
+	columns int
+}
+
+// NewCSVWriter writes the byte order mark and header row to w and returns a CSVWriter for the rows.
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	if _, err := io.WriteString(w, byteOrderMark); err != nil {
+		return nil, err
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	cw := &CSVWriter{csv: csv.NewWriter(w), columns: len(columns)}
+	if err := cw.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *CSVWriter) WriteRow(values []string) error {
+	if len(values) != cw.columns {
+		return errColumnCount(len(values), cw.columns)
+	}
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = escapeFormula(value)
+	}
+	return cw.csv.Write(record)
+}
+
+func (cw *CSVWriter) Flush() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+func (cw *CSVWriter) Close() error {
+	return cw.Flush()
+}
+
+// Function to stop text a user entered being run as a formula when the file is opened in a spreadsheet.
+// Values starting with =, +, - or @ are prefixed with a ' unless they are numbers.
+func escapeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if !isNumber(value) {
+			return "'" + value
+		}
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Export
+ * Writes rows of a table to a spreadsheet file as they are read, so large exports are never held in memory.
+ * CSVWriter writes CSV and XLSXWriter writes an Excel workbook with one sheet.
+ */
+
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Column is a column of an export.
+type Column struct {
+	Name string
+	// Numeric columns are written as numbers where the format has them, other columns are written as text.
+	Numeric bool
+}
+
+// Writer writes the rows of an export, the header row of its columns is written when it is created.
+type Writer interface {
+	// WriteRow writes a row, with a value for each column.
+	WriteRow(values []string) error
+	// Flush sends the rows written so far on to the underlying writer.
+	Flush() error
+	// Close finishes the file, it does not close the underlying writer.
+	Close() error
+}
+
+// Formats that can be exported to and their content types.
+var ContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// NewWriter returns a Writer of format ("csv" or "xlsx") that writes to w.
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case "csv":
+		return NewCSVWriter(w, columns)
+	case "xlsx":
+		return NewXLSXWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func errColumnCount(got, want int) error {
+	return fmt.Errorf("row has %d values for %d columns", got, want)
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
@@ -0,0 +1,161 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * XLSX Export
+ * Writes an export as an Excel workbook (Office Open XML) with one sheet.
+ * The parts of the workbook that do not change are written first, then the sheet's rows are streamed
+ * into the zip as they are written. Text is written as inline strings so no shared string table is kept in memory.
+ *
+ * References
+ * https://docs.microsoft.com/en-us/office/open-xml/structure-of-a-spreadsheetml-document
+ * https://golang.org/pkg/archive/zip/
+ */
+
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parts of the workbook written before the sheet.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	// Style 0 is the default, style 1 is bold for the header row.
+	{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+// XLSXWriter writes an export as an Excel workbook.
+type XLSXWriter struct {
+	zip     *zip.Writer
+	created time.Time
+	sheet   io.Writer
+	columns []Column
+	row     int
+}
+
+// NewXLSXWriter writes the workbook up to the header row of the sheet to w and returns an XLSXWriter for the rows.
+func NewXLSXWriter(w io.Writer, columns []Column) (*XLSXWriter, error) {
+	xw := &XLSXWriter{zip: zip.NewWriter(w), created: time.Now(), columns: columns}
+
+	for _, part := range xlsxParts {
+		f, err := xw.create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := xw.create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = sheet
+	_, err = io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`+
+		`</sheetView></sheetViews><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := xw.writeRow(header, true); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *XLSXWriter) WriteRow(values []string) error {
+	if len(values) != len(xw.columns) {
+		return errColumnCount(len(values), len(xw.columns))
+	}
+	return xw.writeRow(values, false)
+}
+
+func (xw *XLSXWriter) Flush() error {
+	return xw.zip.Flush()
+}
+
+func (xw *XLSXWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// Function to add a compressed file to the workbook dated when the export was created.
+func (xw *XLSXWriter) create(name string) (io.Writer, error) {
+	return xw.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: xw.created})
+}
+
+// Function to write a row of the sheet, numbers in numeric columns are written as numbers.
+func (xw *XLSXWriter) writeRow(values []string, header bool) error {
+	xw.row++
+	row := strconv.Itoa(xw.row)
+
+	var b strings.Builder
+	b.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		ref := columnName(i) + row
+		switch {
+		case header:
+			b.WriteString(`<c r="` + ref + `" s="1" t="inlineStr"><is><t>`)
+			xml.EscapeText(&b, []byte(value))
+			b.WriteString(`</t></is></c>`)
+		case value == "":
+			// Empty cells are left out.
+		case xw.columns[i].Numeric && isNumber(value):
+			b.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+		default:
+			b.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&b, []byte(value))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(xw.sheet, b.String())
+	return err
+}
+
+// Function to get the letters of a column from its index e.g. 0 is "A", 26 is "AA".
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
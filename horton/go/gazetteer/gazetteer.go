@@ -0,0 +1,215 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Gazetteer
+ * Finds the coordinates of places in Ireland from free text locations like "Gort, Co. Galway", offline.
+ * Places are read from a CSV file of towns and townlands with the columns name, county, kind, latitude & longitude.
+ * Names are matched regardless of case, fadas, punctuation and "Co." so "gort co galway" is Gort too.
+ * The bundled ie_places.csv is a sample, the full lists of towns and townlands are read by ReadTownlands and
+ * ReadGeoNames and written in the same columns by the gazetteer command.
+ *
+ * References
+ * https://www.townlands.ie/
+ * https://en.wikipedia.org/wiki/Haversine_formula
+ */
+
+package gazetteer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is whether a place is a town or a townland.
+type Kind string
+
+const (
+	Town     Kind = "town"
+	Townland Kind = "townland"
+)
+
+// earthRadiusKm is the mean radius of the Earth used for distances.
+const earthRadiusKm = 6371.0
+
+// Place is a town or townland with its coordinates.
+type Place struct {
+	Name      string
+	County    string
+	Kind      Kind
+	Latitude  float64
+	Longitude float64
+}
+
+// Label returns how the place is written in a location e.g. "Gort, Co. Galway".
+func (p Place) Label() string {
+	return p.Name + ", Co. " + p.County
+}
+
+// Gazetteer is the places read from a file, it is safe to share between requests once read.
+type Gazetteer struct {
+	// places are sorted towns first then by name, the order places are suggested in.
+	places   []Place
+	byName   map[string][]int
+	counties map[string]string
+}
+
+var (
+	fadas = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u",
+		"Á", "a", "É", "e", "Í", "i", "Ó", "o", "Ú", "u")
+	punctuation = regexp.MustCompile(`[^a-z0-9]+`)
+	// A county at the end of a part of a location e.g. "gort co galway".
+	countySuffix = regexp.MustCompile(`^(.*?) ?\b(?:co|county) ([a-z ]+)$`)
+)
+
+// Read reads a gazetteer from CSV with a header row.
+func Read(r io.Reader) (*Gazetteer, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("gazetteer is empty")
+	}
+
+	g := &Gazetteer{byName: map[string][]int{}, counties: map[string]string{}}
+	for i, row := range rows[1:] {
+		if len(row) != 5 {
+			return nil, fmt.Errorf("gazetteer line %d: want 5 columns, got %d", i+2, len(row))
+		}
+		p := Place{Name: strings.TrimSpace(row[0]), County: strings.TrimSpace(row[1]), Kind: Kind(row[2])}
+		if p.Kind != Town && p.Kind != Townland {
+			return nil, fmt.Errorf("gazetteer line %d: unknown kind %q", i+2, row[2])
+		}
+		if p.Latitude, err = strconv.ParseFloat(row[3], 64); err == nil {
+			p.Longitude, err = strconv.ParseFloat(row[4], 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer line %d: %v", i+2, err)
+		}
+		g.places = append(g.places, p)
+	}
+
+	sort.SliceStable(g.places, func(i, j int) bool {
+		if g.places[i].Kind != g.places[j].Kind {
+			return g.places[i].Kind == Town
+		}
+		return g.places[i].Name < g.places[j].Name
+	})
+	for i, p := range g.places {
+		name := normalise(p.Name)
+		g.byName[name] = append(g.byName[name], i)
+		g.counties[normalise(p.County)] = p.County
+	}
+	return g, nil
+}
+
+// Len returns the number of places in the gazetteer.
+func (g *Gazetteer) Len() int {
+	return len(g.places)
+}
+
+// Locate finds the place a location is at. The parts of the location between commas are tried in order,
+// so "Main Street, Gort, Co. Galway" is Gort, and a county in the location picks between places of the same name.
+// Returns false if no place matches or the location could be more than one place.
+func (g *Gazetteer) Locate(location string) (Place, bool) {
+	var names []string
+	county := ""
+	for _, part := range strings.Split(location, ",") {
+		name := normalise(part)
+		if match := countySuffix.FindStringSubmatch(name); match != nil {
+			if c, ok := g.counties[match[2]]; ok {
+				county, name = c, match[1]
+			}
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	// A county at the end without "Co." e.g. "Gort, Galway" is taken as the county if it names one.
+	if county == "" && len(names) > 1 {
+		if c, ok := g.counties[names[len(names)-1]]; ok {
+			county = c
+		}
+	}
+
+	for _, name := range names {
+		if place, ok := g.pick(g.byName[name], county); ok {
+			return place, true
+		}
+	}
+	return Place{}, false
+}
+
+// Suggest returns up to limit places whose names start with what has been typed of a location, towns first.
+func (g *Gazetteer) Suggest(typed string, limit int) []Place {
+	prefix := normalise(strings.Split(typed, ",")[0])
+	places := []Place{}
+	if prefix == "" {
+		return places
+	}
+	for _, p := range g.places {
+		if len(places) == limit {
+			break
+		}
+		if strings.HasPrefix(normalise(p.Name), prefix) {
+			places = append(places, p)
+		}
+	}
+	return places
+}
+
+// Function to pick the place a name means from the places with the name, in county if it is set.
+// A single town is picked over townlands, otherwise the place must be the only one.
+func (g *Gazetteer) pick(matches []int, county string) (Place, bool) {
+	var towns, townlands []Place
+	for _, i := range matches {
+		p := g.places[i]
+		if county != "" && p.County != county {
+			continue
+		}
+		if p.Kind == Town {
+			towns = append(towns, p)
+		} else {
+			townlands = append(townlands, p)
+		}
+	}
+	switch {
+	case len(towns) == 1:
+		return towns[0], true
+	case len(towns) == 0 && len(townlands) == 1:
+		return townlands[0], true
+	}
+	return Place{}, false
+}
+
+// Distance returns the distance in kilometres between two points along the surface of the Earth.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat, dLon := radians(lat2-lat1), radians(lon2-lon1)
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Bounds returns the box of latitudes and longitudes holding every point within km of a point,
+// for narrowing down the points to work out the distance to.
+func Bounds(lat, lon, km float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := km / earthRadiusKm * 180 / math.Pi
+	dLon := dLat / math.Cos(radians(lat))
+	return lat - dLat, lat + dLat, lon - dLon, lon + dLon
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Function to fold a name to lower case without fadas or punctuation, for matching names however they are written.
+func normalise(name string) string {
+	name = punctuation.ReplaceAllString(strings.ToLower(fadas.Replace(name)), " ")
+	return strings.TrimSpace(name)
+}
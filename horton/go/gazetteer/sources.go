@@ -0,0 +1,158 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Gazetteer Sources
+ * Reads the full lists of Irish places published as open data into places, and writes them as a gazetteer file.
+ * Townlands come from the CSV download of townlands.ie, towns from the IE.txt dump of GeoNames with the county
+ * names of its admin2Codes.txt. Both are under CC BY 4.0.
+ *
+ * References
+ * https://www.townlands.ie/page/download/
+ * https://download.geonames.org/export/dump/readme.txt
+ */
+
+package gazetteer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Columns of the townlands.ie CSV download used for townlands, the English name is used over the name tagged.
+var townlandColumns = []string{"NAME_TAG", "CO_NAME", "LATITUDE", "LONGITUDE"}
+
+// Columns of the GeoNames dump, which is tab separated without a header.
+const (
+	geoName         = 1
+	geoLatitude     = 4
+	geoLongitude    = 5
+	geoFeatureClass = 6
+	geoCountry      = 8
+	geoAdmin1       = 10
+	geoAdmin2       = 11
+	geoColumns      = 19
+)
+
+// countyPrefix is written before the names of counties in GeoNames e.g. "County Galway".
+var countyPrefix = strings.NewReplacer("County ", "", "Co. ", "")
+
+// ReadTownlands reads the townlands of the townlands.ie CSV download.
+// Townlands without a name, county or centroid are skipped.
+func ReadTownlands(r io.Reader) ([]Place, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("townlands: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToUpper(name))] = i
+	}
+	for _, name := range townlandColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("townlands: no %s column", name)
+		}
+	}
+	english, hasEnglish := columns["NAME_EN"]
+
+	var places []Place
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("townlands line %d: %v", line, err)
+		}
+		p := Place{Name: strings.TrimSpace(row[columns["NAME_TAG"]]),
+			County: strings.TrimSpace(countyPrefix.Replace(row[columns["CO_NAME"]])), Kind: Townland}
+		if hasEnglish && strings.TrimSpace(row[english]) != "" {
+			p.Name = strings.TrimSpace(row[english])
+		}
+		lat, latErr := strconv.ParseFloat(row[columns["LATITUDE"]], 64)
+		lon, lonErr := strconv.ParseFloat(row[columns["LONGITUDE"]], 64)
+		if p.Name == "" || p.County == "" || latErr != nil || lonErr != nil {
+			continue
+		}
+		p.Latitude, p.Longitude = lat, lon
+		places = append(places, p)
+	}
+	return places, nil
+}
+
+// ReadGeoNames reads the towns of the GeoNames dump of Ireland (IE.txt), with the counties of admin2Codes.txt.
+// Only populated places (feature class P) in a county are read.
+func ReadGeoNames(dump, admin2 io.Reader) ([]Place, error) {
+	counties := map[string]string{}
+	err := eachTabLine(admin2, func(fields []string) {
+		if len(fields) >= 2 && strings.HasPrefix(fields[0], "IE.") {
+			counties[fields[0]] = strings.TrimSpace(countyPrefix.Replace(fields[1]))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("admin2 codes: %v", err)
+	}
+
+	var places []Place
+	err = eachTabLine(dump, func(fields []string) {
+		if len(fields) != geoColumns || fields[geoFeatureClass] != "P" || fields[geoCountry] != "IE" {
+			return
+		}
+		county, ok := counties["IE."+fields[geoAdmin1]+"."+fields[geoAdmin2]]
+		lat, latErr := strconv.ParseFloat(fields[geoLatitude], 64)
+		lon, lonErr := strconv.ParseFloat(fields[geoLongitude], 64)
+		if !ok || latErr != nil || lonErr != nil || fields[geoName] == "" {
+			return
+		}
+		places = append(places, Place{Name: fields[geoName], County: county, Kind: Town, Latitude: lat,
+			Longitude: lon})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geonames: %v", err)
+	}
+	return places, nil
+}
+
+// Write writes places as a gazetteer file that Read reads, sorted by county and name.
+// A place with the same name, county and kind as one already written is left out.
+func Write(w io.Writer, places []Place) error {
+	sorted := append([]Place(nil), places...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].County != sorted[j].County {
+			return sorted[i].County < sorted[j].County
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"name", "county", "kind", "latitude", "longitude"})
+	written := map[Place]bool{}
+	for _, p := range sorted {
+		key := Place{Name: p.Name, County: p.County, Kind: p.Kind}
+		if written[key] {
+			continue
+		}
+		written[key] = true
+		out.Write([]string{p.Name, p.County, string(p.Kind), strconv.FormatFloat(p.Latitude, 'f', 5, 64),
+			strconv.FormatFloat(p.Longitude, 'f', 5, 64)})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Function to call f with the fields of each line of a tab separated file. Fields are not quoted in GeoNames.
+func eachTabLine(r io.Reader, f func(fields []string)) error {
+	scanner := bufio.NewScanner(r)
+	// The alternate names of a place can be far longer than the default limit of a line.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		f(strings.Split(scanner.Text(), "\t"))
+	}
+	return scanner.Err()
+}
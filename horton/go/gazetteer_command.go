@@ -0,0 +1,95 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Gazetteer Command
+ * Builds the gazetteer from the full lists of Irish towns and townlands, which are too large to bundle.
+ * Point gazetteer in config.ini at the file written, then run the geocode command with -all.
+ *
+ * Usage
+ * go run main.go gazetteer [-townlands townlands.csv] [-geonames IE.txt -admin2 admin2Codes.txt] [-out file]
+ */
+
+package openapi
+
+import (
+	"flag"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+	"io"
+	"os"
+)
+
+// GazetteerCommand runs the gazetteer subcommand with its arguments, returns the exit code.
+func GazetteerCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gazetteer", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	townlands := flags.String("townlands", "", "the CSV download of townlands.ie")
+	geonames := flags.String("geonames", "", "the IE.txt dump of GeoNames, for towns")
+	admin2 := flags.String("admin2", "", "the admin2Codes.txt of GeoNames, for the counties of towns")
+	out := flags.String("out", "go/gazetteer/ie_places_full.csv", "the gazetteer file to write")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go run main.go gazetteer [options]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || (*townlands == "" && *geonames == "") || (*geonames == "") != (*admin2 == "") {
+		flags.Usage()
+		return 2
+	}
+
+	var places []gazetteer.Place
+	towns, townlandCount := 0, 0
+	if *geonames != "" {
+		read, err := readPlaceFiles(func(files []io.Reader) ([]gazetteer.Place, error) {
+			return gazetteer.ReadGeoNames(files[0], files[1])
+		}, *geonames, *admin2)
+		if err != nil {
+			fmt.Fprintln(stderr, "Unable to read GeoNames:", err)
+			return 1
+		}
+		places, towns = append(places, read...), len(read)
+	}
+	if *townlands != "" {
+		read, err := readPlaceFiles(func(files []io.Reader) ([]gazetteer.Place, error) {
+			return gazetteer.ReadTownlands(files[0])
+		}, *townlands)
+		if err != nil {
+			fmt.Fprintln(stderr, "Unable to read townlands:", err)
+			return 1
+		}
+		places, townlandCount = append(places, read...), len(read)
+	}
+
+	file, err := os.Create(*out)
+	if err == nil {
+		err = gazetteer.Write(file, places)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Unable to write the gazetteer:", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%d towns and %d townlands written to %s\n", towns, townlandCount, *out)
+	return 0
+}
+
+// Function to open files and read the places in them with read.
+func readPlaceFiles(read func(files []io.Reader) ([]gazetteer.Place, error),
+	paths ...string) ([]gazetteer.Place, error) {
+	var files []io.Reader
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		files = append(files, file)
+	}
+	return read(files)
+}
@@ -0,0 +1,136 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Geocode Command
+ * Finds the coordinates of the vehicle locations of reports saved before they were stored, or again after the
+ * gazetteer is changed. Reports are geocoded as they are saved, so this is only needed once per change.
+ * The locations that were not found are listed, most reports first, as they are left out of "near" filters.
+ *
+ * Usage
+ * go run main.go geocode [-all] [-dry-run]
+ */
+
+package openapi
+
+import (
+	"flag"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"io"
+	"sort"
+)
+
+// The most locations that were not found listed by the geocode command.
+const unlocatedListed = 20
+
+// GeocodeCommand runs the geocode subcommand with its arguments, returns the exit code.
+func GeocodeCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("geocode", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	all := flags.Bool("all", false, "geocode every report, not only those without coordinates")
+	dryRun := flags.Bool("dry-run", false, "count the reports that would be geocoded without saving")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go run main.go geocode [options]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	places, err := config.Gazetteer()
+	if err != nil {
+		fmt.Fprintln(stderr, "Unable to read the gazetteer:", err)
+		return 1
+	}
+
+	db := config.DbConn()
+	defer db.Close()
+
+	query := "SELECT job_report_id, vehicle_location FROM jobreports WHERE vehicle_location <> ''"
+	if !*all {
+		query += " AND location_lat IS NULL"
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		fmt.Fprintln(stderr, "Unable to get reports:", err)
+		return 1
+	}
+
+	type located struct {
+		reportId      int
+		lat, lon      interface{}
+		locationFound bool
+	}
+	var reports []located
+	unlocated := map[string]int{}
+	for rows.Next() {
+		var reportId int
+		var location string
+		if err := rows.Scan(&reportId, &location); err != nil {
+			rows.Close()
+			fmt.Fprintln(stderr, "Unable to read reports:", err)
+			return 1
+		}
+		r := located{reportId: reportId}
+		if place, ok := places.Locate(location); ok {
+			r.lat, r.lon, r.locationFound = place.Latitude, place.Longitude, true
+		} else {
+			unlocated[location]++
+		}
+		reports = append(reports, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Fprintln(stderr, "Unable to read reports:", err)
+		return 1
+	}
+
+	found := 0
+	for _, r := range reports {
+		if r.locationFound {
+			found++
+		}
+		if *dryRun {
+			continue
+		}
+		// Keep updated_at, the reports have not been changed by their workers.
+		if _, err := db.Exec("UPDATE jobreports SET location_lat = ?, location_lon = ?, updated_at = updated_at "+
+			"WHERE job_report_id = ?", r.lat, r.lon, r.reportId); err != nil {
+			fmt.Fprintln(stderr, "Unable to save report", r.reportId, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d reports, %d located, %d not found", len(reports), found, len(reports)-found)
+	if *dryRun {
+		fmt.Fprint(stdout, " (dry run, nothing was saved)")
+	}
+	fmt.Fprintf(stdout, " with a gazetteer of %d places\n", places.Len())
+
+	locations := make([]string, 0, len(unlocated))
+	for location := range unlocated {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if unlocated[locations[i]] != unlocated[locations[j]] {
+			return unlocated[locations[i]] > unlocated[locations[j]]
+		}
+		return locations[i] < locations[j]
+	})
+	if len(locations) > 0 {
+		fmt.Fprintf(stdout, "%d locations were not found, they are left out of near filters:\n", len(locations))
+	}
+	for i, location := range locations {
+		if i == unlocatedListed {
+			fmt.Fprintf(stdout, "  and %d more\n", len(locations)-unlocatedListed)
+			break
+		}
+		fmt.Fprintf(stdout, "  %s (%d reports)\n", location, unlocated[location])
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * iCal
+ * Writes calendars in the iCalendar format (RFC 5545) that phones and calendar apps subscribe to.
+ * Lines end in CRLF and are folded at 75 octets, text is escaped and times are written in UTC.
+ */
+
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar files.
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	timeLayout = "20060102T150405Z"
+	lineOctets = 75
+)
+
+// Event is an event in a calendar.
+type Event struct {
+	// UID identifies the event across versions of the calendar, it must not change.
+	UID string
+
+	Start, End time.Time
+
+	Summary, Description, Location string
+
+	// Cancelled events are kept in the calendar so subscribers remove them.
+	Cancelled bool
+
+	// Tentative events may not go ahead, they are not confirmed yet.
+	Tentative bool
+
+	Modified time.Time
+}
+
+// Write writes a calendar called name with its events to w. stamp is when the calendar was made.
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(out, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Repota//Horton//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", Escape(name))
+	for _, event := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", Escape(event.UID))
+		line("DTSTAMP", stamp.UTC().Format(timeLayout))
+		line("DTSTART", event.Start.UTC().Format(timeLayout))
+		line("DTEND", event.End.UTC().Format(timeLayout))
+		line("SUMMARY", Escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", Escape(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", Escape(event.Location))
+		}
+		if event.Cancelled {
+			line("STATUS", "CANCELLED")
+		} else if event.Tentative {
+			line("STATUS", "TENTATIVE")
+		} else {
+			line("STATUS", "CONFIRMED")
+		}
+		if !event.Modified.IsZero() {
+			line("LAST-MODIFIED", event.Modified.UTC().Format(timeLayout))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+// Escape escapes the backslashes, semicolons, commas and new lines of a text value.
+func Escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// writeLine writes a content line ending in CRLF, folding it onto lines that start with a space so no line is
+// longer than 75 octets. Lines are not folded in the middle of a UTF-8 character.
+func writeLine(w *bufio.Writer, line string) {
+	limit := lineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		fmt.Fprint(w, line[:cut], "\r\n ")
+		line = line[cut:]
+		limit = lineOctets - 1 // The space starting the folded line counts.
+	}
+	fmt.Fprint(w, line, "\r\n")
+}
@@ -0,0 +1,126 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Import Command
+ * Imports reports from a CSV file on the command line, for files too large to upload or for scripting.
+ * Rows are checked and saved in the same way as POST /jobReports/import (api_report_import.go).
+ *
+ * Usage
+ * go run main.go import -user jshields [-mapping mapping.json] [-dry-run] [-batch 100] [-errors errors.csv] reports.csv
+ */
+
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/config"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/reportimport"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ImportCommand runs the import subcommand with its arguments, returns the exit code.
+func ImportCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	username := flags.String("user", "", "username of the worker the reports are imported for (required)")
+	mappingFile := flags.String("mapping", "", "JSON file of the CSV column for each report field")
+	dryRun := flags.Bool("dry-run", false, "check every row without saving")
+	batchSize := flags.Int("batch", 0, "reports saved in each transaction (default batch_size in config.ini)")
+	errorsFile := flags.String("errors", "", "file to write rejected rows to as CSV")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go run main.go import -user <username> [options] <file.csv>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var mapping reportimport.Mapping
+	if *mappingFile != "" {
+		data, err := ioutil.ReadFile(*mappingFile)
+		if err == nil {
+			err = json.Unmarshal(data, &mapping)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "Unable to read mapping:", err)
+			return 1
+		}
+	}
+
+	if *batchSize <= 0 {
+		size, _, err := config.ImportRules()
+		if err != nil {
+			fmt.Fprintln(stderr, "Unable to load config file:", err)
+			return 1
+		}
+		*batchSize = size
+	}
+
+	if !isValidAccount(*username) {
+		fmt.Fprintf(stderr, "No worker with the username %q\n", *username)
+		return 1
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer file.Close()
+
+	reader, err := reportimport.NewReader(file, mapping)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	db := config.DbConn()
+	defer db.Close()
+
+	result, rejected, err := importReports(db, wa.Id, reader, *dryRun, *batchSize)
+	if err != nil {
+		fmt.Fprintln(stderr, "Import failed:", err)
+		return 1
+	}
+
+	for _, rowError := range result.Errors {
+		for _, field := range rowError.Fields {
+			fmt.Fprintf(stdout, "row %d: %s: %s\n", rowError.Row, field.Field, field.Message)
+		}
+	}
+	fmt.Fprintf(stdout, "%d rows, %d valid, %d imported, %d rejected", result.Rows, result.Valid, result.Imported,
+		result.Rejected)
+	if *dryRun {
+		fmt.Fprint(stdout, " (dry run, nothing was saved)")
+	}
+	fmt.Fprintln(stdout)
+
+	if *errorsFile != "" && len(rejected) > 0 {
+		out, err := os.Create(*errorsFile)
+		if err == nil {
+			err = reportimport.WriteErrors(out, reader.Header(), rejected)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "Unable to write error report:", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Rejected rows written to", *errorsFile)
+	}
+
+	if result.Rejected > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Job Queue
+ * Works out the changes to who a job is assigned to. Supervisors create jobs and assign them to a worker or leave
+ * them in the queue, workers claim jobs from the queue. Supervisors can give a job to another worker or put it back
+ * in the queue e.g. when its worker is off sick.
+ */
+
+package jobqueue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Roles of workers.
+const (
+	Worker     = "worker"
+	Supervisor = "supervisor"
+)
+
+// Actions in the history of who a job is assigned to.
+const (
+	Assigned   = "assigned"
+	Claimed    = "claimed"
+	Reassigned = "reassigned"
+	Unassigned = "unassigned"
+)
+
+// ErrComplete is returned for changes to jobs that are complete.
+var ErrComplete = errors.New("job is complete")
+
+// Assign returns the action of a supervisor giving a job assigned to from to the worker to.
+// from is empty if the job is in the queue and to is empty to put it back in the queue.
+func Assign(from, to string, complete bool) (string, error) {
+	switch {
+	case complete:
+		return "", ErrComplete
+	case from == to && to == "":
+		return "", errors.New("job is already in the queue")
+	case from == to:
+		return "", fmt.Errorf("job is already assigned to %s", to)
+	case from == "":
+		return Assigned, nil
+	case to == "":
+		return Unassigned, nil
+	default:
+		return Reassigned, nil
+	}
+}
+
+// Claim returns an error if a worker cannot claim a job assigned to from, only jobs in the queue can be claimed.
+func Claim(from, worker string, complete bool) error {
+	switch {
+	case complete:
+		return ErrComplete
+	case from == worker:
+		return errors.New("job is already assigned to you")
+	case from != "":
+		return fmt.Errorf("job is already assigned to %s", from)
+	}
+	return nil
+}
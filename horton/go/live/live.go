@@ -0,0 +1,137 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Live
+ * Pushes the events of job reports to clients as they happen, over Server-Sent Events or a WebSocket.
+ * Events are numbered in the order they were recorded, a client that reconnects with the number of the last event it
+ * was sent is sent the events it missed. Events can be committed out of order, so recent events are read again
+ * until they are settled and any committed late are sent then.
+ *
+ * Reference
+ * https://html.spec.whatwg.org/multipage/server-sent-events.html
+ */
+
+package live
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Events of job reports clients are sent. A report given to a worker, claimed, put back in the queue, completed or
+// reopened is sent as report.status.
+const (
+	ReportCreated = "report.created"
+	ReportUpdated = "report.updated"
+	ReportStatus  = "report.status"
+	ReportDeleted = "report.deleted"
+)
+
+// Reset is sent to a client that reconnected after the events it missed were pruned. It must get the reports again.
+const Reset = "reset"
+
+// Statuses of a report.status event besides the actions of the job queue, e.g. assigned.
+const (
+	Completed = "completed"
+	Reopened  = "reopened"
+)
+
+// Event is a change to a job report. Worker IDs are 0 for reports in the queue.
+type Event struct {
+	Id          int64
+	Type        string
+	JobReportId int64
+	// WorkerId is who the report is assigned to after the change, FromWorkerId who it was before if it moved.
+	WorkerId     int
+	FromWorkerId int
+	Status       string
+	CreatedAt    time.Time
+}
+
+// Scope is the events a client is allowed to see. Supervisors see every event, workers the events of their own
+// reports, reports moved away from them and reports in the queue.
+type Scope struct {
+	All      bool
+	WorkerId int
+}
+
+// Allows returns whether a client with this scope can see an event.
+func (s Scope) Allows(e Event) bool {
+	return s.All || e.WorkerId == 0 || e.WorkerId == s.WorkerId || e.FromWorkerId == s.WorkerId
+}
+
+// Missed returns whether a client that was last sent lastEventId may have missed events that were pruned.
+// oldest is the first event still kept, 0 if none are.
+func Missed(lastEventId, oldest int64) bool {
+	if lastEventId == 0 {
+		return false
+	}
+	return oldest == 0 || lastEventId < oldest-1
+}
+
+// Cursor is where a client is in the events. Every event up to its floor has been read or is taken never to be
+// committed, events after it are read again until they are settle old.
+type Cursor struct {
+	settle time.Duration
+	floor  int64
+	seen   map[int64]time.Time
+}
+
+// NewCursor returns a cursor after the event lastEventId, where events are settled once they are settle old.
+func NewCursor(lastEventId int64, settle time.Duration) *Cursor {
+	return &Cursor{settle: settle, floor: lastEventId, seen: map[int64]time.Time{}}
+}
+
+// After returns the ID events are to be read after.
+func (c *Cursor) After() int64 {
+	return c.floor
+}
+
+// Fresh returns whether an event read after the cursor has not been read before, and remembers it.
+func (c *Cursor) Fresh(e Event) bool {
+	if _, ok := c.seen[e.Id]; ok || e.Id <= c.floor {
+		return false
+	}
+	c.seen[e.Id] = e.CreatedAt
+	return true
+}
+
+// Settle moves the cursor past the events read that have settled by now.
+func (c *Cursor) Settle(now time.Time) {
+	// An event started before one that has settled has had as long to be committed, so is taken never to be.
+	for id, createdAt := range c.seen {
+		if now.Sub(createdAt) >= c.settle && id > c.floor {
+			c.floor = id
+		}
+	}
+	for id := range c.seen {
+		if id <= c.floor {
+			delete(c.seen, id)
+		}
+	}
+}
+
+// WriteEvent writes an event as Server-Sent Events, with the ID the client sends back as Last-Event-ID if it
+// reconnects. id is left out if it is 0.
+func WriteEvent(w io.Writer, id int64, event string, data []byte) error {
+	var b strings.Builder
+	if id != 0 {
+		fmt.Fprintf(&b, "id: %d\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteRetry writes how long a client is to wait before reconnecting as Server-Sent Events.
+func WriteRetry(w io.Writer, wait time.Duration) error {
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", wait.Milliseconds())
+	return err
+}
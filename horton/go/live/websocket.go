@@ -0,0 +1,229 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * WebSocket
+ * The server side of a WebSocket that only sends, for clients that would rather not use Server-Sent Events.
+ * Messages from the client are read only to answer pings and close the connection.
+ * Browsers send cookies with a WebSocket handshake from any site, so handshakes from pages on other sites, told by
+ * their Origin, are refused.
+ *
+ * Reference
+ * https://tools.ietf.org/html/rfc6455
+ */
+
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes of WebSocket frames.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// The GUID the Sec-WebSocket-Accept header is made with.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// The most a control frame from a client can carry, larger messages are refused.
+const maxClientPayload = 125
+
+// IsWebSocket returns whether a request asks to be upgraded to a WebSocket.
+func IsWebSocket(r *http.Request) bool {
+	return headerHas(r.Header, "Connection", "upgrade") && headerHas(r.Header, "Upgrade", "websocket")
+}
+
+// Accept returns the Sec-WebSocket-Accept header answering a Sec-WebSocket-Key.
+func Accept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Conn is a WebSocket to a client. Done is closed once the connection is closed by either side.
+type Conn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+	done chan struct{}
+	once sync.Once
+}
+
+// CheckOrigin returns whether the page a handshake is from is allowed to connect, its Origin is one of origins like
+// https://repota.example.com. Handshakes without an Origin are not from a browser and are allowed.
+func CheckOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade answers a request to be upgraded to a WebSocket and takes over its connection.
+// An error is answered with 400 if the request is not a WebSocket handshake, or 403 if it is from a page whose
+// origin is not one of origins.
+func Upgrade(w http.ResponseWriter, r *http.Request, origins []string) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsWebSocket(r) || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "Not a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a websocket handshake")
+	}
+	if !CheckOrigin(r, origins) {
+		http.Error(w, "Origin is not allowed", http.StatusForbidden)
+		return nil, errors.New("origin " + r.Header.Get("Origin") + " is not allowed")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be taken over")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + Accept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, rw: rw, done: make(chan struct{})}
+	go c.read()
+	return c, nil
+}
+
+// Done returns a channel closed once the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// WriteText sends a text message.
+func (c *Conn) WriteText(data []byte) error {
+	return c.write(opText, data)
+}
+
+// Ping sends a ping, which keeps the connection open through proxies.
+func (c *Conn) Ping() error {
+	return c.write(opPing, nil)
+}
+
+// Close sends a close frame and closes the connection.
+func (c *Conn) Close() error {
+	c.write(opClose, []byte{0x03, 0xE8}) // 1000, normal closure.
+	return c.close()
+}
+
+func (c *Conn) close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Function to write a frame. Frames from the server are not masked.
+func (c *Conn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// Function to read frames from the client until it closes the connection, answering pings.
+// The client is not meant to send messages, so one that does or sends a frame that is not masked is closed.
+func (c *Conn) read() {
+	defer c.close()
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opPing:
+			if c.write(opPong, payload) != nil {
+				return
+			}
+		case opPong:
+		case opClose:
+			c.write(opClose, payload)
+			return
+		default:
+			return
+		}
+	}
+}
+
+// Function to read a control frame from the client, unmasking its payload.
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode, masked, n := head[0]&0x0F, head[1]&0x80 != 0, int(head[1]&0x7F)
+	if !masked || n > maxClientPayload {
+		return 0, nil, errors.New("frame is not masked or is too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// Function to get whether a header lists a token, ignoring case.
+func headerHas(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
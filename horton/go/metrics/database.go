@@ -0,0 +1,189 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Database Metrics
+ * Wraps a database driver to time the queries run through it and count those that fail.
+ * Queries are timed until the database answers, not while their rows are read.
+ *
+ * Reference
+ * https://golang.org/pkg/database/sql/driver/
+ */
+
+package metrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// Operations queries are recorded by, the first word of the query. Anything else is recorded as other.
+var operations = []string{"select", "insert", "update", "delete", "replace"}
+
+// Operation returns the operation of a query e.g. "select" for "SELECT * FROM workers".
+func Operation(query string) string {
+	fields := strings.Fields(strings.TrimLeft(query, "("))
+	if len(fields) > 0 {
+		word := strings.ToLower(fields[0])
+		for _, op := range operations {
+			if word == op {
+				return op
+			}
+		}
+	}
+	return "other"
+}
+
+// WrapDriver returns a driver that opens connections with d and records their queries in QueryDuration and
+// QueryErrors.
+func WrapDriver(d driver.Driver) driver.Driver {
+	return wrappedDriver{d}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+}
+
+func (d wrappedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		QueryErrors.Inc("connect")
+		return nil, err
+	}
+	return wrappedConn{conn}, nil
+}
+
+// record records a query that took from start, it is not counted if the driver skipped it for it to be prepared.
+func record(query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	op := Operation(query)
+	QueryDuration.Observe(time.Since(start).Seconds(), op)
+	if err != nil {
+		QueryErrors.Inc(op)
+	}
+}
+
+type wrappedConn struct {
+	driver.Conn
+}
+
+func (c wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		QueryErrors.Inc(Operation(query))
+		return nil, err
+	}
+	return wrappedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result,
+	error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := e.ExecContext(ctx, query, args)
+	record(query, start, err)
+	return result, err
+}
+
+func (c wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows,
+	error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	record(query, start, err)
+	return rows, err
+}
+
+func (c wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+type wrappedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(values(args))
+	}
+	record(s.query, start, err)
+	return result, err
+}
+
+func (s wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	record(s.query, start, err)
+	return rows, err
+}
+
+func (s wrappedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
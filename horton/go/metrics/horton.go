@@ -0,0 +1,56 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Horton Metrics
+ * The metrics Horton records, served at /metrics.
+ */
+
+package metrics
+
+// Default is the registry of Horton's metrics.
+var Default = NewRegistry()
+
+// Requests to the API by the Name of their Route.
+var (
+	Requests = Default.Counter("horton_http_requests_total",
+		"Requests handled by route and status code.", "route", "code")
+
+	RequestDuration = Default.Histogram("horton_http_request_duration_seconds",
+		"Time taken to handle requests by route.", DefaultBuckets, "route")
+)
+
+// Queries of the database by operation e.g. select, see Operation.
+var (
+	QueryDuration = Default.Histogram("horton_db_query_duration_seconds",
+		"Time taken by the database to run queries by operation.", DefaultBuckets, "operation")
+
+	QueryErrors = Default.Counter("horton_db_errors_total",
+		"Database queries and connections that failed by operation.", "operation")
+)
+
+// Sessions and logins.
+var (
+	ActiveSessions = Default.Gauge("horton_sessions_active",
+		"Login sessions that have not expired.")
+
+	LoginFailures = Default.Counter("horton_login_failures_total",
+		"Logins refused by reason, unknown_user or wrong_password.", "reason")
+)
+
+// Requests to Back4App for vehicle data.
+var (
+	Back4AppDuration = Default.Histogram("horton_back4app_request_duration_seconds",
+		"Time taken by Back4App to answer requests.", DefaultBuckets)
+
+	Back4AppFailures = Default.Counter("horton_back4app_failures_total",
+		"Requests to Back4App that failed by reason, request, status or decode.", "reason")
+)
+
+// Deliveries of webhook events.
+var WebhookDeliveries = Default.Counter("horton_webhook_deliveries_total",
+	"Attempts to deliver webhook events by outcome, delivered, retry or failed.", "outcome")
+
+// Messages sent to customers.
+var NotificationMessages = Default.Counter("horton_notification_messages_total",
+	"Attempts to send messages to customers by channel and outcome, sent, retry or failed.", "channel", "outcome")
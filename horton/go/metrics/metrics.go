@@ -0,0 +1,274 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Metrics
+ * Counts and times what Horton does so it can be watched by Prometheus.
+ * Counters, gauges and histograms are kept in a Registry and written in the Prometheus text exposition format.
+ * Each is identified by its name and the values of its labels e.g. horton_http_requests_total{route="Login"}.
+ *
+ * Reference
+ * https://prometheus.io/docs/instrumenting/exposition_formats/
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds in seconds of the buckets latencies are counted in.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry keeps metrics and writes them in the order they were added, it is safe to share between requests.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+// family is a metric and every set of label values it has been recorded with.
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(buf)
+	}
+	return buf.Flush()
+}
+
+func (r *Registry) add(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// labelled is what every kind of metric has, the values recorded are kept by their label values.
+type labelled struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	series map[string][]string
+}
+
+func newLabelled(name, help string, labels []string) labelled {
+	return labelled{name: name, help: help, labels: labels, series: map[string][]string{}}
+}
+
+// key returns the key of a set of label values, panicking if there is not one value for each label.
+// It must be called with mu held.
+func (l *labelled) key(values []string) string {
+	if len(values) != len(l.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, recorded with %d values", l.name, len(l.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := l.series[key]; !ok {
+		l.series[key] = append([]string(nil), values...)
+	}
+	return key
+}
+
+// keys returns the key of every set of label values in order. It must be called with mu held.
+func (l *labelled) keys() []string {
+	keys := make([]string, 0, len(l.series))
+	for k := range l.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (l *labelled) header(w *bufio.Writer, kind string) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(l.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", l.name, help, l.name, kind)
+}
+
+// sample writes a value of the metric with the label values of key and any extra label e.g. the le of a bucket.
+func (l *labelled) sample(w *bufio.Writer, suffix, key string, extra []string, value float64) {
+	pairs := []string{}
+	for i, v := range l.series[key] {
+		pairs = append(pairs, l.labels[i]+`="`+escapeLabel(v)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	w.WriteString(l.name + suffix)
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// Counter is a count that only goes up e.g. of requests.
+type Counter struct {
+	labelled
+	values map[string]float64
+}
+
+// Counter adds a counter with a value for each set of label values.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{labelled: newLabelled(name, help, labels), values: map[string]float64{}}
+	r.add(c)
+	return c
+}
+
+// Inc adds one to the count of the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n to the count of the label values, n must not be negative.
+func (c *Counter) Add(n float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[c.key(values)] += n
+}
+
+// Value returns the count of the label values.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(values, "\xff")]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w, "counter")
+	for _, key := range c.keys() {
+		c.sample(w, "", key, nil, c.values[key])
+	}
+}
+
+// Gauge is a value that goes up and down e.g. of sessions.
+type Gauge struct {
+	labelled
+	values map[string]float64
+}
+
+// Gauge adds a gauge with a value for each set of label values.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{labelled: newLabelled(name, help, labels), values: map[string]float64{}}
+	r.add(g)
+	return g
+}
+
+// Set sets the value of the label values.
+func (g *Gauge) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[g.key(values)] = v
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(w, "gauge")
+	for _, key := range g.keys() {
+		g.sample(w, "", key, nil, g.values[key])
+	}
+}
+
+// Histogram counts values e.g. latencies in buckets by upper bound, with their sum and count.
+type Histogram struct {
+	labelled
+	buckets []float64
+	values  map[string]*observations
+}
+
+type observations struct {
+	// counts is the number of values in each bucket, not counting those in the buckets below.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram adds a histogram with buckets of the upper bounds given in increasing order.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{labelled: newLabelled(name, help, labels), buckets: buckets, values: map[string]*observations{}}
+	r.add(h)
+	return h
+}
+
+// Observe counts a value of the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := h.key(values)
+	o, ok := h.values[key]
+	if !ok {
+		o = &observations{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = o
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		o.counts[i]++
+	}
+	o.sum += v
+	o.count++
+}
+
+// Count returns how many values of the label values have been counted.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if o, ok := h.values[strings.Join(values, "\xff")]; ok {
+		return o.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w, "histogram")
+	for _, key := range h.keys() {
+		o := h.values[key]
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += o.counts[i]
+			h.sample(w, "_bucket", key, []string{"le", formatFloat(bound)}, float64(cumulative))
+		}
+		h.sample(w, "_bucket", key, []string{"le", "+Inf"}, float64(o.count))
+		h.sample(w, "_sum", key, nil, o.sum)
+		h.sample(w, "_count", key, nil, float64(o.count))
+	}
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
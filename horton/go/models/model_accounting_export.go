@@ -0,0 +1,36 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Accounting Export
+ * Model for the runs of exports to accounting packages.
+ */
+
+package models
+
+import "time"
+
+type AccountingExport struct {
+	ExportId int32 `json:"exportId"`
+
+	// Format is the accounting package exported to, "xero" or "sage".
+	Format string `json:"format"`
+
+	// Invoices, credit notes and payments not yet exported to the format are exported. FromTime is when the last run
+	// of the format was made, none for the first, and UpTo when this run was.
+	FromTime *time.Time `json:"fromTime,omitempty"`
+
+	UpTo time.Time `json:"upTo"`
+
+	// FromPaymentId and ToPaymentId are the first and last payment or refund exported.
+	FromPaymentId int32 `json:"fromPaymentId"`
+
+	ToPaymentId int32 `json:"toPaymentId"`
+
+	// Documents is the number of invoices and credit notes exported.
+	Documents int `json:"documents"`
+
+	Payments int `json:"payments"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
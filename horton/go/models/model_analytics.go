@@ -0,0 +1,96 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Analytics
+ * Models for the figures of workshop performance worked out from job reports.
+ */
+
+package models
+
+import "time"
+
+// Analytics is the result of an analytics query with the date range and grouping it covers.
+type Analytics struct {
+	// From and To are the dates of the job reports covered, inclusive.
+	From string `json:"from"`
+
+	To string `json:"to"`
+
+	// Period is the period rows are grouped by, day, week, month, year or all.
+	Period string `json:"period,omitempty"`
+
+	// By is what rows are grouped by within a period.
+	By string `json:"by,omitempty"`
+
+	// GeneratedAt is when the figures were worked out, they are cached for a short time.
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	Rows interface{} `json:"rows"`
+}
+
+// WorkerActivity is the jobs and hours of a worker in a period.
+type WorkerActivity struct {
+	Period string `json:"period"`
+
+	// Worker is the username of the worker, empty for jobs in the queue.
+	Worker string `json:"worker"`
+
+	WorkerName string `json:"workerName,omitempty"`
+
+	Jobs int `json:"jobs"`
+
+	Completed int `json:"completed"`
+
+	Hours int `json:"hours"`
+}
+
+// CommonValue is a cause or parts found in the jobs of a vehicle model and how many jobs.
+type CommonValue struct {
+	VehicleModel string `json:"vehicleModel"`
+
+	Value string `json:"value"`
+
+	Jobs int `json:"jobs"`
+}
+
+// WarrantySplit is the warranty and paid work of a period.
+type WarrantySplit struct {
+	Period string `json:"period"`
+
+	WarrantyJobs int `json:"warrantyJobs"`
+
+	PaidJobs int `json:"paidJobs"`
+
+	WarrantyHours int `json:"warrantyHours"`
+
+	PaidHours int `json:"paidHours"`
+
+	// WarrantyPercent is the percentage of jobs that were warranty work.
+	WarrantyPercent *float64 `json:"warrantyPercent,omitempty"`
+}
+
+// GroupHours is the hours of the completed jobs of a group e.g. a job type.
+type GroupHours struct {
+	Group string `json:"group"`
+
+	Jobs int `json:"jobs"`
+
+	TotalHours int `json:"totalHours"`
+
+	// AverageHours is the average of the jobs with hours recorded.
+	AverageHours *float64 `json:"averageHours,omitempty"`
+}
+
+// CompletionRate is how many of the jobs of a group in a period are complete.
+type CompletionRate struct {
+	Period string `json:"period"`
+
+	Group string `json:"group"`
+
+	Jobs int `json:"jobs"`
+
+	Completed int `json:"completed"`
+
+	CompletionPercent *float64 `json:"completionPercent,omitempty"`
+}
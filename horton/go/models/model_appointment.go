@@ -0,0 +1,153 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Appointment
+ * Models for appointments in the workshop diary and the calendar feeds of them.
+ */
+
+package models
+
+import "time"
+
+type Appointment struct {
+	AppointmentId int32 `json:"appointmentId"`
+
+	// Status is unconfirmed, pending, booked, cancelled or converted.
+	Status string `json:"status"`
+
+	// Source is garage for appointments booked by workers or online for those booked by customers.
+	Source string `json:"source"`
+
+	Start time.Time `json:"start"`
+
+	End time.Time `json:"end"`
+
+	Bay int `json:"bay"`
+
+	// Worker is the username of the worker at the appointment, empty if no one has been given it yet.
+	Worker string `json:"worker,omitempty"`
+
+	WorkerName string `json:"workerName,omitempty"`
+
+	CustomerName string `json:"customerName"`
+
+	CustomerPhone string `json:"customerPhone,omitempty"`
+
+	CustomerEmail string `json:"customerEmail,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+
+	// JobReportId is the report the appointment was converted to.
+	JobReportId int32 `json:"jobReportId,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AppointmentRequest is sent to book or change an appointment.
+type AppointmentRequest struct {
+	Start time.Time `json:"start" binding:"required"`
+
+	// Minutes is how long the appointment is, one slot if it is not set.
+	Minutes int `json:"minutes,omitempty"`
+
+	// Bay is the bay to book, the first free bay if it is not set.
+	Bay int `json:"bay,omitempty"`
+
+	// Worker is the username of the worker to give the appointment to.
+	Worker string `json:"worker,omitempty"`
+
+	CustomerName string `json:"customerName" binding:"required"`
+
+	CustomerPhone string `json:"customerPhone,omitempty"`
+
+	CustomerEmail string `json:"customerEmail,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+}
+
+// BookingRequest is sent by a customer to book an appointment online.
+type BookingRequest struct {
+	Start time.Time `json:"start" binding:"required"`
+
+	// Minutes is how long the appointment is, one slot if it is not set.
+	Minutes int `json:"minutes,omitempty"`
+
+	CustomerName string `json:"customerName" binding:"required"`
+
+	// CustomerEmail is where the link to confirm the booking is sent.
+	CustomerEmail string `json:"customerEmail" binding:"required"`
+
+	CustomerPhone string `json:"customerPhone,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint" binding:"required"`
+}
+
+// Booking is an appointment booked online as the customer sees it.
+type Booking struct {
+	Status string `json:"status"`
+
+	Start time.Time `json:"start"`
+
+	End time.Time `json:"end"`
+
+	CustomerName string `json:"customerName"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+
+	// ConfirmBy is when an unconfirmed booking must be confirmed by.
+	ConfirmBy *time.Time `json:"confirmBy,omitempty"`
+}
+
+// BookingDecision is sent by a supervisor to accept or decline a booking made online.
+type BookingDecision struct {
+	// Worker and Bay are the worker to give an accepted booking to and the bay to put it in, the first free bay
+	// if it is not set.
+	Worker string `json:"worker,omitempty"`
+
+	Bay int `json:"bay,omitempty"`
+
+	// Reason is why a booking is declined.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AppointmentSlot is a time an appointment could be booked at.
+type AppointmentSlot struct {
+	Start time.Time `json:"start"`
+
+	End time.Time `json:"end"`
+
+	FreeBays int `json:"freeBays"`
+}
+
+// CalendarFeed is the address of a calendar of appointments that phones can subscribe to.
+type CalendarFeed struct {
+	// Scope is worker for the user's appointments or garage for all of them.
+	Scope string `json:"scope"`
+
+	Url string `json:"url"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CalendarFeedRequest struct {
+	Scope string `json:"scope" binding:"required"`
+}
@@ -0,0 +1,33 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Attachment
+ * Model for photos and documents attached to reports.
+ */
+
+package models
+
+import "time"
+
+type Attachment struct {
+	AttachmentId int32 `json:"attachmentId"`
+
+	JobReportId int32 `json:"jobReportId"`
+
+	// Kind is what the file is - before, after, diagnostic, signed_form or other.
+	Kind string `json:"kind"`
+
+	FileName string `json:"fileName"`
+
+	ContentType string `json:"contentType"`
+
+	Size int64 `json:"size"`
+
+	UploadedBy string `json:"uploadedBy,omitempty"`
+
+	// StorageKey is where the file is kept in the Blob Store, it is never sent to the client.
+	StorageKey string `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
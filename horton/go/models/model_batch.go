@@ -0,0 +1,62 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Batch
+ * Models for creating, updating and deleting many reports in one request.
+ */
+
+package models
+
+type BatchRequest struct {
+	// Mode is "atomic" to apply all the operations or none of them (default),
+	// or "bestEffort" to apply each operation that succeeds.
+	Mode string `json:"mode"`
+
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchOperation struct {
+	// Op is "create", "update" or "delete".
+	Op string `json:"op"`
+
+	// ClientId is any ID the client gives the operation, sent back in its result to match them up.
+	ClientId string `json:"clientId,omitempty"`
+
+	// JobReportId is the report to update or delete.
+	JobReportId int32 `json:"jobReportId,omitempty"`
+
+	// Report is the report to create, or the new details of the report to update.
+	Report *JobReport `json:"report,omitempty"`
+}
+
+type BatchResult struct {
+	Mode string `json:"mode"`
+
+	// Applied is false when no operation was applied, e.g. an atomic batch that was rolled back.
+	Applied bool `json:"applied"`
+
+	Succeeded int `json:"succeeded"`
+
+	Failed int `json:"failed"`
+
+	Results []BatchItemResult `json:"results"`
+}
+
+type BatchItemResult struct {
+	// Index is the position of the operation in the request.
+	Index int `json:"index"`
+
+	Op string `json:"op"`
+
+	ClientId string `json:"clientId,omitempty"`
+
+	// Status is the HTTP status the operation would have had on its own.
+	// 424 means it was valid but not applied because another operation of an atomic batch failed.
+	Status int `json:"status"`
+
+	// JobReportId is the report operated on, for a create the ID of the new report.
+	JobReportId int32 `json:"jobReportId,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
@@ -0,0 +1,128 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Breakdown
+ * Models for breakdown call-outs, the workers dispatched to them and where workers are.
+ */
+
+package models
+
+import "time"
+
+// Breakdown is a call-out to a broken down vehicle with when it was answered.
+type Breakdown struct {
+	BreakdownId int32 `json:"breakdownId"`
+
+	// JobReportId is the job the worker fills in for the breakdown.
+	JobReportId int32 `json:"jobReportId"`
+
+	// Priority is critical, urgent or normal.
+	Priority string `json:"priority"`
+
+	// Status is waiting, dispatched, acknowledged, arrived, resolved or cancelled.
+	Status string `json:"status"`
+
+	// Contract is the name of the SLA contract the breakdown is under, none if it is held to the default targets.
+	Contract string `json:"contract,omitempty"`
+
+	Latitude float64 `json:"latitude"`
+
+	Longitude float64 `json:"longitude"`
+
+	VehicleLocation string `json:"vehicleLocation,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	CustomerName string `json:"customerName,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+
+	// Worker is the username of the worker dispatched to the breakdown.
+	Worker string `json:"worker,omitempty"`
+
+	JobComplete bool `json:"jobComplete"`
+
+	RequestedAt time.Time `json:"requestedAt"`
+
+	DispatchedAt *time.Time `json:"dispatchedAt,omitempty"`
+
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+
+	ArrivedAt *time.Time `json:"arrivedAt,omitempty"`
+
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+
+	// AcknowledgeMinutes, ArriveMinutes and ResolveMinutes are how long after the breakdown was requested it was
+	// acknowledged, arrived at and resolved.
+	AcknowledgeMinutes *int `json:"acknowledgeMinutes,omitempty"`
+
+	ArriveMinutes *int `json:"arriveMinutes,omitempty"`
+
+	ResolveMinutes *int `json:"resolveMinutes,omitempty"`
+
+	// SLA is how the breakdown is doing against the targets it is held to.
+	SLA []SLAResult `json:"sla"`
+}
+
+// BreakdownRequest is sent to put a breakdown in the dispatch queue.
+type BreakdownRequest struct {
+	// Priority is critical, urgent or normal, normal if it is not set.
+	Priority string `json:"priority,omitempty"`
+
+	// Contract is the name of the SLA contract the breakdown is under, the contract of the report's customer if
+	// it is not set.
+	Contract string `json:"contract,omitempty"`
+
+	// Latitude and Longitude are where the vehicle is, found from the report's vehicle location if they are not set.
+	Latitude *float64 `json:"latitude,omitempty"`
+
+	Longitude *float64 `json:"longitude,omitempty"`
+
+	Report *JobReport `json:"report" binding:"required"`
+}
+
+// DispatchRequest is sent to send a worker to a breakdown.
+type DispatchRequest struct {
+	Worker string `json:"worker" binding:"required"`
+}
+
+// WorkerPosition is where a worker last reported being.
+type WorkerPosition struct {
+	Worker string `json:"worker"`
+
+	WorkerName string `json:"workerName,omitempty"`
+
+	Latitude float64 `json:"latitude"`
+
+	Longitude float64 `json:"longitude"`
+
+	// Available is whether the worker can take call-outs, Busy whether they are on a breakdown.
+	Available bool `json:"available"`
+
+	Busy bool `json:"busy"`
+
+	// Silent is whether the worker is on a call-out and has not been heard from for too long.
+	Silent bool `json:"silent"`
+
+	ReportedAt time.Time `json:"reportedAt"`
+}
+
+// WorkerPositionRequest is sent by a worker to report where they are.
+type WorkerPositionRequest struct {
+	Latitude *float64 `json:"latitude" binding:"required"`
+
+	Longitude *float64 `json:"longitude" binding:"required"`
+
+	// Available is whether the worker can take call-outs, unchanged if it is not set.
+	Available *bool `json:"available,omitempty"`
+}
+
+// DispatchSuggestion is a free worker who could be sent to a breakdown.
+type DispatchSuggestion struct {
+	WorkerPosition
+
+	DistanceKm float64 `json:"distanceKm"`
+}
@@ -0,0 +1,115 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Date
+ * Model for calendar dates on reports.
+ * Dates are read as ISO 8601 (2020-04-03) or the legacy DD-MM-YYYY format (03-04-2020)
+ * and are always sent to the client and stored in MySQL as ISO 8601.
+ */
+
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateLayout is the ISO 8601 layout dates are sent to the client in.
+const DateLayout = "2006-01-02"
+
+// Layouts accepted by ParseDate, ISO 8601 first.
+var dateLayouts = []string{
+	DateLayout,
+	time.RFC3339,
+	"02-01-2006", // Legacy date_stamp format.
+	"02/01/2006",
+}
+
+type Date struct {
+	time.Time
+}
+
+// ParseDate reads a date in ISO 8601 or the legacy DD-MM-YYYY format.
+// Only the calendar date is kept, the time of day of an ISO 8601 timestamp is dropped.
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return NewDate(t), nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD-MM-YYYY", s)
+}
+
+// NewDate returns the calendar date of t.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// String returns the date in ISO 8601.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+// MarshalJSON sends the date as an ISO 8601 string.
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON reads a date sent by the client with ParseDate.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" || s == `""` {
+		*d = Date{}
+		return nil
+	}
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return errors.New("date must be a string")
+	}
+
+	date, err := ParseDate(s[1 : len(s)-1])
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+// Scan reads a DATE column from MySQL.
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case time.Time:
+		*d = NewDate(v)
+		return nil
+	case []byte:
+		date, err := ParseDate(string(v))
+		*d = date
+		return err
+	case string:
+		date, err := ParseDate(v)
+		*d = date
+		return err
+	}
+	return fmt.Errorf("cannot scan %T into Date", value)
+}
+
+// Value writes the date to a DATE column in MySQL.
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
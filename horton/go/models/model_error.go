@@ -12,4 +12,13 @@ type Error struct {
 	Code int32 `json:"code"`
 
 	Messages string `json:"messages"`
+
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is the error for one field of a request that failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+
+	Message string `json:"message"`
 }
@@ -0,0 +1,97 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Estimate
+ * Models for estimates (quotes) given to customers before work starts, and their decisions. Amounts are in cents.
+ */
+
+package models
+
+import "time"
+
+type Estimate struct {
+	EstimateId int32 `json:"estimateId"`
+
+	// Status is draft, sent, accepted, declined, expired or converted.
+	Status string `json:"status"`
+
+	CustomerName string `json:"customerName"`
+
+	// ContactId is the notification preferences the estimate is sent to the customer with, 0 for none.
+	ContactId int32 `json:"contactId,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+
+	Currency string `json:"currency"`
+
+	SubtotalCents int64 `json:"subtotalCents"`
+
+	VatCents int64 `json:"vatCents"`
+
+	TotalCents int64 `json:"totalCents"`
+
+	// Lines are the same as the lines of an invoice.
+	Lines []InvoiceLine `json:"lines,omitempty"`
+
+	ExpiresOn *Date `json:"expiresOn,omitempty"`
+
+	// ApprovalUrl is the link the customer accepts or declines the estimate at, once it is sent.
+	ApprovalUrl string `json:"approvalUrl,omitempty"`
+
+	ApprovalToken string `json:"-"`
+
+	// DecidedBy, DecisionNote and DecidedAt are the name and note the customer gave when they accepted or declined.
+	DecidedBy string `json:"decidedBy,omitempty"`
+
+	DecisionNote string `json:"decisionNote,omitempty"`
+
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+
+	// JobReportId is the report the estimate was converted to.
+	JobReportId int32 `json:"jobReportId,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// EstimateRequest is sent to create or update an estimate.
+type EstimateRequest struct {
+	CustomerName string `json:"customerName" binding:"required"`
+
+	// ContactId is the notification preferences the estimate is sent to the customer with.
+	ContactId int32 `json:"contactId,omitempty"`
+
+	VehicleModel string `json:"vehicleModel,omitempty"`
+
+	VehicleReg string `json:"vehicleReg,omitempty"`
+
+	Complaint string `json:"complaint,omitempty"`
+
+	// LabourHours adds a line of labour at the standard rate.
+	LabourHours float64 `json:"labourHours,omitempty"`
+
+	Lines []InvoiceLine `json:"lines,omitempty"`
+
+	// ExpiresOn is validity_days in config.ini from when the estimate is sent if it is not set.
+	ExpiresOn *Date `json:"expiresOn,omitempty"`
+}
+
+// EstimateDecision is sent by the customer to accept or decline an estimate.
+type EstimateDecision struct {
+	Name string `json:"name,omitempty"`
+
+	Note string `json:"note,omitempty"`
+}
+
+// PublicEstimate is an estimate as the customer sees it at its approval link, with the garage's details.
+type PublicEstimate struct {
+	Garage Garage `json:"garage"`
+
+	Estimate Estimate `json:"estimate"`
+}
@@ -0,0 +1,24 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Garage
+ * Model for the garage's details shown at the top of printed documents.
+ */
+
+package models
+
+type Garage struct {
+	Name string `json:"name,omitempty"`
+
+	Address string `json:"address,omitempty"`
+
+	Phone string `json:"phone,omitempty"`
+
+	Email string `json:"email,omitempty"`
+
+	VatNumber string `json:"vatNumber,omitempty"`
+
+	// LogoPath is the path of a PNG, JPEG or GIF logo, empty for no logo.
+	LogoPath string `json:"-"`
+}
@@ -0,0 +1,38 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Import
+ * Model for the result of importing reports from a CSV file.
+ */
+
+package models
+
+type ImportResult struct {
+	// DryRun is true when the rows were only checked and nothing was saved.
+	DryRun bool `json:"dryRun"`
+
+	// Rows is the number of rows read, not counting the header.
+	Rows int `json:"rows"`
+
+	// Valid is the number of rows that passed validation.
+	Valid int `json:"valid"`
+
+	// Imported is the number of reports saved, always 0 for a dry run.
+	Imported int `json:"imported"`
+
+	Rejected int `json:"rejected"`
+
+	// Errors are the errors of each rejected row.
+	Errors []ImportRowError `json:"errors"`
+
+	// ErrorReport is where to download the rejected rows as CSV, empty if none were rejected.
+	ErrorReport string `json:"errorReport,omitempty"`
+}
+
+type ImportRowError struct {
+	// Row is the number of the row in the file, the header is row 1.
+	Row int `json:"row"`
+
+	Fields []FieldError `json:"fields"`
+}
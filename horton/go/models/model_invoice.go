@@ -0,0 +1,99 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Invoice
+ * Models for invoices and credit notes of job reports. Amounts are in cents.
+ */
+
+package models
+
+import "time"
+
+type Invoice struct {
+	InvoiceId int32 `json:"invoiceId"`
+
+	JobReportId int32 `json:"jobReportId"`
+
+	// Kind is "invoice" or "credit_note".
+	Kind string `json:"kind"`
+
+	// CreditedInvoiceId is the invoice a credit note credits.
+	CreditedInvoiceId int32 `json:"creditedInvoiceId,omitempty"`
+
+	// InvoiceNumber is given when the invoice is issued, from a sequence with no gaps e.g. "INV-000042".
+	InvoiceNumber string `json:"invoiceNumber,omitempty"`
+
+	// Status is draft, issued, paid or void.
+	Status string `json:"status"`
+
+	// BillTo is "customer", or "warranty_provider" for warranty jobs.
+	BillTo string `json:"billTo"`
+
+	BillToName string `json:"billToName"`
+
+	BillToAddress string `json:"billToAddress,omitempty"`
+
+	Currency string `json:"currency"`
+
+	SubtotalCents int64 `json:"subtotalCents"`
+
+	VatCents int64 `json:"vatCents"`
+
+	TotalCents int64 `json:"totalCents"`
+
+	// Reason is why a credit note was raised.
+	Reason string `json:"reason,omitempty"`
+
+	// Lines are only sent with a single invoice, not with lists of invoices.
+	Lines []InvoiceLine `json:"lines,omitempty"`
+
+	IssuedAt *time.Time `json:"issuedAt,omitempty"`
+
+	DueDate *Date `json:"dueDate,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type InvoiceLine struct {
+	// Kind is labour, overtime, warranty_labour, part or other.
+	Kind string `json:"kind"`
+
+	Description string `json:"description"`
+
+	// Quantity is the number of parts or hours of labour.
+	Quantity float64 `json:"quantity"`
+
+	UnitPriceCents int64 `json:"unitPriceCents"`
+
+	// VatRate is a percentage e.g. 13.5 or 0 for zero rated lines, lines sent without one get the rate in config.ini
+	// for their kind.
+	VatRate *float64 `json:"vatRate"`
+
+	NetCents int64 `json:"netCents"`
+
+	VatCents int64 `json:"vatCents"`
+}
+
+// InvoiceRequest is sent to create or update an invoice or raise a credit note.
+type InvoiceRequest struct {
+	// OvertimeHours are the hours of the report's workHours charged at the overtime rate.
+	OvertimeHours float64 `json:"overtimeHours,omitempty"`
+
+	// Lines are the parts and other lines added to the labour of an invoice, or the lines credited by a credit note.
+	Lines []InvoiceLine `json:"lines,omitempty"`
+
+	BillToName string `json:"billToName,omitempty"`
+
+	BillToAddress string `json:"billToAddress,omitempty"`
+
+	// Reason is why a credit note is raised.
+	Reason string `json:"reason,omitempty"`
+}
+
+// InvoiceStatus is sent to move an invoice to another status.
+type InvoiceStatus struct {
+	Status string `json:"status" binding:"required"`
+}
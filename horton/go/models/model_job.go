@@ -0,0 +1,54 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Job
+ * Models for jobs supervisors create and assign to workers or leave in the queue.
+ */
+
+package models
+
+import "time"
+
+// Job is a report created by a supervisor with who it is assigned to.
+type Job struct {
+	JobReport
+
+	// AssignedTo is the username of the worker the job is assigned to, empty while it is in the queue.
+	AssignedTo string `json:"assignedTo,omitempty"`
+
+	// CreatedBy is the username of the supervisor who created the job.
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	AssignedAt *time.Time `json:"assignedAt,omitempty"`
+}
+
+type JobRequest struct {
+	// AssignTo is the username of the worker to assign the job to, empty to leave it in the queue.
+	AssignTo string `json:"assignTo,omitempty"`
+
+	Report *JobReport `json:"report" binding:"required"`
+}
+
+type JobAssignmentRequest struct {
+	// AssignTo is the username of the worker to give the job to, empty to put it back in the queue.
+	AssignTo string `json:"assignTo"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// JobAssignment is a change in the history of who a job is assigned to.
+type JobAssignment struct {
+	// Action is assigned, claimed, reassigned or unassigned.
+	Action string `json:"action"`
+
+	FromWorker string `json:"fromWorker,omitempty"`
+
+	ToWorker string `json:"toWorker,omitempty"`
+
+	ChangedBy string `json:"changedBy"`
+
+	Reason string `json:"reason,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
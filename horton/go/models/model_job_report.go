@@ -8,25 +8,51 @@
 
 package models
 
+import "time"
+
 type JobReport struct {
 	JobReportId int32 `json:"jobReportId,omitempty"`
 
-	Date string `json:"date,omitempty"`
+	Date Date `json:"date"`
 
 	VehicleModel string `json:"vehicleModel,omitempty"`
 
 	VehicleReg string `json:"vehicleReg,omitempty"`
 
+	VehicleRegYear int32 `json:"vehicleRegYear,omitempty"`
+
+	VehicleRegCounty string `json:"vehicleRegCounty,omitempty"`
+
 	VehicleLocation string `json:"vehicleLocation,omitempty"`
 
+	// Latitude and Longitude are where the vehicle location is, found in the gazetteer.
+	// They are not set when the location is not a place the gazetteer knows.
+	Latitude *float64 `json:"latitude,omitempty"`
+
+	Longitude *float64 `json:"longitude,omitempty"`
+
 	MilesOnVehicle int32 `json:"milesOnVehicle,omitempty"`
 
+	OdometerReading int32 `json:"odometerReading,omitempty"`
+
+	OdometerUnit string `json:"odometerUnit,omitempty"`
+
+	OdometerKm int32 `json:"odometerKm,omitempty"`
+
+	OriginalOdometerReading int32 `json:"originalOdometerReading,omitempty"`
+
+	OriginalOdometerUnit string `json:"originalOdometerUnit,omitempty"`
+
 	Warranty int32 `json:"warranty"`
 
 	Breakdown int32 `json:"breakdown"`
 
 	CustomerName string `json:"customerName,omitempty"`
 
+	// ContactId is the notification preferences the customer is sent messages about the job with, none if not set.
+	// An update without it keeps the report's contact, 0 unlinks it.
+	ContactId *int32 `json:"contactId,omitempty"`
+
 	Complaint string `json:"complaint,omitempty"`
 
 	Cause string `json:"cause,omitempty"`
@@ -40,4 +66,15 @@ type JobReport struct {
 	WorkerName string `json:"workerName,omitempty"`
 
 	JobComplete int32 `json:"jobComplete"`
+
+	Signed bool `json:"signed"`
+
+	ModifiedAfterSigning bool `json:"modifiedAfterSigning"`
+
+	// SignedReportHash is the hash of the report when the customer signed it, empty if not signed.
+	SignedReportHash string `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,25 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Location
+ * Model for the places suggested as vehicle locations are typed.
+ */
+
+package models
+
+type Location struct {
+	// Label is how the place is written as a vehicle location e.g. "Gort, Co. Galway".
+	Label string `json:"label"`
+
+	Name string `json:"name"`
+
+	County string `json:"county"`
+
+	// Kind is town or townland.
+	Kind string `json:"kind"`
+
+	Latitude float64 `json:"latitude"`
+
+	Longitude float64 `json:"longitude"`
+}
@@ -0,0 +1,82 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Notification
+ * Models for customers' notification preferences and the log of the messages sent to them.
+ */
+
+package models
+
+import (
+	"time"
+)
+
+// NotificationPreferences is how a customer is sent messages. It is a contact their reports and estimates are
+// linked to by ContactId, so customers with the same name are kept apart.
+type NotificationPreferences struct {
+	ContactId int32 `json:"contactId"`
+
+	CustomerName string `json:"customerName" binding:"required"`
+
+	Email string `json:"email,omitempty"`
+
+	// Phone is in international format e.g. +353871234567.
+	Phone string `json:"phone,omitempty"`
+
+	// Channels are email and/or sms, none for no messages.
+	Channels []string `json:"channels"`
+
+	// OptedOut is true once the customer stops messages, none are sent until it is false again.
+	OptedOut bool `json:"optedOut"`
+
+	OptedOutAt *time.Time `json:"optedOutAt,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NotificationOptOut is a customer's answer to opting out at the link in their messages.
+type NotificationOptOut struct {
+	CustomerName string `json:"customerName"`
+
+	OptedOut bool `json:"optedOut"`
+}
+
+// NotificationMessage is a message to a customer in the message log.
+type NotificationMessage struct {
+	MessageId int64 `json:"messageId"`
+
+	Event string `json:"event"`
+
+	// Channel is email or sms, Recipient the email address or phone number it is sent to.
+	Channel string `json:"channel"`
+
+	CustomerName string `json:"customerName"`
+
+	// ContactId is the preferences the message was sent with, 0 for a booking that matched none.
+	ContactId int32 `json:"contactId,omitempty"`
+
+	Recipient string `json:"recipient"`
+
+	JobReportId *int64 `json:"jobReportId,omitempty"`
+
+	Subject string `json:"subject,omitempty"`
+
+	Body string `json:"body"`
+
+	// Status is pending, sent, failed once every attempt has failed or skipped if the customer opted out.
+	Status string `json:"status"`
+
+	Attempts int `json:"attempts"`
+
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	LastError string `json:"lastError,omitempty"`
+
+	// ProviderId is the Message-ID of an email or the ID the SMS gateway gave a text message.
+	ProviderId string `json:"providerId,omitempty"`
+
+	SentAt *time.Time `json:"sentAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,124 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Payment
+ * Models for payments and refunds against invoices, balances and the aged debtors report. Amounts are in cents.
+ */
+
+package models
+
+import "time"
+
+// Payment is an entry of the payment ledger, a payment or a refund of a payment.
+type Payment struct {
+	PaymentId int32 `json:"paymentId"`
+
+	InvoiceId int32 `json:"invoiceId"`
+
+	// Type is "payment" or "refund".
+	Type string `json:"type"`
+
+	// Method is card, cash or bank_transfer.
+	Method string `json:"method"`
+
+	// AmountCents is negative for refunds.
+	AmountCents int64 `json:"amountCents"`
+
+	Currency string `json:"currency"`
+
+	// Provider and ProviderRef are the payment provider of card payments and its reference for them.
+	Provider string `json:"provider,omitempty"`
+
+	ProviderRef string `json:"providerRef,omitempty"`
+
+	// RefundedPaymentId is the payment a refund gives back.
+	RefundedPaymentId int32 `json:"refundedPaymentId,omitempty"`
+
+	// Reference is the bank transfer reference or receipt number.
+	Reference string `json:"reference,omitempty"`
+
+	Note string `json:"note,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PaymentRequest is sent to record a payment against an invoice.
+type PaymentRequest struct {
+	Method string `json:"method" binding:"required"`
+
+	AmountCents int64 `json:"amountCents" binding:"required"`
+
+	// CardToken is the token of the card from the payment provider, for card payments.
+	CardToken string `json:"cardToken,omitempty"`
+
+	Reference string `json:"reference,omitempty"`
+
+	Note string `json:"note,omitempty"`
+}
+
+// RefundRequest is sent to refund a payment, all that is left of it if AmountCents is 0.
+type RefundRequest struct {
+	AmountCents int64 `json:"amountCents,omitempty"`
+
+	Reason string `json:"reason" binding:"required"`
+}
+
+// InvoiceBalance is what has been paid and is still owed on an invoice.
+type InvoiceBalance struct {
+	InvoiceId int32 `json:"invoiceId"`
+
+	InvoiceNumber string `json:"invoiceNumber"`
+
+	Status string `json:"status"`
+
+	BillToName string `json:"billToName"`
+
+	Currency string `json:"currency"`
+
+	TotalCents int64 `json:"totalCents"`
+
+	// CreditedCents is the total of the invoice's credit notes.
+	CreditedCents int64 `json:"creditedCents"`
+
+	// PaidCents is the net of payments less refunds.
+	PaidCents int64 `json:"paidCents"`
+
+	// OutstandingCents is negative if more has been paid than is owed.
+	OutstandingCents int64 `json:"outstandingCents"`
+
+	DueDate *Date `json:"dueDate,omitempty"`
+
+	// Payments are only sent with the balance of a single invoice.
+	Payments []Payment `json:"payments,omitempty"`
+}
+
+// AgedDebtor is what a debtor owes, split by how long it is overdue.
+type AgedDebtor struct {
+	BillToName string `json:"billToName"`
+
+	// Invoices is the number of invoices with something still owed.
+	Invoices int `json:"invoices"`
+
+	// CurrentCents is not yet due.
+	CurrentCents int64 `json:"currentCents"`
+
+	Days1To30Cents int64 `json:"days1To30Cents"`
+
+	Days31To60Cents int64 `json:"days31To60Cents"`
+
+	Days61To90Cents int64 `json:"days61To90Cents"`
+
+	Over90DaysCents int64 `json:"over90DaysCents"`
+
+	TotalCents int64 `json:"totalCents"`
+}
+
+// AgedDebtors is the aged debtors report.
+type AgedDebtors struct {
+	AsOf Date `json:"asOf"`
+
+	Debtors []AgedDebtor `json:"debtors"`
+
+	Totals AgedDebtor `json:"totals"`
+}
@@ -0,0 +1,34 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Report Event
+ * Model for the live events of job reports pushed to clients.
+ */
+
+package models
+
+import (
+	"time"
+)
+
+// ReportEvent is a change to a job report pushed to a client. Clients get the report to see what changed.
+type ReportEvent struct {
+	// Id is sent back as Last-Event-ID to be sent the events after it when reconnecting.
+	Id int64 `json:"id"`
+
+	Type string `json:"type"`
+
+	JobReportId int64 `json:"jobReportId"`
+
+	// Status is what happened for report.status, e.g. claimed or completed.
+	Status string `json:"status,omitempty"`
+
+	// Worker is who the report is assigned to after the change, empty for reports in the queue.
+	Worker string `json:"worker,omitempty"`
+
+	// PreviousWorker is who the report was assigned to before it moved.
+	PreviousWorker string `json:"previousWorker,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
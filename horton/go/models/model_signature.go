@@ -0,0 +1,51 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Signature
+ * Model for a customer's signature on a report at handover.
+ * The signature is either an image or the strokes drawn on the tablet.
+ */
+
+package models
+
+import "time"
+
+type Signature struct {
+	SignatureId int32 `json:"signatureId,omitempty"`
+
+	JobReportId int32 `json:"jobReportId,omitempty"`
+
+	SignerName string `json:"signerName"`
+
+	// Image is a base64 PNG or JPEG, may be a data URL e.g. "data:image/png;base64,...".
+	Image string `json:"image,omitempty"`
+
+	// Strokes are the lines drawn by the customer, each a list of points.
+	Strokes [][]StrokePoint `json:"strokes,omitempty"`
+
+	// ReportHash is the SHA256 of the report's contents when it was signed.
+	ReportHash string `json:"reportHash,omitempty"`
+
+	// SignatureHash is the SHA256 of the signature image or strokes.
+	SignatureHash string `json:"signatureHash,omitempty"`
+
+	// CurrentHash is the SHA256 of the report's contents now.
+	CurrentHash string `json:"currentHash,omitempty"`
+
+	// ModifiedAfterSigning is true if the report has changed since it was signed.
+	ModifiedAfterSigning bool `json:"modifiedAfterSigning"`
+
+	SignedBy string `json:"signedBy,omitempty"`
+
+	SignedAt time.Time `json:"signedAt"`
+}
+
+// StrokePoint is a point of a signature stroke, T is the milliseconds since the stroke started.
+type StrokePoint struct {
+	X float64 `json:"x"`
+
+	Y float64 `json:"y"`
+
+	T int64 `json:"t,omitempty"`
+}
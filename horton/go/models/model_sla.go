@@ -0,0 +1,96 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * SLA
+ * Models for the response times agreed with customers for breakdowns and how well they were kept to.
+ */
+
+package models
+
+import "time"
+
+// SLAContract is a service level agreement with a customer, the minutes breakdowns are to be acknowledged,
+// arrived at and resolved in from being requested. A target of 0 is no target.
+type SLAContract struct {
+	ContractId int32 `json:"contractId"`
+
+	Name string `json:"name" binding:"required"`
+
+	// CustomerName is the customer whose breakdowns are under the contract unless another is named.
+	CustomerName string `json:"customerName,omitempty"`
+
+	AcknowledgeMinutes int `json:"acknowledgeMinutes"`
+
+	ArriveMinutes int `json:"arriveMinutes"`
+
+	ResolveMinutes int `json:"resolveMinutes"`
+
+	// Active contracts are the ones new breakdowns are put under.
+	Active *bool `json:"active,omitempty"`
+}
+
+// SLAResult is how a breakdown did against the target of a stage, acknowledge, arrive or resolve.
+type SLAResult struct {
+	Stage string `json:"stage"`
+
+	TargetMinutes int `json:"targetMinutes"`
+
+	Due time.Time `json:"due"`
+
+	// Minutes is how long after the breakdown was requested the stage was reached.
+	Minutes *int `json:"minutes,omitempty"`
+
+	// Outcome is met, breached or pending.
+	Outcome string `json:"outcome"`
+
+	// AtRisk is whether the stage is pending and due soon.
+	AtRisk bool `json:"atRisk,omitempty"`
+}
+
+// SLAAlert is the data of the events sent when a stage of a breakdown is at risk of breaching its target or has.
+type SLAAlert struct {
+	Stage string `json:"stage"`
+
+	Breakdown Breakdown `json:"breakdown"`
+}
+
+// SLAStage is the count of a stage's outcomes across the breakdowns of a report.
+type SLAStage struct {
+	Stage string `json:"stage"`
+
+	Total int `json:"total"`
+
+	Met int `json:"met"`
+
+	Breached int `json:"breached"`
+
+	Pending int `json:"pending"`
+
+	// CompliancePercent is the percentage met of those met or breached.
+	CompliancePercent *float64 `json:"compliancePercent,omitempty"`
+
+	AverageMinutes *float64 `json:"averageMinutes,omitempty"`
+}
+
+// SLAContractReport is how the breakdowns under a contract did in a month.
+type SLAContractReport struct {
+	// Contract is the name of the contract, empty for breakdowns held to the default targets.
+	Contract string `json:"contract"`
+
+	Breakdowns int `json:"breakdowns"`
+
+	Stages []SLAStage `json:"stages"`
+}
+
+// SLAReport is the compliance of breakdowns requested in a month with the targets they were held to.
+type SLAReport struct {
+	// Month is like 2020-06.
+	Month string `json:"month"`
+
+	Breakdowns int `json:"breakdowns"`
+
+	Stages []SLAStage `json:"stages"`
+
+	Contracts []SLAContractReport `json:"contracts"`
+}
@@ -0,0 +1,35 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Tracking
+ * Models for the GPS pings workers' devices send.
+ */
+
+package models
+
+import "time"
+
+// WorkerPing is a position recorded by a worker's device.
+type WorkerPing struct {
+	// Worker is the username of the worker, set when pings are listed.
+	Worker string `json:"worker,omitempty"`
+
+	Latitude *float64 `json:"latitude" binding:"required"`
+
+	Longitude *float64 `json:"longitude" binding:"required"`
+
+	// AccuracyM is how far off the position could be in metres.
+	AccuracyM *float64 `json:"accuracyM,omitempty"`
+
+	// RecordedAt is when the device recorded the position, when it is received if it is not set.
+	RecordedAt *time.Time `json:"recordedAt,omitempty"`
+}
+
+// WorkerPingsRequest is sent by a worker's device with the pings it has recorded since it last sent them.
+type WorkerPingsRequest struct {
+	Pings []WorkerPing `json:"pings" binding:"required,min=1,dive"`
+
+	// Available is whether the worker can take call-outs, unchanged if it is not set.
+	Available *bool `json:"available,omitempty"`
+}
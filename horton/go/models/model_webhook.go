@@ -0,0 +1,104 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Webhook
+ * Models for the endpoints the events of job reports are sent to and the log of their deliveries.
+ */
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint events are sent to.
+type Webhook struct {
+	WebhookId int32 `json:"webhookId"`
+
+	URL string `json:"url" binding:"required"`
+
+	// Events are the events sent e.g. report.completed.
+	Events []string `json:"events" binding:"required"`
+
+	// CustomerName limits the events sent to those of the customer's reports.
+	CustomerName string `json:"customerName,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// Active endpoints are sent events, events recorded while an endpoint is not active are not sent to it.
+	Active *bool `json:"active,omitempty"`
+
+	// Secret signs the requests sent to the endpoint, it is only given when the endpoint is added.
+	Secret string `json:"secret,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEvent is the body of the requests sent to endpoints.
+type WebhookEvent struct {
+	// EventId is the same for every delivery of the event, so endpoints can tell when they are sent it again.
+	EventId int64 `json:"id"`
+
+	Type string `json:"type"`
+
+	JobReportId int64 `json:"jobReportId"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Data is the job report when the event happened, as it was before being deleted for report.deleted, or an
+	// SLAAlert for the events of breakdowns.
+	Data json.RawMessage `json:"data"`
+}
+
+// WebhookDelivery is an event being sent to an endpoint.
+type WebhookDelivery struct {
+	DeliveryId int64 `json:"deliveryId"`
+
+	WebhookId int32 `json:"webhookId"`
+
+	URL string `json:"url"`
+
+	EventId int64 `json:"eventId"`
+
+	Event string `json:"event"`
+
+	JobReportId int64 `json:"jobReportId"`
+
+	// Status is pending, delivered or failed once every attempt has failed.
+	Status string `json:"status"`
+
+	Attempts int `json:"attempts"`
+
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	LastStatusCode *int `json:"lastStatusCode,omitempty"`
+
+	LastError string `json:"lastError,omitempty"`
+
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+
+	// RedeliveryOf is the delivery this one sends again.
+	RedeliveryOf *int64 `json:"redeliveryOf,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Log is every attempt to send the event, given for a single delivery.
+	Log []WebhookAttempt `json:"log,omitempty"`
+}
+
+// WebhookAttempt is an attempt to send an event to an endpoint.
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attemptedAt"`
+
+	// StatusCode is the status the endpoint answered with, left out if it did not answer.
+	StatusCode *int `json:"statusCode,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// Response is the start of the endpoint's response.
+	Response string `json:"response,omitempty"`
+
+	DurationMs int `json:"durationMs"`
+}
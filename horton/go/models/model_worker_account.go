@@ -13,4 +13,7 @@ type WorkerAccount struct {
 	Username   string
 	WorkerName string
 	Password   string
+
+	// Role is worker or supervisor, supervisors create and assign jobs.
+	Role string
 }
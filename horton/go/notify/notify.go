@@ -0,0 +1,176 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Notify
+ * Sends customers messages about their jobs and bookings by email and SMS, from templates the garage can edit.
+ * Templates are files named after the event and channel e.g. job.completed.email.tmpl, written with text/template.
+ * Email templates start with a "Subject:" line, the lines after it are the body.
+ *
+ * Reference
+ * https://golang.org/pkg/text/template/
+ */
+
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Events customers are sent messages for.
+const (
+	JobCreated      = "job.created"
+	EstimateSent    = "estimate.sent"
+	JobCompleted    = "job.completed"
+	BookingConfirm  = "booking.confirm"
+	BookingAccepted = "booking.accepted"
+	BookingDeclined = "booking.declined"
+)
+
+// Events is every event customers can be sent messages for.
+var Events = []string{JobCreated, EstimateSent, JobCompleted, BookingConfirm, BookingAccepted, BookingDeclined}
+
+// Channels messages are sent by.
+const (
+	Email = "email"
+	SMS   = "sms"
+)
+
+// Channels is every channel messages can be sent by.
+var Channels = []string{Email, SMS}
+
+// Statuses of a message in the message log. Skipped messages were not sent as the customer opted out.
+const (
+	Pending = "pending"
+	Sent    = "sent"
+	Failed  = "failed"
+	Skipped = "skipped"
+)
+
+// Data is what templates are filled in with. Fields that do not apply to an event are empty.
+type Data struct {
+	GarageName   string
+	GaragePhone  string
+	CustomerName string
+	JobReportId  int64
+	VehicleModel string
+	VehicleReg   string
+	// Link is where the customer confirms a booking or accepts an estimate.
+	Link string
+	// Total is the estimate's total with its currency, ExpiresOn when it can be accepted until.
+	Total     string
+	ExpiresOn string
+	// Start is when a booking starts, Reason why it was declined.
+	Start  time.Time
+	Reason string
+	// OptOutLink is where the customer stops messages, empty if they have no preferences to opt out of.
+	OptOutLink string
+}
+
+// Message is a message to send to a customer.
+type Message struct {
+	Id      int64
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends messages by a channel, returning the ID the provider gave the message if any.
+type Sender interface {
+	Send(m Message) (string, error)
+}
+
+// Templates are the templates of each event and channel, keyed by "event.channel".
+type Templates map[string]*template.Template
+
+// LoadTemplates loads the templates in dir. An event and channel without a template is not sent.
+func LoadTemplates(dir string) (Templates, error) {
+	templates := Templates{}
+	for _, event := range Events {
+		for _, channel := range Channels {
+			name := event + "." + channel
+			text, err := ioutil.ReadFile(filepath.Join(dir, name+".tmpl"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			t, err := template.New(name).Parse(string(text))
+			if err != nil {
+				return nil, err
+			}
+			templates[name] = t
+		}
+	}
+	return templates, nil
+}
+
+// Render fills in the template of an event and channel, returning false if there is none.
+// The subject is only set for email.
+func (t Templates) Render(event, channel string, data Data) (subject, body string, ok bool, err error) {
+	tmpl, ok := t[event+"."+channel]
+	if !ok {
+		return "", "", false, nil
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", "", true, err
+	}
+	body = strings.TrimSpace(out.String())
+	if channel == Email {
+		lines := strings.SplitN(body, "\n", 2)
+		if !strings.HasPrefix(lines[0], "Subject:") || len(lines) < 2 {
+			return "", "", true, fmt.Errorf("%s must start with a Subject: line", tmpl.Name())
+		}
+		subject, body = strings.TrimSpace(strings.TrimPrefix(lines[0], "Subject:")), strings.TrimSpace(lines[1])
+	}
+	return subject, body, true, nil
+}
+
+// CheckChannels returns an error if channels has a channel messages cannot be sent by.
+func CheckChannels(channels []string) error {
+	for _, channel := range channels {
+		if channel != Email && channel != SMS {
+			return fmt.Errorf("%q is not a channel, must be email or sms", channel)
+		}
+	}
+	return nil
+}
+
+// CheckEmail returns an error if email is not an email address.
+func CheckEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return errors.New("must be an email address e.g. name@example.com")
+	}
+	return nil
+}
+
+// NormalisePhone returns a phone number in international format, + and up to 15 digits, without the spaces, dashes,
+// dots and brackets people write them with.
+func NormalisePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	digits := strings.TrimPrefix(phone, "+")
+	if !strings.HasPrefix(phone, "+") || len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", errors.New("must be in international format e.g. +353 87 123 4567")
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", errors.New("must only have digits after the +")
+		}
+	}
+	return phone, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * SMS
+ * Sends text messages through an HTTP SMS gateway. Each message is a POST of JSON with from, to and text to the
+ * gateway's URL, with its token as a Bearer token. A 2xx status is sent, the id in the JSON answered is kept.
+ */
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// The most of a gateway's error response kept in the message log.
+const maxGatewayError = 200
+
+// SMSGateway sends text messages through the gateway at URL, from From.
+type SMSGateway struct {
+	URL    string
+	Token  string
+	From   string
+	Client *http.Client
+}
+
+// Send sends a message's body as a text message, returning the ID the gateway gave it.
+func (g SMSGateway) Send(m Message) (string, error) {
+	body, err := json.Marshal(struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Text string `json:"text"`
+	}{g.From, m.To, m.Body})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Horton-Notifications/1.0")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	answer, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(answer) > maxGatewayError {
+			answer = answer[:maxGatewayError]
+		}
+		text := strings.TrimSpace(strings.ToValidUTF8(string(answer), ""))
+		return "", fmt.Errorf("gateway answered %d %s", resp.StatusCode, text)
+	}
+
+	// Gateways that do not answer with an id are still sent. The id can be a string or a number.
+	var sent struct {
+		Id json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(answer, &sent) != nil || len(sent.Id) == 0 || string(sent.Id) == "null" {
+		return "", nil
+	}
+	return strings.Trim(string(sent.Id), `"`), nil
+}
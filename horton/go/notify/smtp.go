@@ -0,0 +1,139 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * SMTP
+ * Sends email through an SMTP server, e.g. the garage's mail provider or MailHog when testing.
+ * STARTTLS is used when the server offers it, and the login is only sent over TLS or to localhost.
+ *
+ * Reference
+ * https://golang.org/pkg/net/smtp/
+ */
+
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP sends email through the server at Addr, host:port, from From.
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// Send sends a message as plain text email, returning its Message-ID.
+func (s SMTP) Send(m Message) (string, error) {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return "", errors.New("from address is not an email address")
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return "", err
+	}
+	messageId, err := newMessageId(from.Address)
+	if err != nil {
+		return "", err
+	}
+	email, err := buildEmail(from, m, messageId, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Addr, s.Timeout)
+	if err != nil {
+		return "", err
+	}
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return "", err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return "", err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return "", err
+	}
+	if err := client.Rcpt(m.To); err != nil {
+		return "", err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(email); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return messageId, client.Quit()
+}
+
+// Function to write a message as an email with its headers, the body quoted-printable so any line length and
+// character can be sent.
+func buildEmail(from *mail.Address, m Message, messageId string, date time.Time) ([]byte, error) {
+	if err := CheckEmail(m.To); err != nil {
+		return nil, fmt.Errorf("recipient %s", err)
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, errors.New("subject cannot have line breaks")
+	}
+
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", from.String())
+	fmt.Fprintf(&email, "To: %s\r\n", m.To)
+	fmt.Fprintf(&email, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&email, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&email, "Message-ID: %s\r\n", messageId)
+	email.WriteString("MIME-Version: 1.0\r\n")
+	email.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	email.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	// Line breaks are written as CRLF.
+	w := quotedprintable.NewWriter(&email)
+	if _, err := w.Write([]byte(strings.ReplaceAll(m.Body, "\r\n", "\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	email.WriteString("\r\n")
+	return email.Bytes(), nil
+}
+
+// Function to make a unique Message-ID at the domain of the from address.
+func newMessageId(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "<" + hex.EncodeToString(b) + from[strings.LastIndex(from, "@"):] + ">", nil
+}
@@ -0,0 +1,61 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Odometer
+ * Handles odometer readings in kilometres and miles.
+ * Readings are stored in kilometres (the canonical unit) with the value and unit the worker entered kept alongside it.
+ */
+
+package odometer
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Unit is the unit of an odometer reading.
+type Unit string
+
+const (
+	// Kilometres is the canonical unit readings are stored in.
+	Kilometres Unit = "km"
+	// Miles is used by UK and older Irish vehicles.
+	Miles Unit = "mi"
+)
+
+// kmPerMile is the number of kilometres in one international mile.
+const kmPerMile = 1.609344
+
+// ErrUnknownUnit is returned when a unit is not kilometres or miles.
+var ErrUnknownUnit = errors.New("odometer unit must be km or mi")
+
+// ParseUnit reads a unit entered by a user or client e.g. "km", "KMs", "miles".
+// An empty string is returned as Miles as that was the only unit before readings carried a unit.
+func ParseUnit(s string) (Unit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "mi", "mile", "miles":
+		return Miles, nil
+	case "km", "kms", "kilometre", "kilometres", "kilometer", "kilometers":
+		return Kilometres, nil
+	default:
+		return "", ErrUnknownUnit
+	}
+}
+
+// ToKilometres converts a reading in the given unit to kilometres.
+func ToKilometres(value int64, unit Unit) int64 {
+	if unit == Miles {
+		return int64(math.Round(float64(value) * kmPerMile))
+	}
+	return value
+}
+
+// FromKilometres converts a reading in kilometres to the given unit.
+func FromKilometres(km int64, unit Unit) int64 {
+	if unit == Miles {
+		return int64(math.Round(float64(km) / kmPerMile))
+	}
+	return km
+}
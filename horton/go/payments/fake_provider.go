@@ -0,0 +1,73 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Fake Provider
+ * Payment Provider that takes card payments in memory without charging anyone, for testing and development.
+ * Cards with the token "tok_declined" are declined.
+ */
+
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DeclinedToken is the card token the FakeProvider always declines.
+const DeclinedToken = "tok_declined"
+
+// FakeProvider keeps what is left to refund of each payment it has taken.
+type FakeProvider struct {
+	mu       sync.Mutex
+	next     int
+	payments map[string]int64
+}
+
+// NewFakeProvider returns a FakeProvider that has taken no payments.
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{payments: map[string]int64{}}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Charge takes any amount from any card but DeclinedToken.
+func (p *FakeProvider) Charge(ctx context.Context, amountCents int64, currency, token, description string) (string, error) {
+	if token == "" {
+		return "", errors.New("a card token is required")
+	}
+	if token == DeclinedToken {
+		return "", ErrDeclined
+	}
+	if amountCents <= 0 {
+		return "", fmt.Errorf("cannot charge %d cents", amountCents)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next++
+	reference := fmt.Sprintf("fake_ch_%d", p.next)
+	p.payments[reference] = amountCents
+	return reference, nil
+}
+
+// Refund gives back up to what is left of a payment it took.
+func (p *FakeProvider) Refund(ctx context.Context, reference string, amountCents int64) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	left, ok := p.payments[reference]
+	if !ok {
+		return "", fmt.Errorf("no payment %q", reference)
+	}
+	if amountCents <= 0 || amountCents > left {
+		return "", fmt.Errorf("cannot refund %d cents of payment %q, %d cents are left", amountCents, reference, left)
+	}
+	p.payments[reference] = left - amountCents
+	p.next++
+	return fmt.Sprintf("fake_re_%d", p.next), nil
+}
@@ -0,0 +1,120 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Payments
+ * Takes payments against invoices by card, cash or bank transfer, and works out what is still owed.
+ * Card payments and their refunds go through a Provider, cash and bank transfers are only recorded.
+ * Every payment and refund is written to the payment ledger, which is never changed, so the balance of
+ * an invoice is always its total less its credit notes and the sum of its ledger entries.
+ */
+
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Ways a payment can be made.
+const (
+	Card         = "card"
+	Cash         = "cash"
+	BankTransfer = "bank_transfer"
+)
+
+// Kinds of ledger entry.
+const (
+	Payment = "payment"
+	Refund  = "refund"
+)
+
+// ErrDeclined is returned by a Provider when a card payment is refused.
+var ErrDeclined = errors.New("payment declined")
+
+// Provider takes card payments and refunds them.
+type Provider interface {
+	// Name is stored with each payment so it is refunded by the same provider.
+	Name() string
+	// Charge takes amountCents from the card of token, returns the provider's reference for the payment.
+	Charge(ctx context.Context, amountCents int64, currency, token, description string) (string, error)
+	// Refund gives back amountCents of the payment with the provider's reference, returns the reference of the refund.
+	Refund(ctx context.Context, reference string, amountCents int64) (string, error)
+}
+
+// IsMethod reports whether method is a way a payment can be made.
+func IsMethod(method string) bool {
+	switch method {
+	case Card, Cash, BankTransfer:
+		return true
+	}
+	return false
+}
+
+// Outstanding returns what is still owed on an invoice of total after credit notes of credited
+// and the net of payments and refunds of paid. It is negative if the customer has paid too much.
+func Outstanding(total, credited, paid int64) int64 {
+	return total - credited - paid
+}
+
+// Debt is an amount still owed on an invoice.
+type Debt struct {
+	BillToName       string
+	DueDate          time.Time
+	OutstandingCents int64
+}
+
+// Age returns the bucket a debt falls in on the day asOf, 0 if it is not yet due, 1 if it is overdue
+// by 1 to 30 days, 2 by 31 to 60 days, 3 by 61 to 90 days and 4 by more than 90 days.
+func Age(dueDate, asOf time.Time) int {
+	due := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	overdue := int(day.Sub(due).Hours() / 24)
+
+	switch {
+	case overdue <= 0:
+		return 0
+	case overdue <= 30:
+		return 1
+	case overdue <= 60:
+		return 2
+	case overdue <= 90:
+		return 3
+	}
+	return 4
+}
+
+// AgeDebts adds up the debts owed by each debtor in the buckets of Age on the day asOf.
+// Debtors are returned in the order they first appear in debts, with the totals of all of them.
+func AgeDebts(debts []Debt, asOf time.Time) ([]models.AgedDebtor, models.AgedDebtor) {
+	debtors := []models.AgedDebtor{}
+	index := map[string]int{}
+	totals := models.AgedDebtor{BillToName: "Total"}
+
+	for _, debt := range debts {
+		if debt.OutstandingCents <= 0 {
+			continue
+		}
+		i, ok := index[debt.BillToName]
+		if !ok {
+			i = len(debtors)
+			index[debt.BillToName] = i
+			debtors = append(debtors, models.AgedDebtor{BillToName: debt.BillToName})
+		}
+		age := Age(debt.DueDate, asOf)
+		add(&debtors[i], age, debt.OutstandingCents)
+		add(&totals, age, debt.OutstandingCents)
+	}
+	return debtors, totals
+}
+
+func add(debtor *models.AgedDebtor, age int, cents int64) {
+	buckets := []*int64{&debtor.CurrentCents, &debtor.Days1To30Cents, &debtor.Days31To60Cents,
+		&debtor.Days61To90Cents, &debtor.Over90DaysCents}
+	*buckets[age] += cents
+	debtor.TotalCents += cents
+	debtor.Invoices++
+}
@@ -0,0 +1,261 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Plate
+ * Validates and normalises Irish and UK vehicle registration plates.
+ * Plates are read regardless of case, spaces and hyphens so "151 dl 2308" and "151-DL-2308" are the same vehicle.
+ *
+ * References
+ * https://en.wikipedia.org/wiki/Vehicle_registration_plates_of_the_Republic_of_Ireland
+ * https://en.wikipedia.org/wiki/Vehicle_registration_plates_of_the_United_Kingdom
+ */
+
+package plate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Country is the country a plate was issued in.
+type Country string
+
+const (
+	Ireland       Country = "IE"
+	UnitedKingdom Country = "GB"
+)
+
+// Plate is a registration read by Parse.
+type Plate struct {
+	// Registration is the normalised registration e.g. "151-DL-2308" or "AB51 ABC".
+	Registration string
+	Country      Country
+	// Year is the year of first registration, 0 if the plate does not show it (Northern Irish plates).
+	Year int
+	// HalfYear is 1 for January - June and 2 for July - December on Irish plates from 2013,
+	// and 1 for March and 2 for September on UK plates from 2001. 0 when the plate does not show it.
+	HalfYear int
+	// CountyCode is the county code of an Irish plate e.g. "DL".
+	CountyCode string
+	// County is the county of an Irish plate or the region of a UK plate.
+	County string
+	// Sequence is the number of an Irish plate or the serial letters of a UK plate.
+	Sequence string
+}
+
+// ErrEmpty is returned when no registration was entered.
+var ErrEmpty = errors.New("registration is required")
+
+var (
+	separators = regexp.MustCompile(`[\s\-]+`)
+
+	irish     = regexp.MustCompile(`^([0-9]{2,3})([A-Z]{1,2})([1-9][0-9]{0,5})$`)
+	ukCurrent = regexp.MustCompile(`^([A-Z]{2})([0-9]{2})([A-Z]{3})$`)
+	ukPrefix  = regexp.MustCompile(`^([A-Z])([1-9][0-9]{0,2})([A-Z]{3})$`)
+	ukSuffix  = regexp.MustCompile(`^([A-Z]{3})([1-9][0-9]{0,2})([A-Z])$`)
+	// Northern Irish plates always have an I or Z in their last two letters and show no year.
+	northernIrish = regexp.MustCompile(`^([A-Z]?[A-Z][IZ]|[A-Z]?[IZ][A-Z])([1-9][0-9]{0,3})$`)
+)
+
+// Irish county codes and their counties.
+var irishCounties = map[string]string{
+	"C":  "Cork",
+	"CE": "Clare",
+	"CN": "Cavan",
+	"CW": "Carlow",
+	"D":  "Dublin",
+	"DL": "Donegal",
+	"G":  "Galway",
+	"KE": "Kildare",
+	"KK": "Kilkenny",
+	"KY": "Kerry",
+	"L":  "Limerick",
+	"LD": "Longford",
+	"LH": "Louth",
+	"LK": "Limerick",
+	"LM": "Leitrim",
+	"LS": "Laois",
+	"MH": "Meath",
+	"MN": "Monaghan",
+	"MO": "Mayo",
+	"OY": "Offaly",
+	"RN": "Roscommon",
+	"SO": "Sligo",
+	"T":  "Tipperary",
+	"TN": "Tipperary North",
+	"TS": "Tipperary South",
+	"W":  "Waterford",
+	"WD": "Waterford",
+	"WH": "Westmeath",
+	"WW": "Wicklow",
+	"WX": "Wexford",
+}
+
+// County codes retired when the local authorities of Limerick, Tipperary and Waterford merged in 2014.
+// "T" replaced "TN" & "TS" that year.
+var retiredCounties = map[string]bool{"LK": true, "TN": true, "TS": true, "WD": true}
+
+const mergedCountiesYear = 2014
+
+// The months the plates of each half-year are first issued in, Irish plates before 2013 show no half-year.
+var (
+	irishHalfYears = map[int]time.Month{0: time.January, 1: time.January, 2: time.July}
+	ukHalfYears    = map[int]time.Month{1: time.March, 2: time.September}
+)
+
+// Regions of the first letter of a current format UK plate.
+var ukRegions = map[byte]string{
+	'A': "Anglia",
+	'B': "Birmingham",
+	'C': "Cymru",
+	'D': "Deeside",
+	'E': "Essex",
+	'F': "Forest and Fens",
+	'G': "Garden of England",
+	'H': "Hampshire and Dorset",
+	'K': "Milton Keynes",
+	'L': "London",
+	'M': "Manchester and Merseyside",
+	'N': "North",
+	'O': "Oxford",
+	'P': "Preston",
+	'R': "Reading",
+	'S': "Scotland",
+	'V': "Severn Valley",
+	'W': "West of England",
+	'Y': "Yorkshire",
+}
+
+// Year letters of UK prefix (1983 - 2001) and suffix (1963 - 1983) plates, I, O, Q, U and Z were never used.
+// The years of each letter are in ukPrefixYears and ukSuffixYears.
+const ukYearLetters = "ABCDEFGHJKLMNPRSTVWXY"
+
+var ukPrefixYears = []int{1983, 1984, 1985, 1986, 1987, 1988, 1989, 1990, 1991, 1992, 1993, 1994, 1995, 1996, 1997,
+	1998, 1999, 1999, 2000, 2000, 2001}
+var ukSuffixYears = []int{1963, 1964, 1965, 1966, 1967, 1967, 1968, 1969, 1970, 1971, 1972, 1973, 1974, 1975, 1976,
+	1977, 1978, 1979, 1980, 1981, 1982}
+
+// Parse validates a registration and reads the year and county from it.
+// A plate of a year or half-year that has not started yet is refused.
+func Parse(registration string) (Plate, error) {
+	return ParseAt(registration, time.Now())
+}
+
+// ParseAt is Parse on the day of now.
+func ParseAt(registration string, now time.Time) (Plate, error) {
+	compact := separators.ReplaceAllString(strings.ToUpper(strings.TrimSpace(registration)), "")
+	if compact == "" {
+		return Plate{}, ErrEmpty
+	}
+
+	if m := irish.FindStringSubmatch(compact); m != nil {
+		return parseIrish(m[1], m[2], m[3], now)
+	}
+	if m := ukCurrent.FindStringSubmatch(compact); m != nil {
+		return parseUKCurrent(m[1], m[2], m[3], now)
+	}
+	if m := ukPrefix.FindStringSubmatch(compact); m != nil {
+		i := strings.Index(ukYearLetters, m[1])
+		if i < 0 {
+			return Plate{}, fmt.Errorf("%q is not a UK year letter", m[1])
+		}
+		return Plate{Registration: m[1] + m[2] + " " + m[3], Country: UnitedKingdom, Year: ukPrefixYears[i],
+			Sequence: m[3]}, nil
+	}
+	if m := ukSuffix.FindStringSubmatch(compact); m != nil {
+		i := strings.Index(ukYearLetters, m[3])
+		if i < 0 {
+			return Plate{}, fmt.Errorf("%q is not a UK year letter", m[3])
+		}
+		return Plate{Registration: m[1] + " " + m[2] + m[3], Country: UnitedKingdom, Year: ukSuffixYears[i],
+			Sequence: m[1]}, nil
+	}
+	if m := northernIrish.FindStringSubmatch(compact); m != nil {
+		return Plate{Registration: m[1] + " " + m[2], Country: UnitedKingdom, County: "Northern Ireland",
+			Sequence: m[2]}, nil
+	}
+	return Plate{}, fmt.Errorf("%q is not an Irish or UK registration", registration)
+}
+
+// Normalise returns the registration in its normalised form e.g. "151 dl 2308" becomes "151-DL-2308".
+func Normalise(registration string) (string, error) {
+	p, err := Parse(registration)
+	if err != nil {
+		return "", err
+	}
+	return p.Registration, nil
+}
+
+// Function to read an Irish plate - YY-CC-SSSSSS (1987 - 2012) or YYH-CC-SSSSSS (2013 onwards).
+func parseIrish(year, county, sequence string, now time.Time) (Plate, error) {
+	p := Plate{Registration: year + "-" + county + "-" + sequence, Country: Ireland, CountyCode: county,
+		Sequence: sequence}
+
+	yy, _ := strconv.Atoi(year[:2])
+	if len(year) == 3 {
+		// Half-year plates started in 2013.
+		half := int(year[2] - '0')
+		if yy < 13 || (half != 1 && half != 2) {
+			return Plate{}, fmt.Errorf("%q is not a valid year and half-year", year)
+		}
+		p.Year, p.HalfYear = 2000+yy, half
+	} else if yy >= 87 {
+		p.Year = 1900 + yy
+	} else if yy <= 12 {
+		p.Year = 2000 + yy
+	} else {
+		return Plate{}, fmt.Errorf("%q is not a valid year, plates from 2013 show the half-year", year)
+	}
+	if !issuedBy(p.Year, irishHalfYears[p.HalfYear], now) {
+		return Plate{}, fmt.Errorf("%q is a year or half-year that has not started yet", year)
+	}
+
+	name, ok := irishCounties[county]
+	if !ok {
+		return Plate{}, fmt.Errorf("%q is not an Irish county code", county)
+	}
+	if retiredCounties[county] && p.Year >= mergedCountiesYear {
+		return Plate{}, fmt.Errorf("county code %q was not issued after %d", county, mergedCountiesYear-1)
+	}
+	if county == "T" && p.Year < mergedCountiesYear {
+		return Plate{}, fmt.Errorf("county code %q was not issued before %d", county, mergedCountiesYear)
+	}
+	p.County = name
+	return p, nil
+}
+
+// Function to read a current format UK plate - AA99 AAA (2001 onwards).
+// The age identifier is the year for March plates and the year plus 50 for September plates.
+func parseUKCurrent(area, age, serial string, now time.Time) (Plate, error) {
+	p := Plate{Registration: area + age + " " + serial, Country: UnitedKingdom, Sequence: serial}
+
+	n, _ := strconv.Atoi(age)
+	switch {
+	case n >= 2 && n < 50:
+		p.Year, p.HalfYear = 2000+n, 1
+	case n > 50:
+		p.Year, p.HalfYear = 2000+n-50, 2
+	default:
+		return Plate{}, fmt.Errorf("%q is not a UK age identifier", age)
+	}
+	if !issuedBy(p.Year, ukHalfYears[p.HalfYear], now) {
+		return Plate{}, fmt.Errorf("%q is an age identifier that has not been issued yet", age)
+	}
+
+	region, ok := ukRegions[area[0]]
+	if !ok {
+		return Plate{}, fmt.Errorf("%q is not a UK region", area[:1])
+	}
+	p.County = region
+	return p, nil
+}
+
+// Function to check plates of a year, issued from the month from, had been issued by now.
+func issuedBy(year int, from time.Month, now time.Time) bool {
+	return year < now.Year() || (year == now.Year() && now.Month() >= from)
+}
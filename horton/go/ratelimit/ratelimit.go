@@ -0,0 +1,109 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Rate Limit
+ * Limits how often a client e.g. an IP address can use endpoints that need no login.
+ * Each client can make a number of requests in a window of time, counted from its first request in the window.
+ */
+
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clients are forgotten once their window is over, checked when this many are remembered.
+const sweepAt = 1000
+
+// Limiter counts the requests of each client, it is safe to share between requests.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	counts map[string]*count
+}
+
+type count struct {
+	start time.Time
+	n     int
+}
+
+// New returns a Limiter allowing limit requests by a client in each window.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, counts: map[string]*count{}}
+}
+
+// Allow counts a request by client at now and reports whether it is allowed.
+// If not it returns how long until the client can make another.
+func (l *Limiter) Allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.counts) >= sweepAt {
+		for key, c := range l.counts {
+			if now.Sub(c.start) >= l.window {
+				delete(l.counts, key)
+			}
+		}
+	}
+
+	c, ok := l.counts[client]
+	if !ok || now.Sub(c.start) >= l.window {
+		c = &count{start: now}
+		l.counts[client] = c
+	}
+	if c.n >= l.limit {
+		return false, c.start.Add(l.window).Sub(now)
+	}
+	c.n++
+	return true, 0
+}
+
+// Client returns the IP address a request came from, to limit it by. X-Forwarded-For is only read when the request
+// came through one of trusted, the proxies in front of Horton, as any client can send it. The address is then the
+// last one added before it reached a trusted proxy.
+func Client(r *http.Request, trusted []string) string {
+	client, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		client = strings.TrimSpace(r.RemoteAddr)
+	}
+	if !isTrusted(client, trusted) {
+		return client
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(address) == nil {
+			break
+		}
+		client = address
+		if !isTrusted(address, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// Function to report whether address is one of the trusted proxies, IP addresses or CIDR ranges.
+func isTrusted(address string, trusted []string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range trusted {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if proxyIp := net.ParseIP(proxy); proxyIp != nil && proxyIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
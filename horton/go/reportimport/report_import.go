@@ -0,0 +1,240 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Report Import
+ * Reads job reports from CSV files of historical jobs, e.g. spreadsheets kept before Repota.
+ * A mapping gives the CSV column of each field of a report, so files do not need to be re-arranged before importing.
+ * Rows are read one at a time, each with the errors of values that could not be read.
+ *
+ * References
+ * https://golang.org/pkg/encoding/csv/
+ */
+
+package reportimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Mapping is the CSV column header of each field of a report, by the field's name in the JSON of a report.
+// Fields that are not mapped are left empty.
+type Mapping map[string]string
+
+// Fields of a report that can be imported.
+var Fields = []string{"date", "vehicleModel", "vehicleReg", "vehicleLocation", "odometerReading", "odometerUnit",
+	"milesOnVehicle", "warranty", "breakdown", "customerName", "complaint", "cause", "correction", "parts",
+	"workHours", "jobComplete"}
+
+// Row is a row of the CSV file read into a report.
+type Row struct {
+	// Number is the number of the row in the file as a spreadsheet shows it, the header is row 1.
+	Number int
+	// Record is the row as it is in the file.
+	Record []string
+	Report models.JobReport
+	// Errors are the values that could not be read, the row should not be imported if there are any.
+	Errors []models.FieldError
+}
+
+// Reader reads reports from a CSV file with a header row.
+type Reader struct {
+	csv     *csv.Reader
+	header  []string
+	columns map[string]int
+	rows    int
+}
+
+// ErrNoHeader is returned when the file is empty.
+var ErrNoHeader = errors.New("CSV file has no header row")
+
+// NewReader reads the header row of r and checks each mapped column is in it.
+// If mapping is empty the headers are taken to be the names of the fields.
+func NewReader(r io.Reader, mapping Mapping) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, ErrNoHeader
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		// Excel saves CSV files as UTF-8 with a byte order mark.
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if len(mapping) == 0 {
+		mapping = Mapping{}
+		for _, field := range Fields {
+			if _, ok := index[strings.ToLower(field)]; ok {
+				mapping[field] = field
+			}
+		}
+	}
+
+	columns := make(map[string]int, len(mapping))
+	for field, column := range mapping {
+		if !isField(field) {
+			return nil, fmt.Errorf("%q is not a field that can be imported", field)
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("column %q for %s is not in the CSV file", column, field)
+		}
+		columns[field] = i
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no columns of the CSV file are mapped to report fields")
+	}
+
+	return &Reader{csv: cr, header: header, columns: columns, rows: 1}, nil
+}
+
+// Header returns the header row of the file.
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Read reads the next row, io.EOF is returned after the last row.
+// A row that is not valid CSV (e.g. an unclosed quote) is returned with an error for the row
+// rather than failing the whole file.
+func (r *Reader) Read() (Row, error) {
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		return Row{}, err
+	}
+	r.rows++
+	if perr, ok := err.(*csv.ParseError); ok {
+		return Row{Number: r.rows, Record: record,
+			Errors: []models.FieldError{{Field: "row", Message: perr.Err.Error()}}}, nil
+	}
+	if err != nil {
+		return Row{}, err
+	}
+
+	row := Row{Number: r.rows, Record: record}
+	for _, field := range Fields {
+		i, ok := r.columns[field]
+		if !ok {
+			continue
+		}
+		value := ""
+		if i < len(record) {
+			value = strings.TrimSpace(record[i])
+		}
+		if err := setField(&row.Report, field, value); err != nil {
+			row.Errors = append(row.Errors, models.FieldError{Field: field, Message: err.Error()})
+		}
+	}
+	return row, nil
+}
+
+// WriteErrors writes an error report of rejected rows to w, as CSV.
+// Each row is as it was in the file with its row number and errors added at the start.
+func WriteErrors(w io.Writer, header []string, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"row", "errors"}, header...)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		messages := make([]string, len(row.Errors))
+		for i, fe := range row.Errors {
+			messages[i] = fe.Field + ": " + fe.Message
+		}
+		if err := cw.Write(append([]string{strconv.Itoa(row.Number), strings.Join(messages, "; ")}, row.Record...)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to set a field of a report from its value in the CSV file.
+func setField(report *models.JobReport, field, value string) error {
+	var err error
+	switch field {
+	case "date":
+		if value != "" {
+			report.Date, err = models.ParseDate(value)
+		}
+	case "vehicleModel":
+		report.VehicleModel = value
+	case "vehicleReg":
+		report.VehicleReg = value
+	case "vehicleLocation":
+		report.VehicleLocation = value
+	case "odometerReading":
+		report.OdometerReading, err = parseNumber(value)
+	case "odometerUnit":
+		report.OdometerUnit = strings.ToLower(value)
+	case "milesOnVehicle":
+		report.MilesOnVehicle, err = parseNumber(value)
+	case "warranty":
+		report.Warranty, err = parseFlag(value)
+	case "breakdown":
+		report.Breakdown, err = parseFlag(value)
+	case "customerName":
+		report.CustomerName = value
+	case "complaint":
+		report.Complaint = value
+	case "cause":
+		report.Cause = value
+	case "correction":
+		report.Correction = value
+	case "parts":
+		report.Parts = value
+	case "workHours":
+		report.WorkHours, err = parseNumber(value)
+	case "jobComplete":
+		report.JobComplete, err = parseFlag(value)
+	}
+	return err
+}
+
+// Function to read a whole number, thousands separators are allowed e.g. "120,000".
+func parseNumber(value string) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(strings.Replace(value, ",", "", -1), 10, 32)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a whole number", value)
+	}
+	return int32(n), nil
+}
+
+// Function to read a yes/no value as 1 or 0.
+func parseFlag(value string) (int32, error) {
+	switch strings.ToLower(value) {
+	case "", "0", "n", "no", "false":
+		return 0, nil
+	case "1", "y", "yes", "true":
+		return 1, nil
+	}
+	return 0, fmt.Errorf("%q is not yes or no", value)
+}
@@ -0,0 +1,260 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Report PDF
+ * Renders a job report as a printable job sheet for customers and warranty providers.
+ * The sheet has the garage's header and logo, the report, customer, vehicle, parts and hours,
+ * and the customer's signature if the report was signed. Rendered offline with gofpdf.
+ *
+ * References
+ * https://github.com/jung-kurt/gofpdf
+ */
+
+package reportpdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pageMargin  = 15.0
+	labelWidth  = 45.0
+	lineHeight  = 6.0
+	contentFont = "Helvetica"
+)
+
+// Render writes the job sheet of a report as a PDF to w.
+// signature is the customer's signature, nil if the report has not been signed.
+func Render(w io.Writer, report models.JobReport, garage models.Garage, signature *models.Signature) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin+5)
+	pdf.SetTitle(fmt.Sprintf("Job Report %d", report.JobReportId), true)
+	pdf.SetAuthor(garage.Name, true)
+	pdf.SetCreator("Horton", true)
+
+	// Core fonts are not UTF-8, translate text so names like "Ó Súilleabháin" print.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin)
+		pdf.SetFont(contentFont, "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("Job Report %d - Page %d of {nb}", report.JobReportId, pdf.PageNo())),
+			"", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	header(pdf, tr, garage)
+
+	pdf.SetFont(contentFont, "B", 16)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 10, tr(fmt.Sprintf("Job Report #%d", report.JobReportId)), "", 1, "L", false, 0, "")
+
+	section(pdf, tr, "Job")
+	row(pdf, tr, "Date", report.Date.String())
+	row(pdf, tr, "Worker", report.WorkerName)
+	row(pdf, tr, "Warranty", yesNo(report.Warranty))
+	row(pdf, tr, "Breakdown", yesNo(report.Breakdown))
+	row(pdf, tr, "Complete", yesNo(report.JobComplete))
+
+	section(pdf, tr, "Customer")
+	row(pdf, tr, "Name", report.CustomerName)
+	row(pdf, tr, "Complaint", report.Complaint)
+
+	section(pdf, tr, "Vehicle")
+	row(pdf, tr, "Model", report.VehicleModel)
+	reg := report.VehicleReg
+	if report.VehicleRegYear != 0 {
+		reg = fmt.Sprintf("%s (%d, %s)", reg, report.VehicleRegYear, report.VehicleRegCounty)
+	}
+	row(pdf, tr, "Registration", reg)
+	row(pdf, tr, "Location", report.VehicleLocation)
+	row(pdf, tr, "Odometer", fmt.Sprintf("%d %s", report.OdometerReading, report.OdometerUnit))
+
+	section(pdf, tr, "Work Carried Out")
+	row(pdf, tr, "Cause", report.Cause)
+	row(pdf, tr, "Correction", report.Correction)
+	row(pdf, tr, "Parts", report.Parts)
+	row(pdf, tr, "Hours", fmt.Sprintf("%d", report.WorkHours))
+
+	section(pdf, tr, "Customer Signature")
+	signatureBox(pdf, tr, report, signature)
+
+	if pdf.Err() {
+		return pdf.Error()
+	}
+	return pdf.Output(w)
+}
+
+// Function to draw the garage's logo, name and contact details at the top of the first page.
+func header(pdf *gofpdf.Fpdf, tr func(string) string, garage models.Garage) {
+	x := pageMargin
+	if garage.LogoPath != "" {
+		// A missing or unreadable logo is left out rather than failing the whole sheet.
+		info := pdf.RegisterImageOptions(garage.LogoPath, gofpdf.ImageOptions{ReadDpi: true})
+		if pdf.Ok() && info != nil {
+			pdf.ImageOptions(garage.LogoPath, pageMargin, pageMargin, 0, 22, false, gofpdf.ImageOptions{}, 0, "")
+			x += 22*info.Width()/info.Height() + 5
+		} else {
+			pdf.ClearError()
+		}
+	}
+
+	pdf.SetXY(x, pageMargin)
+	pdf.SetFont(contentFont, "B", 18)
+	pdf.CellFormat(0, 8, tr(garage.Name), "", 2, "L", false, 0, "")
+
+	pdf.SetFont(contentFont, "", 9)
+	pdf.SetTextColor(80, 80, 80)
+	for _, line := range []string{garage.Address, contact(garage)} {
+		if line != "" {
+			pdf.SetX(x)
+			pdf.CellFormat(0, 4.5, tr(line), "", 2, "L", false, 0, "")
+		}
+	}
+
+	pdf.SetY(pageMargin + 26)
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.Line(pageMargin, pdf.GetY(), 210-pageMargin, pdf.GetY())
+	pdf.Ln(4)
+}
+
+// Function to join the garage's phone, email and VAT number into one line.
+func contact(garage models.Garage) string {
+	var parts []string
+	if garage.Phone != "" {
+		parts = append(parts, "Tel: "+garage.Phone)
+	}
+	if garage.Email != "" {
+		parts = append(parts, garage.Email)
+	}
+	if garage.VatNumber != "" {
+		parts = append(parts, "VAT No: "+garage.VatNumber)
+	}
+	return strings.Join(parts, "  |  ")
+}
+
+// Function to draw the heading of a section.
+func section(pdf *gofpdf.Fpdf, tr func(string) string, title string) {
+	pdf.Ln(3)
+	pdf.SetFont(contentFont, "B", 11)
+	pdf.SetFillColor(235, 235, 235)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 7, tr(title), "", 1, "L", true, 0, "")
+	pdf.Ln(1)
+}
+
+// Function to draw a label and its value, long values wrap onto more lines.
+func row(pdf *gofpdf.Fpdf, tr func(string) string, label, value string) {
+	pdf.SetFont(contentFont, "B", 10)
+	pdf.SetTextColor(80, 80, 80)
+	pdf.CellFormat(labelWidth, lineHeight, tr(label), "", 0, "L", false, 0, "")
+
+	pdf.SetFont(contentFont, "", 10)
+	pdf.SetTextColor(0, 0, 0)
+	if value == "" {
+		value = "-"
+	}
+	pdf.MultiCell(0, lineHeight, tr(value), "", "L", false)
+}
+
+// Function to draw the customer's signature - the image, or the strokes scaled to fit the box.
+func signatureBox(pdf *gofpdf.Fpdf, tr func(string) string, report models.JobReport, signature *models.Signature) {
+	if signature == nil {
+		row(pdf, tr, "Signed", "Not signed")
+		return
+	}
+
+	const boxWidth, boxHeight = 80.0, 30.0
+	if pdf.GetY()+boxHeight+3*lineHeight > 297-pageMargin-5 {
+		pdf.AddPage()
+	}
+	x, y := pageMargin+labelWidth, pdf.GetY()
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.Rect(x, y, boxWidth, boxHeight, "D")
+
+	if signature.Image != "" {
+		drawSignatureImage(pdf, signature.Image, x, y, boxWidth, boxHeight)
+	} else {
+		drawStrokes(pdf, signature.Strokes, x, y, boxWidth, boxHeight)
+	}
+	pdf.SetY(y + boxHeight + 2)
+
+	row(pdf, tr, "Signed by", signature.SignerName)
+	row(pdf, tr, "Signed at", signature.SignedAt.Format("2006-01-02 15:04 MST"))
+	if report.ModifiedAfterSigning {
+		pdf.SetTextColor(200, 0, 0)
+		row(pdf, tr, "Note", "This report was modified after it was signed.")
+	}
+}
+
+// Function to draw a base64 signature image (or data URL) inside a box.
+func drawSignatureImage(pdf *gofpdf.Fpdf, image string, x, y, w, h float64) {
+	imageType := "PNG"
+	if strings.HasPrefix(image, "data:image/jpeg") {
+		imageType = "JPG"
+	}
+	if i := strings.Index(image, ";base64,"); i > 0 {
+		image = image[i+len(";base64,"):]
+	}
+	data, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		return
+	}
+
+	info := pdf.RegisterImageOptionsReader("signature", gofpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+	if !pdf.Ok() || info == nil {
+		pdf.ClearError()
+		return
+	}
+	// Fit the image inside the box keeping its shape.
+	scale := math.Min((w-4)/info.Width(), (h-4)/info.Height())
+	iw, ih := info.Width()*scale, info.Height()*scale
+	pdf.ImageOptions("signature", x+(w-iw)/2, y+(h-ih)/2, iw, ih, false, gofpdf.ImageOptions{}, 0, "")
+}
+
+// Function to draw signature strokes inside a box, scaled to fit keeping their shape.
+func drawStrokes(pdf *gofpdf.Fpdf, strokes [][]models.StrokePoint, x, y, w, h float64) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, stroke := range strokes {
+		for _, p := range stroke {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return
+	}
+
+	scale := math.Min((w-4)/math.Max(maxX-minX, 1), (h-4)/math.Max(maxY-minY, 1))
+	offsetX := x + (w-(maxX-minX)*scale)/2
+	offsetY := y + (h-(maxY-minY)*scale)/2
+
+	pdf.SetDrawColor(0, 0, 80)
+	pdf.SetLineWidth(0.4)
+	for _, stroke := range strokes {
+		for i := 1; i < len(stroke); i++ {
+			pdf.Line(offsetX+(stroke[i-1].X-minX)*scale, offsetY+(stroke[i-1].Y-minY)*scale,
+				offsetX+(stroke[i].X-minX)*scale, offsetY+(stroke[i].Y-minY)*scale)
+		}
+	}
+	pdf.SetLineWidth(0.2)
+}
+
+func yesNo(value int32) string {
+	if value != 0 {
+		return "Yes"
+	}
+	return "No"
+}
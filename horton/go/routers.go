@@ -19,6 +19,7 @@ import (
 	_ "fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
 // Route is the information for every URI.
@@ -56,28 +57,53 @@ func CORS() gin.HandlerFunc {
 func NewRouter() *gin.Engine {
 
 	router := gin.Default()
+	// Clients can send any X-Forwarded-For, the public endpoints read it only from trusted_proxies.
+	router.ForwardedByClientIP = false
 	// Set logo for tab & "/" endpoint.
 	router.StaticFile("/favicon.ico", "./favicon.ico")
 	router.StaticFile("/", "./favicon.ico")
 
 	// CORS must be called before any routes are called.
 	router.Use(CORS())
+
+	// Custom methods e.g. /jobReports:batch cannot be added to gin's router as ':' starts a path parameter,
+	// they are matched by their exact path when no other route matches.
+	customMethods := map[string]gin.HandlerFunc{}
+	router.NoRoute(func(c *gin.Context) {
+		if handler, ok := customMethods[c.Request.Method+" "+c.Request.URL.Path]; ok {
+			handler(c)
+		}
+	})
+
 	for _, route := range routes {
+		// Requests are counted and timed by the route's Name for /metrics.
+		handler := instrumentRoute(route.Name, route.HandlerFunc)
+
+		if isCustomMethod(route.Pattern) {
+			customMethods[route.Method+" "+route.Pattern] = handler
+			continue
+		}
 		switch route.Method {
 		case http.MethodGet:
-			router.GET(route.Pattern, route.HandlerFunc)
+			router.GET(route.Pattern, handler)
 		case http.MethodPost:
-			router.POST(route.Pattern, route.HandlerFunc)
+			router.POST(route.Pattern, handler)
 		case http.MethodPut:
-			router.PUT(route.Pattern, route.HandlerFunc)
+			router.PUT(route.Pattern, handler)
 		case http.MethodDelete:
-			router.DELETE(route.Pattern, route.HandlerFunc)
+			router.DELETE(route.Pattern, handler)
 		}
 	}
 
 	return router
 }
 
+// Function to check if a pattern is a custom method, a ':' after the start of its last segment e.g. /jobReports:batch.
+func isCustomMethod(pattern string) bool {
+	segment := pattern[strings.LastIndex(pattern, "/")+1:]
+	return strings.Index(segment, ":") > 0
+}
+
 // Index is the index handler - /api/v1/ endpoint.
 func Index(c *gin.Context) {
 	c.String(http.StatusOK, "[INFO] Horton is online...")
@@ -141,6 +167,13 @@ var routes = Routes{
 		GetReports,
 	},
 
+	{
+		"BatchReports",
+		http.MethodPost,
+		"/api/v1/jobReports:batch",
+		BatchReports,
+	},
+
 	{
 		"UpdateReport",
 		http.MethodPut,
@@ -148,6 +181,699 @@ var routes = Routes{
 		UpdateReport,
 	},
 
+	{
+		"ImportReports",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId",
+		ImportReports,
+	},
+
+	{
+		"GetImportErrors",
+		http.MethodGet,
+		"/api/v1/imports/:importId/errors",
+		GetImportErrors,
+	},
+
+	{
+		"UploadAttachment",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId/attachments",
+		UploadAttachment,
+	},
+
+	{
+		"GetAttachments",
+		http.MethodGet,
+		"/api/v1/jobReports/:jobReportId/attachments",
+		GetAttachments,
+	},
+
+	{
+		"GetAttachment",
+		http.MethodGet,
+		"/api/v1/jobReports/:jobReportId/attachments/:attachmentId",
+		GetAttachment,
+	},
+
+	{
+		"DeleteAttachment",
+		http.MethodDelete,
+		"/api/v1/jobReports/:jobReportId/attachments/:attachmentId",
+		DeleteAttachment,
+	},
+
+	{
+		"SignReport",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId/signature",
+		SignReport,
+	},
+
+	{
+		"GetSignature",
+		http.MethodGet,
+		"/api/v1/jobReports/:jobReportId/signature",
+		GetSignature,
+	},
+
+	{
+		"CreateInvoice",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId/invoice",
+		CreateInvoice,
+	},
+
+	{
+		"GetInvoices",
+		http.MethodGet,
+		"/api/v1/invoices",
+		GetInvoices,
+	},
+
+	{
+		"GetInvoice",
+		http.MethodGet,
+		"/api/v1/invoices/:invoiceId",
+		GetInvoice,
+	},
+
+	{
+		"UpdateInvoice",
+		http.MethodPut,
+		"/api/v1/invoices/:invoiceId",
+		UpdateInvoice,
+	},
+
+	{
+		"DeleteInvoice",
+		http.MethodDelete,
+		"/api/v1/invoices/:invoiceId",
+		DeleteInvoice,
+	},
+
+	{
+		"SetInvoiceStatus",
+		http.MethodPost,
+		"/api/v1/invoices/:invoiceId/status",
+		SetInvoiceStatus,
+	},
+
+	{
+		"CreateCreditNote",
+		http.MethodPost,
+		"/api/v1/invoices/:invoiceId/creditNotes",
+		CreateCreditNote,
+	},
+
+	{
+		"RecordPayment",
+		http.MethodPost,
+		"/api/v1/invoices/:invoiceId/payments",
+		RecordPayment,
+	},
+
+	{
+		"GetPayments",
+		http.MethodGet,
+		"/api/v1/invoices/:invoiceId/payments",
+		GetPayments,
+	},
+
+	{
+		"RefundPayment",
+		http.MethodPost,
+		"/api/v1/payments/:paymentId/refunds",
+		RefundPayment,
+	},
+
+	{
+		"GetBalances",
+		http.MethodGet,
+		"/api/v1/balances",
+		GetBalances,
+	},
+
+	{
+		"GetAgedDebtors",
+		http.MethodGet,
+		"/api/v1/agedDebtors",
+		GetAgedDebtors,
+	},
+
+	{
+		"ExportAccounts",
+		http.MethodPost,
+		"/api/v1/accountingExports",
+		ExportAccounts,
+	},
+
+	{
+		"GetAccountingExports",
+		http.MethodGet,
+		"/api/v1/accountingExports",
+		GetAccountingExports,
+	},
+
+	{
+		"GetAccountingExport",
+		http.MethodGet,
+		"/api/v1/accountingExports/:exportId",
+		GetAccountingExport,
+	},
+
+	{
+		"CreateEstimate",
+		http.MethodPost,
+		"/api/v1/estimates",
+		CreateEstimate,
+	},
+
+	{
+		"GetEstimates",
+		http.MethodGet,
+		"/api/v1/estimates",
+		GetEstimates,
+	},
+
+	{
+		"GetEstimate",
+		http.MethodGet,
+		"/api/v1/estimates/:estimateId",
+		GetEstimate,
+	},
+
+	{
+		"UpdateEstimate",
+		http.MethodPut,
+		"/api/v1/estimates/:estimateId",
+		UpdateEstimate,
+	},
+
+	{
+		"DeleteEstimate",
+		http.MethodDelete,
+		"/api/v1/estimates/:estimateId",
+		DeleteEstimate,
+	},
+
+	{
+		"SendEstimate",
+		http.MethodPost,
+		"/api/v1/estimates/:estimateId/send",
+		SendEstimate,
+	},
+
+	{
+		"ConvertEstimate",
+		http.MethodPost,
+		"/api/v1/estimates/:estimateId/convert",
+		ConvertEstimate,
+	},
+
+	{
+		"GetPublicEstimate",
+		http.MethodGet,
+		"/api/v1/public/estimates/:token",
+		GetPublicEstimate,
+	},
+
+	{
+		"AcceptEstimate",
+		http.MethodPost,
+		"/api/v1/public/estimates/:token/accept",
+		AcceptEstimate,
+	},
+
+	{
+		"DeclineEstimate",
+		http.MethodPost,
+		"/api/v1/public/estimates/:token/decline",
+		DeclineEstimate,
+	},
+
+	{
+		"CreateJob",
+		http.MethodPost,
+		"/api/v1/jobs",
+		CreateJob,
+	},
+
+	{
+		"GetAssignedJobs",
+		http.MethodGet,
+		"/api/v1/jobs/assigned",
+		GetAssignedJobs,
+	},
+
+	{
+		"GetUnassignedJobs",
+		http.MethodGet,
+		"/api/v1/jobs/unassigned",
+		GetUnassignedJobs,
+	},
+
+	{
+		"ClaimJob",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId/claim",
+		ClaimJob,
+	},
+
+	{
+		"AssignJob",
+		http.MethodPost,
+		"/api/v1/jobReports/:jobReportId/assign",
+		AssignJob,
+	},
+
+	{
+		"GetJobAssignments",
+		http.MethodGet,
+		"/api/v1/jobReports/:jobReportId/assignments",
+		GetJobAssignments,
+	},
+
+	{
+		"CreateAppointment",
+		http.MethodPost,
+		"/api/v1/appointments",
+		CreateAppointment,
+	},
+
+	{
+		"GetAppointments",
+		http.MethodGet,
+		"/api/v1/appointments",
+		GetAppointments,
+	},
+
+	{
+		"GetAppointment",
+		http.MethodGet,
+		"/api/v1/appointments/:appointmentId",
+		GetAppointment,
+	},
+
+	{
+		"UpdateAppointment",
+		http.MethodPut,
+		"/api/v1/appointments/:appointmentId",
+		UpdateAppointment,
+	},
+
+	{
+		"CancelAppointment",
+		http.MethodDelete,
+		"/api/v1/appointments/:appointmentId",
+		CancelAppointment,
+	},
+
+	{
+		"ConvertAppointment",
+		http.MethodPost,
+		"/api/v1/appointments/:appointmentId/convert",
+		ConvertAppointment,
+	},
+
+	{
+		"GetAppointmentSlots",
+		http.MethodGet,
+		"/api/v1/appointmentSlots",
+		GetAppointmentSlots,
+	},
+
+	{
+		"CreateCalendarFeed",
+		http.MethodPost,
+		"/api/v1/calendarFeeds",
+		CreateCalendarFeed,
+	},
+
+	{
+		"DeleteCalendarFeed",
+		http.MethodDelete,
+		"/api/v1/calendarFeeds",
+		DeleteCalendarFeed,
+	},
+
+	{
+		"GetCalendarFeed",
+		http.MethodGet,
+		"/api/v1/public/calendars/:token",
+		GetCalendarFeed,
+	},
+
+	{
+		"GetBookingSlots",
+		http.MethodGet,
+		"/api/v1/public/bookingSlots",
+		GetBookingSlots,
+	},
+
+	{
+		"CreateBooking",
+		http.MethodPost,
+		"/api/v1/public/bookings",
+		CreateBooking,
+	},
+
+	{
+		"GetBooking",
+		http.MethodGet,
+		"/api/v1/public/bookings/:token",
+		GetBooking,
+	},
+
+	{
+		"ConfirmBooking",
+		http.MethodPost,
+		"/api/v1/public/bookings/:token/confirm",
+		ConfirmBooking,
+	},
+
+	{
+		"GetPendingBookings",
+		http.MethodGet,
+		"/api/v1/bookings",
+		GetPendingBookings,
+	},
+
+	{
+		"AcceptBooking",
+		http.MethodPost,
+		"/api/v1/appointments/:appointmentId/accept",
+		AcceptBooking,
+	},
+
+	{
+		"DeclineBooking",
+		http.MethodPost,
+		"/api/v1/appointments/:appointmentId/decline",
+		DeclineBooking,
+	},
+
+	{
+		"GetLocations",
+		http.MethodGet,
+		"/api/v1/locations",
+		GetLocations,
+	},
+
+	{
+		"CreateBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns",
+		CreateBreakdown,
+	},
+
+	{
+		"GetBreakdowns",
+		http.MethodGet,
+		"/api/v1/breakdowns",
+		GetBreakdowns,
+	},
+
+	{
+		"GetBreakdown",
+		http.MethodGet,
+		"/api/v1/breakdowns/:breakdownId",
+		GetBreakdown,
+	},
+
+	{
+		"GetDispatchSuggestions",
+		http.MethodGet,
+		"/api/v1/breakdowns/:breakdownId/suggestions",
+		GetDispatchSuggestions,
+	},
+
+	{
+		"DispatchBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns/:breakdownId/dispatch",
+		DispatchBreakdown,
+	},
+
+	{
+		"AcknowledgeBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns/:breakdownId/acknowledge",
+		AcknowledgeBreakdown,
+	},
+
+	{
+		"ArriveBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns/:breakdownId/arrive",
+		ArriveBreakdown,
+	},
+
+	{
+		"CancelBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns/:breakdownId/cancel",
+		CancelBreakdown,
+	},
+
+	{
+		"UpdateWorkerPosition",
+		http.MethodPut,
+		"/api/v1/workerPosition",
+		UpdateWorkerPosition,
+	},
+
+	{
+		"GetWorkerPositions",
+		http.MethodGet,
+		"/api/v1/workerPositions",
+		GetWorkerPositions,
+	},
+
+	{
+		"GetWorkerPositionFeed",
+		http.MethodGet,
+		"/api/v1/workerPositions/live",
+		GetWorkerPositionFeed,
+	},
+
+	{
+		"GetSilentWorkers",
+		http.MethodGet,
+		"/api/v1/workerPositions/silent",
+		GetSilentWorkers,
+	},
+
+	{
+		"RecordWorkerPings",
+		http.MethodPost,
+		"/api/v1/workerPings",
+		RecordWorkerPings,
+	},
+
+	{
+		"GetWorkerPings",
+		http.MethodGet,
+		"/api/v1/workerPings",
+		GetWorkerPings,
+	},
+
+	{
+		"ResolveBreakdown",
+		http.MethodPost,
+		"/api/v1/breakdowns/:breakdownId/resolve",
+		ResolveBreakdown,
+	},
+
+	{
+		"GetSLAContracts",
+		http.MethodGet,
+		"/api/v1/slaContracts",
+		GetSLAContracts,
+	},
+
+	{
+		"CreateSLAContract",
+		http.MethodPost,
+		"/api/v1/slaContracts",
+		CreateSLAContract,
+	},
+
+	{
+		"UpdateSLAContract",
+		http.MethodPut,
+		"/api/v1/slaContracts/:contractId",
+		UpdateSLAContract,
+	},
+
+	{
+		"GetSLABreaches",
+		http.MethodGet,
+		"/api/v1/slaBreaches",
+		GetSLABreaches,
+	},
+
+	{
+		"GetSLAReport",
+		http.MethodGet,
+		"/api/v1/slaReports/:month",
+		GetSLAReport,
+	},
+
+	{
+		"GetWorkerAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/workers",
+		GetWorkerAnalytics,
+	},
+
+	{
+		"GetCauseAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/causes",
+		GetCauseAnalytics,
+	},
+
+	{
+		"GetPartsAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/parts",
+		GetPartsAnalytics,
+	},
+
+	{
+		"GetWarrantyAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/warranty",
+		GetWarrantyAnalytics,
+	},
+
+	{
+		"GetHoursAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/hours",
+		GetHoursAnalytics,
+	},
+
+	{
+		"GetCompletionAnalytics",
+		http.MethodGet,
+		"/api/v1/analytics/completion",
+		GetCompletionAnalytics,
+	},
+
+	{
+		"GetWebhooks",
+		http.MethodGet,
+		"/api/v1/webhooks",
+		GetWebhooks,
+	},
+
+	{
+		"CreateWebhook",
+		http.MethodPost,
+		"/api/v1/webhooks",
+		CreateWebhook,
+	},
+
+	{
+		"UpdateWebhook",
+		http.MethodPut,
+		"/api/v1/webhooks/:webhookId",
+		UpdateWebhook,
+	},
+
+	{
+		"DeleteWebhook",
+		http.MethodDelete,
+		"/api/v1/webhooks/:webhookId",
+		DeleteWebhook,
+	},
+
+	{
+		"GetWebhookDeliveries",
+		http.MethodGet,
+		"/api/v1/webhookDeliveries",
+		GetWebhookDeliveries,
+	},
+
+	{
+		"GetWebhookDelivery",
+		http.MethodGet,
+		"/api/v1/webhookDeliveries/:deliveryId",
+		GetWebhookDelivery,
+	},
+
+	{
+		"RedeliverWebhook",
+		http.MethodPost,
+		"/api/v1/webhookDeliveries/:deliveryId/redeliver",
+		RedeliverWebhook,
+	},
+
+	{
+		"GetReportEvents",
+		http.MethodGet,
+		"/api/v1/reportEvents",
+		GetReportEvents,
+	},
+
+	{
+		"GetNotificationPreferences",
+		http.MethodGet,
+		"/api/v1/notificationPreferences",
+		GetNotificationPreferences,
+	},
+
+	{
+		"CreateNotificationPreferences",
+		http.MethodPost,
+		"/api/v1/notificationPreferences",
+		CreateNotificationPreferences,
+	},
+
+	{
+		"UpdateNotificationPreferences",
+		http.MethodPut,
+		"/api/v1/notificationPreferences/:contactId",
+		UpdateNotificationPreferences,
+	},
+
+	{
+		"DeleteNotificationPreferences",
+		http.MethodDelete,
+		"/api/v1/notificationPreferences/:contactId",
+		DeleteNotificationPreferences,
+	},
+
+	{
+		"GetNotifications",
+		http.MethodGet,
+		"/api/v1/notifications",
+		GetNotifications,
+	},
+
+	{
+		"GetNotification",
+		http.MethodGet,
+		"/api/v1/notifications/:messageId",
+		GetNotification,
+	},
+
+	{
+		"OptOutNotifications",
+		http.MethodPost,
+		"/api/v1/public/notifications/:token/optOut",
+		OptOutNotifications,
+	},
+
+	{
+		"GetMetrics",
+		http.MethodGet,
+		"/metrics",
+		GetMetrics,
+	},
+
 	{
 		"CarApiData",
 		http.MethodGet,
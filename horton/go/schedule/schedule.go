@@ -0,0 +1,193 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Schedule
+ * Places appointments in the workshop diary. Appointments are booked in slots within opening hours, each in one of
+ * the workshop's bays, and a worker can only be at one appointment at a time. Works out the slots still free on a day.
+ */
+
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Statuses of an appointment. Online bookings are unconfirmed until the customer confirms them,
+// then pending until a supervisor accepts them.
+const (
+	Unconfirmed = "unconfirmed"
+	Pending     = "pending"
+	Booked      = "booked"
+	Cancelled   = "cancelled"
+	Converted   = "converted"
+)
+
+var (
+	ErrNoBay      = errors.New("no workshop bay is free at that time")
+	ErrBayTaken   = errors.New("the bay is already booked at that time")
+	ErrWorkerBusy = errors.New("the worker already has an appointment at that time")
+)
+
+// Settings are the opening hours and bays of the workshop.
+type Settings struct {
+	// Bays is the number of bays, numbered from 1.
+	Bays int
+
+	// Slot is the length appointments are booked in multiples of, from opening time.
+	Slot time.Duration
+
+	// Opens and Closes are the times of day the workshop opens and closes, as the time since midnight.
+	Opens, Closes time.Duration
+
+	// Days are the days the workshop is open.
+	Days map[time.Weekday]bool
+
+	// Location is the time zone of the opening hours.
+	Location *time.Location
+}
+
+// Booking is an appointment already in the diary.
+type Booking struct {
+	AppointmentId int32
+
+	// WorkerId is the worker at the appointment, 0 for none yet.
+	WorkerId int
+
+	Bay int
+
+	Start, End time.Time
+}
+
+// Overlaps reports whether the booking is at any time between start and end.
+func (b Booking) Overlaps(start, end time.Time) bool {
+	return b.Start.Before(end) && start.Before(b.End)
+}
+
+// Check returns an error if an appointment from start to end is not in whole slots within opening hours.
+func (s Settings) Check(start, end time.Time) error {
+	if !end.After(start) {
+		return errors.New("an appointment must end after it starts")
+	}
+	from, to := start.In(s.Location), end.In(s.Location)
+	if !s.Days[from.Weekday()] {
+		return fmt.Errorf("the workshop is closed on %s", from.Weekday())
+	}
+	if y, m, d := from.Date(); to.Day() != d || to.Month() != m || to.Year() != y {
+		return errors.New("an appointment must end on the day it starts")
+	}
+	if sinceMidnight(from) < s.Opens || sinceMidnight(to) > s.Closes {
+		return fmt.Errorf("appointments must be between %s and %s", clock(s.Opens), clock(s.Closes))
+	}
+	if (sinceMidnight(from)-s.Opens)%s.Slot != 0 || end.Sub(start)%s.Slot != 0 {
+		return fmt.Errorf("appointments are booked in slots of %d minutes from %s", int(s.Slot.Minutes()),
+			clock(s.Opens))
+	}
+	return nil
+}
+
+// Place returns the bay of a new or moved appointment b among the bookings in the diary, the bay it asks for or
+// the first free bay if it asks for none (0). Returns an error if it is outside opening hours or clashes.
+func (s Settings) Place(bookings []Booking, b Booking) (int, error) {
+	if err := s.Check(b.Start, b.End); err != nil {
+		return 0, err
+	}
+	if b.Bay < 0 || b.Bay > s.Bays {
+		return 0, fmt.Errorf("bay must be between 1 and %d", s.Bays)
+	}
+
+	used, busy := s.used(bookings, b)
+	if busy {
+		return 0, ErrWorkerBusy
+	}
+	if b.Bay != 0 {
+		if used[b.Bay] {
+			return 0, ErrBayTaken
+		}
+		return b.Bay, nil
+	}
+	for bay := 1; bay <= s.Bays; bay++ {
+		if !used[bay] {
+			return bay, nil
+		}
+	}
+	return 0, ErrNoBay
+}
+
+// Slots returns the times on the day of day an appointment of length could start, with the number of bays free
+// for all of it. If workerId is not 0 only times the worker is free are returned.
+func (s Settings) Slots(bookings []Booking, day time.Time, length time.Duration, workerId int) []models.AppointmentSlot {
+	slots := []models.AppointmentSlot{}
+	y, m, d := day.In(s.Location).Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, s.Location)
+	if !s.Days[midnight.Weekday()] {
+		return slots
+	}
+	if length < s.Slot {
+		length = s.Slot
+	}
+	length = (length + s.Slot - 1) / s.Slot * s.Slot
+
+	for start := midnight.Add(s.Opens); !start.Add(length).After(midnight.Add(s.Closes)); start = start.Add(s.Slot) {
+		b := Booking{WorkerId: workerId, Start: start, End: start.Add(length)}
+		used, busy := s.used(bookings, b)
+		if free := s.Bays - len(used); !busy && free > 0 {
+			slots = append(slots, models.AppointmentSlot{Start: b.Start, End: b.End, FreeBays: free})
+		}
+	}
+	return slots
+}
+
+// CheckNotice returns an error if a customer booking online at now cannot book an appointment at start,
+// less than minNotice or more than maxAhead from now.
+func CheckNotice(start, now time.Time, minNotice, maxAhead time.Duration) error {
+	if start.Before(now.Add(minNotice)) {
+		return fmt.Errorf("appointments must be booked at least %s ahead", hours(minNotice))
+	}
+	if start.After(now.Add(maxAhead)) {
+		return fmt.Errorf("appointments cannot be booked more than %d days ahead", int(maxAhead.Hours()/24))
+	}
+	return nil
+}
+
+// ToReport returns the job report of an appointment, dated on the day of the appointment with its vehicle,
+// customer and complaint.
+func (s Settings) ToReport(a models.Appointment) models.JobReport {
+	return models.JobReport{Date: models.NewDate(a.Start.In(s.Location)), VehicleModel: a.VehicleModel,
+		VehicleReg: a.VehicleReg, CustomerName: a.CustomerName, Complaint: a.Complaint}
+}
+
+// used returns the bays booked at any time during b, and whether b's worker is at another appointment then.
+func (s Settings) used(bookings []Booking, b Booking) (map[int]bool, bool) {
+	used := map[int]bool{}
+	for _, other := range bookings {
+		if (b.AppointmentId != 0 && other.AppointmentId == b.AppointmentId) || !other.Overlaps(b.Start, b.End) {
+			continue
+		}
+		if b.WorkerId != 0 && other.WorkerId == b.WorkerId {
+			return used, true
+		}
+		used[other.Bay] = true
+	}
+	return used, false
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func hours(d time.Duration) string {
+	if d == time.Hour {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%g hours", d.Hours())
+}
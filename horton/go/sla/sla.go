@@ -0,0 +1,210 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * SLA
+ * Works out whether breakdowns were answered within the response times agreed with customers.
+ * A service level agreement (SLA) sets targets in minutes from a breakdown being requested to it being
+ * acknowledged, arrived at and resolved. Each stage is met if it happened by its target, breached if it happened
+ * late or has not happened and is overdue, and pending until then.
+ */
+
+package sla
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stages of a breakdown with targets.
+const (
+	Acknowledge = "acknowledge"
+	Arrive      = "arrive"
+	Resolve     = "resolve"
+)
+
+// Stages is every stage in the order they happen.
+var Stages = []string{Acknowledge, Arrive, Resolve}
+
+// Outcomes of a stage.
+const (
+	Met      = "met"
+	Breached = "breached"
+	Pending  = "pending"
+)
+
+// Targets are the minutes from a breakdown being requested to each stage, 0 where there is no target.
+type Targets struct {
+	AcknowledgeMinutes int
+	ArriveMinutes      int
+	ResolveMinutes     int
+}
+
+// Minutes returns the target of a stage.
+func (t Targets) Minutes(stage string) int {
+	switch stage {
+	case Acknowledge:
+		return t.AcknowledgeMinutes
+	case Arrive:
+		return t.ArriveMinutes
+	case Resolve:
+		return t.ResolveMinutes
+	}
+	return 0
+}
+
+// Check returns an error if the targets cannot be met in order, each must be no sooner than the one before.
+func (t Targets) Check() error {
+	last := 0
+	for _, stage := range Stages {
+		minutes := t.Minutes(stage)
+		if minutes < 0 {
+			return fmt.Errorf("%s target cannot be negative", stage)
+		}
+		if minutes > 0 && minutes < last {
+			return fmt.Errorf("%s target of %d minutes is sooner than the one before", stage, minutes)
+		}
+		if minutes > 0 {
+			last = minutes
+		}
+	}
+	return nil
+}
+
+// Times are when a breakdown was requested and reached each stage, nil for stages it has not reached.
+type Times struct {
+	Requested    time.Time
+	Acknowledged *time.Time
+	Arrived      *time.Time
+	Resolved     *time.Time
+}
+
+// At returns when a stage was reached.
+func (t Times) At(stage string) *time.Time {
+	switch stage {
+	case Acknowledge:
+		return t.Acknowledged
+	case Arrive:
+		return t.Arrived
+	case Resolve:
+		return t.Resolved
+	}
+	return nil
+}
+
+// Result is how a breakdown did against the target of a stage.
+type Result struct {
+	Stage         string
+	TargetMinutes int
+	Due           time.Time
+	// Minutes is how long after the breakdown was requested the stage was reached, nil if it has not been.
+	Minutes *int
+	Outcome string
+}
+
+// DueWithin returns whether a pending stage is due within d of now.
+func (r Result) DueWithin(now time.Time, d time.Duration) bool {
+	return r.Outcome == Pending && r.Due.Sub(now) <= d
+}
+
+// Evaluate returns the result of each stage with a target at now, in the order they happen.
+func Evaluate(targets Targets, times Times, now time.Time) []Result {
+	var results []Result
+	for _, stage := range Stages {
+		target := targets.Minutes(stage)
+		if target <= 0 {
+			continue
+		}
+		r := Result{Stage: stage, TargetMinutes: target, Due: times.Requested.Add(time.Duration(target) * time.Minute)}
+		if at := times.At(stage); at != nil {
+			minutes := int(at.Sub(times.Requested).Minutes())
+			r.Minutes = &minutes
+			r.Outcome = Met
+			if at.After(r.Due) {
+				r.Outcome = Breached
+			}
+		} else if now.After(r.Due) {
+			r.Outcome = Breached
+		} else {
+			r.Outcome = Pending
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Tally counts the results of a stage across breakdowns.
+type Tally struct {
+	Stage    string
+	Total    int
+	Met      int
+	Breached int
+	Pending  int
+	// minutes is the sum of the Minutes of the stages reached, reached how many.
+	minutes int
+	reached int
+}
+
+// Add counts a result of the tally's stage.
+func (t *Tally) Add(r Result) {
+	t.Total++
+	switch r.Outcome {
+	case Met:
+		t.Met++
+	case Breached:
+		t.Breached++
+	default:
+		t.Pending++
+	}
+	if r.Minutes != nil {
+		t.minutes += *r.Minutes
+		t.reached++
+	}
+}
+
+// Compliance returns the percentage of the stages decided that were met, nil if none have been decided.
+func (t Tally) Compliance() *float64 {
+	decided := t.Met + t.Breached
+	if decided == 0 {
+		return nil
+	}
+	percent := float64(t.Met) * 100 / float64(decided)
+	return &percent
+}
+
+// AverageMinutes returns the average time taken to reach the stage, nil if it has not been reached.
+func (t Tally) AverageMinutes() *float64 {
+	if t.reached == 0 {
+		return nil
+	}
+	average := float64(t.minutes) / float64(t.reached)
+	return &average
+}
+
+// Summarise tallies the results of breakdowns by stage, in the order stages happen.
+func Summarise(results [][]Result) []Tally {
+	tallies := make([]Tally, len(Stages))
+	for i, stage := range Stages {
+		tallies[i].Stage = stage
+	}
+	for _, breakdown := range results {
+		for _, r := range breakdown {
+			for i := range tallies {
+				if tallies[i].Stage == r.Stage {
+					tallies[i].Add(r)
+				}
+			}
+		}
+	}
+	return tallies
+}
+
+// Month returns the start of a month written like "2020-06" in loc and the start of the month after.
+func Month(month string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("month must be like 2020-06")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,48 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Validation
+ * Checks reports from the client before they are saved to the database.
+ * Errors are returned for each invalid field so the client can show them on the form.
+ */
+
+package openapi
+
+import (
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/odometer"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+)
+
+// Function to validate a report before it is created or updated.
+// Sets the fields that are stored in a normalised form - the odometer reading in kilometres & the registration,
+// and the coordinates of the vehicle location.
+// Returns an error for each invalid field, none if the report is valid.
+func validateJobReport(report *models.JobReport) []models.FieldError {
+	var fields []models.FieldError
+
+	// Reports must have a date.
+	if report.Date.IsZero() {
+		fields = append(fields, models.FieldError{Field: "date", Message: "date is required"})
+	}
+
+	// Convert the odometer reading to kilometres, keeping what the user entered.
+	if err := setOdometer(report); err == odometer.ErrUnknownUnit {
+		fields = append(fields, models.FieldError{Field: "odometerUnit", Message: err.Error()})
+	} else if err != nil {
+		fields = append(fields, models.FieldError{Field: "odometerReading", Message: err.Error()})
+	}
+
+	// Store the registration in its normalised form e.g. "151 dl 2308" as "151-DL-2308".
+	if reg, err := plate.Normalise(report.VehicleReg); err != nil {
+		fields = append(fields, models.FieldError{Field: "vehicleReg", Message: err.Error()})
+	} else {
+		report.VehicleReg = reg
+	}
+
+	// Find where the vehicle is e.g. "Gort, Co. Galway", locations the gazetteer does not know are kept as text.
+	locateReport(report)
+
+	return fields
+}
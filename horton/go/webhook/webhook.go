@@ -0,0 +1,272 @@
+/*
+ * John Shields
+ * Horton - API version: 1.0.0
+ *
+ * Webhook
+ * Sends the events of job reports to the endpoints customers register, signed so they can tell they are from Horton.
+ * The body of each request is signed with HMAC-SHA256 with the endpoint's secret, over the Unix time it was sent,
+ * a "." and the body. Requests that fail are tried again later, waiting twice as long after each failure.
+ * Endpoints cannot be on Horton's own host or network, e.g. the cloud metadata service at 169.254.169.254, as the
+ * start of their responses is kept in the delivery log. Addresses are checked when an endpoint is added and again
+ * when each request connects, so a name that resolves to another address later is not sent to either.
+ *
+ * Reference
+ * https://golang.org/pkg/crypto/hmac/
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Events of job reports endpoints can be sent. A report created or updated as complete is also sent as completed.
+const (
+	ReportCreated   = "report.created"
+	ReportUpdated   = "report.updated"
+	ReportCompleted = "report.completed"
+	ReportDeleted   = "report.deleted"
+)
+
+// Events of breakdowns endpoints can be sent, once for each stage of a breakdown that is due soon or overdue.
+const (
+	BreakdownSLAAtRisk   = "breakdown.sla_at_risk"
+	BreakdownSLABreached = "breakdown.sla_breached"
+)
+
+// Events is every event endpoints can be sent.
+var Events = []string{ReportCreated, ReportUpdated, ReportCompleted, ReportDeleted, BreakdownSLAAtRisk,
+	BreakdownSLABreached}
+
+// Statuses of a delivery of an event to an endpoint.
+const (
+	Pending   = "pending"
+	Delivered = "delivered"
+	Failed    = "failed"
+)
+
+// Headers of the requests sent to endpoints.
+const (
+	EventHeader     = "X-Horton-Event"
+	DeliveryHeader  = "X-Horton-Delivery"
+	TimestampHeader = "X-Horton-Timestamp"
+	SignatureHeader = "X-Horton-Signature"
+)
+
+// The most of an endpoint's response kept in the delivery log.
+const maxResponse = 1000
+
+// Networks endpoints cannot be on unless private addresses are allowed: this host, private and shared networks,
+// link-local addresses like the cloud metadata service, and addresses that are not of a single host.
+var blockedNetworks = parseNetworks("0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/3", "::/128", "::1/128",
+	"64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8")
+
+// CheckEvents returns an error if events is empty or has an event endpoints cannot be sent.
+func CheckEvents(events []string) error {
+	if len(events) == 0 {
+		return errors.New("at least one event is needed")
+	}
+	for _, event := range events {
+		known := false
+		for _, e := range Events {
+			known = known || e == event
+		}
+		if !known {
+			return fmt.Errorf("%q is not an event, must be one of %s", event, strings.Join(Events, ", "))
+		}
+	}
+	return nil
+}
+
+// Blocked returns whether ip is an address endpoints cannot be at unless private addresses are allowed.
+func Blocked(ip net.IP) bool {
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL returns an error if the host of an endpoint's URL is, or resolves to, an address that is Blocked.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("%s cannot be found", host)
+		}
+	}
+	for _, ip := range ips {
+		if Blocked(ip) {
+			return fmt.Errorf("%s is not a public address", host)
+		}
+	}
+	return nil
+}
+
+// NewClient returns the client requests are sent to endpoints with. Redirects are not followed, the POST would be
+// sent on as a GET. Unless allowPrivate, connections to Blocked addresses are refused as they are dialled.
+// Proxies are not used, the address checked would be the proxy's.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || Blocked(ip) {
+				return fmt.Errorf("%s is not a public address", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// NewSecret returns a random secret to sign the requests to an endpoint with.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature of a body sent at a time, "sha256=" and the hex of the HMAC.
+func Sign(secret string, sentAt time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(sentAt.Unix(), 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns an error if a signature is not of the body and timestamp, or the timestamp is more than tolerance
+// from now. It is what endpoints check the requests they are sent with.
+func Verify(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("timestamp must be a Unix time")
+	}
+	sentAt := time.Unix(unix, 0)
+	if d := now.Sub(sentAt); d > tolerance || d < -tolerance {
+		return errors.New("timestamp is too old or too far ahead")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, sentAt, body))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// Backoff is how long to wait before trying a failed delivery again, Base after the first failure and twice as
+// long after each one after, up to Max. A delivery fails for good after MaxAttempts.
+type Backoff struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+// Next returns how long to wait after a delivery has failed attempts times, false if it is not to be tried again.
+func (b Backoff) Next(attempts int) (time.Duration, bool) {
+	if attempts >= b.MaxAttempts {
+		return 0, false
+	}
+	wait := b.Base
+	for i := 1; i < attempts && wait < b.Max; i++ {
+		wait *= 2
+	}
+	if wait > b.Max {
+		wait = b.Max
+	}
+	return wait, true
+}
+
+// Message is an event to send to an endpoint.
+type Message struct {
+	DeliveryId int64
+	Event      string
+	URL        string
+	Secret     string
+	Body       []byte
+}
+
+// Attempt is what happened when a message was sent.
+type Attempt struct {
+	// StatusCode is the status the endpoint answered with, 0 if it did not answer.
+	StatusCode int
+	// Error is why the endpoint did not answer, empty if it did.
+	Error string
+	// Response is the start of the endpoint's response.
+	Response string
+	Duration time.Duration
+}
+
+// Delivered returns whether the endpoint answered with a 2xx status.
+func (a Attempt) Delivered() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Send posts a message signed at now to its endpoint with client.
+func Send(client *http.Client, m Message, now time.Time) Attempt {
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodPost, m.URL, bytes.NewReader(m.Body))
+	if err != nil {
+		return Attempt{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Horton-Webhooks/1.0")
+	req.Header.Set(EventHeader, m.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(m.DeliveryId, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(m.Secret, now, m.Body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Attempt{Error: err.Error(), Duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	response, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	// Responses are kept in the delivery log as text, anything that is not is dropped.
+	return Attempt{StatusCode: resp.StatusCode, Response: strings.ToValidUTF8(string(response), ""),
+		Duration: time.Since(start)}
+}
+
+// Function to parse the networks in CIDR notation of a list that is known to be valid.
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
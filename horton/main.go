@@ -12,11 +12,41 @@ import (
 	sw "github.com/GIT_USER_ID/GIT_REPO_ID/go"
 	_ "github.com/gin-gonic/gin"
 	"log"
+	"os"
 )
 
 // Routes & CORS are set up in ./go/routers.go
+// "import" imports reports from a CSV file instead of starting the server, see ./go/import_command.go
+// "geocode" finds the coordinates of reports' vehicle locations, see ./go/geocode_command.go
+// "gazetteer" builds the gazetteer from the full lists of towns and townlands, see ./go/gazetteer_command.go
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(sw.ImportCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "geocode" {
+		os.Exit(sw.GeocodeCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gazetteer" {
+		os.Exit(sw.GazetteerCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	router := sw.NewRouter()
+	// /metrics is also served on internal_port in config.ini when it is set, see ./go/api_metrics.go
+	if err := sw.ServeMetrics(); err != nil {
+		log.Fatal(err)
+	}
+	// Events of job reports are sent to webhook endpoints in the background, see ./go/api_webhook.go
+	if err := sw.StartWebhooks(); err != nil {
+		log.Fatal(err)
+	}
+	// Messages to customers are sent in the background, see ./go/api_notification.go
+	if err := sw.StartNotifications(); err != nil {
+		log.Fatal(err)
+	}
+	// Open breakdowns are checked for SLA targets at risk or breached in the background, see ./go/api_sla.go
+	if err := sw.StartSLAAlerts(); err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println("[INFO] Horton is starting...")
 
 	// Start up router.
@@ -0,0 +1,132 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Accounting Test
+ * Tests for exporting invoices and payments to the Xero and Sage import files.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/accounting"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+var accountingCodes = accounting.Codes{
+	Sales:   map[string]string{"labour": "4000", "part": "4020"},
+	Bank:    map[string]string{"card": "1200"},
+	Tax:     map[string]string{"23": "T1", "13.5": "T3"},
+	Debtors: "1100",
+	NoTax:   "T9",
+}
+
+// Function to make a batch of an invoice, a credit note, a payment and a refund.
+func accountingBatch() accounting.Batch {
+	issued := time.Date(2020, 6, 3, 10, 0, 0, 0, time.UTC)
+	invoice := models.Invoice{InvoiceId: 1, JobReportId: 121, Kind: "invoice", InvoiceNumber: "INV-000001",
+		BillToName: "Joe Kendal", TotalCents: 13512, IssuedAt: &issued, Lines: []models.InvoiceLine{
+			{Kind: "labour", Description: "Labour", NetCents: 6500, VatRate: billing.Rate(13.5), VatCents: 878},
+			{Kind: "part", Description: "Brake pads", NetCents: 4900, VatRate: billing.Rate(23), VatCents: 1127},
+		}}
+	credit := models.Invoice{InvoiceId: 2, JobReportId: 121, Kind: "credit_note", InvoiceNumber: "CN-000001",
+		BillToName: "Joe Kendal", TotalCents: 6027, IssuedAt: &issued, Lines: []models.InvoiceLine{
+			{Kind: "part", Description: "Brake pads", NetCents: 4900, VatRate: billing.Rate(23), VatCents: 1127},
+		}}
+	paid := time.Date(2020, 6, 4, 9, 0, 0, 0, time.UTC)
+	return accounting.Batch{
+		Documents: []models.Invoice{invoice, credit},
+		Payments: []accounting.Payment{
+			{Payment: models.Payment{Method: "card", AmountCents: 7485, ProviderRef: "fake_ch_1", CreatedAt: paid},
+				InvoiceNumber: "INV-000001", BillToName: "Joe Kendal"},
+			{Payment: models.Payment{Method: "card", AmountCents: -500, ProviderRef: "fake_re_2", CreatedAt: paid},
+				InvoiceNumber: "INV-000001", BillToName: "Joe Kendal"},
+		},
+	}
+}
+
+// Function to test the Xero manual journal export.
+// Passes if each document and payment is a journal, with debits positive and credits negative.
+func TestAccountingXero(t *testing.T) {
+	fmt.Println("[TEST] Testing Accounting Xero...")
+
+	exporter, err := accounting.New("xero")
+	if err != nil {
+		t.Fatalf("\n[FAIL] New: %v", err)
+	}
+	var out bytes.Buffer
+	if err := exporter.Export(&out, accountingBatch(), accountingCodes); err != nil {
+		t.Fatalf("\n[FAIL] Export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"*Narration,*Date,Description,*AccountCode,*TaxRate,*Amount,TrackingName1,TrackingOption1,TrackingName2,TrackingOption2",
+		"Invoice INV-000001 - Joe Kendal,03/06/2020,Joe Kendal,1100,T9,135.12,,,,",
+		"Invoice INV-000001 - Joe Kendal,03/06/2020,Labour,4000,T3,-65.00,,,,",
+		"Invoice INV-000001 - Joe Kendal,03/06/2020,Brake pads,4020,T1,-49.00,,,,",
+		"Credit Note CN-000001 - Joe Kendal,03/06/2020,Joe Kendal,1100,T9,-60.27,,,,",
+		"Credit Note CN-000001 - Joe Kendal,03/06/2020,Brake pads,4020,T1,49.00,,,,",
+		"Payment of INV-000001 - Joe Kendal,04/06/2020,card,1200,T9,74.85,,,,",
+		"Payment of INV-000001 - Joe Kendal,04/06/2020,card,1100,T9,-74.85,,,,",
+		"Refund of INV-000001 - Joe Kendal,04/06/2020,card,1200,T9,-5.00,,,,",
+		"Refund of INV-000001 - Joe Kendal,04/06/2020,card,1100,T9,5.00,,,,",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("\n[FAIL] Xero export was\n%s", out.String())
+	}
+}
+
+// Function to test the Sage audit trail export.
+// Passes if invoice lines are SI rows, credit note lines SC rows, payments SA rows and refunds BP rows.
+func TestAccountingSage(t *testing.T) {
+	fmt.Println("[TEST] Testing Accounting Sage...")
+
+	exporter, _ := accounting.New("sage")
+	var out bytes.Buffer
+	if err := exporter.Export(&out, accountingBatch(), accountingCodes); err != nil {
+		t.Fatalf("\n[FAIL] Export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"SI,JOEKENDA,4000,0,03/06/2020,INV-000001,Labour,65.00,T3,8.78,,Job Report 121,,,",
+		"SI,JOEKENDA,4020,0,03/06/2020,INV-000001,Brake pads,49.00,T1,11.27,,Job Report 121,,,",
+		"SC,JOEKENDA,4020,0,03/06/2020,CN-000001,Brake pads,49.00,T1,11.27,,Job Report 121,,,",
+		"SA,JOEKENDA,1200,0,04/06/2020,INV-000001,Payment by card,74.85,T9,0.00,,fake_ch_1,,,",
+		"BP,1200,1100,0,04/06/2020,INV-000001,Refund to Joe Kendal,5.00,T9,0.00,,fake_re_2,,,",
+	}
+	if len(lines) != 6 || strings.Join(lines[1:], "\n") != strings.Join(want, "\n") {
+		t.Errorf("\n[FAIL] Sage export was\n%s", out.String())
+	}
+}
+
+// Function to test exporting with codes missing.
+// Passes if the export fails without writing anything and unknown formats are refused.
+func TestAccountingMissingCode(t *testing.T) {
+	fmt.Println("[TEST] Testing Accounting Missing Code...")
+
+	codes := accountingCodes
+	codes.Tax = map[string]string{"23": "T1"}
+	for _, format := range accounting.Formats() {
+		exporter, _ := accounting.New(format)
+		var out bytes.Buffer
+		if err := exporter.Export(&out, accountingBatch(), codes); err == nil || out.Len() > 0 {
+			t.Errorf("\n[FAIL] %s exported without a tax code for 13.5%%: %v", format, err)
+		}
+	}
+
+	if _, err := accounting.New("quickbooks"); err == nil {
+		t.Error("\n[FAIL] New returned an exporter of an unknown format")
+	}
+	if ref := accounting.SageAccountRef("Mary O'Byrne-Smith"); ref != "MARYOBYR" {
+		t.Errorf("\n[FAIL] SageAccountRef was %q", ref)
+	}
+}
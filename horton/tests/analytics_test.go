@@ -0,0 +1,118 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Analytics Test
+ * Tests for caching analytics results and working out the figures shown from them.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/analytics"
+)
+
+// Function to test keeping analytics results for a short time.
+// Passes if results expire after their time to live and the result that expires soonest is dropped when full.
+func TestAnalyticsCache(t *testing.T) {
+	fmt.Println("[TEST] Testing Analytics Cache...")
+
+	now := onWednesday(9, 0)
+	cache := analytics.NewCache(time.Minute, 2)
+
+	cache.Set("workers", 1, now)
+	cache.Set("causes", 2, now.Add(10*time.Second))
+	if value, ok := cache.Get("workers", now.Add(59*time.Second)); !ok || value != 1 {
+		t.Errorf("\n[FAIL] Got %v, %t - wanted the cached workers", value, ok)
+	}
+	if _, ok := cache.Get("workers", now.Add(time.Minute)); ok {
+		t.Errorf("\n[FAIL] Workers were cached after they expired")
+	}
+
+	// Full, workers expire soonest so are dropped.
+	cache.Set("parts", 3, now.Add(20*time.Second))
+	if _, ok := cache.Get("workers", now.Add(30*time.Second)); ok {
+		t.Errorf("\n[FAIL] Workers were kept when the cache was full")
+	}
+	for _, key := range []string{"causes", "parts"} {
+		if _, ok := cache.Get(key, now.Add(30*time.Second)); !ok {
+			t.Errorf("\n[FAIL] %s were dropped from the cache", key)
+		}
+	}
+
+	off := analytics.NewCache(0, 2)
+	off.Set("workers", 1, now)
+	if _, ok := off.Get("workers", now); ok {
+		t.Errorf("\n[FAIL] A cache with no time to live kept a result")
+	}
+}
+
+// Function to test the most common values of each vehicle model.
+// Passes if up to the limit of values are kept per model, most found first.
+func TestAnalyticsTop(t *testing.T) {
+	fmt.Println("[TEST] Testing Analytics Top...")
+
+	counts := []analytics.Count{
+		{Group: "Corolla", Value: "Battery", N: 2},
+		{Group: "Avensis", Value: "Clutch", N: 1},
+		{Group: "Corolla", Value: "Tyre", N: 5},
+		{Group: "Corolla", Value: "Alternator", N: 2},
+		{Group: "Avensis", Value: "Brakes", N: 4},
+	}
+	var got []string
+	for _, c := range analytics.Top(counts, 2) {
+		got = append(got, fmt.Sprintf("%s %s %d", c.Group, c.Value, c.N))
+	}
+	want := "[Avensis Brakes 4 Avensis Clutch 1 Corolla Tyre 5 Corolla Alternator 2]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("\n[FAIL] Top values were %v - wanted %s", got, want)
+	}
+}
+
+// Function to test percentages and periods of analytics.
+// Passes if percentages are rounded to one decimal place and only known periods are allowed.
+func TestAnalyticsRate(t *testing.T) {
+	fmt.Println("[TEST] Testing Analytics Rate...")
+
+	if rate := analytics.Rate(2, 3); rate == nil || *rate != 66.7 {
+		t.Errorf("\n[FAIL] 2 of 3 was %v - wanted 66.7", rate)
+	}
+	if rate := analytics.Rate(0, 0); rate != nil {
+		t.Errorf("\n[FAIL] 0 of 0 was %v - wanted none", *rate)
+	}
+
+	for _, period := range analytics.Periods {
+		if err := analytics.CheckPeriod(period); err != nil {
+			t.Errorf("\n[FAIL] Period %s: %v", period, err)
+		}
+	}
+	if analytics.CheckPeriod("fortnight") == nil {
+		t.Errorf("\n[FAIL] Period fortnight was allowed")
+	}
+}
+
+// Function to test the parts counted from the parts of a job.
+// Passes if lists are split on commas, quantities are dropped and each part is counted once.
+func TestAnalyticsParts(t *testing.T) {
+	fmt.Println("[TEST] Testing Analytics Parts...")
+
+	tests := map[string]string{
+		"2 x Brake pads":                   "BRAKE PADS",
+		"1 DOOR LOCK":                      "DOOR LOCK",
+		"2 CABLES, 2 BRAKE PADS":           "CABLES|BRAKE PADS",
+		"2x wipers,  bulbs X2 , 1 X-PIPE":  "WIPERS|BULBS|X-PIPE",
+		"10W40 OIL, 2 XENON BULBS":         "10W40 OIL|XENON BULBS",
+		"Brake pads, 2 x brake  pads, , 3": "BRAKE PADS",
+		"":                                 "",
+	}
+	for list, want := range tests {
+		if got := strings.Join(analytics.Parts(list), "|"); got != want {
+			t.Errorf("\n[FAIL] Parts of %q were %q - wanted %q", list, got, want)
+		}
+	}
+}
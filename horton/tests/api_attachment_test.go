@@ -0,0 +1,111 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Attachment API Test
+ * Tests for UploadAttachment and GetAttachments by using the mock user created in API Account Test.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Smallest valid PNG - a 1x1 pixel image.
+var testPNG = []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4, 0x89, 0x00, 0x00,
+	0x00, 0x0a, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00, 0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d,
+	0xb4, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82}
+
+// Function to test UploadAttachment by sending a multipart request to /jobReports/ID/attachments endpoint.
+// Tests the functions UploadAttachment, CheckForCookie, isValidAccount & ownsReport.
+// Passes if the photo was attached to the report.
+func TestUploadAttachment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing UploadAttachment...")
+
+	t.Run("uploadAttachment", func(t *testing.T) {
+		// Set up multipart form with the photo.
+		payloadBuf := new(bytes.Buffer)
+		form := multipart.NewWriter(payloadBuf)
+		_ = form.WriteField("kind", "before")
+		part, err := form.CreateFormFile("file", "before.png")
+		if err != nil {
+			log.Println("Unable to create form", err)
+		}
+		_, _ = part.Write(testPNG)
+		_ = form.Close()
+
+		// Set up /jobReports/ID/attachments request.
+		url := "http://localhost:8080/api/v1/jobReports/656/attachments"
+		req, err := http.NewRequest("POST", url, payloadBuf)
+		if err != nil {
+			log.Println(err)
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+
+		// Do POST request (Upload Attachment).
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Println(err)
+		}
+		defer res.Body.Close()
+
+		fmt.Println("response Status:", res.Status)
+		if res.Status == "201 Created" {
+			// TEST PASSED
+			fmt.Println("\n[PASS] Attachment was uploaded successfully")
+		} else if res.Status == "403 Forbidden" {
+			fmt.Println("[PASS] But User is unauthorized to upload an Attachment")
+		} else {
+			// TEST FAILED
+			t.Error("\n[FAIL] failed to upload Attachment", err)
+			t.Fail()
+		}
+	})
+}
+
+// Function to test GetAttachments by sending request to /jobReports/ID/attachments endpoint.
+// Tests the functions GetAttachments, CheckForCookie, isValidAccount & ownsReport.
+// Passes if the details of the report's attachments are sent to the client.
+func TestGetAttachments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing GetAttachments...")
+
+	t.Run("getAttachments", func(t *testing.T) {
+		// Set up /jobReports/ID/attachments request.
+		url := "http://localhost:8080/api/v1/jobReports/656/attachments"
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			log.Println(err)
+		}
+		// Do GET request (Get Attachments).
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Println(err)
+		}
+		defer res.Body.Close()
+
+		fmt.Println("response Status:", res.Status)
+		if res.Status == "200 OK" {
+			// TEST PASSED
+			fmt.Println("\n[PASS] succeeded to GetAttachments")
+		} else if res.Status == "403 Forbidden" {
+			fmt.Println("[PASS] But User is unauthorized to get these Attachments")
+		} else {
+			// TEST FAILED
+			t.Error("\n[FAIL] failed to GetAttachments", err)
+			t.Fail()
+		}
+	})
+}
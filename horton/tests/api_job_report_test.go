@@ -29,10 +29,11 @@ func TestCreateReport(t *testing.T) {
 	fmt.Println("[TEST] Testing CreateReport...")
 
 	t.Run("createReport", func(t *testing.T) {
+		date, _ := models.ParseDate("2019-01-06")
 		body := &models.JobReport{
-			Date:            "06-01-2019",
+			Date:            date,
 			VehicleModel:    "Peugeot Spinner",
-			VehicleReg:      "191-LA-2049",
+			VehicleReg:      "191-LH-2049",
 			VehicleLocation: "Drogheda, Co. Louth",
 			MilesOnVehicle:  1337,
 			Warranty:        1,
@@ -161,10 +162,11 @@ func TestUpdateReport(t *testing.T) {
 
 	t.Run("updateReport", func(t *testing.T) {
 		// Set up JobReport Payload.
+		date, _ := models.ParseDate("2019-01-06")
 		body := &models.JobReport{
-			Date:            "06-01-2019",
+			Date:            date,
 			VehicleModel:    "Peugeot Spinner",
-			VehicleReg:      "191-LA-2049",
+			VehicleReg:      "191-LH-2049",
 			VehicleLocation: "Drogheda, Co. Louth",
 			MilesOnVehicle:  1337,
 			Warranty:        1,
@@ -211,6 +213,85 @@ func TestUpdateReport(t *testing.T) {
 	})
 }
 
+// Function to test UpdateReport keeps a report's contact by sending requests to /jobReports/ID endpoint.
+// Tests the functions UpdateReport, CheckForCookie & checkContact.
+// Passes if a report updated without a contactId, as the web app does, is still linked to its contact.
+func TestUpdateReportKeepsContact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing UpdateReport keeps the Contact...")
+
+	t.Run("updateReportKeepsContact", func(t *testing.T) {
+		client := &http.Client{}
+		send := func(method, url string, body interface{}) (*http.Response, error) {
+			payloadBuf := new(bytes.Buffer)
+			if body != nil {
+				if err := json.NewEncoder(payloadBuf).Encode(body); err != nil {
+					log.Println("Unable to Encode", err)
+				}
+			}
+			req, err := http.NewRequest(method, url, payloadBuf)
+			if err != nil {
+				return nil, err
+			}
+			return client.Do(req)
+		}
+
+		// Add a contact for the report's customer.
+		res, err := send("POST", "http://localhost:8080/api/v1/notificationPreferences",
+			&models.NotificationPreferences{CustomerName: "Joe Kendal", Email: "joe@example.com",
+				Channels: []string{"email"}})
+		if err != nil {
+			t.Fatal("\n[FAIL] failed to create Contact", err)
+		}
+		var contact models.NotificationPreferences
+		json.NewDecoder(res.Body).Decode(&contact)
+		res.Body.Close()
+		if res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusUnauthorized {
+			fmt.Println("[PASS] But User is unauthorized to create a Contact")
+			return
+		}
+		if res.StatusCode != http.StatusCreated {
+			t.Fatal("\n[FAIL] failed to create Contact:", res.Status)
+		}
+
+		date, _ := models.ParseDate("2019-01-06")
+		report := models.JobReport{Date: date, VehicleModel: "Peugeot Spinner", VehicleReg: "191-LH-2049",
+			CustomerName: "Joe Kendal", Complaint: "Door handle broken.", ContactId: &contact.ContactId}
+		url := "http://localhost:8080/api/v1/jobReports/656"
+
+		// Link the report to the contact, then update it without a contactId.
+		unlinked := report
+		unlinked.ContactId = nil
+		for _, update := range []models.JobReport{report, unlinked} {
+			res, err := send("PUT", url, &update)
+			if err != nil {
+				t.Fatal("\n[FAIL] failed to update Report", err)
+			}
+			res.Body.Close()
+			if res.StatusCode == http.StatusForbidden {
+				fmt.Println("[PASS] But User is unauthorized to update this Report")
+				return
+			}
+			if res.StatusCode != http.StatusAccepted {
+				t.Fatal("\n[FAIL] failed to update Report:", res.Status)
+			}
+		}
+
+		res, err = send("GET", url, nil)
+		if err != nil {
+			t.Fatal("\n[FAIL] failed to get Report", err)
+		}
+		defer res.Body.Close()
+		var reports []models.JobReport
+		json.NewDecoder(res.Body).Decode(&reports)
+		if len(reports) != 1 || reports[0].ContactId == nil || *reports[0].ContactId != contact.ContactId {
+			t.Errorf("\n[FAIL] Report lost its Contact %d when updated without one: %+v", contact.ContactId, reports)
+		} else {
+			fmt.Println("\n[PASS] Report kept its Contact")
+		}
+	})
+}
+
 // Function to test DeleteReport by sending request to /jobReports/ID endpoint.
 // Tests the Functions DeleteReport & CheckForCookie.
 // Passes if the requested Report was successfully deleted.
@@ -0,0 +1,81 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Report Batch API Test
+ * Tests for BatchReports by using the mock user created in API Account Test.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"testing"
+)
+
+// Function to test BatchReports by sending request to /jobReports:batch endpoint.
+// Tests the functions BatchReports, CheckForCookie, isValidAccount & validateJobReport.
+// Passes if the batch was processed, the update of a report that does not exist fails on its own.
+func TestBatchReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing BatchReports...")
+
+	t.Run("batchReports", func(t *testing.T) {
+		date, _ := models.ParseDate("2019-01-06")
+		report := &models.JobReport{Date: date, VehicleModel: "Ford Focus", VehicleReg: "191-LH-2049",
+			OdometerReading: 12000, OdometerUnit: "km", CustomerName: "Joe Kendal", Complaint: "Engine light on"}
+
+		// Set up Batch Payload.
+		body := &models.BatchRequest{
+			Mode: "bestEffort",
+			Operations: []models.BatchOperation{
+				{Op: "create", ClientId: "offline-1", Report: report},
+				{Op: "update", ClientId: "offline-2", JobReportId: 999999, Report: report},
+			},
+		}
+
+		// Encode Batch.
+		payloadBuf := new(bytes.Buffer)
+		err := json.NewEncoder(payloadBuf).Encode(body)
+		if err != nil {
+			log.Println("Unable to Encode", err)
+		}
+
+		// Set up /jobReports:batch request.
+		url := "http://localhost:8080/api/v1/jobReports:batch"
+		req, err := http.NewRequest("POST", url, payloadBuf)
+		if err != nil {
+			log.Println(err)
+		}
+		// Do POST request (Batch Reports).
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Println(err)
+		}
+		defer res.Body.Close()
+
+		fmt.Println("response Status:", res.Status)
+		if res.Status == "200 OK" {
+			var result models.BatchResult
+			json.NewDecoder(res.Body).Decode(&result)
+			if len(result.Results) != 2 || result.Results[0].Status != 201 || result.Results[1].Status != 404 {
+				t.Error("\n[FAIL] unexpected Batch results", result.Results)
+			}
+			// TEST PASSED
+			fmt.Println("\n[PASS] Batch has been processed")
+		} else if res.Status == "403 Forbidden" {
+			fmt.Println("[PASS] But User is unauthorized to batch Reports")
+		} else {
+			// TEST FAILED
+			t.Error("\n[FAIL] failed to process Batch", err)
+			t.Fail()
+		}
+	})
+}
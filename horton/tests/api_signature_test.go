@@ -0,0 +1,107 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Signature API Test
+ * Tests for SignReport and GetSignature by using the mock user created in API Account Test.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"testing"
+)
+
+// Function to test SignReport by sending request to /jobReports/ID/signature endpoint.
+// Tests the functions SignReport, CheckForCookie, isValidAccount & findReport.
+// Passes if the customer's signature was stored, or the report was already signed.
+func TestSignReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing SignReport...")
+
+	t.Run("signReport", func(t *testing.T) {
+		// Set up Signature Payload.
+		body := &models.Signature{
+			SignerName: "Joe Kendal",
+			Strokes: [][]models.StrokePoint{
+				{{X: 10, Y: 20, T: 0}, {X: 40, Y: 22, T: 16}, {X: 70, Y: 18, T: 32}},
+			},
+		}
+
+		// Encode Signature.
+		payloadBuf := new(bytes.Buffer)
+		err := json.NewEncoder(payloadBuf).Encode(body)
+		if err != nil {
+			log.Println("Unable to Encode", err)
+		}
+
+		// Set up /jobReports/ID/signature request.
+		url := "http://localhost:8080/api/v1/jobReports/656/signature"
+		req, err := http.NewRequest("POST", url, payloadBuf)
+		if err != nil {
+			log.Println(err)
+		}
+		// Do POST request (Sign Report).
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Println(err)
+		}
+		defer res.Body.Close()
+
+		fmt.Println("response Status:", res.Status)
+		if res.Status == "201 Created" || res.Status == "409 Conflict" {
+			// TEST PASSED
+			fmt.Println("\n[PASS] Report has been signed")
+		} else if res.Status == "403 Forbidden" {
+			fmt.Println("[PASS] But User is unauthorized to sign this Report")
+		} else {
+			// TEST FAILED
+			t.Error("\n[FAIL] failed to sign Report", err)
+			t.Fail()
+		}
+	})
+}
+
+// Function to test GetSignature by sending request to /jobReports/ID/signature endpoint.
+// Tests the functions GetSignature, CheckForCookie, isValidAccount & findReport.
+// Passes if the signature is sent to the client.
+func TestGetSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fmt.Println("[TEST] Testing GetSignature...")
+
+	t.Run("getSignature", func(t *testing.T) {
+		// Set up /jobReports/ID/signature request.
+		url := "http://localhost:8080/api/v1/jobReports/656/signature"
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			log.Println(err)
+		}
+		// Do GET request (Get Signature).
+		client := &http.Client{}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Println(err)
+		}
+		defer res.Body.Close()
+
+		fmt.Println("response Status:", res.Status)
+		if res.Status == "200 OK" {
+			// TEST PASSED
+			fmt.Println("\n[PASS] succeeded to GetSignature")
+		} else if res.Status == "403 Forbidden" {
+			fmt.Println("[PASS] But User is unauthorized to get this Signature")
+		} else {
+			// TEST FAILED
+			t.Error("\n[FAIL] failed to GetSignature", err)
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Billing Test
+ * Tests for the labour lines, totals and numbering of invoices.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/billing"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+var rates = billing.Rates{Standard: 6500, Overtime: 9750, Warranty: 5500, LabourVAT: 13.5, PartsVAT: 23,
+	Currency: "EUR"}
+
+// Function to test the labour lines of a job.
+// Passes if hours are split between the standard and overtime rates, and warranty jobs use the warranty rate.
+func TestBillingLabourLines(t *testing.T) {
+	fmt.Println("[TEST] Testing Billing Labour Lines...")
+
+	lines := billing.LabourLines(3, 1.5, false, rates)
+	if len(lines) != 2 || lines[0].Kind != billing.Labour || lines[0].Quantity != 3 || lines[0].UnitPriceCents != 6500 ||
+		lines[1].Kind != billing.Overtime || lines[1].Quantity != 1.5 || lines[1].UnitPriceCents != 9750 {
+		t.Errorf("\n[FAIL] Labour lines were %+v", lines)
+	}
+
+	lines = billing.LabourLines(3, 1.5, true, rates)
+	if len(lines) != 1 || lines[0].Kind != billing.WarrantyLabour || lines[0].Quantity != 4.5 ||
+		lines[0].UnitPriceCents != 5500 {
+		t.Errorf("\n[FAIL] Warranty labour lines were %+v", lines)
+	}
+
+	if lines := billing.LabourLines(0, 0, false, rates); len(lines) != 0 {
+		t.Errorf("\n[FAIL] No hours gave lines %+v", lines)
+	}
+}
+
+// Function to test working out the totals of an invoice.
+// Passes if VAT is worked out and rounded on each line with the rate of its kind.
+func TestBillingCalculate(t *testing.T) {
+	fmt.Println("[TEST] Testing Billing Calculate...")
+
+	lines := []models.InvoiceLine{
+		{Kind: billing.Labour, Description: "Labour", Quantity: 1.5, UnitPriceCents: 6500},
+		{Kind: billing.Part, Description: "Brake pads", Quantity: 2, UnitPriceCents: 2449},
+		{Description: "Disposal", Quantity: 1, UnitPriceCents: 333, VatRate: billing.Rate(0.5)},
+	}
+	subtotal, vat, total, err := billing.Calculate(lines, rates)
+	if err != nil {
+		t.Fatalf("\n[FAIL] Calculate: %v", err)
+	}
+
+	// 9750 @ 13.5% = 1316.25, 4898 @ 23% = 1126.54, 333 @ 0.5% = 1.665
+	if lines[0].NetCents != 9750 || lines[0].VatCents != 1316 || lines[1].VatCents != 1127 ||
+		lines[2].Kind != billing.Other || lines[2].VatCents != 2 {
+		t.Errorf("\n[FAIL] Lines were %+v", lines)
+	}
+	if subtotal != 14981 || vat != 2445 || total != 17426 {
+		t.Errorf("\n[FAIL] Totals were %d + %d = %d - wanted 14981 + 2445 = 17426", subtotal, vat, total)
+	}
+
+	// A rate of 0 is zero rated rather than the rate of the line's kind.
+	zero := []models.InvoiceLine{{Kind: billing.Part, Description: "Exempt part", Quantity: 1, UnitPriceCents: 1000,
+		VatRate: billing.Rate(0)}}
+	if _, vat, total, err := billing.Calculate(zero, rates); err != nil || vat != 0 || total != 1000 ||
+		*zero[0].VatRate != 0 {
+		t.Errorf("\n[FAIL] Zero rated line was %+v, VAT %d, total %d, %v", zero[0], vat, total, err)
+	}
+	if *lines[1].VatRate != 23 {
+		t.Errorf("\n[FAIL] Line without a rate got %v - wanted 23", *lines[1].VatRate)
+	}
+
+	bad := [][]models.InvoiceLine{
+		{{Description: "", Quantity: 1, UnitPriceCents: 100}},
+		{{Description: "Credit", Quantity: 1, UnitPriceCents: 100, VatRate: billing.Rate(-1)}},
+		{{Description: "Refund", Quantity: 1, UnitPriceCents: -100}},
+		{{Kind: "discount", Description: "Discount", Quantity: 1, UnitPriceCents: 100}},
+	}
+	for _, lines := range bad {
+		if _, _, _, err := billing.Calculate(lines, rates); err == nil {
+			t.Errorf("\n[FAIL] Calculate accepted %+v", lines)
+		}
+	}
+}
+
+// Function to test the statuses an invoice can move between.
+// Passes if only the allowed changes of status are.
+func TestBillingCanTransition(t *testing.T) {
+	fmt.Println("[TEST] Testing Billing Can Transition...")
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{billing.Draft, billing.Issued, true},
+		{billing.Draft, billing.Paid, false},
+		{billing.Issued, billing.Paid, true},
+		{billing.Issued, billing.Draft, false},
+		{billing.Paid, billing.Issued, true},
+		{billing.Void, billing.Issued, false},
+	}
+	for _, test := range tests {
+		if got := billing.CanTransition(test.from, test.to); got != test.want {
+			t.Errorf("\n[FAIL] CanTransition(%s, %s) was %v", test.from, test.to, got)
+		}
+	}
+}
+
+// Function to test invoice numbers.
+// Passes if numbers are the prefix and the sequence padded to six digits.
+func TestBillingFormatNumber(t *testing.T) {
+	fmt.Println("[TEST] Testing Billing Format Number...")
+
+	if got := billing.FormatNumber("INV-", 42); got != "INV-000042" {
+		t.Errorf("\n[FAIL] FormatNumber was %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Blob Store Test
+ * Tests storing, getting and deleting attachments in the Local and S3 Blob Stores.
+ * The S3 Store is tested against a fake S3 server, set HORTON_TEST_S3_ENDPOINT (and HORTON_TEST_S3_BUCKET,
+ * HORTON_TEST_S3_ACCESS_KEY, HORTON_TEST_S3_SECRET_KEY) to also test it against a local MinIO.
+ */
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/blobstore"
+)
+
+// Function to put, get and delete a file in a Blob Store.
+func testBlobStore(t *testing.T, store blobstore.Store) {
+	ctx := context.Background()
+	key := "jobReports/121/before.txt"
+	data := "before photo"
+
+	if err := store.Put(ctx, key, strings.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		t.Fatal("\n[FAIL] Unable to Put file", err)
+	}
+
+	file, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal("\n[FAIL] Unable to Get file", err)
+	}
+	got, _ := ioutil.ReadAll(file)
+	file.Close()
+	if string(got) != data {
+		t.Errorf("\n[FAIL] Got %q, wanted %q", got, data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal("\n[FAIL] Unable to Delete file", err)
+	}
+	if _, err := store.Get(ctx, key); err != blobstore.ErrNotFound {
+		t.Errorf("\n[FAIL] Deleted file was found: %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("\n[FAIL] Deleting a missing file failed: %v", err)
+	}
+}
+
+// Function to test the Local Store in a temporary directory.
+// Passes if a file is stored, read back and deleted, and keys cannot point outside of the directory.
+func TestLocalStore(t *testing.T) {
+	fmt.Println("[TEST] Testing Local Store...")
+
+	dir, err := ioutil.TempDir("", "horton-attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := blobstore.NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+
+	// "../" is kept inside the directory.
+	data := "x"
+	if err := store.Put(context.Background(), "../escape.txt", strings.NewReader(data), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir + "/escape.txt"); err != nil {
+		t.Error("\n[FAIL] Key was stored outside of the directory", err)
+	}
+}
+
+// Function to test the S3 Store against a fake S3 server that keeps objects in memory.
+// Passes if requests are signed and a file is stored, read back and deleted.
+func TestS3Store(t *testing.T) {
+	fmt.Println("[TEST] Testing S3 Store...")
+
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") || r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := blobstore.NewS3Store(server.URL, "horton", "", "minio", "minio123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+}
+
+// Function to test the S3 Store against a local MinIO, skipped if HORTON_TEST_S3_ENDPOINT is not set.
+// e.g. docker run -p 9000:9000 minio/minio server /data, then create the bucket.
+func TestS3StoreMinIO(t *testing.T) {
+	endpoint := os.Getenv("HORTON_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("HORTON_TEST_S3_ENDPOINT is not set")
+	}
+	fmt.Println("[TEST] Testing S3 Store against MinIO...")
+
+	store, err := blobstore.NewS3Store(endpoint, os.Getenv("HORTON_TEST_S3_BUCKET"), "",
+		os.Getenv("HORTON_TEST_S3_ACCESS_KEY"), os.Getenv("HORTON_TEST_S3_SECRET_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+}
@@ -0,0 +1,98 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Booking Test
+ * Tests for the notice customers must book online with and rate limiting the public booking endpoints.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/ratelimit"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/schedule"
+)
+
+// Function to test checking online bookings are made with notice and not too far ahead.
+// Passes if bookings less than two hours or more than 60 days ahead are refused.
+func TestBookingNotice(t *testing.T) {
+	fmt.Println("[TEST] Testing Booking Notice...")
+
+	now := onWednesday(9, 0)
+	notice, ahead := 2*time.Hour, 60*24*time.Hour
+	tests := []struct {
+		start time.Time
+		valid bool
+	}{
+		{onWednesday(11, 0), true},
+		{onWednesday(10, 30), false},
+		{onWednesday(8, 0), false},
+		{now.Add(ahead), true},
+		{now.Add(ahead).Add(30 * time.Minute), false},
+	}
+	for _, test := range tests {
+		if err := schedule.CheckNotice(test.start, now, notice, ahead); (err == nil) != test.valid {
+			t.Errorf("\n[FAIL] Booking at %s: %v", test.start.Format("2 Jan 15:04"), err)
+		}
+	}
+}
+
+// Function to test limiting how often a client can use the public endpoints.
+// Passes if each client is limited separately and allowed again once its window is over.
+func TestRateLimitAllow(t *testing.T) {
+	fmt.Println("[TEST] Testing Rate Limit Allow...")
+
+	limiter := ratelimit.New(2, time.Minute)
+	now := onWednesday(9, 0)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("10.0.0.1", now.Add(time.Duration(i)*time.Second)); !ok {
+			t.Errorf("\n[FAIL] Request %d was refused", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("10.0.0.1", now.Add(20*time.Second))
+	if ok || wait != 40*time.Second {
+		t.Errorf("\n[FAIL] Third request returned %v, %s - wanted false, 40s", ok, wait)
+	}
+	if ok, _ := limiter.Allow("10.0.0.2", now.Add(20*time.Second)); !ok {
+		t.Errorf("\n[FAIL] Another client was refused")
+	}
+	if ok, _ := limiter.Allow("10.0.0.1", now.Add(time.Minute)); !ok {
+		t.Errorf("\n[FAIL] Request after the window was refused")
+	}
+}
+
+// Function to test which address requests to the public endpoints are limited by.
+// Passes if X-Forwarded-For is ignored unless the request came through a trusted proxy, and then the address is the
+// last one added before a trusted proxy.
+func TestRateLimitClient(t *testing.T) {
+	fmt.Println("[TEST] Testing Rate Limit Client...")
+
+	trusted := []string{"10.0.0.0/8", "192.168.1.5"}
+	tests := []struct {
+		name, remote, forwarded, want string
+	}{
+		{"no proxy", "203.0.113.7:5123", "", "203.0.113.7"},
+		{"spoofed", "203.0.113.7:5123", "198.51.100.1", "203.0.113.7"},
+		{"proxy", "10.1.2.3:443", "198.51.100.1", "198.51.100.1"},
+		{"spoofed through proxy", "10.1.2.3:443", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"two proxies", "192.168.1.5:443", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+		{"proxy without header", "10.1.2.3:443", "", "10.1.2.3"},
+		{"garbage", "10.1.2.3:443", "not-an-ip", "10.1.2.3"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/v1/public/bookings", nil)
+		r.RemoteAddr = test.remote
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if got := ratelimit.Client(r, trusted); got != test.want {
+			t.Errorf("\n[FAIL] Client of %s was %s - wanted %s", test.name, got, test.want)
+		}
+	}
+}
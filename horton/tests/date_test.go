@@ -0,0 +1,56 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Date Test
+ * Tests for reading report dates in ISO 8601 and the legacy DD-MM-YYYY format.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Function to test ParseDate with ISO 8601 and legacy dates.
+// Passes if each format is read as the same date and invalid dates are rejected.
+func TestParseDate(t *testing.T) {
+	fmt.Println("[TEST] Testing ParseDate...")
+
+	for _, in := range []string{"2020-04-03", "2020-04-03T09:30:00Z", "03-04-2020", "03/04/2020"} {
+		date, err := models.ParseDate(in)
+		if err != nil || date.String() != "2020-04-03" {
+			t.Errorf("\n[FAIL] ParseDate(%q) = %q, %v", in, date, err)
+		}
+	}
+
+	for _, in := range []string{"", "2020-13-01", "31-02-2020", "April 3rd"} {
+		if _, err := models.ParseDate(in); err == nil {
+			t.Errorf("\n[FAIL] ParseDate accepted %q", in)
+		}
+	}
+}
+
+// Function to test the JSON of a report date.
+// Passes if a legacy date from the client is sent back in ISO 8601.
+func TestDateJSON(t *testing.T) {
+	fmt.Println("[TEST] Testing Date JSON...")
+
+	var report models.JobReport
+	if err := json.Unmarshal([]byte(`{"date": "06-01-2019"}`), &report); err != nil {
+		t.Fatal("\n[FAIL] Unable to decode report date", err)
+	}
+
+	out, err := json.Marshal(report.Date)
+	if err != nil || string(out) != `"2019-01-06"` {
+		t.Errorf("\n[FAIL] Date encoded as %s, %v", out, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"date": "someday"}`), &report); err == nil {
+		t.Error("\n[FAIL] Invalid date was decoded")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Dispatch Test
+ * Tests for the breakdown dispatch queue, suggesting the nearest free worker and distances by road.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+)
+
+// Function to test the changes to the status of a breakdown.
+// Passes if workers are dispatched until one arrives, acknowledged and arrived follow dispatched and only
+// breakdowns arrived at are resolved.
+func TestDispatchAdvance(t *testing.T) {
+	fmt.Println("[TEST] Testing Dispatch Advance...")
+
+	tests := []struct {
+		status, next string
+		allowed      bool
+	}{
+		{dispatch.Waiting, dispatch.Dispatched, true},
+		{dispatch.Acknowledged, dispatch.Dispatched, true},
+		{dispatch.Arrived, dispatch.Dispatched, false},
+		{dispatch.Waiting, dispatch.Acknowledged, false},
+		{dispatch.Dispatched, dispatch.Acknowledged, true},
+		{dispatch.Dispatched, dispatch.Arrived, true},
+		{dispatch.Acknowledged, dispatch.Arrived, true},
+		{dispatch.Waiting, dispatch.Arrived, false},
+		{dispatch.Waiting, dispatch.Cancelled, true},
+		{dispatch.Arrived, dispatch.Cancelled, false},
+		{dispatch.Cancelled, dispatch.Dispatched, false},
+		{dispatch.Arrived, dispatch.Resolved, true},
+		{dispatch.Acknowledged, dispatch.Resolved, false},
+		{dispatch.Resolved, dispatch.Dispatched, false},
+		{dispatch.Resolved, dispatch.Cancelled, false},
+	}
+	for _, test := range tests {
+		if err := dispatch.Advance(test.status, test.next); (err == nil) != test.allowed {
+			t.Errorf("\n[FAIL] %s to %s: %v", test.status, test.next, err)
+		}
+	}
+
+	if priority, err := dispatch.ParsePriority("Critical"); err != nil || priority != dispatch.Critical {
+		t.Errorf("\n[FAIL] \"Critical\" is priority %d %v", priority, err)
+	}
+	if priority, _ := dispatch.ParsePriority(""); priority != dispatch.Normal {
+		t.Errorf("\n[FAIL] No priority is %d - wanted normal", priority)
+	}
+	if _, err := dispatch.ParsePriority("asap"); err == nil {
+		t.Errorf("\n[FAIL] \"asap\" was a priority")
+	}
+}
+
+// Function to test suggesting workers for a breakdown.
+// Passes if only free workers with a recent position are suggested, nearest first, up to the limit.
+func TestDispatchSuggest(t *testing.T) {
+	fmt.Println("[TEST] Testing Dispatch Suggest...")
+
+	now := onWednesday(9, 0)
+	gort := dispatch.Point{Latitude: 53.0661, Longitude: -8.8189}
+	positions := []dispatch.Position{
+		{Username: "galway", Point: dispatch.Point{Latitude: 53.2707, Longitude: -9.0568}, Available: true,
+			ReportedAt: now.Add(-5 * time.Minute)},
+		{Username: "kinvara", Point: dispatch.Point{Latitude: 53.1394, Longitude: -8.9372}, Available: true,
+			ReportedAt: now.Add(-10 * time.Minute)},
+		{Username: "ennis", Point: dispatch.Point{Latitude: 52.8436, Longitude: -8.9864}, Available: true,
+			ReportedAt: now},
+		{Username: "busy", Point: gort, Available: true, Busy: true, ReportedAt: now},
+		{Username: "off", Point: gort, Available: false, ReportedAt: now},
+		{Username: "stale", Point: gort, Available: true, ReportedAt: now.Add(-time.Hour)},
+	}
+
+	suggestions := dispatch.Suggest(gort, positions, dispatch.StraightLine{}, now, 30*time.Minute, 2)
+	var got []string
+	for _, s := range suggestions {
+		got = append(got, s.Username)
+	}
+	if strings.Join(got, ",") != "kinvara,ennis" {
+		t.Errorf("\n[FAIL] Suggested %v - wanted kinvara, ennis", got)
+	}
+	if len(suggestions) > 0 && (suggestions[0].DistanceKm < 10 || suggestions[0].DistanceKm > 12) {
+		t.Errorf("\n[FAIL] Kinvara is %.1f km from Gort", suggestions[0].DistanceKm)
+	}
+}
+
+// Function to test distances by road on the road graph bundled with Horton.
+// Passes if distances follow the roads, are never shorter than a straight line and points off the graph
+// are measured in a straight line.
+func TestDispatchRoads(t *testing.T) {
+	fmt.Println("[TEST] Testing Dispatch Roads...")
+
+	places := readGazetteer(t)
+	file, err := os.Open("../go/dispatch/ie_roads.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	roads, err := dispatch.ReadRoads(file, places)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point := func(location string) dispatch.Point {
+		place, ok := places.Locate(location)
+		if !ok {
+			t.Fatalf("%q is not in the gazetteer", location)
+		}
+		return dispatch.Point{Latitude: place.Latitude, Longitude: place.Longitude}
+	}
+
+	// Galway to Gort by Oranmore.
+	if km := roads.Distance(point("Galway"), point("Gort")); km != 39 {
+		t.Errorf("\n[FAIL] Galway to Gort by road is %.1f km - wanted 39", km)
+	}
+	// Galway to Ennis by Oranmore and Gort.
+	if km := roads.Distance(point("Galway"), point("Ennis")); km != 74 {
+		t.Errorf("\n[FAIL] Galway to Ennis by road is %.1f km - wanted 74", km)
+	}
+	for _, trip := range [][2]string{{"Clifden", "Nenagh"}, {"Westport", "Dublin"}, {"Kilkee", "Tuam"}} {
+		from, to := point(trip[0]), point(trip[1])
+		if road, straight := roads.Distance(from, to), (dispatch.StraightLine{}).Distance(from, to); road < straight {
+			t.Errorf("\n[FAIL] %s to %s is %.1f km by road, shorter than %.1f km straight", trip[0], trip[1], road,
+				straight)
+		}
+	}
+	// Two points nearer each other than to any town.
+	a, b := dispatch.Point{Latitude: 53.20, Longitude: -9.50}, dispatch.Point{Latitude: 53.21, Longitude: -9.50}
+	if road, straight := roads.Distance(a, b), (dispatch.StraightLine{}).Distance(a, b); road != straight {
+		t.Errorf("\n[FAIL] Points off the graph are %.1f km apart by road - wanted %.1f", road, straight)
+	}
+
+	if _, err := dispatch.ReadRoads(strings.NewReader("from,to,km\nGort,Atlantis,5\n"), places); err == nil {
+		t.Errorf("\n[FAIL] Road to a place not in the gazetteer was read")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Estimate Test
+ * Tests for the expiry, decisions and conversion of estimates.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/estimate"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+)
+
+// Function to test the expiry of sent estimates.
+// Passes if an estimate can be decided up to the end of the day it expires on and only once.
+func TestEstimateDecide(t *testing.T) {
+	fmt.Println("[TEST] Testing Estimate Decide...")
+
+	expiresOn, _ := models.ParseDate("2020-06-30")
+	sent := models.Estimate{Status: estimate.Sent, ExpiresOn: &expiresOn}
+
+	lastDay := time.Date(2020, 6, 30, 23, 0, 0, 0, time.UTC)
+	if err := estimate.CanDecide(sent, lastDay); err != nil {
+		t.Errorf("\n[FAIL] Estimate could not be decided on the day it expires: %v", err)
+	}
+
+	nextDay := time.Date(2020, 7, 1, 8, 0, 0, 0, time.UTC)
+	if status := estimate.Status(sent, nextDay); status != estimate.Expired {
+		t.Errorf("\n[FAIL] Status the day after expiry was %s", status)
+	}
+	if err := estimate.CanDecide(sent, nextDay); err == nil {
+		t.Error("\n[FAIL] Expired estimate could be decided")
+	}
+
+	for _, status := range []string{estimate.Draft, estimate.Accepted, estimate.Declined, estimate.Converted} {
+		decided := models.Estimate{Status: status, ExpiresOn: &expiresOn}
+		if err := estimate.CanDecide(decided, lastDay); err == nil {
+			t.Errorf("\n[FAIL] %s estimate could be decided", status)
+		}
+		if got := estimate.Status(decided, nextDay); got != status {
+			t.Errorf("\n[FAIL] %s estimate read as %s after expiry", status, got)
+		}
+	}
+
+	if estimate.IsExpired(models.Date{}, nextDay) {
+		t.Error("\n[FAIL] Estimate without an expiry date expired")
+	}
+}
+
+// Function to test converting an accepted estimate to a report.
+// Passes if the report has the estimate's details, parts and hours of labour rounded up.
+func TestEstimateToReport(t *testing.T) {
+	fmt.Println("[TEST] Testing Estimate To Report...")
+
+	est := models.Estimate{Status: estimate.Accepted, CustomerName: "Joe Kendal", VehicleModel: "Ford Focus",
+		VehicleReg: "191-D-12345", Complaint: "Grinding when braking", Lines: []models.InvoiceLine{
+			{Kind: "labour", Description: "Labour", Quantity: 1.5},
+			{Kind: "part", Description: "Brake pads", Quantity: 2},
+			{Kind: "part", Description: "Brake fluid", Quantity: 0.5},
+			{Kind: "other", Description: "Disposal", Quantity: 1},
+		}}
+	now := time.Date(2020, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	report := estimate.ToReport(est, now)
+	if report.Date.Format("2006-01-02") != "2020-06-03" || report.CustomerName != "Joe Kendal" ||
+		report.VehicleModel != "Ford Focus" || report.VehicleReg != "191-D-12345" ||
+		report.Complaint != "Grinding when braking" {
+		t.Errorf("\n[FAIL] Report was %+v", report)
+	}
+	if report.Parts != "2 x Brake pads, 0.5 x Brake fluid" {
+		t.Errorf("\n[FAIL] Parts were %q", report.Parts)
+	}
+	if report.WorkHours != 2 {
+		t.Errorf("\n[FAIL] WorkHours were %d - wanted 2", report.WorkHours)
+	}
+}
+
+// Function to test the tokens of approval links.
+// Passes if tokens are long enough not to be guessed and are not repeated.
+func TestEstimateNewToken(t *testing.T) {
+	fmt.Println("[TEST] Testing Estimate New Token...")
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		token, err := estimate.NewToken()
+		if err != nil {
+			t.Fatalf("\n[FAIL] NewToken: %v", err)
+		}
+		if len(token) != 32 || seen[token] {
+			t.Fatalf("\n[FAIL] Token %q was too short or repeated", token)
+		}
+		seen[token] = true
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Export Test
+ * Tests for writing exports as CSV and XLSX.
+ */
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/export"
+)
+
+var exportColumns = []export.Column{{Name: "jobReportId", Numeric: true}, {Name: "customerName"}, {Name: "parts"}}
+
+// Function to test the CSV Writer.
+// Passes if the header and rows are written, with values quoted and formulas escaped.
+func TestExportCSV(t *testing.T) {
+	fmt.Println("[TEST] Testing Export CSV...")
+
+	var out bytes.Buffer
+	w, err := export.NewWriter("csv", &out, exportColumns)
+	if err != nil {
+		t.Fatalf("\n[FAIL] NewWriter: %v", err)
+	}
+	w.WriteRow([]string{"1", "Kendal, Joe", "=HYPERLINK(\"x\")"})
+	w.WriteRow([]string{"2", "Seán", "-5"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("\n[FAIL] Close: %v", err)
+	}
+
+	want := "\ufeffjobReportId,customerName,parts\n1,\"Kendal, Joe\",\"'=HYPERLINK(\"\"x\"\")\"\n2,Seán,-5\n"
+	if out.String() != want {
+		t.Errorf("\n[FAIL] CSV was %q - wanted %q", out.String(), want)
+	}
+}
+
+// Function to test the XLSX Writer.
+// Passes if the workbook is a zip with a sheet holding the header and rows.
+func TestExportXLSX(t *testing.T) {
+	fmt.Println("[TEST] Testing Export XLSX...")
+
+	var out bytes.Buffer
+	w, err := export.NewWriter("xlsx", &out, exportColumns)
+	if err != nil {
+		t.Fatalf("\n[FAIL] NewWriter: %v", err)
+	}
+	w.WriteRow([]string{"1", "Joe & Sons <Ltd>", ""})
+	if err := w.Close(); err != nil {
+		t.Fatalf("\n[FAIL] Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("\n[FAIL] XLSX is not a zip: %v", err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := f.Open()
+			data, _ := ioutil.ReadAll(r)
+			sheet = string(data)
+		}
+	}
+
+	for _, want := range []string{`<c r="C1" s="1" t="inlineStr"><is><t>parts</t></is></c>`, `<c r="A2"><v>1</v></c>`,
+		`Joe &amp; Sons &lt;Ltd&gt;`, `</sheetData></worksheet>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("\n[FAIL] Sheet does not contain %q", want)
+		}
+	}
+	if strings.Contains(sheet, `r="C2"`) {
+		t.Errorf("\n[FAIL] Empty cell was written")
+	}
+}
+
+// Function to test a row with the wrong number of values.
+// Passes if WriteRow returns an error.
+func TestExportColumnCount(t *testing.T) {
+	fmt.Println("[TEST] Testing Export Column Count...")
+
+	w, _ := export.NewWriter("csv", ioutil.Discard, exportColumns)
+	if err := w.WriteRow([]string{"1"}); err == nil {
+		t.Errorf("\n[FAIL] WriteRow accepted a row with too few values")
+	}
+}
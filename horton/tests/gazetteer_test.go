@@ -0,0 +1,175 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Gazetteer Test
+ * Tests for finding the coordinates of vehicle locations and the distances between them.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/gazetteer"
+)
+
+// Function to read the gazetteer bundled with Horton.
+func readGazetteer(t *testing.T) *gazetteer.Gazetteer {
+	file, err := os.Open("../go/gazetteer/ie_places.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	places, err := gazetteer.Read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return places
+}
+
+// Function to test finding the place of a vehicle location.
+// Passes if locations are found however they are written, counties pick between places of the same name
+// and unknown or ambiguous locations are not found.
+func TestGazetteerLocate(t *testing.T) {
+	fmt.Println("[TEST] Testing Gazetteer Locate...")
+
+	places := readGazetteer(t)
+	tests := []struct {
+		location, name, county string
+		found                  bool
+	}{
+		{"Gort, Co. Galway", "Gort", "Galway", true},
+		{"gort co galway", "Gort", "Galway", true},
+		{"Main Street, Gort, County Galway", "Gort", "Galway", true},
+		{"Dun Laoghaire", "Dún Laoghaire", "Dublin", true},
+		{"Carrick on Shannon", "Carrick-on-Shannon", "Leitrim", true},
+		{"Ballina, Co. Mayo", "Ballina", "Mayo", true},
+		{"Ballina, Tipperary", "Ballina", "Tipperary", true},
+		{"Kiltartan, Co. Galway", "Kiltartan", "Galway", true},
+		{"Ballina", "", "", false},
+		{"Gort, Co. Mayo", "", "", false},
+		{"Co. Galway", "", "", false},
+		{"Atlantis", "", "", false},
+		{"", "", "", false},
+	}
+	for _, test := range tests {
+		place, ok := places.Locate(test.location)
+		if ok != test.found || place.Name != test.name || place.County != test.county {
+			t.Errorf("\n[FAIL] %q found %v %q, Co. %q - wanted %v %q, Co. %q", test.location, ok, place.Name,
+				place.County, test.found, test.name, test.county)
+		}
+	}
+}
+
+// Function to test suggesting places as a location is typed.
+// Passes if places starting with what has been typed are suggested, towns first, up to the limit.
+func TestGazetteerSuggest(t *testing.T) {
+	fmt.Println("[TEST] Testing Gazetteer Suggest...")
+
+	places := readGazetteer(t)
+
+	suggested := places.Suggest("gor", 10)
+	if len(suggested) != 2 || suggested[0].Label() != "Gorey, Co. Wexford" || suggested[1].Label() != "Gort, Co. Galway" {
+		t.Errorf("\n[FAIL] \"gor\" suggested %v", suggested)
+	}
+	if suggested := places.Suggest("Bally", 3); len(suggested) != 3 || suggested[0].Kind != gazetteer.Town {
+		t.Errorf("\n[FAIL] \"Bally\" suggested %v - wanted 3 towns", suggested)
+	}
+	if suggested := places.Suggest("kilt", 10); len(suggested) != 1 || suggested[0].Kind != gazetteer.Townland {
+		t.Errorf("\n[FAIL] \"kilt\" suggested %v - wanted Kiltartan", suggested)
+	}
+	if suggested := places.Suggest(" ", 10); len(suggested) != 0 {
+		t.Errorf("\n[FAIL] Nothing typed suggested %v", suggested)
+	}
+}
+
+// Function to test the distances between places.
+// Passes if Oranmore is within 20 km of Galway, Gort is not, and the box holds every point within the distance.
+func TestGazetteerDistance(t *testing.T) {
+	fmt.Println("[TEST] Testing Gazetteer Distance...")
+
+	places := readGazetteer(t)
+	galway, _ := places.Locate("Galway")
+	oranmore, _ := places.Locate("Oranmore")
+	gort, _ := places.Locate("Gort")
+
+	if km := gazetteer.Distance(galway.Latitude, galway.Longitude, oranmore.Latitude, oranmore.Longitude); km > 20 {
+		t.Errorf("\n[FAIL] Galway to Oranmore is %.1f km", km)
+	}
+	if km := gazetteer.Distance(galway.Latitude, galway.Longitude, gort.Latitude, gort.Longitude); km < 20 || km > 35 {
+		t.Errorf("\n[FAIL] Galway to Gort is %.1f km", km)
+	}
+	// Dublin to Cork is about 220 km.
+	if km := gazetteer.Distance(53.3498, -6.2603, 51.8985, -8.4756); math.Abs(km-220) > 5 {
+		t.Errorf("\n[FAIL] Dublin to Cork is %.1f km", km)
+	}
+
+	minLat, maxLat, minLon, maxLon := gazetteer.Bounds(galway.Latitude, galway.Longitude, 20)
+	for _, bearing := range []float64{0, 90, 180, 270} {
+		lat, lon := pointFrom(galway.Latitude, galway.Longitude, 19.9, bearing)
+		if lat < minLat || lat > maxLat || lon < minLon || lon > maxLon {
+			t.Errorf("\n[FAIL] Point 19.9 km from Galway at %g degrees is outside the box", bearing)
+		}
+	}
+}
+
+// Function to find the point km from a point in the direction of bearing in degrees.
+func pointFrom(lat, lon, km, bearing float64) (float64, float64) {
+	d := km / 6371
+	lat1, lon1, b := lat*math.Pi/180, lon*math.Pi/180, bearing*math.Pi/180
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return lat2 * 180 / math.Pi, lon2 * 180 / math.Pi
+}
+
+// Function to test building a gazetteer from the townlands.ie download and the GeoNames dump.
+// Passes if townlands and towns in a county are read, other places are skipped, and the file written is read
+// back with each place located.
+func TestGazetteerSources(t *testing.T) {
+	fmt.Println("[TEST] Testing Gazetteer Sources...")
+
+	townlands, err := gazetteer.ReadTownlands(strings.NewReader(
+		"OSM_ID,NAME_TAG,NAME_GA,NAME_EN,CO_NAME,LATITUDE,LONGITUDE\n" +
+			"1,Baile an Mhuilinn,Baile an Mhuilinn,Milltown,Galway,53.61290,-8.89880\n" +
+			"2,Kilcreevanty,,,Galway,53.52660,-8.86210\n" +
+			"3,Nowhere,,,,53.5,-8.8\n"))
+	if err != nil || len(townlands) != 2 || townlands[0].Name != "Milltown" || townlands[1].Name != "Kilcreevanty" {
+		t.Errorf("\n[FAIL] Townlands were %+v, %v", townlands, err)
+	}
+	if _, err := gazetteer.ReadTownlands(strings.NewReader("NAME,COUNTY\n")); err == nil {
+		t.Errorf("\n[FAIL] Townlands without the columns were read")
+	}
+
+	geoRow := func(name, class, admin1, admin2 string) string {
+		return strings.Join([]string{"1", name, name, "", "53.06270", "-8.81930", class, "PPL", "IE", "", admin1,
+			admin2, "", "", "0", "", "30", "Europe/Dublin", "2020-01-01"}, "\t") + "\n"
+	}
+	towns, err := gazetteer.ReadGeoNames(strings.NewReader(geoRow("Gort", "P", "C", "10")+
+		geoRow("Slieve Aughty", "T", "C", "10")+geoRow("Atlantis", "P", "C", "99")),
+		strings.NewReader("IE.C.10\tCounty Galway\tCounty Galway\t2964179\n"))
+	if err != nil || len(towns) != 1 || towns[0].Name != "Gort" || towns[0].County != "Galway" ||
+		towns[0].Kind != gazetteer.Town {
+		t.Errorf("\n[FAIL] Towns were %+v, %v", towns, err)
+	}
+
+	var file bytes.Buffer
+	if err := gazetteer.Write(&file, append(append(towns, townlands...), towns...)); err != nil {
+		t.Fatal(err)
+	}
+	places, err := gazetteer.Read(&file)
+	if err != nil || places.Len() != 3 {
+		t.Fatalf("\n[FAIL] Gazetteer written was not read back: %v", err)
+	}
+	for _, location := range []string{"Gort, Co. Galway", "Milltown, Co. Galway", "Kilcreevanty"} {
+		if _, ok := places.Locate(location); !ok {
+			t.Errorf("\n[FAIL] %s was not located", location)
+		}
+	}
+}
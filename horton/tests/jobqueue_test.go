@@ -0,0 +1,60 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Job Queue Test
+ * Tests for assigning, reassigning and claiming jobs.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/jobqueue"
+)
+
+// Function to test supervisors assigning jobs.
+// Passes if each change is the right action and jobs cannot be given to who has them or moved once complete.
+func TestJobQueueAssign(t *testing.T) {
+	fmt.Println("[TEST] Testing Job Queue Assign...")
+
+	tests := []struct {
+		from, to string
+		complete bool
+		want     string
+	}{
+		{"", "steve_mon", false, jobqueue.Assigned},
+		{"steve_mon", "john_shields", false, jobqueue.Reassigned},
+		{"steve_mon", "", false, jobqueue.Unassigned},
+		{"steve_mon", "steve_mon", false, ""},
+		{"", "", false, ""},
+		{"steve_mon", "john_shields", true, ""},
+	}
+	for _, test := range tests {
+		action, err := jobqueue.Assign(test.from, test.to, test.complete)
+		if action != test.want || (test.want == "") != (err != nil) {
+			t.Errorf("\n[FAIL] Assign %q to %q was %q %v - wanted %q", test.from, test.to, action, err, test.want)
+		}
+	}
+}
+
+// Function to test workers claiming jobs.
+// Passes if only open jobs in the queue can be claimed.
+func TestJobQueueClaim(t *testing.T) {
+	fmt.Println("[TEST] Testing Job Queue Claim...")
+
+	if err := jobqueue.Claim("", "steve_mon", false); err != nil {
+		t.Errorf("\n[FAIL] Job in the queue could not be claimed: %v", err)
+	}
+	if err := jobqueue.Claim("john_shields", "steve_mon", false); err == nil {
+		t.Error("\n[FAIL] Job assigned to another worker was claimed")
+	}
+	if err := jobqueue.Claim("steve_mon", "steve_mon", false); err == nil {
+		t.Error("\n[FAIL] Job was claimed twice")
+	}
+	if err := jobqueue.Claim("", "steve_mon", true); err != jobqueue.ErrComplete {
+		t.Errorf("\n[FAIL] Complete job claimed: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Live Test
+ * Tests for the events of job reports pushed to clients over Server-Sent Events and WebSockets.
+ */
+
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/live"
+)
+
+// Function to test reading events after the last a client was sent.
+// Passes if every event is returned once, including events committed late, and the cursor only moves past events
+// once they have settled.
+func TestLiveCursor(t *testing.T) {
+	fmt.Println("[TEST] Testing Live Cursor...")
+
+	now := onWednesday(9, 0)
+	event := func(id int64, age time.Duration) live.Event {
+		return live.Event{Id: id, Type: live.ReportUpdated, CreatedAt: now.Add(-age)}
+	}
+	fresh := func(cursor *live.Cursor, events ...live.Event) []int64 {
+		var ids []int64
+		for _, e := range events {
+			if cursor.Fresh(e) {
+				ids = append(ids, e.Id)
+			}
+		}
+		return ids
+	}
+
+	cursor := live.NewCursor(10, 10*time.Second)
+	ids := fresh(cursor, event(10, time.Minute), event(11, 20*time.Second), event(13, time.Second))
+	if fmt.Sprint(ids) != "[11 13]" {
+		t.Errorf("\n[FAIL] Fresh events were %v - wanted [11 13]", ids)
+	}
+	cursor.Settle(now)
+	if cursor.After() != 11 {
+		t.Errorf("\n[FAIL] Cursor was after %d - wanted 11", cursor.After())
+	}
+
+	// Event 12 was committed after 13 was read.
+	if ids := fresh(cursor, event(12, 2*time.Second), event(13, time.Second)); fmt.Sprint(ids) != "[12]" {
+		t.Errorf("\n[FAIL] Fresh events were %v - wanted [12]", ids)
+	}
+	cursor.Settle(now.Add(time.Minute))
+	if cursor.After() != 13 {
+		t.Errorf("\n[FAIL] Cursor was after %d - wanted 13", cursor.After())
+	}
+	if ids := fresh(cursor, event(13, time.Second)); len(ids) != 0 {
+		t.Errorf("\n[FAIL] Settled events were returned again %v", ids)
+	}
+}
+
+// Function to test which events clients can see and when they have missed events.
+// Passes if supervisors see every event and workers only their own reports, reports moved away from them and the
+// queue, and clients that were last sent a pruned event are reset.
+func TestLiveScope(t *testing.T) {
+	fmt.Println("[TEST] Testing Live Scope...")
+
+	supervisor := live.Scope{All: true, WorkerId: 1}
+	worker := live.Scope{WorkerId: 2}
+	tests := []struct {
+		name       string
+		event      live.Event
+		supervisor bool
+		worker     bool
+	}{
+		{"own report", live.Event{WorkerId: 2}, true, true},
+		{"other's report", live.Event{WorkerId: 3}, true, false},
+		{"queue", live.Event{}, true, true},
+		{"moved away", live.Event{WorkerId: 3, FromWorkerId: 2}, true, true},
+		{"moved between others", live.Event{WorkerId: 3, FromWorkerId: 4}, true, false},
+	}
+	for _, test := range tests {
+		if supervisor.Allows(test.event) != test.supervisor || worker.Allows(test.event) != test.worker {
+			t.Errorf("\n[FAIL] Scope of %s was wrong", test.name)
+		}
+	}
+
+	for _, test := range []struct {
+		last, oldest int64
+		missed       bool
+	}{{0, 5, false}, {0, 0, false}, {4, 5, false}, {7, 5, false}, {3, 5, true}, {3, 0, true}} {
+		if live.Missed(test.last, test.oldest) != test.missed {
+			t.Errorf("\n[FAIL] Missed after %d with %d the oldest was not %t", test.last, test.oldest, test.missed)
+		}
+	}
+}
+
+// Function to test writing events as Server-Sent Events.
+// Passes if events are written with their ID, name and each line of data.
+func TestLiveWriteEvent(t *testing.T) {
+	fmt.Println("[TEST] Testing Live Write Event...")
+
+	var out bytes.Buffer
+	live.WriteRetry(&out, 3*time.Second)
+	live.WriteEvent(&out, 7, live.ReportStatus, []byte(`{"id":7}`))
+	live.WriteEvent(&out, 0, live.Reset, []byte("a\nb"))
+	want := "retry: 3000\n\nid: 7\nevent: report.status\ndata: {\"id\":7}\n\nevent: reset\ndata: a\ndata: b\n\n"
+	if out.String() != want {
+		t.Errorf("\n[FAIL] Events were %q - wanted %q", out.String(), want)
+	}
+}
+
+// Function to test sending messages over a WebSocket.
+// Passes if the handshake is answered, messages are sent as unmasked text frames, pings are answered and the
+// connection is done once the client closes it.
+func TestLiveWebSocket(t *testing.T) {
+	fmt.Println("[TEST] Testing Live WebSocket...")
+
+	if got := live.Accept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("\n[FAIL] Accept was %s", got)
+	}
+
+	closed := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := live.Upgrade(w, r, []string{"https://repota.example.com/"})
+		if err != nil {
+			return
+		}
+		conn.WriteText([]byte(`{"id":1}`))
+		select {
+		case <-conn.Done():
+			closed <- true
+		case <-time.After(5 * time.Second):
+			closed <- false
+		}
+	}))
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL); err != nil || resp.StatusCode != 400 {
+		t.Errorf("\n[FAIL] Request that was not a handshake was not refused")
+	}
+
+	// A handshake from another site's page is refused, one from the app's is not.
+	for origin, status := range map[string]int{"https://evil.example.com": 403, "null": 403,
+		"https://repota.example.com": 101} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != status {
+			t.Errorf("\n[FAIL] Handshake from %s was answered with %v, %v - wanted %d", origin, resp, err, status)
+			continue
+		}
+		resp.Body.Close()
+		if status == 101 {
+			<-closed
+		}
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: horton\r\nConnection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil || resp.StatusCode != 101 || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("\n[FAIL] Handshake was answered with %v, %v", resp, err)
+	}
+
+	frame := make([]byte, 10)
+	if _, err := io.ReadFull(reader, frame); err != nil || string(frame) != "\x81\x08{\"id\":1}" {
+		t.Errorf("\n[FAIL] Message was %q, %v", frame, err)
+	}
+
+	// Frames from clients are masked, a ping of "hi" and then a close.
+	conn.Write([]byte{0x89, 0x82, 1, 2, 3, 4, 'h' ^ 1, 'i' ^ 2})
+	pong := make([]byte, 4)
+	if _, err := io.ReadFull(reader, pong); err != nil || string(pong) != "\x8a\x02hi" {
+		t.Errorf("\n[FAIL] Pong was %q, %v", pong, err)
+	}
+	conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+	if !<-closed {
+		t.Errorf("\n[FAIL] Connection was not done once the client closed it")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Metrics Test
+ * Tests for writing metrics for Prometheus and recording the queries of the database.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/metrics"
+)
+
+// Function to test writing metrics in the Prometheus text exposition format.
+// Passes if counters, gauges and histograms are written with their help, type, escaped labels and cumulative buckets.
+func TestMetricsWrite(t *testing.T) {
+	fmt.Println("[TEST] Testing Metrics Write...")
+
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("test_requests_total", "Requests by route.", "route", "code")
+	sessions := registry.Gauge("test_sessions", "Sessions.")
+	latency := registry.Histogram("test_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	requests.Inc("Login", "204")
+	requests.Inc("Login", "204")
+	requests.Inc(`Say "hi"`, "500")
+	sessions.Set(3)
+	latency.Observe(0.05, "Login")
+	latency.Observe(0.1, "Login")
+	latency.Observe(2, "Login")
+
+	var out bytes.Buffer
+	if err := registry.Write(&out); err != nil {
+		t.Fatalf("\n[FAIL] Unable to write metrics: %v", err)
+	}
+	want := `# HELP test_requests_total Requests by route.
+# TYPE test_requests_total counter
+test_requests_total{route="Login",code="204"} 2
+test_requests_total{route="Say \"hi\"",code="500"} 1
+# HELP test_sessions Sessions.
+# TYPE test_sessions gauge
+test_sessions 3
+# HELP test_seconds Latency.
+# TYPE test_seconds histogram
+test_seconds_bucket{route="Login",le="0.1"} 2
+test_seconds_bucket{route="Login",le="1"} 2
+test_seconds_bucket{route="Login",le="+Inf"} 3
+test_seconds_sum{route="Login"} 2.15
+test_seconds_count{route="Login"} 3
+`
+	if out.String() != want {
+		t.Errorf("\n[FAIL] Metrics were:\n%s\nwanted:\n%s", out.String(), want)
+	}
+}
+
+// Function to test finding the operation of a query.
+// Passes if queries are recorded by their first word, anything unknown as other.
+func TestMetricsOperation(t *testing.T) {
+	fmt.Println("[TEST] Testing Metrics Operation...")
+
+	tests := map[string]string{
+		"SELECT * FROM workers":                "select",
+		"\n\tinsert INTO session VALUES (?)":   "insert",
+		"(SELECT 1) UNION (SELECT 2)":          "select",
+		"DELETE FROM session WHERE user=?":     "delete",
+		"SHOW TABLES":                          "other",
+		"":                                     "other",
+		"UPDATE jobreports SET status=? WHERE": "update",
+	}
+	for query, want := range tests {
+		if got := metrics.Operation(query); got != want {
+			t.Errorf("\n[FAIL] Operation of %q was %s - wanted %s", query, got, want)
+		}
+	}
+}
+
+// Function to test recording the queries run through a wrapped driver.
+// Passes if queries are timed by operation and those that fail are counted as errors.
+func TestMetricsDriver(t *testing.T) {
+	fmt.Println("[TEST] Testing Metrics Driver...")
+
+	sql.Register("metrics-test", metrics.WrapDriver(fakeDriver{}))
+	db, err := sql.Open("metrics-test", "")
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	selects := metrics.QueryDuration.Count("select")
+	updates := metrics.QueryDuration.Count("update")
+	errs := metrics.QueryErrors.Value("update")
+
+	rows, err := db.Query("SELECT name FROM workers WHERE worker_id=?", 1)
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to query: %v", err)
+	}
+	rows.Close()
+	if _, err := db.Exec("UPDATE workers SET name='fail'"); err == nil {
+		t.Errorf("\n[FAIL] Failing update did not fail")
+	}
+
+	if got := metrics.QueryDuration.Count("select") - selects; got != 1 {
+		t.Errorf("\n[FAIL] %d selects were timed - wanted 1", got)
+	}
+	if got := metrics.QueryDuration.Count("update") - updates; got != 1 {
+		t.Errorf("\n[FAIL] %d updates were timed - wanted 1", got)
+	}
+	if got := metrics.QueryErrors.Value("update") - errs; got != 1 {
+		t.Errorf("\n[FAIL] %v update errors were counted - wanted 1", got)
+	}
+}
+
+// fakeDriver answers every query with no rows, and fails queries with "fail" in them.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("failed")
+	}
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"name"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
@@ -15,7 +15,7 @@ import (
 
 // MockDbConn to set up a Mock Database for testing.
 func MockDbConn() (db *sql.DB) {
-	db, err := sql.Open("mysql", "mock_user:mock@tcp(127.0.0.1:3306)/mock_repotadb")
+	db, err := sql.Open("mysql", "mock_user:mock@tcp(127.0.0.1:3306)/mock_repotadb?parseTime=true")
 
 	if err != nil {
 		panic(err.Error())
@@ -0,0 +1,216 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Notify Test
+ * Tests for the messages customers are sent by email and SMS.
+ */
+
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/notify"
+)
+
+// Function to test the templates Horton ships with.
+// Passes if every event has an email and SMS template, emails have a subject and the opt-out link is only written
+// when there is one.
+func TestNotifyTemplates(t *testing.T) {
+	fmt.Println("[TEST] Testing Notify Templates...")
+
+	templates, err := notify.LoadTemplates("../go/config/notifications")
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to load templates: %v", err)
+	}
+	data := notify.Data{GarageName: "Horton Motors", CustomerName: "Mary", JobReportId: 311, VehicleModel: "Corolla",
+		VehicleReg: "191-G-1234", Link: "http://localhost:8080/x", Total: "€120.00 EUR", ExpiresOn: "2021-03-24",
+		Start: onWednesday(9, 30), Reason: "No bays free", OptOutLink: "http://localhost:8080/optOut"}
+	for _, event := range notify.Events {
+		for _, channel := range notify.Channels {
+			subject, body, ok, err := templates.Render(event, channel, data)
+			if !ok || err != nil {
+				t.Errorf("\n[FAIL] %s.%s was not rendered: %v", event, channel, err)
+				continue
+			}
+			if (channel == notify.Email) != (subject != "") || strings.HasPrefix(body, "Subject:") {
+				t.Errorf("\n[FAIL] %s.%s subject was %q", event, channel, subject)
+			}
+			if strings.Contains(body, "<no value>") {
+				t.Errorf("\n[FAIL] %s.%s was missing data\n%s", event, channel, body)
+			}
+		}
+	}
+
+	_, body, _, _ := templates.Render(notify.JobCompleted, notify.Email, data)
+	if !strings.Contains(body, data.OptOutLink) {
+		t.Errorf("\n[FAIL] Opt-out link was not written\n%s", body)
+	}
+	data.OptOutLink = ""
+	if _, body, _, _ = templates.Render(notify.JobCompleted, notify.Email, data); strings.Contains(body, "stop") {
+		t.Errorf("\n[FAIL] Opt-out was written without a link\n%s", body)
+	}
+	if _, _, ok, _ := (notify.Templates{}).Render(notify.JobCreated, notify.SMS, data); ok {
+		t.Errorf("\n[FAIL] Event without a template was rendered")
+	}
+}
+
+// Function to test checking customers' email addresses and phone numbers.
+// Passes if phone numbers are put in international format and invalid ones are refused.
+func TestNotifyPhone(t *testing.T) {
+	fmt.Println("[TEST] Testing Notify Phone...")
+
+	for phone, want := range map[string]string{
+		"+353 87 123 4567":   "+353871234567",
+		"00353-87-123-4567":  "+353871234567",
+		"+44 (20) 7946.0958": "+442079460958",
+		"087 123 4567":       "",
+		"+353 87":            "",
+		"+353 87 12a 4567":   "",
+		"+0353871234567":     "",
+	} {
+		got, err := notify.NormalisePhone(phone)
+		if got != want || (err == nil) != (want != "") {
+			t.Errorf("\n[FAIL] %q was %q, %v - wanted %q", phone, got, err, want)
+		}
+	}
+
+	for email, valid := range map[string]bool{"mary@example.com": true, "Mary <mary@example.com>": false,
+		"mary": false, "": false} {
+		if (notify.CheckEmail(email) == nil) != valid {
+			t.Errorf("\n[FAIL] %q was not valid %t", email, valid)
+		}
+	}
+	if notify.CheckChannels([]string{"email", "sms"}) != nil || notify.CheckChannels([]string{"post"}) == nil {
+		t.Errorf("\n[FAIL] Channels were not checked")
+	}
+}
+
+// Function to test sending email to an SMTP server, standing in for MailHog.
+// Passes if the email is sent from and to the right addresses with its headers and quoted-printable body.
+func TestNotifySMTP(t *testing.T) {
+	fmt.Println("[TEST] Testing Notify SMTP...")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+		var lines []string
+		data := false
+		fmt.Fprint(conn, "220 mailhog ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case data && line == ".":
+				data = false
+				fmt.Fprint(conn, "250 Ok: queued\r\n")
+			case data:
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprint(conn, "250-mailhog\r\n250 8BITMIME\r\n")
+			case line == "DATA":
+				data = true
+				fmt.Fprint(conn, "354 End data with <CR><LF>.<CR><LF>\r\n")
+			case line == "QUIT":
+				fmt.Fprint(conn, "221 Bye\r\n")
+				received <- lines
+				return
+			default:
+				fmt.Fprint(conn, "250 Ok\r\n")
+			}
+		}
+		received <- lines
+	}()
+
+	sender := notify.SMTP{Addr: listener.Addr().String(), From: "Horton Motors <workshop@example.com>",
+		Timeout: 5 * time.Second}
+	messageId, err := sender.Send(notify.Message{To: "mary@example.com", Subject: "Your Corolla is ready – €120",
+		Body: "Hi Mary,\nYour job is completed.\n" + strings.Repeat("x", 100)})
+	if err != nil {
+		t.Fatalf("\n[FAIL] Unable to send: %v", err)
+	}
+	if !strings.HasPrefix(messageId, "<") || !strings.HasSuffix(messageId, "@example.com>") {
+		t.Errorf("\n[FAIL] Message-ID was %s", messageId)
+	}
+
+	session := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<workshop@example.com>",
+		"RCPT TO:<mary@example.com>",
+		"From: \"Horton Motors\" <workshop@example.com>",
+		"To: mary@example.com",
+		"Subject: =?utf-8?q?Your_Corolla_is_ready_=E2=80=93_=E2=82=AC120?=",
+		"Message-ID: " + messageId,
+		"Content-Transfer-Encoding: quoted-printable",
+		"Hi Mary,\nYour job is completed.\n" + strings.Repeat("x", 75) + "=",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("\n[FAIL] Email was missing %q\n%s", want, session)
+		}
+	}
+
+	if _, err := sender.Send(notify.Message{To: "mary", Body: "Hi"}); err == nil {
+		t.Errorf("\n[FAIL] Email was sent to an invalid address")
+	}
+}
+
+// Function to test sending text messages through an SMS gateway.
+// Passes if messages are posted as JSON with the gateway's token, its ID is kept and failures are errors.
+func TestNotifySMS(t *testing.T) {
+	fmt.Println("[TEST] Testing Notify SMS...")
+
+	fail := false
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(500)
+			fmt.Fprint(w, "  gateway down  ")
+			return
+		}
+		var sms struct{ From, To, Text string }
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer secret" ||
+			r.Header.Get("Content-Type") != "application/json" || json.Unmarshal(body, &sms) != nil ||
+			sms.From != "Repota" || sms.To != "+353871234567" || sms.Text != "Your Corolla is ready" {
+			t.Errorf("\n[FAIL] Gateway was sent %s %s", r.Method, body)
+		}
+		fmt.Fprint(w, `{"id":12345678901234567890,"status":"queued"}`)
+	}))
+	defer gateway.Close()
+
+	sender := notify.SMSGateway{URL: gateway.URL, Token: "secret", From: "Repota", Client: gateway.Client()}
+	message := notify.Message{To: "+353871234567", Body: "Your Corolla is ready"}
+	if id, err := sender.Send(message); err != nil || id != "12345678901234567890" {
+		t.Errorf("\n[FAIL] Sent was %q, %v - wanted 12345678901234567890", id, err)
+	}
+
+	fail = true
+	if _, err := sender.Send(message); err == nil || err.Error() != "gateway answered 500 gateway down" {
+		t.Errorf("\n[FAIL] Failure was %v", err)
+	}
+}
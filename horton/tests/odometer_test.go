@@ -0,0 +1,58 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Odometer Test
+ * Tests for parsing odometer units and converting readings between kilometres and miles.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/odometer"
+)
+
+// Function to test ParseUnit with the units users enter.
+// Passes if each unit is read as kilometres or miles and unknown units are rejected.
+func TestParseUnit(t *testing.T) {
+	fmt.Println("[TEST] Testing ParseUnit...")
+
+	units := map[string]odometer.Unit{
+		"km":         odometer.Kilometres,
+		" KMs ":      odometer.Kilometres,
+		"kilometres": odometer.Kilometres,
+		"mi":         odometer.Miles,
+		"Miles":      odometer.Miles,
+		"":           odometer.Miles, // older clients send no unit
+	}
+
+	for in, want := range units {
+		got, err := odometer.ParseUnit(in)
+		if err != nil || got != want {
+			t.Errorf("\n[FAIL] ParseUnit(%q) = %q, %v - wanted %q", in, got, err, want)
+		}
+	}
+
+	if _, err := odometer.ParseUnit("furlongs"); err == nil {
+		t.Error("\n[FAIL] ParseUnit accepted an unknown unit")
+	}
+}
+
+// Function to test converting readings to and from kilometres.
+// Passes if a reading in miles round trips through kilometres unchanged.
+func TestOdometerConversion(t *testing.T) {
+	fmt.Println("[TEST] Testing Odometer Conversion...")
+
+	if km := odometer.ToKilometres(508538, odometer.Miles); km != 818413 {
+		t.Errorf("\n[FAIL] 508538 mi should be 818413 km, got %d", km)
+	}
+	if km := odometer.ToKilometres(1337, odometer.Kilometres); km != 1337 {
+		t.Errorf("\n[FAIL] km readings should not be converted, got %d", km)
+	}
+	if mi := odometer.FromKilometres(818413, odometer.Miles); mi != 508538 {
+		t.Errorf("\n[FAIL] 818413 km should be 508538 mi, got %d", mi)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Payments Test
+ * Tests for the fake payment provider, balances and ageing of debts.
+ */
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/payments"
+)
+
+// Function to test taking and refunding card payments with the fake provider.
+// Passes if declined cards are refused and no more than was taken can be refunded.
+func TestPaymentsFakeProvider(t *testing.T) {
+	fmt.Println("[TEST] Testing Payments Fake Provider...")
+
+	ctx := context.Background()
+	var provider payments.Provider = payments.NewFakeProvider()
+
+	if _, err := provider.Charge(ctx, 5000, "EUR", payments.DeclinedToken, "Invoice INV-000001"); err != payments.ErrDeclined {
+		t.Errorf("\n[FAIL] Declined card returned %v", err)
+	}
+
+	reference, err := provider.Charge(ctx, 5000, "EUR", "tok_visa", "Invoice INV-000001")
+	if err != nil || reference == "" {
+		t.Fatalf("\n[FAIL] Charge: %q %v", reference, err)
+	}
+	if _, err := provider.Refund(ctx, reference, 3000); err != nil {
+		t.Errorf("\n[FAIL] Part refund: %v", err)
+	}
+	if _, err := provider.Refund(ctx, reference, 2001); err == nil {
+		t.Error("\n[FAIL] Refunded more than was left of the payment")
+	}
+	if _, err := provider.Refund(ctx, reference, 2000); err != nil {
+		t.Errorf("\n[FAIL] Refund of the rest: %v", err)
+	}
+	if _, err := provider.Refund(ctx, "fake_ch_99", 100); err == nil {
+		t.Error("\n[FAIL] Refunded a payment that was never taken")
+	}
+}
+
+// Function to test the buckets debts are aged in.
+// Passes if debts are current until their due date and then bucketed by 30 days overdue.
+func TestPaymentsAge(t *testing.T) {
+	fmt.Println("[TEST] Testing Payments Age...")
+
+	due := time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		asOf string
+		want int
+	}{
+		{"2020-03-01", 0}, {"2020-03-31", 0}, {"2020-04-01", 1}, {"2020-04-30", 1}, {"2020-05-01", 2},
+		{"2020-06-29", 3}, {"2020-06-30", 4},
+	}
+	for _, test := range tests {
+		asOf, _ := time.Parse("2006-01-02", test.asOf)
+		if got := payments.Age(due, asOf.Add(15*time.Hour)); got != test.want {
+			t.Errorf("\n[FAIL] Age on %s was %d - wanted %d", test.asOf, got, test.want)
+		}
+	}
+}
+
+// Function to test the aged debtors report.
+// Passes if each debtor's debts are added up in their buckets and settled or overpaid invoices are left out.
+func TestPaymentsAgeDebts(t *testing.T) {
+	fmt.Println("[TEST] Testing Payments Age Debts...")
+
+	asOf := time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC)
+	debts := []payments.Debt{
+		{BillToName: "Joe Kendal", DueDate: asOf.AddDate(0, 0, 10), OutstandingCents: 1000},
+		{BillToName: "Mary Byrne", DueDate: asOf.AddDate(0, 0, -45), OutstandingCents: 2500},
+		{BillToName: "Joe Kendal", DueDate: asOf.AddDate(0, 0, -100), OutstandingCents: 700},
+		{BillToName: "Mary Byrne", DueDate: asOf.AddDate(0, 0, -5), OutstandingCents: -300},
+	}
+
+	debtors, totals := payments.AgeDebts(debts, asOf)
+	if len(debtors) != 2 || debtors[0].BillToName != "Joe Kendal" || debtors[0].CurrentCents != 1000 ||
+		debtors[0].Over90DaysCents != 700 || debtors[0].TotalCents != 1700 || debtors[0].Invoices != 2 {
+		t.Errorf("\n[FAIL] Debtors were %+v", debtors)
+	}
+	if len(debtors) == 2 && (debtors[1].Days31To60Cents != 2500 || debtors[1].Invoices != 1) {
+		t.Errorf("\n[FAIL] Mary Byrne was %+v", debtors[1])
+	}
+	if totals.TotalCents != 4200 || totals.Invoices != 3 || totals.Days1To30Cents != 0 {
+		t.Errorf("\n[FAIL] Totals were %+v", totals)
+	}
+
+	if got := payments.Outstanding(10000, 2000, 3000); got != 5000 {
+		t.Errorf("\n[FAIL] Outstanding was %d - wanted 5000", got)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Plate Test
+ * Tests for validating and normalising Irish and UK registration plates.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/plate"
+)
+
+// Function to test Normalise with registrations as users type them.
+// Passes if each registration is normalised to the form it is stored in.
+func TestNormalisePlate(t *testing.T) {
+	fmt.Println("[TEST] Testing Normalise Plate...")
+
+	plates := map[string]string{
+		"151-DL-2308": "151-DL-2308",
+		"151 dl 2308": "151-DL-2308",
+		"08ky667":     "08-KY-667",
+		" 99-D-12 ":   "99-D-12",
+		"ab51abc":     "AB51 ABC",
+		"A123 BCD":    "A123 BCD",
+		"abc 123a":    "ABC 123A",
+		"AIZ 1234":    "AIZ 1234",
+	}
+
+	for in, want := range plates {
+		got, err := plate.Normalise(in)
+		if err != nil || got != want {
+			t.Errorf("\n[FAIL] Normalise(%q) = %q, %v - wanted %q", in, got, err, want)
+		}
+	}
+}
+
+// Function to test Parse reads the year and county of a registration.
+// Passes if the year, half-year and county match the plate.
+func TestParsePlate(t *testing.T) {
+	fmt.Println("[TEST] Testing Parse Plate...")
+
+	p, err := plate.Parse("152-G-1234")
+	if err != nil || p.Country != plate.Ireland || p.Year != 2015 || p.HalfYear != 2 || p.County != "Galway" {
+		t.Errorf("\n[FAIL] Parse(152-G-1234) = %+v, %v", p, err)
+	}
+
+	p, err = plate.Parse("08-KY-667")
+	if err != nil || p.Year != 2008 || p.HalfYear != 0 || p.CountyCode != "KY" || p.County != "Kerry" {
+		t.Errorf("\n[FAIL] Parse(08-KY-667) = %+v, %v", p, err)
+	}
+
+	p, err = plate.Parse("92-TN-5")
+	if err != nil || p.Year != 1992 || p.County != "Tipperary North" {
+		t.Errorf("\n[FAIL] Parse(92-TN-5) = %+v, %v", p, err)
+	}
+
+	p, err = plate.Parse("BD51 SMR")
+	if err != nil || p.Country != plate.UnitedKingdom || p.Year != 2001 || p.HalfYear != 2 || p.County != "Birmingham" {
+		t.Errorf("\n[FAIL] Parse(BD51 SMR) = %+v, %v", p, err)
+	}
+}
+
+// Function to test Parse rejects invalid registrations.
+// Passes if each registration returns an error.
+func TestInvalidPlate(t *testing.T) {
+	fmt.Println("[TEST] Testing Invalid Plates...")
+
+	for _, in := range []string{
+		"",            // empty
+		"191-LA-2049", // LA is not a county
+		"54-SF-135",   // 2 digit years stopped in 2012
+		"133-D-1",     // no third half-year
+		"163-TS-1459", // TS merged into T in 2014
+		"11-T-1",      // T was not issued before 2014
+		"08-KY-0667",  // sequence cannot start with 0
+		"AB01 ABC",    // 01 was never issued
+		"HELLO",
+	} {
+		if p, err := plate.Parse(in); err == nil {
+			t.Errorf("\n[FAIL] Parse accepted %q as %+v", in, p)
+		}
+	}
+}
+
+// Function to test the years of UK prefix plates, which changed letter each August from 1983 and each March and
+// September from 1999.
+// Passes if each prefix letter is read as the year it was first issued.
+func TestUKPrefixPlateYears(t *testing.T) {
+	fmt.Println("[TEST] Testing UK Prefix Plate Years...")
+
+	tests := []struct {
+		registration string
+		year         int
+	}{
+		{"A123 ABC", 1983},
+		{"H123 ABC", 1990},
+		{"R123 ABC", 1997},
+		{"S123 ABC", 1998},
+		{"T123 ABC", 1999},
+		{"V123 ABC", 1999},
+		{"W123 ABC", 2000},
+		{"X123 ABC", 2000},
+		{"Y123 ABC", 2001},
+	}
+	for _, test := range tests {
+		p, err := plate.Parse(test.registration)
+		if err != nil || p.Year != test.year {
+			t.Errorf("\n[FAIL] Parse(%s) year = %d, %v - wanted %d", test.registration, p.Year, err, test.year)
+		}
+	}
+}
+
+// Function to test plates of years and half-years that have not started, on 10 April 2021.
+// Passes if plates issued by then are read and later ones are refused.
+func TestFuturePlate(t *testing.T) {
+	fmt.Println("[TEST] Testing Future Plate...")
+
+	now := time.Date(2021, time.April, 10, 12, 0, 0, 0, time.UTC)
+	plates := map[string]bool{
+		"211-D-1":  true,
+		"212-D-1":  false,
+		"221-D-1":  false,
+		"991-D-1":  false,
+		"AB21 ABC": true,
+		"AB71 ABC": false,
+		"AB22 ABC": false,
+		"AB49 ABC": false,
+		"AB70 ABC": true,
+	}
+	for registration, issued := range plates {
+		if _, err := plate.ParseAt(registration, now); (err == nil) != issued {
+			t.Errorf("\n[FAIL] ParseAt(%s) = %v - wanted issued %v", registration, err, issued)
+		}
+	}
+}
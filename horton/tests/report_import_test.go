@@ -0,0 +1,93 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Report Import Test
+ * Tests for reading reports from CSV files with a column mapping.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/reportimport"
+)
+
+const importCSV = "Job Date,Reg,Customer,Miles,Warranty\n" +
+	"06/01/2019,191 lh 2049,Joe Kendal,\"120,000\",yes\n" +
+	"not a date,08-KY-667,Mary Byrne,lots,maybe\n"
+
+// Function to test reading rows with a mapping of fields to columns.
+// Passes if the values are read into reports and bad values are returned as errors on their row.
+func TestImportReadRows(t *testing.T) {
+	fmt.Println("[TEST] Testing Import Read Rows...")
+
+	reader, err := reportimport.NewReader(strings.NewReader(importCSV), reportimport.Mapping{
+		"date": "Job Date", "vehicleReg": "reg", "customerName": "Customer", "milesOnVehicle": "Miles",
+		"warranty": "Warranty"})
+	if err != nil {
+		t.Fatalf("\n[FAIL] NewReader: %v", err)
+	}
+
+	row, err := reader.Read()
+	if err != nil || len(row.Errors) > 0 {
+		t.Fatalf("\n[FAIL] First row: %v %v", err, row.Errors)
+	}
+	if row.Number != 2 || row.Report.Date.String() != "2019-01-06" || row.Report.VehicleReg != "191 lh 2049" ||
+		row.Report.CustomerName != "Joe Kendal" || row.Report.MilesOnVehicle != 120000 || row.Report.Warranty != 1 {
+		t.Errorf("\n[FAIL] First row read as %+v", row)
+	}
+
+	row, _ = reader.Read()
+	fields := map[string]bool{}
+	for _, fe := range row.Errors {
+		fields[fe.Field] = true
+	}
+	if row.Number != 3 || !fields["date"] || !fields["milesOnVehicle"] || !fields["warranty"] || len(fields) != 3 {
+		t.Errorf("\n[FAIL] Second row errors were %v", row.Errors)
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("\n[FAIL] Read after the last row returned %v", err)
+	}
+}
+
+// Function to test mappings that do not match the file.
+// Passes if NewReader refuses unknown fields and missing columns.
+func TestImportBadMapping(t *testing.T) {
+	fmt.Println("[TEST] Testing Import Bad Mapping...")
+
+	for _, mapping := range []reportimport.Mapping{{"date": "Date Of Job"}, {"workerName": "Customer"}} {
+		if _, err := reportimport.NewReader(strings.NewReader(importCSV), mapping); err == nil {
+			t.Errorf("\n[FAIL] NewReader accepted mapping %v", mapping)
+		}
+	}
+	if _, err := reportimport.NewReader(strings.NewReader(""), nil); err != reportimport.ErrNoHeader {
+		t.Errorf("\n[FAIL] NewReader of an empty file returned %v", err)
+	}
+}
+
+// Function to test the error report of rejected rows.
+// Passes if each row is written as it was in the file with its row number and errors.
+func TestImportWriteErrors(t *testing.T) {
+	fmt.Println("[TEST] Testing Import Write Errors...")
+
+	reader, _ := reportimport.NewReader(strings.NewReader(importCSV), reportimport.Mapping{"date": "Job Date"})
+	reader.Read()
+	row, _ := reader.Read()
+
+	var out bytes.Buffer
+	if err := reportimport.WriteErrors(&out, reader.Header(), []reportimport.Row{row}); err != nil {
+		t.Fatalf("\n[FAIL] WriteErrors: %v", err)
+	}
+	want := "row,errors,Job Date,Reg,Customer,Miles,Warranty\n" +
+		"3,\"date: invalid date \"\"not a date\"\", expected YYYY-MM-DD or DD-MM-YYYY\",not a date,08-KY-667,Mary Byrne,lots,maybe\n"
+	if out.String() != want {
+		t.Errorf("\n[FAIL] Error report was %q - wanted %q", out.String(), want)
+	}
+}
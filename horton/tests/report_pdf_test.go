@@ -0,0 +1,62 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Report PDF Test
+ * Tests for rendering a job report as a PDF job sheet.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/reportpdf"
+)
+
+func pdfTestReport() models.JobReport {
+	date, _ := models.ParseDate("2019-01-06")
+	return models.JobReport{JobReportId: 1, Date: date, VehicleModel: "Ford Focus", VehicleReg: "191-LH-2049",
+		VehicleRegYear: 2019, VehicleRegCounty: "Louth", VehicleLocation: "Dundalk", OdometerReading: 12000,
+		OdometerUnit: "km", CustomerName: "Seán Ó Súilleabháin", Complaint: "Engine light on", Cause: "Faulty sensor",
+		Correction: "Replaced sensor", Parts: "O2 sensor", WorkHours: 2, WorkerName: "John", JobComplete: 1}
+}
+
+// Function to test Render without a signature.
+// Passes if a PDF is written.
+func TestRenderReportPdf(t *testing.T) {
+	fmt.Println("[TEST] Testing Render Report PDF...")
+
+	var out bytes.Buffer
+	garage := models.Garage{Name: "Repota", Address: "Main Street, Dundalk", Phone: "042 123 4567"}
+	if err := reportpdf.Render(&out, pdfTestReport(), garage, nil); err != nil {
+		t.Fatalf("\n[FAIL] Render: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+		t.Errorf("\n[FAIL] Render did not write a PDF")
+	}
+}
+
+// Function to test Render with a signature drawn as strokes and a logo that does not exist.
+// Passes if a PDF is still written, the missing logo is left out.
+func TestRenderReportPdfSigned(t *testing.T) {
+	fmt.Println("[TEST] Testing Render Signed Report PDF...")
+
+	report := pdfTestReport()
+	report.Signed, report.ModifiedAfterSigning = true, true
+	signature := &models.Signature{SignerName: "Seán", SignedAt: time.Now(),
+		Strokes: [][]models.StrokePoint{{{X: 0, Y: 0}, {X: 40, Y: 20}, {X: 80, Y: 5}}}}
+
+	var out bytes.Buffer
+	garage := models.Garage{Name: "Repota", LogoPath: "missing-logo.png"}
+	if err := reportpdf.Render(&out, report, garage, signature); err != nil {
+		t.Fatalf("\n[FAIL] Render: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+		t.Errorf("\n[FAIL] Render did not write a PDF")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Schedule Test
+ * Tests for placing appointments in the workshop diary and the iCalendar feeds of them.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/ical"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/models"
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/schedule"
+)
+
+// A workshop with two bays open 08:00 to 18:00 on weekdays, in UTC.
+var workshop = schedule.Settings{Bays: 2, Slot: 30 * time.Minute, Opens: 8 * time.Hour, Closes: 18 * time.Hour,
+	Days: map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true,
+		time.Friday: true}, Location: time.UTC}
+
+// Function to make the time of day on Wednesday 3 June 2020.
+func onWednesday(hour, minute int) time.Time {
+	return time.Date(2020, 6, 3, hour, minute, 0, 0, time.UTC)
+}
+
+// Function to test checking appointments are within opening hours.
+// Passes if appointments must be whole slots on open days between opening and closing.
+func TestScheduleCheck(t *testing.T) {
+	fmt.Println("[TEST] Testing Schedule Check...")
+
+	tests := []struct {
+		start, end time.Time
+		valid      bool
+	}{
+		{onWednesday(8, 0), onWednesday(9, 0), true},
+		{onWednesday(17, 30), onWednesday(18, 0), true},
+		{onWednesday(7, 30), onWednesday(8, 30), false},
+		{onWednesday(17, 30), onWednesday(18, 30), false},
+		{onWednesday(9, 15), onWednesday(9, 45), false},
+		{onWednesday(9, 0), onWednesday(9, 20), false},
+		{onWednesday(9, 0), onWednesday(9, 0), false},
+		{onWednesday(9, 0).AddDate(0, 0, 3), onWednesday(10, 0).AddDate(0, 0, 3), false}, // Saturday
+	}
+	for _, test := range tests {
+		if err := workshop.Check(test.start, test.end); (err == nil) != test.valid {
+			t.Errorf("\n[FAIL] %s to %s: %v", test.start.Format("Mon 15:04"), test.end.Format("15:04"), err)
+		}
+	}
+}
+
+// Function to test placing appointments in bays.
+// Passes if appointments get the first free bay and clashes of bays and workers are refused.
+func TestSchedulePlace(t *testing.T) {
+	fmt.Println("[TEST] Testing Schedule Place...")
+
+	bookings := []schedule.Booking{
+		{AppointmentId: 1, WorkerId: 141, Bay: 1, Start: onWednesday(9, 0), End: onWednesday(10, 0)},
+	}
+
+	bay, err := workshop.Place(bookings, schedule.Booking{Start: onWednesday(9, 30), End: onWednesday(10, 30)})
+	if err != nil || bay != 2 {
+		t.Errorf("\n[FAIL] Overlapping appointment got bay %d %v - wanted 2", bay, err)
+	}
+	if _, err := workshop.Place(bookings, schedule.Booking{Bay: 1, Start: onWednesday(9, 30),
+		End: onWednesday(10, 0)}); err != schedule.ErrBayTaken {
+		t.Errorf("\n[FAIL] Taken bay returned %v", err)
+	}
+	if _, err := workshop.Place(bookings, schedule.Booking{WorkerId: 141, Start: onWednesday(9, 30),
+		End: onWednesday(10, 0)}); err != schedule.ErrWorkerBusy {
+		t.Errorf("\n[FAIL] Busy worker returned %v", err)
+	}
+	if bay, err := workshop.Place(bookings, schedule.Booking{WorkerId: 141, Start: onWednesday(10, 0),
+		End: onWednesday(11, 0)}); err != nil || bay != 1 {
+		t.Errorf("\n[FAIL] Appointment straight after got bay %d %v - wanted 1", bay, err)
+	}
+
+	bookings = append(bookings, schedule.Booking{AppointmentId: 2, Bay: 2, Start: onWednesday(9, 0),
+		End: onWednesday(9, 30)})
+	if _, err := workshop.Place(bookings, schedule.Booking{Start: onWednesday(9, 0), End: onWednesday(9, 30)}); err != schedule.ErrNoBay {
+		t.Errorf("\n[FAIL] Full workshop returned %v", err)
+	}
+
+	// Moving an appointment does not clash with itself.
+	if bay, err := workshop.Place(bookings, schedule.Booking{AppointmentId: 1, WorkerId: 141, Bay: 1,
+		Start: onWednesday(9, 30), End: onWednesday(10, 30)}); err != nil || bay != 1 {
+		t.Errorf("\n[FAIL] Moved appointment got bay %d %v - wanted 1", bay, err)
+	}
+}
+
+// Function to test the free slots of a day.
+// Passes if only times with a free bay for the whole appointment are returned with the bays free.
+func TestScheduleSlots(t *testing.T) {
+	fmt.Println("[TEST] Testing Schedule Slots...")
+
+	bookings := []schedule.Booking{
+		{AppointmentId: 1, WorkerId: 141, Bay: 1, Start: onWednesday(8, 0), End: onWednesday(12, 0)},
+		{AppointmentId: 2, Bay: 2, Start: onWednesday(8, 0), End: onWednesday(9, 0)},
+	}
+
+	slots := workshop.Slots(bookings, onWednesday(0, 0), time.Hour, 0)
+	if len(slots) != 17 || !slots[0].Start.Equal(onWednesday(9, 0)) || slots[0].FreeBays != 1 {
+		t.Fatalf("\n[FAIL] Slots were %+v", slots)
+	}
+	if last := slots[len(slots)-1]; !last.Start.Equal(onWednesday(17, 0)) || last.FreeBays != 2 {
+		t.Errorf("\n[FAIL] Last slot was %+v", last)
+	}
+
+	if slots := workshop.Slots(bookings, onWednesday(0, 0), time.Hour, 141); !slots[0].Start.Equal(onWednesday(12, 0)) {
+		t.Errorf("\n[FAIL] First slot the worker is free was %+v", slots[0])
+	}
+	if slots := workshop.Slots(nil, onWednesday(0, 0).AddDate(0, 0, 4), time.Hour, 0); len(slots) != 0 {
+		t.Errorf("\n[FAIL] Sunday had %d slots", len(slots))
+	}
+
+	report := workshop.ToReport(models.Appointment{Start: onWednesday(9, 0), CustomerName: "Joe Kendal",
+		VehicleReg: "191-D-12345", Complaint: "Flat battery"})
+	if report.Date.Format("2006-01-02") != "2020-06-03" || report.CustomerName != "Joe Kendal" ||
+		report.VehicleReg != "191-D-12345" || report.Complaint != "Flat battery" {
+		t.Errorf("\n[FAIL] Report was %+v", report)
+	}
+}
+
+// Function to test writing an iCalendar file.
+// Passes if lines end in CRLF, are folded at 75 octets, text is escaped and each event has its status.
+func TestICalWrite(t *testing.T) {
+	fmt.Println("[TEST] Testing iCal Write...")
+
+	events := []ical.Event{{UID: "appointment-1@horton", Start: onWednesday(9, 0), End: onWednesday(10, 0),
+		Summary: "191-D-12345 Ford Focus - Joe Kendal", Description: "Grinding when braking; front, left\nPhone: 087",
+		Location: strings.Repeat("Bay é ", 20), Cancelled: true},
+		{UID: "appointment-2@horton", Start: onWednesday(11, 0), End: onWednesday(12, 0), Summary: "Online booking",
+			Tentative: true}}
+	var out bytes.Buffer
+	if err := ical.Write(&out, "Horton Garage", events, onWednesday(8, 0)); err != nil {
+		t.Fatalf("\n[FAIL] Write: %v", err)
+	}
+
+	file := out.String()
+	if !strings.HasPrefix(file, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(file, "END:VCALENDAR\r\n") {
+		t.Errorf("\n[FAIL] Calendar was\n%s", file)
+	}
+	for _, want := range []string{"DTSTART:20200603T090000Z\r\n", "DTSTAMP:20200603T080000Z\r\n",
+		`DESCRIPTION:Grinding when braking\; front\, left\nPhone: 087` + "\r\n", "STATUS:CANCELLED\r\n",
+		"STATUS:TENTATIVE\r\n"} {
+		if !strings.Contains(file, want) {
+			t.Errorf("\n[FAIL] Calendar did not have %q", want)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(file, "\r\n"), "\r\n") {
+		if len(line) > 75 || strings.Contains(line, "\n") {
+			t.Errorf("\n[FAIL] Line was not folded: %q", line)
+		}
+	}
+	unfolded := strings.Replace(file, "\r\n ", "", -1)
+	if !strings.Contains(unfolded, "LOCATION:"+strings.Repeat("Bay é ", 20)+"\r\n") {
+		t.Errorf("\n[FAIL] Folded line did not unfold to the location")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * SLA Test
+ * Tests for working out whether breakdowns kept to their response time targets and compliance reports.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/sla"
+)
+
+// Function to test the outcome of each stage of a breakdown against its targets.
+// Passes if stages reached by their target are met, reached late or overdue are breached, stages without a target
+// are left out and the rest are pending.
+func TestSLAEvaluate(t *testing.T) {
+	fmt.Println("[TEST] Testing SLA Evaluate...")
+
+	requested := onWednesday(9, 0)
+	at := func(minutes int) *time.Time {
+		t := requested.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	targets := sla.Targets{AcknowledgeMinutes: 10, ArriveMinutes: 60}
+	times := sla.Times{Requested: requested, Acknowledged: at(10)}
+
+	results := sla.Evaluate(targets, times, requested.Add(30*time.Minute))
+	if len(results) != 2 || results[0].Outcome != sla.Met || *results[0].Minutes != 10 ||
+		results[1].Outcome != sla.Pending {
+		t.Fatalf("\n[FAIL] At 30 minutes got %+v - wanted acknowledge met and arrive pending", results)
+	}
+	if !results[1].DueWithin(requested.Add(50*time.Minute), 10*time.Minute) ||
+		results[1].DueWithin(requested.Add(30*time.Minute), 10*time.Minute) {
+		t.Errorf("\n[FAIL] Arrive is at risk from 50 minutes, not before")
+	}
+
+	if results = sla.Evaluate(targets, times, requested.Add(61*time.Minute)); results[1].Outcome != sla.Breached {
+		t.Errorf("\n[FAIL] Arrive overdue at 61 minutes is %s - wanted breached", results[1].Outcome)
+	}
+	times.Arrived = at(75)
+	if results = sla.Evaluate(targets, times, requested.Add(2*time.Hour)); results[1].Outcome != sla.Breached ||
+		*results[1].Minutes != 75 {
+		t.Errorf("\n[FAIL] Arrived at 75 minutes is %s - wanted breached", results[1].Outcome)
+	}
+
+	if err := (sla.Targets{AcknowledgeMinutes: 30, ArriveMinutes: 20}).Check(); err == nil {
+		t.Errorf("\n[FAIL] Arrive target sooner than acknowledge was allowed")
+	}
+	if err := (sla.Targets{AcknowledgeMinutes: 30, ResolveMinutes: 240}).Check(); err != nil {
+		t.Errorf("\n[FAIL] Targets without arrive were refused: %v", err)
+	}
+}
+
+// Function to test tallying breakdowns into a compliance report.
+// Passes if compliance is the percentage met of the stages decided and pending stages are only counted.
+func TestSLASummarise(t *testing.T) {
+	fmt.Println("[TEST] Testing SLA Summarise...")
+
+	minutes := func(m int) *int { return &m }
+	results := [][]sla.Result{
+		{{Stage: sla.Acknowledge, Minutes: minutes(5), Outcome: sla.Met},
+			{Stage: sla.Arrive, Minutes: minutes(50), Outcome: sla.Met}},
+		{{Stage: sla.Acknowledge, Minutes: minutes(15), Outcome: sla.Breached},
+			{Stage: sla.Arrive, Outcome: sla.Pending}},
+		{{Stage: sla.Acknowledge, Minutes: minutes(7), Outcome: sla.Met}},
+		{},
+	}
+
+	tallies := sla.Summarise(results)
+	if len(tallies) != 3 || tallies[0].Stage != sla.Acknowledge || tallies[2].Stage != sla.Resolve {
+		t.Fatalf("\n[FAIL] Got tallies %+v - wanted one for each stage in order", tallies)
+	}
+	ack, arrive, resolve := tallies[0], tallies[1], tallies[2]
+	if ack.Total != 3 || ack.Met != 2 || ack.Breached != 1 {
+		t.Errorf("\n[FAIL] Acknowledge tally is %+v", ack)
+	}
+	if compliance := ack.Compliance(); compliance == nil || fmt.Sprintf("%.1f", *compliance) != "66.7" {
+		t.Errorf("\n[FAIL] Acknowledge compliance is %v - wanted 66.7", compliance)
+	}
+	if average := ack.AverageMinutes(); average == nil || *average != 9 {
+		t.Errorf("\n[FAIL] Acknowledge average is %v - wanted 9", average)
+	}
+	if compliance := arrive.Compliance(); arrive.Pending != 1 || compliance == nil || *compliance != 100 {
+		t.Errorf("\n[FAIL] Arrive tally is %+v with compliance %v - wanted 1 pending and 100", arrive, compliance)
+	}
+	if resolve.Total != 0 || resolve.Compliance() != nil || resolve.AverageMinutes() != nil {
+		t.Errorf("\n[FAIL] Resolve without results is %+v", resolve)
+	}
+
+	dublin, _ := time.LoadLocation("Europe/Dublin")
+	start, end, err := sla.Month("2020-06", dublin)
+	if err != nil || start.UTC().Format(time.RFC3339) != "2020-05-31T23:00:00Z" || end.Month() != time.July {
+		t.Errorf("\n[FAIL] June 2020 is %s to %s %v", start.UTC(), end.UTC(), err)
+	}
+	if _, _, err := sla.Month("June", dublin); err == nil {
+		t.Errorf("\n[FAIL] \"June\" was a month")
+	}
+}
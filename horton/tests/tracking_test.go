@@ -0,0 +1,87 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Tracking Test
+ * Tests for checking the GPS pings of workers' devices and the live feed of where workers are.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/dispatch"
+)
+
+// Function to test checking pings before they are kept.
+// Passes if pings off the map, ahead of the server's clock or older than pings are kept are refused.
+func TestTrackingPingRules(t *testing.T) {
+	fmt.Println("[TEST] Testing Tracking Ping Rules...")
+
+	now := onWednesday(9, 0)
+	rules := dispatch.PingRules{Retention: 30 * 24 * time.Hour, Skew: 2 * time.Minute}
+	gort := dispatch.Point{Latitude: 53.0661, Longitude: -8.8189}
+	negative := -1.0
+
+	tests := []struct {
+		name string
+		ping dispatch.Ping
+		ok   bool
+	}{
+		{"now", dispatch.Ping{Point: gort, RecordedAt: now}, true},
+		{"out of coverage", dispatch.Ping{Point: gort, RecordedAt: now.Add(-6 * time.Hour)}, true},
+		{"clock ahead", dispatch.Ping{Point: gort, RecordedAt: now.Add(time.Minute)}, true},
+		{"future", dispatch.Ping{Point: gort, RecordedAt: now.Add(time.Hour)}, false},
+		{"expired", dispatch.Ping{Point: gort, RecordedAt: now.AddDate(0, 0, -31)}, false},
+		{"off the map", dispatch.Ping{Point: dispatch.Point{Latitude: 95, Longitude: -8.8}, RecordedAt: now}, false},
+		{"negative accuracy", dispatch.Ping{Point: gort, AccuracyM: &negative, RecordedAt: now}, false},
+	}
+	for _, test := range tests {
+		if err := rules.Check(test.ping, now); (err == nil) != test.ok {
+			t.Errorf("\n[FAIL] Ping %s: %v", test.name, err)
+		}
+	}
+
+	latest := dispatch.Latest([]dispatch.Ping{tests[1].ping, tests[0].ping, tests[1].ping})
+	if !latest.RecordedAt.Equal(now) {
+		t.Errorf("\n[FAIL] Latest ping was recorded at %s - wanted %s", latest.RecordedAt, now)
+	}
+}
+
+// Function to test the live feed of where workers are.
+// Passes if only changed positions are sent after the first update, and workers on a call-out are alerted on
+// once when they go silent and once when they are heard from.
+func TestTrackingFeed(t *testing.T) {
+	fmt.Println("[TEST] Testing Tracking Feed...")
+
+	now := onWednesday(9, 0)
+	feed := dispatch.NewFeed(15 * time.Minute)
+	positions := []dispatch.Position{
+		{WorkerId: 2, Username: "kinvara", Available: true, Busy: true, ReportedAt: now.Add(-10 * time.Minute)},
+		{WorkerId: 1, Username: "gort", Available: true, ReportedAt: now.Add(-time.Hour)},
+	}
+
+	changed, silent, heard := feed.Update(positions, now)
+	if len(changed) != 2 || changed[0].Username != "gort" || len(silent) != 0 || len(heard) != 0 {
+		t.Errorf("\n[FAIL] First update sent %v, %v silent and %v heard - wanted every position", changed, silent,
+			heard)
+	}
+
+	// Ten minutes later kinvara has not pinged, gort is not on a call-out so is never silent.
+	changed, silent, _ = feed.Update(positions, now.Add(10*time.Minute))
+	if len(changed) != 0 || len(silent) != 1 || silent[0].Username != "kinvara" {
+		t.Errorf("\n[FAIL] Sent %v and %v silent - wanted kinvara silent", changed, silent)
+	}
+	if _, silent, _ = feed.Update(positions, now.Add(11*time.Minute)); len(silent) != 0 {
+		t.Errorf("\n[FAIL] Kinvara was alerted on twice")
+	}
+
+	positions[0].ReportedAt = now.Add(12 * time.Minute)
+	changed, silent, heard = feed.Update(positions, now.Add(12*time.Minute))
+	if len(changed) != 1 || len(silent) != 0 || len(heard) != 1 || heard[0].Username != "kinvara" {
+		t.Errorf("\n[FAIL] Sent %v, %v silent and %v heard - wanted kinvara heard", changed, silent, heard)
+	}
+}
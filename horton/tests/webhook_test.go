@@ -0,0 +1,156 @@
+/*
+ * John Shields
+ * Horton API - Tests
+ *
+ * Webhook Test
+ * Tests for signing the events sent to webhook endpoints, retrying failed deliveries and sending events.
+ */
+
+package tests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/GIT_USER_ID/GIT_REPO_ID/go/webhook"
+)
+
+// Function to test signing events and checking their signatures.
+// Passes if a signature only matches its own secret, body and timestamp, and old timestamps are refused.
+func TestWebhookSign(t *testing.T) {
+	fmt.Println("[TEST] Testing Webhook Sign...")
+
+	now := onWednesday(9, 0)
+	body := []byte(`{"id":1,"type":"report.completed"}`)
+	signature := webhook.Sign("whsec_test", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		now       time.Time
+		ok        bool
+	}{
+		{"matching", "whsec_test", timestamp, string(body), now, true},
+		{"received later", "whsec_test", timestamp, string(body), now.Add(4 * time.Minute), true},
+		{"other secret", "whsec_other", timestamp, string(body), now, false},
+		{"changed body", "whsec_test", timestamp, `{"id":1,"type":"report.deleted"}`, now, false},
+		{"changed timestamp", "whsec_test", strconv.FormatInt(now.Unix()+1, 10), string(body), now, false},
+		{"replayed", "whsec_test", timestamp, string(body), now.Add(10 * time.Minute), false},
+		{"no timestamp", "whsec_test", "", string(body), now, false},
+	}
+	for _, test := range tests {
+		err := webhook.Verify(test.secret, signature, test.timestamp, []byte(test.body), test.now, 5*time.Minute)
+		if (err == nil) != test.ok {
+			t.Errorf("\n[FAIL] Signature %s: %v", test.name, err)
+		}
+	}
+
+	if webhook.CheckEvents([]string{webhook.ReportCompleted, webhook.ReportDeleted, webhook.BreakdownSLABreached}) != nil {
+		t.Errorf("\n[FAIL] Known events were refused")
+	}
+	if webhook.CheckEvents([]string{"report.signed"}) == nil || webhook.CheckEvents(nil) == nil {
+		t.Errorf("\n[FAIL] Unknown or no events were allowed")
+	}
+}
+
+// Function to test refusing endpoints on this host or a private network.
+// Passes if loopback, private and link-local addresses are refused when added and when connected to, unless private
+// addresses are allowed.
+func TestWebhookAddresses(t *testing.T) {
+	fmt.Println("[TEST] Testing Webhook Addresses...")
+
+	for address, blocked := range map[string]bool{"127.0.0.1": true, "169.254.169.254": true, "10.1.2.3": true,
+		"172.20.0.1": true, "192.168.1.10": true, "100.64.0.1": true, "0.0.0.0": true, "::1": true, "fe80::1": true,
+		"fd00::1": true, "::ffff:127.0.0.1": true, "93.184.216.34": false, "172.32.0.1": false,
+		"2606:2800:220:1::1": false} {
+		if got := webhook.Blocked(net.ParseIP(address)); got != blocked {
+			t.Errorf("\n[FAIL] %s was blocked %t - wanted %t", address, got, blocked)
+		}
+	}
+
+	for rawURL, ok := range map[string]bool{"http://169.254.169.254/latest/meta-data/": false,
+		"http://[::1]:8080/hook": false, "https://localhost/hook": false, "https://93.184.216.34/hook": true} {
+		if err := webhook.CheckURL(rawURL); (err == nil) != ok {
+			t.Errorf("\n[FAIL] %s was checked %v", rawURL, err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	message := webhook.Message{DeliveryId: 8, Event: webhook.ReportCreated, URL: server.URL, Secret: "whsec_test",
+		Body: []byte(`{"id":3}`)}
+	if attempt := webhook.Send(webhook.NewClient(time.Second, false), message, time.Now()); attempt.Delivered() ||
+		attempt.StatusCode != 0 {
+		t.Errorf("\n[FAIL] Attempt to this host was %+v - wanted refused", attempt)
+	}
+	if attempt := webhook.Send(webhook.NewClient(time.Second, true), message, time.Now()); !attempt.Delivered() {
+		t.Errorf("\n[FAIL] Attempt with private addresses allowed was %+v - wanted delivered", attempt)
+	}
+}
+
+// Function to test how long failed deliveries wait before they are tried again.
+// Passes if the wait doubles after each failure up to the most, and stops after the most attempts.
+func TestWebhookBackoff(t *testing.T) {
+	fmt.Println("[TEST] Testing Webhook Backoff...")
+
+	backoff := webhook.Backoff{Base: 30 * time.Second, Max: 5 * time.Minute, MaxAttempts: 6}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if wait, ok := backoff.Next(i + 1); !ok || wait != w {
+			t.Errorf("\n[FAIL] After %d failures waited %s, %t - wanted %s", i+1, wait, ok, w)
+		}
+	}
+	if _, ok := backoff.Next(6); ok {
+		t.Errorf("\n[FAIL] Delivery was tried again after the most attempts")
+	}
+}
+
+// Function to test sending an event to an endpoint.
+// Passes if the endpoint is sent the body with headers it can check the signature with, and only 2xx statuses are
+// delivered.
+func TestWebhookSend(t *testing.T) {
+	fmt.Println("[TEST] Testing Webhook Send...")
+
+	status := http.StatusOK
+	var verified error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		verified = webhook.Verify("whsec_test", r.Header.Get(webhook.SignatureHeader),
+			r.Header.Get(webhook.TimestampHeader), body, time.Now(), time.Minute)
+		if r.Header.Get(webhook.EventHeader) != webhook.ReportCompleted || r.Header.Get(webhook.DeliveryHeader) != "7" {
+			t.Errorf("\n[FAIL] Sent headers %v", r.Header)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("thanks"))
+	}))
+	defer server.Close()
+
+	message := webhook.Message{DeliveryId: 7, Event: webhook.ReportCompleted, URL: server.URL, Secret: "whsec_test",
+		Body: []byte(`{"id":3}`)}
+	attempt := webhook.Send(server.Client(), message, time.Now())
+	if !attempt.Delivered() || attempt.Response != "thanks" || verified != nil {
+		t.Errorf("\n[FAIL] Attempt was %+v, signature %v - wanted delivered", attempt, verified)
+	}
+
+	status = http.StatusServiceUnavailable
+	if attempt := webhook.Send(server.Client(), message, time.Now()); attempt.Delivered() || attempt.StatusCode != status {
+		t.Errorf("\n[FAIL] Attempt was %+v - wanted not delivered", attempt)
+	}
+
+	message.URL = "http://127.0.0.1:0"
+	if attempt := webhook.Send(server.Client(), message, time.Now()); attempt.Delivered() || attempt.Error == "" {
+		t.Errorf("\n[FAIL] Attempt was %+v - wanted an error", attempt)
+	}
+}